@@ -0,0 +1,137 @@
+package genjson
+
+import (
+	"errors"
+	"io"
+	"math"
+)
+
+// Aggregate holds the statistics of a group of values.
+type Aggregate struct {
+	// Count is the number of values in the group.
+	Count int
+	// Numbers is the number of values in the group with a number at the aggregated path. Sum, Min
+	// and Max are only meaningful if Numbers is greater than 0.
+	Numbers int
+	Sum     float64
+	Min     float64
+	Max     float64
+}
+
+func (a *Aggregate) add(v Value, path []string) {
+	a.Count++
+	n, ok := lookupPath(v, path).(Number)
+	if !ok {
+		return
+	}
+	f := n.float64()
+	if a.Numbers == 0 {
+		a.Min, a.Max = f, f
+	}
+	a.Numbers++
+	a.Sum += f
+	a.Min = math.Min(a.Min, f)
+	a.Max = math.Max(a.Max, f)
+}
+
+// Value returns the aggregate as an object with the keys count and numbers, along with sum, min
+// and max if the group contained any numbers.
+func (a *Aggregate) Value() Object {
+	var o Object
+	o.Add("count", Number{Integer: uint64(a.Count)})
+	o.Add("numbers", Number{Integer: uint64(a.Numbers)})
+	if a.Numbers > 0 {
+		o.Add("sum", floatNumber(a.Sum))
+		o.Add("min", floatNumber(a.Min))
+		o.Add("max", floatNumber(a.Max))
+	}
+	return o
+}
+
+// Aggregator folds a stream of values, such as json lines log records, into an Aggregate per
+// group. The zero Aggregator counts every value in a single group.
+type Aggregator struct {
+	// GroupBy is the path of the value used to group values. Strings are used as the group key
+	// as is, other values are serialized and missing values have the key "". If GroupBy is empty,
+	// every value is in the group "".
+	GroupBy []string
+	// Path is the path of the number summarized by Sum, Min and Max. An empty path refers to the
+	// value itself.
+	Path []string
+
+	groups map[string]*Aggregate
+	keys   []string
+}
+
+// Add adds the value to its group.
+func (ag *Aggregator) Add(v Value) {
+	key := ""
+	if len(ag.GroupBy) > 0 {
+		key = groupKey(lookupPath(v, ag.GroupBy))
+	}
+	a, ok := ag.groups[key]
+	if !ok {
+		if ag.groups == nil {
+			ag.groups = make(map[string]*Aggregate)
+		}
+		a = &Aggregate{}
+		ag.groups[key] = a
+		ag.keys = append(ag.keys, key)
+	}
+	a.add(v, ag.Path)
+}
+
+// Decode adds every value read from the decoder. It stops at the first error other than io.EOF.
+func (ag *Aggregator) Decode(dec *Decoder) error {
+	for {
+		v, err := dec.DecodeValue()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		ag.Add(v)
+	}
+}
+
+// Keys returns the group keys in the order they were first seen.
+func (ag *Aggregator) Keys() []string {
+	return cloneStrings(ag.keys)
+}
+
+// Group returns the aggregate of the group with the given key, or nil if no value was added to
+// the group.
+func (ag *Aggregator) Group(key string) *Aggregate {
+	return ag.groups[key]
+}
+
+// Value returns an object mapping each group key to the value of its aggregate, in the order the
+// groups were first seen.
+func (ag *Aggregator) Value() Object {
+	var o Object
+	for _, k := range ag.keys {
+		o.Add(k, ag.groups[k].Value())
+	}
+	return o
+}
+
+func groupKey(v Value) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case String:
+		return string(v)
+	default:
+		return string(Serialize(v))
+	}
+}
+
+// lookupPath returns the value at path, or nil if the path does not exist.
+func lookupPath(v Value, path []string) Value {
+	v, err := GetPath(v, path)
+	if err != nil {
+		return nil
+	}
+	return v
+}
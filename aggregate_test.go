@@ -0,0 +1,70 @@
+package genjson
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAggregator(t *testing.T) {
+	const lines = `{"level": "info", "ms": 10}
+{"level": "error", "ms": 30.5}
+{"level": "info", "ms": -2}
+{"level": "info"}
+{"ms": 4}
+`
+	tests := []struct {
+		name string
+		ag   Aggregator
+		want map[string]Aggregate
+		keys []string
+	}{
+		{
+			name: "count",
+			ag:   Aggregator{},
+			want: map[string]Aggregate{"": {Count: 5}},
+			keys: []string{""},
+		},
+		{
+			name: "sum",
+			ag:   Aggregator{Path: []string{"ms"}},
+			want: map[string]Aggregate{"": {Count: 5, Numbers: 4, Sum: 42.5, Min: -2, Max: 30.5}},
+			keys: []string{""},
+		},
+		{
+			name: "group-by",
+			ag:   Aggregator{GroupBy: []string{"level"}, Path: []string{"ms"}},
+			want: map[string]Aggregate{
+				"info":  {Count: 3, Numbers: 2, Sum: 8, Min: -2, Max: 10},
+				"error": {Count: 1, Numbers: 1, Sum: 30.5, Min: 30.5, Max: 30.5},
+				"":      {Count: 1, Numbers: 1, Sum: 4, Min: 4, Max: 4},
+			},
+			keys: []string{"info", "error", ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.ag.Decode(NewDecoder(strings.NewReader(lines))); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(tt.ag.Keys(), tt.keys) {
+				t.Errorf("unexpected keys %v", tt.ag.Keys())
+			}
+			for k, want := range tt.want {
+				if got := tt.ag.Group(k); got == nil || *got != want {
+					t.Errorf("unexpected aggregate for %q %+v != %+v", k, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAggregatorValue(t *testing.T) {
+	ag := Aggregator{GroupBy: []string{"ok"}, Path: []string{"n"}}
+	ag.Add(MustDeserializeString(`{"ok": true, "n": 1}`))
+	ag.Add(MustDeserializeString(`{"ok": false}`))
+	want := `{"true":{"count":1,"numbers":1,"sum":1.0,"min":1.0,"max":1.0},"false":{"count":1,"numbers":0}}`
+	if got := string(Serialize(ag.Value())); got != want {
+		t.Errorf("unexpected value %s", got)
+	}
+}
@@ -0,0 +1,132 @@
+package genjson
+
+// Allocator provides the memory for the output buffers of a Serializer and the elements of the
+// arrays created by a Deserializer, so that memory constrained embedders, such as TinyGo or wasm
+// programs, can serve the bulk of their allocations from fixed buffers. It does not cover every
+// allocation: objects, strings, the location data of parsed values and the internal buffers of
+// Decoder are always allocated on the heap, and output that outgrows the buffer returned by Bytes
+// is grown on the heap by append.
+type Allocator interface {
+	// Bytes returns a slice with length 0 and capacity at least n.
+	Bytes(n int) []byte
+	// Values returns a slice with length n.
+	Values(n int) []Value
+}
+
+// HeapAllocator is the default Allocator, which allocates on the heap with make.
+type HeapAllocator struct{}
+
+func (HeapAllocator) Bytes(n int) []byte {
+	return make([]byte, 0, n)
+}
+
+func (HeapAllocator) Values(n int) []Value {
+	return make([]Value, n)
+}
+
+// Arena is an Allocator that serves allocations from buffers allocated once by NewArena, falling
+// back to the heap when they are exhausted. Slices returned by Bytes have exactly the requested
+// capacity. Serialize and Write instead serialize into the unused part of the arena, so output of
+// any size stays in the arena as long as it fits; Serialize keeps only the bytes of its output and
+// Write keeps none. The elements of arrays parsed by a deserialization that fails, or that a
+// Decoder retries once more input is read, are returned to the arena. An Arena must not be used
+// concurrently.
+type Arena struct {
+	bytes  []byte
+	values []Value
+	// Overflows is the number of allocations that did not fit in the buffers and were made on the
+	// heap.
+	Overflows int
+}
+
+// NewArena returns an Arena with room for the given number of bytes and values.
+func NewArena(bytes, values int) *Arena {
+	return &Arena{
+		bytes:  make([]byte, 0, bytes),
+		values: make([]Value, 0, values),
+	}
+}
+
+func (a *Arena) Bytes(n int) []byte {
+	l := len(a.bytes)
+	if n > cap(a.bytes)-l {
+		a.Overflows++
+		return make([]byte, 0, n)
+	}
+	a.bytes = a.bytes[:l+n]
+	return a.bytes[l : l : l+n]
+}
+
+func (a *Arena) Values(n int) []Value {
+	l := len(a.values)
+	if n > cap(a.values)-l {
+		a.Overflows++
+		return make([]Value, n)
+	}
+	a.values = a.values[:l+n]
+	return a.values[l : l+n : l+n]
+}
+
+// rest returns the unused bytes of the arena as an empty buffer, without reserving them.
+func (a *Arena) rest() []byte {
+	l := len(a.bytes)
+	return a.bytes[l:l:cap(a.bytes)]
+}
+
+// reserve marks buf, a buffer returned by rest that was appended to, as used. Output that outgrew
+// the arena was moved to the heap by append and counts as an overflow.
+func (a *Arena) reserve(buf []byte) {
+	rest := a.rest()
+	if cap(buf) != cap(rest) || cap(rest) == 0 || &buf[:1][0] != &rest[:1][0] {
+		a.Overflows++
+		return
+	}
+	a.bytes = a.bytes[:len(a.bytes)+len(buf)]
+}
+
+// mark returns the position of the next allocation of values, for release.
+func (a *Arena) mark() int {
+	return len(a.values)
+}
+
+// release returns the values allocated since mark to the arena, for parses whose result is
+// discarded.
+func (a *Arena) release(mark int) {
+	for i := mark; i < len(a.values); i++ {
+		a.values[i] = nil
+	}
+	a.values = a.values[:mark]
+}
+
+// Reset makes the whole buffers of the arena available again. Values and output created with the
+// arena must no longer be used, as their memory is reused by later allocations.
+func (a *Arena) Reset() {
+	for i := range a.values {
+		a.values[i] = nil
+	}
+	a.bytes = a.bytes[:0]
+	a.values = a.values[:0]
+	a.Overflows = 0
+}
+
+// allocator returns the allocator of the deserializer, which may be nil.
+func (ds *Deserializer) allocator() Allocator {
+	if ds == nil || ds.Allocator == nil {
+		return HeapAllocator{}
+	}
+	return ds.Allocator
+}
+
+// arena returns the allocator of the deserializer if it is an Arena.
+func (ds *Deserializer) arena() *Arena {
+	a, _ := ds.allocator().(*Arena)
+	return a
+}
+
+// allocator returns the allocator of the serializer.
+func (s *Serializer) allocator() Allocator {
+	if s.Allocator == nil {
+		return HeapAllocator{}
+	}
+	return s.Allocator
+}
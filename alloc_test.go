@@ -0,0 +1,117 @@
+package genjson
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+type countingAllocator struct {
+	HeapAllocator
+	bytes, values int
+}
+
+func (a *countingAllocator) Bytes(n int) []byte {
+	a.bytes++
+	return a.HeapAllocator.Bytes(n)
+}
+
+func (a *countingAllocator) Values(n int) []Value {
+	a.values += n
+	return a.HeapAllocator.Values(n)
+}
+
+func TestAllocator(t *testing.T) {
+	var a countingAllocator
+	ds := Deserializer{Allocator: &a}
+	v, err := ds.DeserializeString(`{"a": [[1, 2], [], [3]], "b": [true]}`)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if a.values != 7 {
+		t.Errorf("unexpected number of values %d", a.values)
+	}
+
+	s := Serializer{Allocator: &a}
+	want := `{"a":[[1,2],[],[3]],"b":[true]}`
+	if got := string(s.Serialize(v)); got != want {
+		t.Errorf("unexpected output %s", got)
+	}
+	var buf bytes.Buffer
+	if err := s.Write(&buf, v); err != nil || buf.String() != want {
+		t.Errorf("unexpected output %s %v", buf.String(), err)
+	}
+	if a.bytes != 2 {
+		t.Errorf("unexpected number of buffers %d", a.bytes)
+	}
+}
+
+func TestArena(t *testing.T) {
+	arena := NewArena(2048, 4)
+	ds := Deserializer{Allocator: arena}
+	v, err := ds.DeserializeString(`[[1, 2], [3]]`)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if arena.Overflows != 1 {
+		t.Errorf("unexpected overflows %d", arena.Overflows)
+	}
+
+	s := Serializer{Allocator: arena}
+	out1, out2 := s.Serialize(v), s.Serialize(v)
+	if string(out1) != "[[1,2],[3]]" || string(out2) != string(out1) {
+		t.Errorf("unexpected output %s %s", out1, out2)
+	}
+	// Only the bytes of the output are used, and Write does not keep its buffer.
+	if err := s.Write(io.Discard, v); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(arena.bytes) != 2*len(out1) || arena.Overflows != 1 {
+		t.Errorf("unexpected arena use %d %d", len(arena.bytes), arena.Overflows)
+	}
+	// Output larger than a single buffer stays in the arena while it fits.
+	large := String(strings.Repeat("a", 1500))
+	if out := s.Serialize(large); len(out) != 1502 || len(arena.bytes) != 2*len(out1)+1502 {
+		t.Errorf("unexpected arena use %d", len(arena.bytes))
+	}
+	if out := s.Serialize(large); len(out) != 1502 || arena.Overflows != 2 {
+		t.Errorf("unexpected overflows %d", arena.Overflows)
+	}
+	if string(out1) != "[[1,2],[3]]" {
+		t.Errorf("output overwritten %s", out1)
+	}
+
+	arena.Reset()
+	if v, err = ds.DeserializeString(`[1, 2, 3, 4]`); err != nil || len(v.(Array)) != 4 {
+		t.Fatalf("unexpected result %v %v", v, err)
+	}
+	if arena.Overflows != 0 {
+		t.Errorf("unexpected overflows after reset %d", arena.Overflows)
+	}
+}
+
+func TestArenaRetries(t *testing.T) {
+	arena := NewArena(0, 16)
+	ds := Deserializer{Allocator: arena}
+	// The arrays of a failed parse are returned to the arena.
+	if _, err := ds.DeserializeString(`[[1, 2], [3], x]`); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if len(arena.values) != 0 {
+		t.Errorf("unexpected values in use %d", len(arena.values))
+	}
+	// A Decoder reading one byte at a time parses values several times, but only keeps the
+	// elements of the final result.
+	dec := NewDecoder(iotest.OneByteReader(strings.NewReader(`[[1, 2], [3]] [4]`)))
+	dec.Deserializer = ds
+	for _, want := range []int{5, 6} {
+		if _, err := dec.DecodeValue(); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if len(arena.values) != want || arena.Overflows != 0 {
+			t.Errorf("unexpected arena use %d %d", len(arena.values), arena.Overflows)
+		}
+	}
+}
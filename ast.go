@@ -0,0 +1,56 @@
+package genjson
+
+// DumpAST returns the syntax tree of the document as a json value, for debugging the parser and
+// for tools that need the position of every value. Every value is described by an object with
+// its "type", its "start" and exclusive "end" locations and:
+//   - "value" with the value itself for null, booleans, numbers and strings.
+//   - "elements" with the description of every element for arrays.
+//   - "members" with an object for every entry, in source order, for objects. Each member has
+//     the "key", its "keyStart" and exclusive "keyEnd" locations and the description of the
+//     "value".
+//
+// Locations are objects with a 1-based "row" and "col" and a 0-based byte "offset".
+func DumpAST(d *Document) Value {
+	return dumpNode(d.value, &d.node)
+}
+
+func dumpNode(v Value, n *node) Value {
+	var o Object
+	o.init()
+	o.Add("type", String(TypeOf(v).String()))
+	o.Add("start", dumpLoc(n.start))
+	o.Add("end", dumpLoc(n.end))
+	switch v := v.(type) {
+	case Array:
+		elems := make(Array, len(v))
+		for i, elem := range v {
+			elems[i] = dumpNode(elem, &n.arrayNodes[i])
+		}
+		o.Add("elements", elems)
+	case Object:
+		members := make(Array, 0, v.Len())
+		for i, e := range v.Entries() {
+			kv := &n.objectNodes[i]
+			var m Object
+			m.init()
+			m.Add("key", String(e.Key))
+			m.Add("keyStart", dumpLoc(kv.keyStart))
+			m.Add("keyEnd", dumpLoc(kv.keyEnd))
+			m.Add("value", dumpNode(e.Value, &kv.node))
+			members = append(members, m)
+		}
+		o.Add("members", members)
+	default:
+		o.Add("value", v)
+	}
+	return o
+}
+
+func dumpLoc(l Loc) Value {
+	var o Object
+	o.init()
+	o.Add("row", Number{Integer: uint64(l.Row)})
+	o.Add("col", Number{Integer: uint64(l.Col)})
+	o.Add("offset", Number{Integer: uint64(l.Offset)})
+	return o
+}
@@ -0,0 +1,19 @@
+package genjson
+
+import "testing"
+
+func TestDumpAST(t *testing.T) {
+	doc, err := DeserializeDocument([]byte(`{"a": [1, true]}`))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	want := `{"type":"object","start":{"row":1,"col":1,"offset":0},"end":{"row":1,"col":17,"offset":16},"members":[` +
+		`{"key":"a","keyStart":{"row":1,"col":2,"offset":1},"keyEnd":{"row":1,"col":5,"offset":4},"value":` +
+		`{"type":"array","start":{"row":1,"col":7,"offset":6},"end":{"row":1,"col":16,"offset":15},"elements":[` +
+		`{"type":"number","start":{"row":1,"col":8,"offset":7},"end":{"row":1,"col":9,"offset":8},"value":1},` +
+		`{"type":"bool","start":{"row":1,"col":11,"offset":10},"end":{"row":1,"col":15,"offset":14},"value":true}` +
+		`]}}]}`
+	if got := string(Serialize(DumpAST(doc))); got != want {
+		t.Errorf("unexpected ast\n%s\n%s", got, want)
+	}
+}
@@ -0,0 +1,95 @@
+package genjson
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrInvalidCheckpoint is returned by ResumeDecoder for a checkpoint that was not returned by
+// Decoder.Checkpoint.
+var ErrInvalidCheckpoint = errors.New("invalid decoder checkpoint")
+
+// Checkpoint is a snapshot of the position of a Decoder, so that decoding can be resumed later by
+// ResumeDecoder, e.g. after a batch job processing a large file is restarted. The fields are
+// exported so that a checkpoint can be persisted, for example with Marshal.
+type Checkpoint struct {
+	// Offset is the offset in the input of the first byte that has not been decoded.
+	Offset int64
+	// Row and Col are the location of Offset, so that a resumed decoder reports the same
+	// locations as the original.
+	Row int
+	Col int
+	// Containers holds the opening delimiters of the arrays and objects opened by Token that have
+	// not been closed, outermost first. A checkpoint is within a partially decoded value if it is
+	// not empty.
+	Containers string
+	// State is the position of the decoder within the innermost container. It should be treated
+	// as opaque.
+	State int
+}
+
+// Checkpoint returns the current position of the decoder. It may be called between any calls to
+// Decode, DecodeValue and Token, including within the containers opened by Token. Input that has
+// been read from the underlying reader but not yet decoded is not part of the checkpoint.
+func (dec *Decoder) Checkpoint() Checkpoint {
+	cp := Checkpoint{
+		Offset:     int64(dec.d.base + dec.d.idx),
+		Row:        dec.d.row,
+		Col:        dec.d.col,
+		Containers: string(dec.tokenStack),
+		State:      int(dec.tokenState),
+	}
+	if !dec.started {
+		cp.Row, cp.Col = 1, 1
+	}
+	return cp
+}
+
+// ResumeDecoder returns a decoder that continues decoding from a checkpoint. r must be positioned
+// at cp.Offset of the original input, e.g. by seeking a file to it, and the options of the
+// decoder, such as Separator and Deserializer, must match those of the original decoder.
+func ResumeDecoder(r io.Reader, cp Checkpoint) (*Decoder, error) {
+	if err := cp.validate(); err != nil {
+		return nil, err
+	}
+	dec := &Decoder{r: r}
+	if cp.Offset == 0 && cp.Containers == "" {
+		// Nothing has been decoded, so a byte order mark may still need to be skipped.
+		return dec, nil
+	}
+	dec.started = true
+	dec.d = deserializer{row: cp.Row, col: cp.Col, base: int(cp.Offset), opts: &dec.Deserializer}
+	dec.tokenStack = []byte(cp.Containers)
+	dec.tokenState = tokenState(cp.State)
+	return dec, nil
+}
+
+// validate checks that the state of the checkpoint can be reached by the decoder.
+func (cp Checkpoint) validate() error {
+	if cp.Offset < 0 || cp.Row < 1 || cp.Col < 1 {
+		return ErrInvalidCheckpoint
+	}
+	for i := 0; i < len(cp.Containers); i++ {
+		if c := cp.Containers[i]; c != '[' && c != '{' {
+			return ErrInvalidCheckpoint
+		}
+	}
+	state := tokenState(cp.State)
+	if cp.Containers == "" {
+		if state != tokenValue {
+			return ErrInvalidCheckpoint
+		}
+		return nil
+	}
+	switch cp.Containers[len(cp.Containers)-1] {
+	case '[':
+		if state != tokenValue && state != tokenArrayStart && state != tokenArrayValue {
+			return ErrInvalidCheckpoint
+		}
+	default:
+		if state != tokenValue && (state < tokenObjectStart || state > tokenObjectValue) {
+			return ErrInvalidCheckpoint
+		}
+	}
+	return nil
+}
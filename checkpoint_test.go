@@ -0,0 +1,161 @@
+package genjson
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// checkpointSteps runs the steps against the decoder, t calling Token and v calling DecodeValue,
+// and describes the results.
+func checkpointSteps(dec *Decoder, steps string) ([]string, error) {
+	var got []string
+	for _, step := range steps {
+		if step == 't' {
+			tok, err := dec.Token()
+			if err != nil {
+				return got, err
+			}
+			got = append(got, fmt.Sprintf("%s@%d:%d", tokenString(tok), tok.Start.Row, tok.Start.Col))
+			continue
+		}
+		v, err := dec.DecodeValue()
+		if err != nil {
+			return got, err
+		}
+		got = append(got, string(Serialize(v)))
+	}
+	return got, nil
+}
+
+func TestDecoderCheckpoint(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		separator Separator
+		// steps is the sequence of calls, see checkpointSteps. The checkpoint is taken after the
+		// first at steps.
+		steps string
+		at    int
+		want  Checkpoint
+	}{
+		{
+			name:  "start",
+			input: "1 2",
+			steps: "vv",
+			want:  Checkpoint{Offset: 0, Row: 1, Col: 1},
+		},
+		{
+			name:  "json-lines",
+			input: "{\"a\": 1}\n{\"a\": 2}\n{\"a\": 3}\n",
+			steps: "vvv",
+			at:    2,
+			want:  Checkpoint{Offset: 18, Row: 3, Col: 1},
+		},
+		{
+			name:      "seq",
+			input:     "\x1e1\n\x1e2\n\x1e3\n",
+			separator: SeparatorRecord,
+			steps:     "vvv",
+			at:        1,
+			want:      Checkpoint{Offset: 3, Row: 2, Col: 1},
+		},
+		{
+			name:  "array-start",
+			input: "[\n  {\"id\": 1},\n  {\"id\": 2}\n]",
+			steps: "tvvt",
+			at:    1,
+			want:  Checkpoint{Offset: 1, Row: 1, Col: 2, Containers: "[", State: int(tokenArrayStart)},
+		},
+		{
+			name:  "array-elements",
+			input: "[\n  {\"id\": 1},\n  {\"id\": 2}\n]",
+			steps: "tvvt",
+			at:    2,
+			want:  Checkpoint{Offset: 13, Row: 2, Col: 12, Containers: "[", State: int(tokenArrayValue)},
+		},
+		{
+			name:  "object-key",
+			input: `{"items": [1, 2], "n": 3}`,
+			steps: "tttvvttvt",
+			at:    2,
+			want:  Checkpoint{Offset: 8, Row: 1, Col: 9, Containers: "{", State: int(tokenObjectColon)},
+		},
+		{
+			name:  "nested",
+			input: `{"items": [1, 2], "n": 3}`,
+			steps: "tttvvttvt",
+			at:    4,
+			want:  Checkpoint{Offset: 12, Row: 1, Col: 13, Containers: "{[", State: int(tokenArrayValue)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tt.input))
+			dec.Separator = tt.separator
+			want, err := checkpointSteps(dec, tt.steps)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if _, err := dec.Token(); err != io.EOF {
+				t.Fatalf("expected EOF but got %v", err)
+			}
+
+			dec = NewDecoder(iotest.OneByteReader(strings.NewReader(tt.input)))
+			dec.Separator = tt.separator
+			before, err := checkpointSteps(dec, tt.steps[:tt.at])
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			cp := dec.Checkpoint()
+			if cp != tt.want {
+				t.Fatalf("unexpected checkpoint\n%+v\n%+v", cp, tt.want)
+			}
+
+			// Round trip the checkpoint as it would be persisted.
+			b, err := Marshal(cp)
+			if err != nil {
+				t.Fatalf("unexpected marshal error %v", err)
+			}
+			var restored Checkpoint
+			if err := Unmarshal(b, &restored); err != nil {
+				t.Fatalf("unexpected unmarshal error %v", err)
+			}
+			resumed, err := ResumeDecoder(strings.NewReader(tt.input[restored.Offset:]), restored)
+			if err != nil {
+				t.Fatalf("unexpected resume error %v", err)
+			}
+			resumed.Separator = tt.separator
+			after, err := checkpointSteps(resumed, tt.steps[tt.at:])
+			if err != nil {
+				t.Fatalf("unexpected error after resuming %v", err)
+			}
+			if got := append(before, after...); !reflect.DeepEqual(got, want) {
+				t.Errorf("unexpected results\n%v\n%v", got, want)
+			}
+			if _, err := resumed.Token(); err != io.EOF {
+				t.Errorf("expected EOF but got %v", err)
+			}
+		})
+	}
+}
+
+func TestResumeDecoderInvalid(t *testing.T) {
+	for _, cp := range []Checkpoint{
+		{Offset: -1, Row: 1, Col: 1},
+		{Offset: 5, Row: 0, Col: 1},
+		{Offset: 5, Row: 1, Col: 1, Containers: "[("},
+		{Offset: 5, Row: 1, Col: 1, State: int(tokenArrayValue)},
+		{Offset: 5, Row: 1, Col: 1, Containers: "[", State: int(tokenObjectKey)},
+		{Offset: 5, Row: 1, Col: 1, Containers: "{", State: int(tokenArrayStart)},
+		{Offset: 5, Row: 1, Col: 1, Containers: "{", State: 100},
+	} {
+		if _, err := ResumeDecoder(strings.NewReader(""), cp); !errors.Is(err, ErrInvalidCheckpoint) {
+			t.Errorf("expected ErrInvalidCheckpoint for %+v but got %v", cp, err)
+		}
+	}
+}
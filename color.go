@@ -0,0 +1,81 @@
+package genjson
+
+// Colors are the ANSI escape sequences used to highlight the elements of serialized json, see
+// Serializer.Colors. Elements with an empty sequence are not highlighted.
+type Colors struct {
+	Key    string
+	String string
+	Number string
+	// Literal is used for true, false and null.
+	Literal string
+	// Punctuation is used for brackets, braces, commas and colons.
+	Punctuation string
+}
+
+// DefaultColors highlights keys in bold blue, strings in green, numbers in cyan and literals in
+// magenta, leaving punctuation in the default color of the terminal.
+var DefaultColors = Colors{
+	Key:     "\x1b[1;34m",
+	String:  "\x1b[32m",
+	Number:  "\x1b[36m",
+	Literal: "\x1b[35m",
+}
+
+// colorReset is the ANSI escape sequence that restores the default color.
+const colorReset = "\x1b[0m"
+
+type colorRole int8
+
+const (
+	colorKey colorRole = iota
+	colorString
+	colorNumber
+	colorLiteral
+	colorPunctuation
+)
+
+// color returns the escape sequence of the role, or "" if it is not highlighted.
+func (s *Serializer) color(role colorRole) string {
+	c := s.Colors
+	if c == nil {
+		return ""
+	}
+	switch role {
+	case colorKey:
+		return c.Key
+	case colorString:
+		return c.String
+	case colorNumber:
+		return c.Number
+	case colorLiteral:
+		return c.Literal
+	}
+	return c.Punctuation
+}
+
+// startColor appends the escape sequence of the role, if it is highlighted.
+func (s *Serializer) startColor(bb []byte, role colorRole) []byte {
+	return append(bb, s.color(role)...)
+}
+
+// endColor restores the default color after an element of the role, if it is highlighted.
+func (s *Serializer) endColor(bb []byte, role colorRole) []byte {
+	if s.color(role) == "" {
+		return bb
+	}
+	return append(bb, colorReset...)
+}
+
+// appendColored appends text highlighted with the color of the role.
+func (s *Serializer) appendColored(bb []byte, role colorRole, text string) []byte {
+	bb = s.startColor(bb, role)
+	bb = append(bb, text...)
+	return s.endColor(bb, role)
+}
+
+// appendColorString appends the string literal of str highlighted with the color of the role.
+func (s *Serializer) appendColorString(bb []byte, role colorRole, str string) []byte {
+	bb = s.startColor(bb, role)
+	bb = appendString(s, bb, str)
+	return s.endColor(bb, role)
+}
@@ -0,0 +1,64 @@
+package genjson
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSerializeColors(t *testing.T) {
+	colors := &Colors{Key: "K", String: "S", Number: "N", Literal: "L", Punctuation: "P"}
+	v := MustDeserializeString(`{"a": [1, "b", true, null], "c": {}}`)
+	tests := []struct {
+		name string
+		s    Serializer
+		want string
+	}{
+		{
+			name: "all",
+			s:    Serializer{Colors: colors},
+			want: `P{|K"a"|P:|P[|N1|P,|S"b"|P,|Ltrue|P,|Lnull|P]|P,|K"c"|P:|P{|P}|P}|`,
+		},
+		{
+			name: "no-punctuation",
+			s:    Serializer{Colors: &Colors{Key: "K", Number: "N"}, Indent: 1, KeyValueGap: 1},
+			want: "{\n K\"a\"|: [\n  N1|,\n  \"b\",\n  true,\n  null\n ],\n K\"c\"|: {}\n}",
+		},
+		{
+			name: "options",
+			s:    Serializer{Colors: colors, EmptyAsNull: true, UnsafeIntegersAsStrings: true},
+			want: `P{|K"a"|P:|P[|N1|P,|S"b"|P,|Ltrue|P,|Lnull|P]|P,|K"c"|P:|Lnull|P}|`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := strings.ReplaceAll(string(tt.s.Serialize(v)), colorReset, "|")
+			if got != tt.want {
+				t.Errorf("unexpected output\n%s\n%s", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("unsafe-integer", func(t *testing.T) {
+		s := Serializer{Colors: colors, UnsafeIntegersAsStrings: true}
+		got := strings.ReplaceAll(string(s.Serialize(Number{Integer: MaxSafeInteger + 1})), colorReset, "|")
+		if want := `S"9007199254740992"|`; got != want {
+			t.Errorf("unexpected output %s != %s", got, want)
+		}
+	})
+	t.Run("serialize-to", func(t *testing.T) {
+		m := Marshaler{Serializer: Serializer{Colors: &DefaultColors, Indent: 2}}
+		in := map[string]any{"a": []any{1, "b", true, nil}, "c": struct{ D string }{"d"}}
+		want, err := m.Marshal(in)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		var buf bytes.Buffer
+		if err := m.Write(&buf, in); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("unexpected output\n%q\n%q", buf.Bytes(), want)
+		}
+	})
+}
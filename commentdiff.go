@@ -0,0 +1,214 @@
+package genjson
+
+import (
+	"bytes"
+	"strings"
+)
+
+// SourceChanges returns the changes between the json sources a and b, which may contain // line
+// comments and /* */ block comments as written by Serializer.Comments. The values are compared as
+// with Changes. If Comments is set, the comments that were added, removed or edited are reported
+// too, with Comment set.
+//
+// A comment is attached to the value or object member that follows it, or to the value that
+// precedes it on the same line. Comments at the end of an array or object are attached to it.
+// Comments are compared by their text without the comment markers and surrounding whitespace, so
+// rewriting a line comment as a block comment is not a change.
+func (df *Differ) SourceChanges(a, b []byte) (Changes, error) {
+	ac, err := parseCommented(a)
+	if err != nil {
+		return nil, err
+	}
+	bc, err := parseCommented(b)
+	if err != nil {
+		return nil, err
+	}
+	cs := df.Changes(ac.value, bc.value)
+	if df.Comments {
+		cs = append(cs, commentChanges(ac.comments, bc.comments)...)
+	}
+	return cs, nil
+}
+
+// DiffSourceChanges returns the changes between the json sources with the default Differ. See
+// Differ.SourceChanges.
+func DiffSourceChanges(a, b []byte) (Changes, error) {
+	return defDiffer.SourceChanges(a, b)
+}
+
+// commentedSource is a value parsed from json with comments.
+type commentedSource struct {
+	value    Value
+	comments []attachedComment
+}
+
+// attachedComment is a comment with the path of the value it is attached to.
+type attachedComment struct {
+	path Path
+	Comment
+}
+
+// commentAnchor is a value or object member that comments can be attached to. For members, start
+// is the start of the key.
+type commentAnchor struct {
+	path       Path
+	start, end int
+	container  bool
+}
+
+func parseCommented(src []byte) (commentedSource, error) {
+	ranges, err := commentRanges(src)
+	if err != nil {
+		return commentedSource{}, err
+	}
+	blanked := src
+	if len(ranges) > 0 {
+		if blanked, err = blankComments(src); err != nil {
+			return commentedSource{}, err
+		}
+	}
+	o, err := deserialize(blanked)
+	if err != nil {
+		return commentedSource{}, err
+	}
+	var anchors []commentAnchor
+	collectAnchors(o.value, &o.node, Path{}, o.node.start.Offset, &anchors)
+	cs := commentedSource{value: o.value}
+	for _, r := range ranges {
+		cs.comments = append(cs.comments, attachComment(src, r, anchors))
+	}
+	return cs, nil
+}
+
+// collectAnchors appends the anchors of v and its descendants in source order. start is the start
+// of the anchor of v.
+func collectAnchors(v Value, n *node, p Path, start int, anchors *[]commentAnchor) {
+	a := commentAnchor{path: p, start: start, end: n.end.Offset}
+	switch v := v.(type) {
+	case Array:
+		a.container = true
+		*anchors = append(*anchors, a)
+		for i, elem := range v {
+			collectAnchors(elem, &n.arrayNodes[i], p.Append(Index(i)), n.arrayNodes[i].start.Offset, anchors)
+		}
+	case Object:
+		a.container = true
+		*anchors = append(*anchors, a)
+		for i, e := range v.Entries() {
+			step := Key(e.Key)
+			if e.Ordinal > 0 {
+				step = KeyAt(e.Key, e.Ordinal)
+			}
+			kv := &n.objectNodes[i]
+			collectAnchors(e.Value, &kv.node, p.Append(step), kv.keyStart.Offset, anchors)
+		}
+	default:
+		*anchors = append(*anchors, a)
+	}
+}
+
+// attachComment returns the comment in the range of src attached to its anchor.
+func attachComment(src []byte, r [2]int, anchors []commentAnchor) attachedComment {
+	c := attachedComment{Comment: Comment{Text: commentText(src[r[0]:r[1]]), Position: CommentAfter}}
+	// A comment on the same line as the end of a value follows it. The value that ends last is the
+	// one closest to the comment.
+	var (
+		prev      *commentAnchor
+		lineStart = bytes.LastIndexByte(src[:r[0]], '\n') + 1
+	)
+	for i := range anchors {
+		a := &anchors[i]
+		if a.end <= r[0] && a.end > lineStart && (prev == nil || a.end > prev.end) {
+			prev = a
+		}
+	}
+	if prev != nil {
+		c.path = prev.path
+		return c
+	}
+	// Otherwise the comment precedes the next value within the innermost container around it, or
+	// is at the end of the container.
+	var container *commentAnchor
+	for i := range anchors {
+		a := &anchors[i]
+		if a.container && a.start < r[0] && a.end >= r[1] {
+			container = a
+		}
+	}
+	for i := range anchors {
+		a := &anchors[i]
+		if a.start >= r[1] && (container == nil || a.end <= container.end) {
+			c.path, c.Position = a.path, CommentBefore
+			return c
+		}
+	}
+	if container != nil {
+		c.path = container.path
+	} else {
+		// A comment after the whole value.
+		c.path = Path{}
+	}
+	return c
+}
+
+// commentText returns the text of a comment without its markers and surrounding whitespace.
+func commentText(comment []byte) string {
+	text := string(comment[2:])
+	if comment[1] == '*' {
+		text = text[:len(text)-2]
+	}
+	return strings.TrimSpace(text)
+}
+
+// commentChanges returns the changes between the comments of two sources. The comments attached
+// to the same path and position are matched in order.
+func commentChanges(a, b []attachedComment) Changes {
+	type (
+		groupKey struct {
+			path     string
+			position CommentPosition
+		}
+		group struct {
+			path Path
+			a, b []string
+		}
+	)
+	var (
+		groups []*group
+		byKey  = map[groupKey]*group{}
+	)
+	add := func(c attachedComment, inB bool) {
+		key := groupKey{c.path.String(), c.Position}
+		g, ok := byKey[key]
+		if !ok {
+			g = &group{path: c.path}
+			byKey[key] = g
+			groups = append(groups, g)
+		}
+		if inB {
+			g.b = append(g.b, c.Text)
+		} else {
+			g.a = append(g.a, c.Text)
+		}
+	}
+	for _, c := range a {
+		add(c, false)
+	}
+	for _, c := range b {
+		add(c, true)
+	}
+	var cs Changes
+	for _, g := range groups {
+		for i := 0; i < len(g.a) || i < len(g.b); i++ {
+			switch {
+			case i >= len(g.b):
+				cs = append(cs, Change{Kind: ChangeRemoved, Path: g.path, Before: String(g.a[i]), Comment: true})
+			case i >= len(g.a):
+				cs = append(cs, Change{Kind: ChangeAdded, Path: g.path, After: String(g.b[i]), Comment: true})
+			case g.a[i] != g.b[i]:
+				cs = append(cs, Change{Kind: ChangeModified, Path: g.path, Before: String(g.a[i]), After: String(g.b[i]), Comment: true})
+			}
+		}
+	}
+	return cs
+}
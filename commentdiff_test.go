@@ -0,0 +1,105 @@
+package genjson
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSourceChanges(t *testing.T) {
+	base := `// Service config.
+{
+	// The port to listen on.
+	"port": 8080, // default
+	"hosts": [
+		"a", /* primary */
+		"b"
+		// more hosts may be added
+	],
+	"url": "http://x//y"
+}`
+	tests := []struct {
+		name     string
+		b        string
+		comments bool
+		want     string
+	}{
+		{name: "unchanged", b: base, comments: true, want: ``},
+		{
+			name: "reformatted",
+			b: `/* Service config. */ {
+	/* The port to listen on. */ "port": 8080 /* default */,
+	"hosts": ["a" /*primary*/, "b"
+		/* more hosts may be added */],
+	"url": "http://x//y"
+}`,
+			comments: true,
+			want:     ``,
+		},
+		{
+			name: "edited",
+			b: `// Service config.
+{
+	// The TCP port to listen on.
+	"port": 8081,
+	"hosts": [
+		"a", /* primary */
+		"b" // secondary
+		// more hosts may be added
+	],
+	"url": "http://x//y"
+}`,
+			comments: true,
+			want: `~ port: 8080 -> 8081
+~ port (comment): "The port to listen on." -> "The TCP port to listen on."
+- port (comment): "default"
++ hosts[1] (comment): "secondary"`,
+		},
+		{
+			name: "values-only",
+			b: `{
+	"port": 8081,
+	"hosts": ["a", "b"],
+	"url": "http://x//y"
+}`,
+			want: `~ port: 8080 -> 8081`,
+		},
+		{
+			name: "removed-member",
+			b: `// Service config.
+{
+	"hosts": [
+		"a", /* primary */
+		"b"
+		// more hosts may be added
+	],
+	"url": "http://x//y"
+}`,
+			comments: true,
+			want: `- port: 8080
+- port (comment): "The port to listen on."
+- port (comment): "default"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			df := Differ{Comments: tt.comments}
+			got, err := df.SourceChanges([]byte(base), []byte(tt.b))
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("unexpected changes\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSourceChangesErrors(t *testing.T) {
+	if _, err := DiffSourceChanges([]byte(`{"a": 1 /* x`), []byte(`{}`)); !errors.Is(err, ErrUnterminatedComment) {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := DiffSourceChanges([]byte(`{}`), []byte(`{"a": // x
+	}`)); err == nil {
+		t.Errorf("expected a syntax error")
+	}
+}
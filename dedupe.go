@@ -0,0 +1,123 @@
+package genjson
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"sync"
+)
+
+// Deduper detects duplicate records in streams of values, such as json lines logs. Record keys are
+// compared as with Equal, so the formatting of numbers and the key order of the source json do not
+// affect the result. The zero Deduper compares whole records and remembers
+// every record exactly. A Deduper may be used concurrently, so that several streams can be
+// deduplicated against each other.
+type Deduper struct {
+	// KeyPath is the path of the value that identifies a record. If empty, the whole record is
+	// used. Records without a value at KeyPath are never duplicates.
+	KeyPath []string
+	// BloomBits, if non-zero, bounds the memory used to BloomBits bits by remembering keys in a
+	// bloom filter instead of exactly. Unique records are then reported as duplicates with a
+	// probability that grows with the number of records, see BloomFalsePositiveRate. Duplicates
+	// are always detected.
+	BloomBits int
+	// BloomHashes is the number of hashes used by the bloom filter. If 0, a default of 7 is used,
+	// which is optimal for about BloomBits/10 records.
+	BloomHashes int
+
+	mu    sync.Mutex
+	seen  map[[16]byte]struct{}
+	bloom []uint64
+}
+
+const defaultBloomHashes = 7
+
+// Seen records the key of the value and reports whether it had already been recorded.
+func (dd *Deduper) Seen(v Value) bool {
+	key := v
+	if len(dd.KeyPath) > 0 {
+		if key = lookupPath(v, dd.KeyPath); key == nil {
+			return false
+		}
+	}
+	sum := sha256.Sum256(defEqualer.appendKey(nil, key))
+	var h [16]byte
+	copy(h[:], sum[:])
+
+	dd.mu.Lock()
+	defer dd.mu.Unlock()
+	if dd.BloomBits > 0 {
+		return dd.seenBloom(h)
+	}
+	if _, ok := dd.seen[h]; ok {
+		return true
+	}
+	if dd.seen == nil {
+		dd.seen = make(map[[16]byte]struct{})
+	}
+	dd.seen[h] = struct{}{}
+	return false
+}
+
+// seenBloom sets the bits of the hash in the bloom filter and reports whether they were all set.
+// The bit indexes are derived from the two halves of the hash by double hashing.
+func (dd *Deduper) seenBloom(h [16]byte) bool {
+	if dd.bloom == nil {
+		dd.bloom = make([]uint64, (dd.BloomBits+63)/64)
+	}
+	h1 := binary.LittleEndian.Uint64(h[:8])
+	h2 := binary.LittleEndian.Uint64(h[8:])
+	seen := true
+	for i := 0; i < dd.bloomHashes(); i++ {
+		bit := (h1 + uint64(i)*h2) % uint64(dd.BloomBits)
+		word, mask := bit/64, uint64(1)<<(bit%64)
+		if dd.bloom[word]&mask == 0 {
+			seen = false
+			dd.bloom[word] |= mask
+		}
+	}
+	return seen
+}
+
+func (dd *Deduper) bloomHashes() int {
+	if dd.BloomHashes > 0 {
+		return dd.BloomHashes
+	}
+	return defaultBloomHashes
+}
+
+// BloomFalsePositiveRate returns the expected probability that a unique record is reported as a
+// duplicate after n unique records have been seen, for the bloom filter options of the Deduper.
+// It is 0 if the Deduper does not use a bloom filter.
+func (dd *Deduper) BloomFalsePositiveRate(n int) float64 {
+	if dd.BloomBits <= 0 {
+		return 0
+	}
+	k := float64(dd.bloomHashes())
+	return math.Pow(1-math.Exp(-k*float64(n)/float64(dd.BloomBits)), k)
+}
+
+// Filter calls fn with every value read from the decoder that is not a duplicate and returns the
+// number of duplicates skipped. It stops at the first error other than io.EOF, including errors
+// returned by fn.
+func (dd *Deduper) Filter(dec *Decoder, fn func(v Value) error) (int, error) {
+	dups := 0
+	for {
+		v, err := dec.DecodeValue()
+		if errors.Is(err, io.EOF) {
+			return dups, nil
+		}
+		if err != nil {
+			return dups, err
+		}
+		if dd.Seen(v) {
+			dups++
+			continue
+		}
+		if err := fn(v); err != nil {
+			return dups, err
+		}
+	}
+}
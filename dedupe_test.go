@@ -0,0 +1,106 @@
+package genjson
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDeduper(t *testing.T) {
+	input := `{"id": 1, "msg": "a"}
+{"msg": "a", "id": 1.0e0}
+{"id": 2, "msg": "a"}
+{"id": 1, "msg": "b"}
+{"msg": "c"}
+{"msg": "c"}
+`
+	tests := []struct {
+		name string
+		dd   *Deduper
+		want []string
+	}{
+		{
+			name: "record",
+			dd:   &Deduper{},
+			want: []string{`{"id":1,"msg":"a"}`, `{"id":2,"msg":"a"}`, `{"id":1,"msg":"b"}`, `{"msg":"c"}`},
+		},
+		{
+			name: "key",
+			dd:   &Deduper{KeyPath: []string{"id"}},
+			want: []string{`{"id":1,"msg":"a"}`, `{"id":2,"msg":"a"}`, `{"msg":"c"}`, `{"msg":"c"}`},
+		},
+		{
+			name: "bloom",
+			dd:   &Deduper{KeyPath: []string{"msg"}, BloomBits: 1024},
+			want: []string{`{"id":1,"msg":"a"}`, `{"id":1,"msg":"b"}`, `{"msg":"c"}`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			dups, err := tt.dd.Filter(NewDecoder(strings.NewReader(input)), func(v Value) error {
+				got = append(got, string(Serialize(v)))
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("unexpected records %v != %v", got, tt.want)
+			}
+			if dups != 6-len(tt.want) {
+				t.Errorf("unexpected duplicates %d", dups)
+			}
+		})
+	}
+}
+
+func TestDeduperConcurrent(t *testing.T) {
+	dd := &Deduper{KeyPath: []string{"id"}}
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		unique int
+	)
+	for s := 0; s < 4; s++ {
+		var sb strings.Builder
+		for i := 0; i < 100; i++ {
+			fmt.Fprintf(&sb, "{\"id\": %d, \"stream\": %d}\n", i, s)
+		}
+		wg.Add(1)
+		go func(input string) {
+			defer wg.Done()
+			_, err := dd.Filter(NewDecoder(strings.NewReader(input)), func(v Value) error {
+				mu.Lock()
+				unique++
+				mu.Unlock()
+				return nil
+			})
+			if err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+		}(sb.String())
+	}
+	wg.Wait()
+	if unique != 100 {
+		t.Errorf("unexpected unique records %d", unique)
+	}
+}
+
+func TestDeduperBloomFalsePositiveRate(t *testing.T) {
+	dd := Deduper{BloomBits: 10000}
+	positives := 0
+	for i := 0; i < 1000; i++ {
+		if dd.Seen(integer(uint64(i))) {
+			positives++
+		}
+	}
+	// The expected rate after 1000 records is about 0.008, so the total is expected to be ~3.
+	if rate := dd.BloomFalsePositiveRate(1000); rate < 0.005 || rate > 0.01 || positives > 20 {
+		t.Errorf("unexpected false positives %d with rate %f", positives, rate)
+	}
+	if (&Deduper{}).BloomFalsePositiveRate(1000) != 0 {
+		t.Errorf("unexpected rate for exact deduper")
+	}
+}
@@ -1,10 +1,15 @@
 package genjson
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+	"unsafe"
 
 	. "github.com/mattpgray/go-genjson/internal/funcparser"
 )
@@ -12,6 +17,11 @@ import (
 var (
 	ErrUnmatchedQuote       = errors.New("unmatched quote")
 	ErrUnexpectedEndOfInput = errors.New("unexpected end of input")
+	// ErrEmptyInput is returned when the input is empty or only contains whitespace.
+	ErrEmptyInput = errors.New("empty input")
+	// ErrTopLevelScalar is returned when the top-level value is not an object or array and the
+	// options require one.
+	ErrTopLevelScalar = errors.New("top-level value must be an object or array")
 )
 
 type InvalidTokenError struct {
@@ -34,60 +44,216 @@ func (ie InvalidEscapeSequence) Error() string {
 	return fmt.Sprintf("%d:%d: invalid escape sequence '%s'", ie.Row, ie.Col, ie.Seq)
 }
 
-func Deserialize(b []byte) (Value, error) {
-	d, err := deserialize(b)
+// HookError is returned when a Deserializer hook fails. The location is the start of the value
+// that was passed to the hook.
+type HookError struct {
+	Err error
+	Row int
+	Col int
+}
+
+func (he HookError) Error() string {
+	return fmt.Sprintf("%d:%d: %v", he.Row, he.Col, he.Err)
+}
+
+func (he HookError) Unwrap() error {
+	return he.Err
+}
+
+// Deserializer contains the options used when deserializing json.
+type Deserializer struct {
+	// ParseNumber, if set, is called with the raw bytes of every number literal instead of the
+	// default number parsing. This allows numbers to be converted into a different Value, such as
+	// a String for numbers that do not fit into a Number.
+	ParseNumber func(raw []byte) (Value, error)
+	// TransformString, if set, is called with every decoded string. isKey reports whether the
+	// string is an object key. The returned string is used in place of the decoded one, allowing
+	// for interning, normalization, trimming etc. at parse time.
+	TransformString func(s string, isKey bool) (string, error)
+	// NormalizeNewlines replaces CRLF line endings inside decoded strings with LF.
+	NormalizeNewlines bool
+	// StripBOM removes byte order marks from decoded strings and skips a byte order mark at the
+	// start of the input.
+	//
+	// Unicode normalization, such as NFC, is not built in. It can be applied with TransformString.
+	StripBOM bool
+	// RequireContainer rejects top-level values that are not objects or arrays with
+	// ErrTopLevelScalar, as required by RFC 4627. RFC 8259 allows any value at the top level.
+	RequireContainer bool
+	// Allocator, if set, provides the elements of deserialized arrays. See Allocator.
+	Allocator Allocator
+}
+
+var defDeserializer Deserializer
+
+func (ds *Deserializer) Deserialize(b []byte) (Value, error) {
+	d, err := ds.deserialize(b)
 	if err != nil {
 		return nil, err
 	}
 	return d.value, nil
 }
 
+// DeserializeString deserializes the json in s without copying it into a byte slice.
+func (ds *Deserializer) DeserializeString(s string) (Value, error) {
+	// The parser never modifies its input and every string it produces is copied from it, so the
+	// bytes are not modified or retained. The exception is the raw slice passed to the
+	// ParseNumber hook, which must not be modified.
+	return ds.Deserialize(unsafeBytes(s))
+}
+
+func Deserialize(b []byte) (Value, error) {
+	return defDeserializer.Deserialize(b)
+}
+
+func DeserializeString(s string) (Value, error) {
+	return defDeserializer.DeserializeString(s)
+}
+
+// MustDeserialize deserializes b, panicking if it is invalid. It is intended for tests and package
+// level variables where the json is known to be valid.
+func MustDeserialize(b []byte) Value {
+	v, err := Deserialize(b)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// MustDeserializeString is like MustDeserialize but does not copy s.
+func MustDeserializeString(s string) Value {
+	v, err := DeserializeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// MustParseString parses the json in s, panicking if it is invalid. It is the same as
+// MustDeserializeString and is the form used by the package level variables of GoSnippet.
+func MustParseString(s string) Value {
+	return MustDeserializeString(s)
+}
+
+// unsafeBytes returns the bytes of s without copying them. The bytes must not be modified.
+func unsafeBytes(s string) []byte {
+	return *(*[]byte)(unsafe.Pointer(&struct {
+		string
+		int
+	}{s, len(s)}))
+}
+
 func deserialize(b []byte) (output, error) {
-	d := deserializer{
-		b:   b,
-		idx: 0,
-		row: 1,
-		col: 1,
+	return defDeserializer.deserialize(b)
+}
+
+func (ds *Deserializer) deserialize(b []byte) (output, error) {
+	d := newDeserializer(b, ds)
+	if skipSpace(d).idx == len(b) {
+		return output{}, ErrEmptyInput
+	}
+	_, v, err := deserializeNext(d)
+	if err != nil {
+		return output{}, err
 	}
-	_, v, er := jsonParserE()(d)
+	if err := ds.checkTopLevel(v.value); err != nil {
+		return output{}, err
+	}
+	return v, nil
+}
+
+// checkTopLevel checks that a top-level value is allowed by the options.
+func (ds *Deserializer) checkTopLevel(v Value) error {
+	if ds == nil || !ds.RequireContainer {
+		return nil
+	}
+	switch v.(type) {
+	case Array, Object:
+		return nil
+	}
+	return ErrTopLevelScalar
+}
+
+// deserializeNext parses a single json value, returning the state after the value.
+func deserializeNext(d deserializer) (deserializer, output, error) {
+	arena := d.opts.arena()
+	var mark int
+	if arena != nil {
+		mark = arena.mark()
+	}
+	d, v, er := jsonParserE()(d)
 	if er.Err != nil {
-		return output{}, er.Err
+		if arena != nil {
+			// The arrays of a failed parse are discarded.
+			arena.release(mark)
+		}
+		return d, output{}, er.Err
 	}
+	return d, v, nil
+}
 
-	return v, nil
+const bom = "\uFEFF"
+
+// newDeserializer returns the parser state at the start of b, skipping a leading byte order mark
+// if the options require it.
+func newDeserializer(b []byte, opts *Deserializer) deserializer {
+	d := deserializer{
+		b:    b,
+		idx:  0,
+		row:  1,
+		col:  1,
+		opts: opts,
+	}
+	if opts != nil && opts.StripBOM && bytes.HasPrefix(b, []byte(bom)) {
+		// The byte order mark is not part of the first line, so its columns start after it. Offsets
+		// still count it.
+		d.idx = len(bom)
+		d.col = 1
+	}
+	return d
 }
 
 type deserializer struct {
-	b   []byte
-	idx int
-	row int
-	col int
+	b    []byte
+	idx  int
+	row  int
+	col  int
+	opts *Deserializer
+	// base is the offset of b within the input. It is non-zero when b only holds part of the
+	// input, as it does for a Decoder.
+	base int
+	// partial, if set, is set to true when the end of b is reached. The Decoder uses it to detect
+	// parses that may depend on input that has not been read yet.
+	partial *bool
 }
 
 func read(d deserializer) (deserializer, byte, *BoolResult) {
 	if d.idx < len(d.b) {
 		b := d.b[d.idx]
-		row := d.row
-		col := d.col
 		if b == '\n' {
-			row++
-			col = 1
+			d.row++
+			d.col = 1
 		} else {
-			col++
+			d.col++
 		}
-		return deserializer{
-			b:   d.b,
-			idx: d.idx + 1,
-			row: row,
-			col: col,
-		}, b, OK(true)
+		d.idx++
+		return d, b, OK(true)
+	}
+	if d.partial != nil {
+		*d.partial = true
 	}
 	return d, 0, OK(false)
 }
 
+func (d deserializer) loc() Loc {
+	return Loc{Row: d.row, Col: d.col, Offset: d.base + d.idx}
+}
+
 type Loc struct {
 	Row int
 	Col int
+	// Offset is the byte offset from the start of the input.
+	Offset int
 }
 
 type nodeKeyValue struct {
@@ -127,9 +293,9 @@ type locV[V any] struct {
 
 func locParser[O Output, R Result](p parser[O, R]) parser[locV[O], R] {
 	return func(d deserializer) (deserializer, locV[O], R) {
-		start := Loc{Row: d.row, Col: d.col}
+		start := d.loc()
 		d, o, r := p(d)
-		end := Loc{Row: d.row, Col: d.col}
+		end := d.loc()
 		return d, locV[O]{v: o, start: start, end: end}, r
 	}
 }
@@ -156,6 +322,16 @@ func jsonParserC() parser[output, *CombineResult] {
 	)
 }
 
+// scalarParser parses any value except arrays and objects.
+func scalarParser() parserC[output] {
+	return Try(
+		nullParser(),
+		boolParser(),
+		numberParser(),
+		stringParser(),
+	)
+}
+
 func jsonParserE() parser[output, *ErrResult] {
 	return trimSpaceParser(
 		MapR(
@@ -220,30 +396,68 @@ func boolParser() parserC[output] {
 
 func numberParser() parserC[output] {
 	return outputParser(
-		MapO(
-			Try(
-				MapO(
-					surroundParser[Number](
-						Discard(byteParser('-')),
-					)(
-						positiveNumberParser(),
-					)(),
-					func(n Number) Number {
-						n.IsNeg = true
-						return n
-					},
-				),
-				positiveNumberParser(),
+		func(d deserializer) (deserializer, Value, *CombineResult) {
+			if d.opts != nil && d.opts.ParseNumber != nil {
+				return numberHookParser(d.opts.ParseNumber)(d)
+			}
+			return defaultNumberParser()(d)
+		},
+	)
+}
+
+func numberHookParser(parseNumber func([]byte) (Value, error)) parserC[Value] {
+	return func(d deserializer) (deserializer, Value, *CombineResult) {
+		d2, raw, br := rawNumberParser()(d)
+		if !br.OK {
+			return d, nil, COK(false)
+		}
+		v, err := parseNumber(raw)
+		if err != nil {
+			return d, nil, CErr(HookError{Err: err, Row: d.row, Col: d.col})
+		}
+		return d2, v, COK(true)
+	}
+}
+
+// rawNumberParser matches the bytes of a number literal without converting them.
+func rawNumberParser() parserB[[]byte] {
+	return Flatten(
+		optionalParser(Chain(byteParser('-'))),
+		digitsParser(),
+		optionalParser(
+			Flatten(
+				Chain(byteParser('.')),
+				digitsParser(),
 			),
-			func(n Number) Value {
-				return n
-			},
 		),
+		optionalParser(exponentParser()),
+	)
+}
+
+func defaultNumberParser() parserC[Value] {
+	return MapO(
+		Try(
+			MapO(
+				surroundParser[Number](
+					Discard(byteParser('-')),
+				)(
+					positiveNumberParser(),
+				)(),
+				func(n Number) Number {
+					n.IsNeg = true
+					return n
+				},
+			),
+			positiveNumberParser(),
+		),
+		func(n Number) Value {
+			return n
+		},
 	)
 }
 func positiveNumberParser() parser[Number, *CombineResult] {
 	return Try(
-		MapO(floatParser(), func(i float64) Number { return Number{Float: i, IsFloat: true} }),
+		floatParser(),
 		MapO(intParser(), func(i uint64) Number { return Number{Integer: i} }),
 	)
 }
@@ -251,7 +465,7 @@ func positiveNumberParser() parser[Number, *CombineResult] {
 func stringParser() parserC[output] {
 	return outputParser(
 		MapO(
-			rawStringParser(),
+			stringHookParser(rawStringParser(), false),
 			func(s string) Value {
 				return String(s)
 			},
@@ -260,32 +474,38 @@ func stringParser() parserC[output] {
 }
 
 func arrayParser() parser[output, *CombineResult] {
-	return MapO(
-		locParser(
-			compositeParser(
-				Discard(byteParser('[')),
-				Discard(trimSpaceParser(byteParser(']'))),
-				Discard(trimSpaceParser(byteParser(','))),
-				LazyP(jsonParserC),
-			),
+	elems := locParser(
+		compositeParser(
+			Discard(byteParser('[')),
+			Discard(trimSpaceParser(byteParser(']'))),
+			Discard(trimSpaceParser(byteParser(','))),
+			LazyP(jsonParserC),
 		),
-		func(val locV[[]output]) output {
-			var vals []Value
-			var nodes []node
-			for _, o := range val.v {
-				vals = append(vals, o.value)
-				nodes = append(nodes, o.node)
-			}
-			return output{
-				value: Array(vals),
-				node: node{
-					arrayNodes: nodes,
-					start:      val.start,
-					end:        val.end,
-				},
-			}
-		},
 	)
+	return func(d deserializer) (deserializer, output, *CombineResult) {
+		d2, val, cr := elems(d)
+		if !cr.Valid() {
+			return d2, output{}, cr
+		}
+		var vals []Value
+		var nodes []node
+		if len(val.v) > 0 {
+			vals = d.opts.allocator().Values(len(val.v))
+			nodes = make([]node, len(val.v))
+		}
+		for i, o := range val.v {
+			vals[i] = o.value
+			nodes[i] = o.node
+		}
+		return d2, output{
+			value: Array(vals),
+			node: node{
+				arrayNodes: nodes,
+				start:      val.start,
+				end:        val.end,
+			},
+		}, cr
+	}
 }
 
 func objectParser() parserC[output] {
@@ -298,7 +518,7 @@ func objectParser() parserC[output] {
 		Chain(
 			surroundParser[keyValue]()(
 				MapO(
-					locParser(rawStringParser()),
+					locParser(stringHookParser(rawStringParser(), true)),
 					func(s locV[string]) keyValue { return keyValue{key: s} },
 				),
 			)(
@@ -336,6 +556,7 @@ func objectParser() parserC[output] {
 			nodes := []nodeKeyValue{}
 			for _, kv := range kvs.v {
 				nodes = append(nodes, nodeKeyValue{
+					key:      kv.key.v,
 					node:     kv.value.node,
 					keyStart: kv.key.start,
 					keyEnd:   kv.key.end,
@@ -451,7 +672,6 @@ func rawStringParser() parser[string, *CombineResult] {
 			ToC(Chain(byteParser('"'))),
 			func(d deserializer) (deserializer, []byte, *CombineResult) {
 				var buf []byte
-				inEscape := false
 				for {
 					var (
 						b  byte
@@ -461,25 +681,15 @@ func rawStringParser() parser[string, *CombineResult] {
 					if !br.OK {
 						return d, nil, CErr(ErrUnmatchedQuote)
 					}
-					if inEscape {
-						inEscape = false
-						switch b {
-						case '/', '"', '\\':
-							buf = append(buf, b)
-						default:
-							return d, nil, CErr(InvalidEscapeSequence{
-								Seq: []byte{'\\', b},
-								Row: d.row,
-								Col: d.col,
-							})
+					if b == '\\' {
+						var err error
+						if d, buf, err = appendEscape(d, buf); err != nil {
+							return d, nil, CErr(err)
 						}
 						continue
 					}
 					buf = append(buf, b)
-					switch b {
-					case '\\':
-						inEscape = true
-					case '"':
+					if b == '"' {
 						return d, buf, COK(true)
 					}
 				}
@@ -491,25 +701,143 @@ func rawStringParser() parser[string, *CombineResult] {
 	)
 }
 
-func floatParser() parserC[float64] {
+// appendEscape decodes the escape sequence after the backslash that starts it has been read, and
+// appends the escaped character to buf.
+func appendEscape(d deserializer, buf []byte) (deserializer, []byte, error) {
+	d, b, br := read(d)
+	if !br.OK {
+		return d, buf, ErrUnmatchedQuote
+	}
+	switch b {
+	case '/', '"', '\\':
+		return d, append(buf, b), nil
+	case 'b':
+		return d, append(buf, '\b'), nil
+	case 'f':
+		return d, append(buf, '\f'), nil
+	case 'n':
+		return d, append(buf, '\n'), nil
+	case 'r':
+		return d, append(buf, '\r'), nil
+	case 't':
+		return d, append(buf, '\t'), nil
+	case 'u':
+		d, r, err := unicodeEscape(d)
+		if err != nil {
+			return d, buf, err
+		}
+		return d, utf8.AppendRune(buf, r), nil
+	}
+	return d, buf, InvalidEscapeSequence{Seq: []byte{'\\', b}, Row: d.row, Col: d.col}
+}
+
+// unicodeEscape decodes the hex digits of a \u escape sequence, after the \u has been read. A
+// surrogate pair written as two escape sequences is decoded as a single rune. Surrogates that are
+// not part of a pair are decoded as U+FFFD, as they cannot be represented in utf-8.
+func unicodeEscape(d deserializer) (deserializer, rune, error) {
+	d, r, err := hexRune(d)
+	if err != nil || !utf16.IsSurrogate(r) {
+		return d, r, err
+	}
+	if d2, b1, br1 := read(d); br1.OK && b1 == '\\' {
+		if d3, b2, br2 := read(d2); br2.OK && b2 == 'u' {
+			if d4, r2, err := hexRune(d3); err == nil {
+				if pair := utf16.DecodeRune(r, r2); pair != utf8.RuneError {
+					return d4, pair, nil
+				}
+			}
+		}
+	}
+	return d, utf8.RuneError, nil
+}
+
+// hexRune reads the 4 hex digits of a \u escape sequence.
+func hexRune(d deserializer) (deserializer, rune, error) {
+	seq := []byte{'\\', 'u'}
+	var r rune
+	for i := 0; i < 4; i++ {
+		d2, b, br := read(d)
+		if !br.OK {
+			return d, 0, ErrUnmatchedQuote
+		}
+		var digit byte
+		switch {
+		case b >= '0' && b <= '9':
+			digit = b - '0'
+		case b >= 'a' && b <= 'f':
+			digit = b - 'a' + 10
+		case b >= 'A' && b <= 'F':
+			digit = b - 'A' + 10
+		default:
+			return d2, 0, InvalidEscapeSequence{Seq: append(seq, b), Row: d2.row, Col: d2.col}
+		}
+		seq = append(seq, b)
+		r = r<<4 | rune(digit)
+		d = d2
+	}
+	return d, r, nil
+}
+
+// stringHookParser applies the string normalization options and TransformString hook to strings
+// matched by p.
+func stringHookParser(p parser[string, *CombineResult], isKey bool) parser[string, *CombineResult] {
+	return func(d deserializer) (deserializer, string, *CombineResult) {
+		d2, s, cr := p(d)
+		if !cr.Valid() || d.opts == nil {
+			return d2, s, cr
+		}
+		if d.opts.NormalizeNewlines {
+			s = strings.ReplaceAll(s, "\r\n", "\n")
+		}
+		if d.opts.StripBOM {
+			s = strings.ReplaceAll(s, bom, "")
+		}
+		if d.opts.TransformString == nil {
+			return d2, s, cr
+		}
+		s, err := d.opts.TransformString(s, isKey)
+		if err != nil {
+			return d, "", CErr(HookError{Err: err, Row: d.row, Col: d.col})
+		}
+		return d2, s, cr
+	}
+}
+
+// floatParser parses a number with a fraction, an exponent or both.
+func floatParser() parserC[Number] {
 	return Validate(
 		ToC(
 			Flatten(
 				digitsParser(),
-				Chain(byteParser('.')),
-				digitsParser(),
+				Try(
+					Flatten(
+						Chain(byteParser('.')),
+						digitsParser(),
+						optionalParser(exponentParser()),
+					),
+					exponentParser(),
+				),
 			),
 		),
-		func(bb []byte) (float64, *CombineResult) {
+		func(bb []byte) (Number, *CombineResult) {
 			f, err := strconv.ParseFloat(string(bb), 64)
 			if err != nil {
-				return 0, CErr(err)
+				return Number{}, CErr(err)
 			}
-			return f, COK(true)
+			return Number{Float: f, IsFloat: true, IsExp: bytes.ContainsAny(bb, "eE")}, COK(true)
 		},
 	)
 }
 
+// exponentParser matches the exponent of a number, such as e10, E-3 or e+6.
+func exponentParser() parserB[[]byte] {
+	return Flatten(
+		Chain(Try(byteParser('e'), byteParser('E'))),
+		optionalParser(Chain(Try(byteParser('+'), byteParser('-')))),
+		digitsParser(),
+	)
+}
+
 func intParser() parserC[uint64] {
 	return Validate(
 		ToC(digitsParser()),
@@ -544,6 +872,18 @@ func predicateParser(predicate func(b byte) bool) parserB[[]byte] {
 	}
 }
 
+// optionalParser always succeeds, returning the empty value if p does not match.
+func optionalParser[V any](p func(deserializer) (deserializer, V, *BoolResult)) parserB[V] {
+	return func(d deserializer) (deserializer, V, *BoolResult) {
+		d2, v, br := p(d)
+		if !br.OK {
+			var empty V
+			return d, empty, OK(true)
+		}
+		return d2, v, br
+	}
+}
+
 func byteParser(b byte) parser[byte, *BoolResult] {
 	return func(d deserializer) (deserializer, byte, *BoolResult) {
 		if d2, bb, br := read(d); br.OK && bb == b {
@@ -1,6 +1,9 @@
 package genjson
 
 import (
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -146,3 +149,303 @@ func TestDeserialize(t *testing.T) {
 		})
 	}
 }
+
+func TestDeserializeParseNumber(t *testing.T) {
+	var raws []string
+	ds := Deserializer{
+		ParseNumber: func(raw []byte) (Value, error) {
+			raws = append(raws, string(raw))
+			if string(raw) == "0" {
+				return nil, errors.New("zero")
+			}
+			return String(raw), nil
+		},
+	}
+	v, err := ds.Deserialize([]byte(`[1, -2.5, 123456789012345678901234567890, 1.5E+6]`))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	want := Array{String("1"), String("-2.5"), String("123456789012345678901234567890"), String("1.5E+6")}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("unexpected value %v != %v", v, want)
+	}
+	if len(raws) != 4 {
+		t.Errorf("unexpected hook calls %v", raws)
+	}
+
+	_, err = ds.Deserialize([]byte(`[1, 0]`))
+	var he HookError
+	if !errors.As(err, &he) || he.Row != 1 || he.Col != 5 {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestDeserializeExponent(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Number
+		out   string
+	}{
+		{input: `1e10`, want: Number{Float: 1e10, IsFloat: true, IsExp: true}, out: `1e10`},
+		{input: `2.5E-3`, want: Number{Float: 2.5e-3, IsFloat: true, IsExp: true}, out: `2.5e-3`},
+		{input: `1e+6`, want: Number{Float: 1e6, IsFloat: true, IsExp: true}, out: `1e6`},
+		{input: `-1.25e02`, want: Number{Float: 125, IsFloat: true, IsNeg: true, IsExp: true}, out: `-1.25e2`},
+		{input: `0E0`, want: Number{IsFloat: true, IsExp: true}, out: `0e0`},
+		{input: `1.5`, want: Number{Float: 1.5, IsFloat: true}, out: `1.5`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			v, err := Deserialize([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if v != tt.want {
+				t.Errorf("unexpected value %#v != %#v", v, tt.want)
+			}
+			if out := string(Serialize(v)); out != tt.out {
+				t.Errorf("unexpected output %s != %s", out, tt.out)
+			}
+		})
+	}
+	if _, err := Deserialize([]byte(`1e400`)); err == nil {
+		t.Errorf("expected an error for an out of range exponent")
+	}
+}
+
+func TestDeserializeTransformString(t *testing.T) {
+	ds := Deserializer{
+		TransformString: func(s string, isKey bool) (string, error) {
+			if isKey {
+				return strings.ToUpper(s), nil
+			}
+			return strings.TrimSpace(s), nil
+		},
+	}
+	v, err := ds.Deserialize([]byte(`{"key": " value\t", "list": [" a "]}`))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if got := string(Serialize(v)); got != `{"KEY":"value","LIST":["a"]}` {
+		t.Errorf("unexpected value %s", got)
+	}
+}
+
+func TestDeserializeEscapes(t *testing.T) {
+	v, err := Deserialize([]byte(`"\"\\\/\b\f\n\r\t"`))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if want := String("\"\\/\b\f\n\r\t"); v != want {
+		t.Errorf("unexpected value %q != %q", v, want)
+	}
+}
+
+func TestDeserializeUnicodeEscapes(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Value
+		wantErr error
+	}{
+		{input: `"\u0041\u00e9\u00E9"`, want: String("Aéé")},
+		{input: `"\u20ac"`, want: String("€")},
+		{input: `"\ud83d\ude00"`, want: String("😀")},
+		{input: `"\uD83D\uDE00!"`, want: String("😀!")},
+		{input: `"\ud83d"`, want: String("\uFFFD")},
+		{input: `"\ude00\ud83d"`, want: String("\uFFFD\uFFFD")},
+		{input: `"\ud83dx"`, want: String("\uFFFDx")},
+		{input: `"\ud83d\u0041"`, want: String("\uFFFDA")},
+		{input: `"\u0000"`, want: String("\x00")},
+		{input: `"\u00g0"`, wantErr: InvalidEscapeSequence{Seq: []byte(`\u00g`), Row: 1, Col: 7}},
+		{input: `"\ud83d\u00g0"`, wantErr: InvalidEscapeSequence{Seq: []byte(`\u00g`), Row: 1, Col: 13}},
+		{input: `"\u00`, wantErr: ErrUnmatchedQuote},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			v, err := Deserialize([]byte(tt.input))
+			if !reflect.DeepEqual(err, tt.wantErr) {
+				t.Fatalf("unexpected error %v != %v", err, tt.wantErr)
+			}
+			if v != tt.want {
+				t.Errorf("unexpected value %q != %q", v, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeserializeString(t *testing.T) {
+	for _, input := range []string{``, `{"key": ["value", 1, null]}`} {
+		got, gotErr := DeserializeString(input)
+		want, wantErr := Deserialize([]byte(input))
+		if (gotErr != nil) != (wantErr != nil) || !reflect.DeepEqual(got, want) {
+			t.Errorf("unexpected result %v %v != %v %v", got, gotErr, want, wantErr)
+		}
+	}
+}
+
+func TestDeserializeEmpty(t *testing.T) {
+	tests := []struct {
+		input string
+		want  error
+	}{
+		{input: "", want: ErrEmptyInput},
+		{input: " \n\t", want: ErrEmptyInput},
+		{input: " \"a", want: ErrUnmatchedQuote},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if _, err := Deserialize([]byte(tt.input)); err != tt.want {
+				t.Errorf("unexpected error %v != %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeserializeRequireContainer(t *testing.T) {
+	ds := Deserializer{RequireContainer: true}
+	tests := []struct {
+		input string
+		want  error
+	}{
+		{input: ` {"a": 1}`},
+		{input: `[1]`},
+		{input: `1`, want: ErrTopLevelScalar},
+		{input: ` "a" `, want: ErrTopLevelScalar},
+		{input: `null`, want: ErrTopLevelScalar},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if _, err := ds.Deserialize([]byte(tt.input)); err != tt.want {
+				t.Errorf("unexpected error %v != %v", err, tt.want)
+			}
+		})
+	}
+
+	dec := NewDecoder(strings.NewReader("[1]\n2\n"))
+	dec.Deserializer = ds
+	if _, err := dec.DecodeValue(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := dec.DecodeValue(); err != ErrTopLevelScalar {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestMust(t *testing.T) {
+	if v := MustDeserialize([]byte(`[1]`)); !reflect.DeepEqual(v, Array{integer(1)}) {
+		t.Errorf("unexpected value %v", v)
+	}
+	if v := MustDeserializeString(`"a"`); v != String("a") {
+		t.Errorf("unexpected value %v", v)
+	}
+	if v := MustParseString(`["a"]`); !Equal(v, Array{String("a")}) {
+		t.Errorf("unexpected value %v", v)
+	}
+	if b := (&Serializer{IntegerOnly: true}).MustSerialize(Array{floatNumber(1)}); string(b) != `[1]` {
+		t.Errorf("unexpected output %s", b)
+	}
+	for name, f := range map[string]func(){
+		"deserialize":       func() { MustDeserialize([]byte(`[`)) },
+		"parse-string":      func() { MustParseString(`[`) },
+		"serialize":         func() { (&Serializer{IntegerOnly: true}).MustSerialize(floatNumber(2.5)) },
+		"require-container": func() { (&Serializer{RequireContainer: true}).MustSerialize(Null{}) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected panic")
+				}
+			}()
+			f()
+		})
+	}
+}
+
+func TestDeserializeNormalize(t *testing.T) {
+	ds := Deserializer{
+		NormalizeNewlines: true,
+		StripBOM:          true,
+	}
+	v, err := ds.Deserialize([]byte("\ufeff{\"\ufeffkey\": \"a\\r\\nb\r\nc\"}"))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if got := string(Serialize(v)); got != `{"key":"a\nb\nc"}` {
+		t.Errorf("unexpected value %s", got)
+	}
+}
+
+func TestDeserializeStripBOMLocation(t *testing.T) {
+	ds := Deserializer{StripBOM: true, NormalizeNewlines: true}
+	doc, err := ds.DeserializeDocument([]byte("\ufeff{\"a\": 1,\r\n\"b\": \"c\r\nd\"}"))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if got := string(Serialize(doc.Value())); got != `{"a":1,"b":"c\nd"}` {
+		t.Errorf("unexpected value %s", got)
+	}
+	for _, tt := range []struct {
+		got, want Loc
+	}{
+		{doc.node.start, Loc{Row: 1, Col: 1, Offset: 3}},
+		{doc.node.objectNodes[0].keyStart, Loc{Row: 1, Col: 2, Offset: 4}},
+		{doc.node.objectNodes[0].node.start, Loc{Row: 1, Col: 7, Offset: 9}},
+		{doc.node.objectNodes[1].keyStart, Loc{Row: 2, Col: 1, Offset: 13}},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("unexpected location %+v != %+v", tt.got, tt.want)
+		}
+	}
+
+	for data, want := range map[string]string{
+		"\ufeff{\"a\": x}":              "1:7: invalid token 'x'",
+		"\ufeff{\"a\": 1,\r\n\"b\": x}": "2:6: invalid token 'x'",
+	} {
+		if _, err := ds.Deserialize([]byte(data)); err == nil || err.Error() != want {
+			t.Errorf("unexpected error %v != %s", err, want)
+		}
+	}
+}
+
+func TestAppendEscape(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr error
+		// rest is the input left after the escape sequence.
+		rest string
+	}{
+		{in: `"`, want: `"`},
+		{in: `\`, want: `\`},
+		{in: `/`, want: `/`},
+		{in: `b`, want: "\b"},
+		{in: `f`, want: "\f"},
+		{in: `n`, want: "\n"},
+		{in: `r`, want: "\r"},
+		{in: `t`, want: "\t"},
+		{in: `tx`, want: "\t", rest: "x"},
+		{in: `u00e9`, want: "é"},
+		{in: `ud83d\ude00"`, want: "😀", rest: `"`},
+		{in: `ud83d"`, want: "�", rest: `"`},
+		{in: `x`, wantErr: InvalidEscapeSequence{Seq: []byte(`\x`), Row: 1, Col: 2}},
+		{in: `u12g4`, wantErr: InvalidEscapeSequence{Seq: []byte(`\u12g`), Row: 1, Col: 5}},
+		{in: `u12`, wantErr: ErrUnmatchedQuote},
+		{in: ``, wantErr: ErrUnmatchedQuote},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			d, got, err := appendEscape(newDeserializer([]byte(tt.in), nil), []byte("a"))
+			if !reflect.DeepEqual(err, tt.wantErr) {
+				t.Fatalf("unexpected error %v != %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if string(got) != "a"+tt.want {
+				t.Errorf("unexpected result %q != %q", got, "a"+tt.want)
+			}
+			if rest := string(d.b[d.idx:]); rest != tt.rest {
+				t.Errorf("unexpected rest %q != %q", rest, tt.rest)
+			}
+		})
+	}
+}
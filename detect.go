@@ -0,0 +1,131 @@
+package genjson
+
+import "unicode"
+
+// Format is the format of a json-like input.
+type Format int8
+
+const (
+	// FormatUnknown is returned for inputs that are not in any of the detected formats.
+	FormatUnknown Format = iota
+	// FormatJSON is a single json value.
+	FormatJSON
+	// FormatNDJSON is a sequence of json values, one per line.
+	FormatNDJSON
+	// FormatJSON5 is json extended with comments, trailing commas, single quoted strings or
+	// unquoted keys, such as JSON5 or JSONC.
+	FormatJSON5
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatJSON:
+		return "json"
+	case FormatNDJSON:
+		return "ndjson"
+	case FormatJSON5:
+		return "json5"
+	}
+	return "unknown"
+}
+
+// DetectFormat heuristically identifies the format of data. Data that deserializes to a single
+// value is FormatJSON, while multiple values that each occupy a single line are FormatNDJSON.
+// Data that is not valid json but starts like an object or array and uses a JSON5 or JSONC
+// extension is FormatJSON5. Everything else, including empty input, is FormatUnknown.
+func DetectFormat(data []byte) Format {
+	d := skipSpace(newDeserializer(data, &Deserializer{StripBOM: true}))
+	values := 0
+	oneLine := true
+	for d.idx < len(d.b) {
+		row := d.row
+		nd, _, err := deserializeNext(d)
+		if err != nil {
+			if looksLikeJSON5(d.b[d.idx:]) {
+				return FormatJSON5
+			}
+			return FormatUnknown
+		}
+		if nd.row != row {
+			oneLine = false
+		}
+		d = skipSpace(nd)
+		if values++; d.idx < len(d.b) && d.row == nd.row {
+			// Multiple values on the same line are neither json nor ndjson.
+			return FormatUnknown
+		}
+	}
+	switch {
+	case values == 1:
+		return FormatJSON
+	case values > 1 && oneLine:
+		return FormatNDJSON
+	}
+	return FormatUnknown
+}
+
+// looksLikeJSON5 reports whether b starts with an object, array or comment and contains a JSON5
+// extension outside of double quoted strings.
+func looksLikeJSON5(b []byte) bool {
+	if len(b) == 0 || (b[0] != '{' && b[0] != '[' && b[0] != '/') {
+		return false
+	}
+	inString := false
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		if inString {
+			switch c {
+			case '\\':
+				i++
+			case '"':
+				inString = false
+			}
+			continue
+		}
+		switch {
+		case c == '"':
+			inString = true
+		case c == '\'':
+			return true
+		case c == '/' && i+1 < len(b) && (b[i+1] == '/' || b[i+1] == '*'):
+			return true
+		case c == ',':
+			j := i + 1
+			for j < len(b) && unicode.IsSpace(rune(b[j])) {
+				j++
+			}
+			if j < len(b) && (b[j] == '}' || b[j] == ']') {
+				return true
+			}
+		}
+		if (c == '{' || c == ',') && unquotedKey(b[i+1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// unquotedKey reports whether b starts with optional whitespace followed by an identifier and a
+// colon.
+func unquotedKey(b []byte) bool {
+	i := 0
+	for i < len(b) && unicode.IsSpace(rune(b[i])) {
+		i++
+	}
+	start := i
+	for i < len(b) && isIdentByte(b[i], i == start) {
+		i++
+	}
+	if i == start {
+		return false
+	}
+	for i < len(b) && unicode.IsSpace(rune(b[i])) {
+		i++
+	}
+	return i < len(b) && b[i] == ':'
+}
+
+func isIdentByte(b byte, first bool) bool {
+	r := rune(b)
+	return b == '_' || b == '$' || unicode.IsLetter(r) || (!first && unicode.IsDigit(r))
+}
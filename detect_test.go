@@ -0,0 +1,37 @@
+package genjson
+
+import "testing"
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want Format
+	}{
+		{name: "empty", data: "", want: FormatUnknown},
+		{name: "whitespace", data: " \n", want: FormatUnknown},
+		{name: "object", data: `{"a": 1}`, want: FormatJSON},
+		{name: "multi-line-object", data: "{\n\"a\": 1\n}\n", want: FormatJSON},
+		{name: "bom", data: "\ufeff[1]", want: FormatJSON},
+		{name: "scalar", data: `1`, want: FormatJSON},
+		{name: "ndjson", data: "{\"a\": 1}\n{\"a\": 2}\n", want: FormatNDJSON},
+		{name: "ndjson-blank-lines", data: "1\n\n\"a\"\r\n[]", want: FormatNDJSON},
+		{name: "same-line", data: `1 2`, want: FormatUnknown},
+		{name: "multi-line-values", data: "{\n}\n{\n}", want: FormatUnknown},
+		{name: "line-comment", data: "{\n// comment\n\"a\": 1}", want: FormatJSON5},
+		{name: "block-comment", data: "/* c */ [1]", want: FormatJSON5},
+		{name: "trailing-comma", data: "[1, 2, ]", want: FormatJSON5},
+		{name: "single-quotes", data: "{'a': 1}", want: FormatJSON5},
+		{name: "unquoted-key", data: "{a: 1, $b_2: 2}", want: FormatJSON5},
+		{name: "comment-in-string", data: `{"a": "//", "b"}`, want: FormatUnknown},
+		{name: "text", data: "hello world", want: FormatUnknown},
+		{name: "invalid", data: `{"a" 1}`, want: FormatUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectFormat([]byte(tt.data)); got != tt.want {
+				t.Errorf("unexpected format %s != %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package genjson
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Differ contains the options used when generating json patches.
+type Differ struct {
+	// ReplaceArrays replaces arrays that differ as a whole instead of patching their elements by
+	// index. This produces larger patches for small changes to long arrays, but the patch does not
+	// depend on the elements of the original array staying at the same positions.
+	ReplaceArrays bool
+	// Comments makes SourceChanges also report comments that were added, removed or edited, so
+	// that changes to the documentation of a config are reported alongside changes to its values.
+	Comments bool
+}
+
+var defDiffer Differ
+
+// Diff returns a json patch (RFC 6902) that transforms a into b, such that applying it with Patch
+// returns a value equal to b. Members of objects are compared by key and added, removed or patched
+// recursively, and elements of arrays by index unless ReplaceArrays is set. Values that are equal
+// as with Array.Contains produce no operations, so the patch of equal values is empty.
+//
+// Objects with duplicate keys cannot be addressed by json pointers, so a PathError with a
+// DuplicateKeyError is returned for them.
+func (df *Differ) Diff(a, b Value) (Value, error) {
+	if err := checkNoDuplicateKeys(a, nil); err != nil {
+		return nil, err
+	}
+	if err := checkNoDuplicateKeys(b, nil); err != nil {
+		return nil, err
+	}
+	ops := Array{}
+	df.diff(a, b, Pointer{}, &ops)
+	return ops, nil
+}
+
+// Diff returns a json patch that transforms a into b with the default Differ. See Differ.Diff.
+func Diff(a, b Value) (Value, error) {
+	return defDiffer.Diff(a, b)
+}
+
+// diff appends the operations that transform a into b to ops. p is the pointer to a.
+func (df *Differ) diff(a, b Value, p Pointer, ops *Array) {
+	ao, aObject := a.(Object)
+	bo, bObject := b.(Object)
+	if aObject && bObject {
+		df.diffObjects(ao, bo, p, ops)
+		return
+	}
+	if Equal(a, b) {
+		return
+	}
+	aa, aArray := a.(Array)
+	ba, bArray := b.(Array)
+	if aArray && bArray && !df.ReplaceArrays {
+		df.diffArrays(aa, ba, p, ops)
+		return
+	}
+	*ops = append(*ops, patchOp("replace", p, b))
+}
+
+func (df *Differ) diffObjects(a, b Object, p Pointer, ops *Array) {
+	for _, e := range a.Entries() {
+		if bv, ok := b.Get(e.Key); ok {
+			df.diff(e.Value, bv, p.Append(e.Key), ops)
+		} else {
+			*ops = append(*ops, patchOp("remove", p.Append(e.Key), nil))
+		}
+	}
+	for _, e := range b.Entries() {
+		if _, ok := a.Get(e.Key); !ok {
+			*ops = append(*ops, patchOp("add", p.Append(e.Key), e.Value))
+		}
+	}
+}
+
+func (df *Differ) diffArrays(a, b Array, p Pointer, ops *Array) {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		df.diff(a[i], b[i], p.Append(strconv.Itoa(i)), ops)
+	}
+	// Remove from the end so that the indexes of the remaining elements do not change.
+	for i := len(a) - 1; i >= n; i-- {
+		*ops = append(*ops, patchOp("remove", p.Append(strconv.Itoa(i)), nil))
+	}
+	for i := n; i < len(b); i++ {
+		*ops = append(*ops, patchOp("add", p.Append("-"), b[i]))
+	}
+}
+
+// ChangeKind is the kind of a Change.
+type ChangeKind int8
+
+const (
+	// ChangeAdded is a value that is only present in the new value.
+	ChangeAdded ChangeKind = iota
+	// ChangeRemoved is a value that is only present in the old value.
+	ChangeRemoved
+	// ChangeModified is a value that is present in both values but differs.
+	ChangeModified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeModified:
+		return "modified"
+	}
+	return "unknown"
+}
+
+// Change is a single difference between two values.
+type Change struct {
+	Kind ChangeKind
+	// Path is the path of the value within the compared values.
+	Path Path
+	// Before is the old value. It is nil for added values.
+	Before Value
+	// After is the new value. It is nil for removed values.
+	After Value
+	// Comment reports whether the change is to a comment attached to the value at Path rather
+	// than to the value itself, as reported by SourceChanges. Before and After are then Strings
+	// with the text of the comment.
+	Comment bool
+}
+
+// String returns the change as a line of a report: the path prefixed with +, - or ~ for added,
+// removed and modified values, followed by the values as compact json.
+func (c Change) String() string {
+	var sb strings.Builder
+	switch c.Kind {
+	case ChangeAdded:
+		sb.WriteString("+ ")
+	case ChangeRemoved:
+		sb.WriteString("- ")
+	default:
+		sb.WriteString("~ ")
+	}
+	if len(c.Path) == 0 {
+		sb.WriteString("(root)")
+	} else {
+		sb.WriteString(c.Path.String())
+	}
+	if c.Comment {
+		sb.WriteString(" (comment)")
+	}
+	sb.WriteString(": ")
+	if c.Before != nil {
+		sb.Write(defSerializer.Serialize(c.Before))
+	}
+	if c.Before != nil && c.After != nil {
+		sb.WriteString(" -> ")
+	}
+	if c.After != nil {
+		sb.Write(defSerializer.Serialize(c.After))
+	}
+	return sb.String()
+}
+
+// Changes lists the differences between two values, as returned by Differ.Changes.
+type Changes []Change
+
+// String returns a report of the changes with one line per change, e.g. for test failures:
+//
+//	~ user.name: "a" -> "b"
+//	- user.tags[1]: "x"
+//	+ user.age: 3
+//	~ user.age (comment): "in years" -> "in whole years"
+func (cs Changes) String() string {
+	lines := make([]string, len(cs))
+	for i, c := range cs {
+		lines[i] = c.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Changes returns the differences between a and b as a list of changes with the values before and
+// after them, rather than as a json patch. Values are compared as Diff compares them, so equal
+// values have no changes and arrays are compared by index unless ReplaceArrays is set. Unlike Diff,
+// duplicate keys are supported: the entries of a key are matched by their ordinal, which is part
+// of the path of their changes.
+func (df *Differ) Changes(a, b Value) Changes {
+	var cs Changes
+	df.changes(a, b, Path{}, &cs)
+	return cs
+}
+
+// DiffChanges returns the differences between a and b with the default Differ. See
+// Differ.Changes.
+func DiffChanges(a, b Value) Changes {
+	return defDiffer.Changes(a, b)
+}
+
+// changes appends the changes between a and b to cs. p is the path of a.
+func (df *Differ) changes(a, b Value, p Path, cs *Changes) {
+	ao, aObject := a.(Object)
+	bo, bObject := b.(Object)
+	if aObject && bObject {
+		df.objectChanges(ao, bo, p, cs)
+		return
+	}
+	if Equal(a, b) {
+		return
+	}
+	aa, aArray := a.(Array)
+	ba, bArray := b.(Array)
+	if aArray && bArray && !df.ReplaceArrays {
+		df.arrayChanges(aa, ba, p, cs)
+		return
+	}
+	*cs = append(*cs, Change{Kind: ChangeModified, Path: p, Before: a, After: b})
+}
+
+// entryKey identifies an entry of an object by its key and ordinal.
+type entryKey struct {
+	key     string
+	ordinal int
+}
+
+func (df *Differ) objectChanges(a, b Object, p Path, cs *Changes) {
+	step := func(e Entry) PathStep {
+		if e.Ordinal > 0 {
+			return KeyAt(e.Key, e.Ordinal)
+		}
+		return Key(e.Key)
+	}
+	aEntries, bEntries := a.Entries(), b.Entries()
+	bValues := make(map[entryKey]Value, len(bEntries))
+	for _, e := range bEntries {
+		bValues[entryKey{e.Key, e.Ordinal}] = e.Value
+	}
+	aKeys := make(map[entryKey]bool, len(aEntries))
+	for _, e := range aEntries {
+		k := entryKey{e.Key, e.Ordinal}
+		aKeys[k] = true
+		if bv, ok := bValues[k]; ok {
+			df.changes(e.Value, bv, p.Append(step(e)), cs)
+		} else {
+			*cs = append(*cs, Change{Kind: ChangeRemoved, Path: p.Append(step(e)), Before: e.Value})
+		}
+	}
+	for _, e := range bEntries {
+		if !aKeys[entryKey{e.Key, e.Ordinal}] {
+			*cs = append(*cs, Change{Kind: ChangeAdded, Path: p.Append(step(e)), After: e.Value})
+		}
+	}
+}
+
+func (df *Differ) arrayChanges(a, b Array, p Path, cs *Changes) {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		switch {
+		case i >= len(b):
+			*cs = append(*cs, Change{Kind: ChangeRemoved, Path: p.Append(Index(i)), Before: a[i]})
+		case i >= len(a):
+			*cs = append(*cs, Change{Kind: ChangeAdded, Path: p.Append(Index(i)), After: b[i]})
+		default:
+			df.changes(a[i], b[i], p.Append(Index(i)), cs)
+		}
+	}
+}
+
+// patchOp returns a patch operation. The value is omitted if it is nil.
+func patchOp(op string, p Pointer, v Value) Object {
+	entries := []Entry{
+		{Key: "op", Value: String(op)},
+		{Key: "path", Value: String(p.String())},
+	}
+	if v != nil {
+		entries = append(entries, Entry{Key: "value", Value: v})
+	}
+	return NewObject(entries...)
+}
+
+// checkNoDuplicateKeys returns an error if v contains an object with duplicate keys. at is the
+// path of v.
+func checkNoDuplicateKeys(v Value, at Path) error {
+	switch v := v.(type) {
+	case Object:
+		for _, e := range v.Entries() {
+			if e.Ordinal > 0 {
+				return PathError{Path: at.Append(KeyAt(e.Key, e.Ordinal)), Err: DuplicateKeyError{Key: e.Key}}
+			}
+			if err := checkNoDuplicateKeys(e.Value, at.Append(Key(e.Key))); err != nil {
+				return err
+			}
+		}
+	case Array:
+		for i, elem := range v {
+			if err := checkNoDuplicateKeys(elem, at.Append(Index(i))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
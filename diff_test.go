@@ -0,0 +1,115 @@
+package genjson
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		replace bool
+		want    string
+	}{
+		{name: "equal", a: `{"a": [1, {"b": 2.0}]}`, b: `{"a": [1, {"b": 2}]}`, want: `[]`},
+		{name: "scalar", a: `1`, b: `"x"`, want: `[{"op": "replace", "path": "", "value": "x"}]`},
+		{name: "members", a: `{"a": 1, "b": 2, "c": 3}`, b: `{"c": 3, "b": 4, "d": 5}`, want: `[
+			{"op": "remove", "path": "/a"},
+			{"op": "replace", "path": "/b", "value": 4},
+			{"op": "add", "path": "/d", "value": 5}
+		]`},
+		{name: "nested", a: `{"a": {"b": {"c": 1}}}`, b: `{"a": {"b": {"c": 2}}}`, want: `[{"op": "replace", "path": "/a/b/c", "value": 2}]`},
+		{name: "escaped-keys", a: `{"a/b": 1, "~": 2}`, b: `{"a/b": 2}`, want: `[
+			{"op": "replace", "path": "/a~1b", "value": 2},
+			{"op": "remove", "path": "/~0"}
+		]`},
+		{name: "array-shrink", a: `[1, 2, 3, 4]`, b: `[1, 5]`, want: `[
+			{"op": "replace", "path": "/1", "value": 5},
+			{"op": "remove", "path": "/3"},
+			{"op": "remove", "path": "/2"}
+		]`},
+		{name: "array-grow", a: `{"a": [1]}`, b: `{"a": [1, [2], {"c": 3}]}`, want: `[
+			{"op": "add", "path": "/a/-", "value": [2]},
+			{"op": "add", "path": "/a/-", "value": {"c": 3}}
+		]`},
+		{name: "array-replace", a: `{"a": [1, 2, 3]}`, b: `{"a": [1, 2, 4]}`, replace: true, want: `[{"op": "replace", "path": "/a", "value": [1, 2, 4]}]`},
+		{name: "array-replace-equal", a: `[1, {"a": 1, "b": 2}]`, b: `[1, {"b": 2, "a": 1}]`, replace: true, want: `[]`},
+		{name: "type-change", a: `{"a": [1]}`, b: `{"a": {"0": 1}}`, want: `[{"op": "replace", "path": "/a", "value": {"0": 1}}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := MustDeserializeString(tt.a), MustDeserializeString(tt.b)
+			df := Differ{ReplaceArrays: tt.replace}
+			patch, err := df.Diff(a, b)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if got, want := string(Serialize(patch)), string(Serialize(MustDeserializeString(tt.want))); got != want {
+				t.Errorf("unexpected patch\n%s\n%s", got, want)
+			}
+			patched, err := Patch(a, patch)
+			if err != nil {
+				t.Fatalf("unexpected patch error %v", err)
+			}
+			if !Equal(patched, b) {
+				t.Errorf("patched value %s != %s", Serialize(patched), Serialize(b))
+			}
+		})
+	}
+}
+
+func TestDiffDuplicateKeys(t *testing.T) {
+	_, err := Diff(MustDeserializeString(`{"a": [{"b": 1, "b": 2}]}`), Object{})
+	var pe PathError
+	want := Path{Key("a"), Index(0), KeyAt("b", 1)}
+	if !errors.As(err, &pe) || pe.Path.String() != want.String() {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !errors.Is(err, DuplicateKeyError{Key: "b"}) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestDiffChanges(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		replace bool
+		want    string
+	}{
+		{name: "equal", a: `{"a": [1, {"b": 2.0}]}`, b: `{"b": null, "a": [1, {"b": 2}]}`, want: "+ b: null"},
+		{name: "scalar", a: `1`, b: `"x"`, want: `~ (root): 1 -> "x"`},
+		{name: "members", a: `{"a": 1, "b": 2, "c": 3}`, b: `{"c": 3, "b": 4, "d": [5]}`, want: "- a: 1\n~ b: 2 -> 4\n+ d: [5]"},
+		{name: "nested", a: `{"a": {"b c": [{"d": 1}]}}`, b: `{"a": {"b c": [{"d": 2}]}}`, want: `~ a["b c"][0].d: 1 -> 2`},
+		{name: "array-shrink", a: `[1, 2, 3, 4]`, b: `[1, 5]`, want: "~ [1]: 2 -> 5\n- [2]: 3\n- [3]: 4"},
+		{name: "array-grow", a: `{"a": [1]}`, b: `{"a": [1, {"c": 3}]}`, want: `+ a[1]: {"c":3}`},
+		{name: "array-replace", a: `{"a": [1, 2]}`, b: `{"a": [1, 3]}`, replace: true, want: "~ a: [1,2] -> [1,3]"},
+		{name: "duplicate-keys", a: `{"a": 1, "a": 2, "a": 3}`, b: `{"a": 1, "a": 4}`, want: "~ a#1: 2 -> 4\n- a#2: 3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			df := Differ{ReplaceArrays: tt.replace}
+			cs := df.Changes(MustDeserializeString(tt.a), MustDeserializeString(tt.b))
+			if got := cs.String(); got != tt.want {
+				t.Errorf("unexpected changes\n%s\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffChangesValues(t *testing.T) {
+	cs := DiffChanges(MustDeserializeString(`{"a": 1, "b": 2}`), MustDeserializeString(`{"a": 3, "c": 4}`))
+	want := Changes{
+		{Kind: ChangeModified, Path: Path{Key("a")}, Before: Number{Integer: 1}, After: Number{Integer: 3}},
+		{Kind: ChangeRemoved, Path: Path{Key("b")}, Before: Number{Integer: 2}},
+		{Kind: ChangeAdded, Path: Path{Key("c")}, After: Number{Integer: 4}},
+	}
+	if !reflect.DeepEqual(cs, want) {
+		t.Errorf("unexpected changes %#v", cs)
+	}
+	if len(DiffChanges(MustDeserializeString(`[1, 2]`), MustDeserializeString(`[1, 2.0]`))) != 0 {
+		t.Errorf("expected no changes for equal values")
+	}
+}
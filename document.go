@@ -0,0 +1,153 @@
+package genjson
+
+// Document is a deserialized json value together with its source and the location of every value
+// within it.
+type Document struct {
+	src   []byte
+	value Value
+	node  node
+	// opts are the options the document was deserialized with. They are reused when the document
+	// is edited.
+	opts *Deserializer
+}
+
+// DeserializeDocument deserializes b into a Document. The Document retains b, which must not be
+// modified.
+func (ds *Deserializer) DeserializeDocument(b []byte) (*Document, error) {
+	o, err := ds.deserialize(b)
+	if err != nil {
+		return nil, err
+	}
+	return &Document{
+		src:   b,
+		value: o.value,
+		node:  o.node,
+		opts:  ds,
+	}, nil
+}
+
+func DeserializeDocument(b []byte) (*Document, error) {
+	return defDeserializer.DeserializeDocument(b)
+}
+
+// Value returns the value of the document. The value must not be modified as the location
+// information of the document would no longer match it.
+func (d *Document) Value() Value {
+	return d.value
+}
+
+// Source returns the json the document was deserialized from.
+func (d *Document) Source() []byte {
+	return d.src
+}
+
+// UnsafeIntegers returns the location of every integer in the document that cannot be represented
+// exactly by a javascript number. See MaxSafeInteger.
+func (d *Document) UnsafeIntegers() []Loc {
+	var locs []Loc
+	var walk func(v Value, n *node)
+	walk = func(v Value, n *node) {
+		switch v := v.(type) {
+		case Number:
+			if !v.IsSafeInteger() {
+				locs = append(locs, n.start)
+			}
+		case Array:
+			for i, elem := range v {
+				walk(elem, &n.arrayNodes[i])
+			}
+		case Object:
+			for i, e := range v.Entries() {
+				walk(e.Value, &n.objectNodes[i].node)
+			}
+		}
+	}
+	walk(d.value, &d.node)
+	return locs
+}
+
+// DuplicateKey is a key that appears more than once in the same object.
+type DuplicateKey struct {
+	Key string
+	// Loc is the location of the duplicate key.
+	Loc Loc
+	// First is the location of the first occurrence of the key in the object.
+	First Loc
+}
+
+// DuplicateKeys returns every key in the document that repeats an earlier key of the same object,
+// in document order.
+func (d *Document) DuplicateKeys() []DuplicateKey {
+	var dups []DuplicateKey
+	var walk func(v Value, n *node)
+	walk = func(v Value, n *node) {
+		switch v := v.(type) {
+		case Array:
+			for i, elem := range v {
+				walk(elem, &n.arrayNodes[i])
+			}
+		case Object:
+			first := make(map[string]Loc)
+			for i, e := range v.Entries() {
+				kv := &n.objectNodes[i]
+				if e.Ordinal == 0 {
+					first[e.Key] = kv.keyStart
+				} else {
+					dups = append(dups, DuplicateKey{Key: e.Key, Loc: kv.keyStart, First: first[e.Key]})
+				}
+				walk(e.Value, &kv.node)
+			}
+		}
+	}
+	walk(d.value, &d.node)
+	return dups
+}
+
+// Loc returns the location of the start of the value at path within the document, resolved as by
+// Path.Get.
+func (d *Document) Loc(path Path) (Loc, error) {
+	_, n, err := d.lookup(path)
+	if err != nil {
+		return Loc{}, err
+	}
+	return n.start, nil
+}
+
+// lookup returns the value at path and its node.
+func (d *Document) lookup(path Path) (Value, *node, error) {
+	v, n := d.value, &d.node
+	for i, step := range path {
+		child, exists, err := childAt(v, step)
+		if err != nil {
+			return nil, nil, PathError{Path: path[:i+1], Err: err}
+		}
+		if !exists {
+			return nil, nil, PathError{Path: path[:i+1], Err: ErrPathNotFound}
+		}
+		if step.IsIndex {
+			n = &n.arrayNodes[step.Index]
+		} else {
+			for j, e := range v.(Object).Entries() {
+				if e.Key == step.Key && e.Ordinal == step.Ordinal {
+					n = &n.objectNodes[j].node
+					break
+				}
+			}
+		}
+		v = child
+	}
+	return v, n, nil
+}
+
+// advance returns the location of the byte at offset, starting from a known location before it.
+func (d *Document) advance(from Loc, offset int) Loc {
+	for ; from.Offset < offset; from.Offset++ {
+		if d.src[from.Offset] == '\n' {
+			from.Row++
+			from.Col = 1
+		} else {
+			from.Col++
+		}
+	}
+	return from
+}
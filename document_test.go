@@ -0,0 +1,60 @@
+package genjson
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestUnsafeIntegers(t *testing.T) {
+	doc, err := DeserializeDocument([]byte(`{"a": [9007199254740991, 9007199254740992], "b": -9007199254740993}`))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	want := []Loc{{Row: 1, Col: 26, Offset: 25}, {Row: 1, Col: 50, Offset: 49}}
+	if got := doc.UnsafeIntegers(); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected locations %+v != %+v", got, want)
+	}
+
+	s := Serializer{UnsafeIntegersAsStrings: true}
+	if got := string(s.Serialize(doc.Value())); got != `{"a":[9007199254740991,"9007199254740992"],"b":"-9007199254740993"}` {
+		t.Errorf("unexpected output %s", got)
+	}
+}
+
+func TestDuplicateKeys(t *testing.T) {
+	doc, err := DeserializeDocument([]byte("{\"a\": 1, \"b\": [{\"c\": 1, \"c\": 2}],\n\"a\": 3, \"a\": 4}"))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	want := []DuplicateKey{
+		{Key: "c", Loc: Loc{Row: 1, Col: 25, Offset: 24}, First: Loc{Row: 1, Col: 17, Offset: 16}},
+		{Key: "a", Loc: Loc{Row: 2, Col: 1, Offset: 34}, First: Loc{Row: 1, Col: 2, Offset: 1}},
+		{Key: "a", Loc: Loc{Row: 2, Col: 9, Offset: 42}, First: Loc{Row: 1, Col: 2, Offset: 1}},
+	}
+	if got := doc.DuplicateKeys(); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected duplicates %+v != %+v", got, want)
+	}
+}
+
+func TestDocumentLoc(t *testing.T) {
+	doc, err := DeserializeDocument([]byte("{\"a\": [1, {\"b\": 2}],\n\"a\": 3}"))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, tt := range []struct {
+		path Path
+		want Loc
+	}{
+		{Path{}, Loc{Row: 1, Col: 1, Offset: 0}},
+		{Path{Key("a"), Index(1), Key("b")}, Loc{Row: 1, Col: 17, Offset: 16}},
+		{Path{KeyAt("a", 1)}, Loc{Row: 2, Col: 6, Offset: 26}},
+	} {
+		if got, err := doc.Loc(tt.path); err != nil || got != tt.want {
+			t.Errorf("%s: unexpected location %+v %v", tt.path, got, err)
+		}
+	}
+	if _, err := doc.Loc(Path{Key("a"), Index(2)}); !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
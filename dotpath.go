@@ -0,0 +1,135 @@
+package genjson
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidDotPath is returned when a string is not a valid dot path.
+var ErrInvalidDotPath = errors.New("invalid dot path")
+
+// DotPath is a path in the dot syntax of gjson, such as a.b.2.c, for compatibility with code
+// written against gjson. Each element is an unescaped key, so like a Pointer a DotPath can be used
+// anywhere a path is expected, such as GetPath and SetPath. Keys are applied to arrays as indexes
+// if they are valid integers.
+//
+// Only plain paths are supported. Wildcards, queries, modifiers and pipes are rejected by
+// ParseDotPath.
+type DotPath []string
+
+// ParseDotPath parses a gjson style path. Keys are separated by dots and a backslash escapes the
+// following character, so a\.b is the single key a.b. The empty string is the path to the whole
+// document.
+func ParseDotPath(s string) (DotPath, error) {
+	if s == "" {
+		return DotPath{}, nil
+	}
+	var (
+		p       DotPath
+		sb      strings.Builder
+		escaped bool
+	)
+	// raw is the unescaped text of the current key, used to detect gjson syntax.
+	raw := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			if i+1 == len(s) {
+				return nil, fmt.Errorf("%w %q: trailing backslash", ErrInvalidDotPath, s)
+			}
+			i++
+			sb.WriteByte(s[i])
+			escaped = true
+			continue
+		case c == '.':
+			if err := checkDotKey(s, s[raw:i], escaped); err != nil {
+				return nil, err
+			}
+			p = append(p, sb.String())
+			sb.Reset()
+			raw, escaped = i+1, false
+			continue
+		case c == '*' || c == '?' || c == '|':
+			return nil, fmt.Errorf("%w %q: unsupported gjson syntax %q at offset %d", ErrInvalidDotPath, s, c, i)
+		}
+		sb.WriteByte(c)
+	}
+	if err := checkDotKey(s, s[raw:], escaped); err != nil {
+		return nil, err
+	}
+	return append(p, sb.String()), nil
+}
+
+// checkDotKey rejects the gjson syntax for array lengths, queries and modifiers, which are only
+// special at the start of an unescaped key.
+func checkDotKey(s, raw string, escaped bool) error {
+	if escaped {
+		return nil
+	}
+	if raw == "#" || strings.HasPrefix(raw, "#(") || strings.HasPrefix(raw, "@") {
+		return fmt.Errorf("%w %q: unsupported gjson syntax %q", ErrInvalidDotPath, s, raw)
+	}
+	return nil
+}
+
+// MustParseDotPath parses a dot path, panicking if it is invalid. It is intended for paths that
+// are known to be valid, such as constants.
+func MustParseDotPath(s string) DotPath {
+	p, err := ParseDotPath(s)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// String returns the path with every key escaped, as gjson.Escape does.
+func (p DotPath) String() string {
+	var sb strings.Builder
+	for i, key := range p {
+		if i > 0 {
+			sb.WriteByte('.')
+		}
+		sb.WriteString(EscapeDotPathKey(key))
+	}
+	return sb.String()
+}
+
+// Get returns the value the path refers to within root. Like GetPath, a key of the form key#N that
+// is not a key of its object selects the entry of key with ordinal N.
+func (p DotPath) Get(root Value) (Value, error) {
+	return GetPath(root, p)
+}
+
+// DotPath returns the path as a gjson style dot path. Ordinals are written as key#N.
+func (p Path) DotPath() DotPath {
+	return DotPath(p.Pointer())
+}
+
+// GetDotPath returns the value at the gjson style path s within root.
+func GetDotPath(root Value, s string) (Value, error) {
+	p, err := ParseDotPath(s)
+	if err != nil {
+		return nil, err
+	}
+	return p.Get(root)
+}
+
+// EscapeDotPathKey escapes a key for use in a dot path. Every ascii punctuation character other
+// than _, - and : is escaped with a backslash.
+func EscapeDotPathKey(key string) string {
+	var sb strings.Builder
+	for i := 0; i < len(key); i++ {
+		if c := key[i]; !safeDotPathChar(c) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(key[i])
+	}
+	return sb.String()
+}
+
+func safeDotPathChar(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') ||
+		c <= ' ' || c > '~' || c == '_' || c == '-' || c == ':'
+}
@@ -0,0 +1,83 @@
+package genjson
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseDotPath(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    DotPath
+		wantErr bool
+	}{
+		{in: "", want: DotPath{}},
+		{in: "a", want: DotPath{"a"}},
+		{in: "a.b.2.c", want: DotPath{"a", "b", "2", "c"}},
+		{in: `fav\.movie.x`, want: DotPath{"fav.movie", "x"}},
+		{in: `a\\b.\*\?`, want: DotPath{`a\b`, "*?"}},
+		{in: `\#.\@x`, want: DotPath{"#", "@x"}},
+		{in: "a#1.b", want: DotPath{"a#1", "b"}},
+		{in: "a..b", want: DotPath{"a", "", "b"}},
+		{in: `a\`, wantErr: true},
+		{in: "a.*", wantErr: true},
+		{in: "a?", wantErr: true},
+		{in: "a|b", wantErr: true},
+		{in: "friends.#", wantErr: true},
+		{in: `friends.#(last=="Murphy")`, wantErr: true},
+		{in: "children.@reverse", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseDotPath(tt.in)
+			if err != nil != tt.wantErr {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if err != nil {
+				if !errors.Is(err, ErrInvalidDotPath) {
+					t.Errorf("unexpected error %v", err)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unexpected path %q != %q", got, tt.want)
+			}
+			if again := MustParseDotPath(got.String()); !reflect.DeepEqual(again, got) {
+				t.Errorf("path does not round trip through %s: %q", got.String(), again)
+			}
+		})
+	}
+}
+
+func TestDotPathGet(t *testing.T) {
+	v := MustDeserializeString(`{"name": {"first": "Tom"}, "fav.movie": "Deer Hunter", "friends": [{"age": 44}, {"age": 68}], "a": 1, "a": 2}`)
+	tests := []struct {
+		path    string
+		want    Value
+		wantErr error
+	}{
+		{path: "name.first", want: String("Tom")},
+		{path: `fav\.movie`, want: String("Deer Hunter")},
+		{path: "friends.1.age", want: integer(68)},
+		{path: "a#1", want: integer(2)},
+		{path: "friends.2", wantErr: ErrPathNotFound},
+		{path: "friends.#", wantErr: ErrInvalidDotPath},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := GetDotPath(v, tt.path)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unexpected value %v != %v", got, tt.want)
+			}
+		})
+	}
+
+	p := Path{Key("fav.movie"), Index(1), KeyAt("a", 1)}
+	if got, want := p.DotPath().String(), `fav\.movie.1.a\#1`; got != want {
+		t.Errorf("unexpected dot path %s != %s", got, want)
+	}
+}
@@ -0,0 +1,198 @@
+package genjson
+
+import "fmt"
+
+// EditRangeError is returned by Document.Edit when the edited range is not within the source.
+type EditRangeError struct {
+	Start, End, Len int
+}
+
+func (e EditRangeError) Error() string {
+	return fmt.Sprintf("edit range %d:%d is invalid for a document of length %d", e.Start, e.End, e.Len)
+}
+
+// Edit returns a new document with the source bytes between the offsets start and end replaced by
+// text. Only the smallest value containing the edit is deserialized again, falling back to its
+// parents if the edit changes where the value ends. The location of every value after the edit is
+// shifted rather than recomputed, which keeps edits of large documents cheap.
+//
+// The returned document shares unedited values with d. d remains valid and is not modified.
+func (d *Document) Edit(start, end int, text []byte) (*Document, error) {
+	if start < 0 || start > end || end > len(d.src) {
+		return nil, EditRangeError{Start: start, End: end, Len: len(d.src)}
+	}
+	src := make([]byte, 0, len(d.src)-(end-start)+len(text))
+	src = append(src, d.src[:start]...)
+	src = append(src, text...)
+	src = append(src, d.src[end:]...)
+	nd := &Document{src: src, opts: d.opts}
+
+	path := enclosingPath(d.value, &d.node, start, end)
+	for depth := len(path); depth > 0; depth-- {
+		n := d.nodeAt(path[:depth])
+		delta := len(text) - (end - start)
+		dd := deserializer{
+			b:    src,
+			idx:  n.start.Offset,
+			row:  n.start.Row,
+			col:  n.start.Col,
+			opts: d.opts,
+		}
+		_, o, err := deserializeNext(dd)
+		if err != nil || o.node.start.Offset != n.start.Offset || o.node.end.Offset != n.end.Offset+delta {
+			continue
+		}
+		sh := shift{
+			end:    end,
+			delta:  delta,
+			oldEnd: d.advance(n.start, end),
+			newEnd: nd.advance(n.start, start+len(text)),
+		}
+		nd.value, nd.node = replaceAt(d.value, &d.node, path[:depth], o, sh)
+		return nd, nil
+	}
+
+	o, err := d.opts.deserialize(src)
+	if err != nil {
+		return nil, err
+	}
+	nd.value, nd.node = o.value, o.node
+	return nd, nil
+}
+
+// nodeAt returns the node at the path of child indexes.
+func (d *Document) nodeAt(path []int) *node {
+	n := &d.node
+	for _, i := range path {
+		if n.objectNodes != nil {
+			n = &n.objectNodes[i].node
+		} else {
+			n = &n.arrayNodes[i]
+		}
+	}
+	return n
+}
+
+// enclosingPath returns the child indexes of the deepest value containing the range.
+func enclosingPath(v Value, n *node, start, end int) []int {
+	var path []int
+	for {
+		found := false
+		switch vv := v.(type) {
+		case Array:
+			for i, elem := range vv {
+				en := &n.arrayNodes[i]
+				if en.start.Offset <= start && end <= en.end.Offset {
+					path = append(path, i)
+					v, n, found = elem, en, true
+					break
+				}
+			}
+		case Object:
+			for i, e := range vv.Entries() {
+				kv := &n.objectNodes[i]
+				if kv.start.Offset <= start && end <= kv.end.Offset {
+					path = append(path, i)
+					v, n, found = e.Value, &kv.node, true
+					break
+				}
+			}
+		}
+		if !found {
+			return path
+		}
+	}
+}
+
+// shift moves locations after an edit of the source.
+type shift struct {
+	// end is the offset of the end of the edit in the original source.
+	end   int
+	delta int
+	// oldEnd and newEnd are the locations of the end of the edit before and after it was applied.
+	oldEnd Loc
+	newEnd Loc
+}
+
+func (sh shift) loc(l Loc) Loc {
+	if l.Offset < sh.end {
+		return l
+	}
+	if l.Row == sh.oldEnd.Row {
+		l.Col += sh.newEnd.Col - sh.oldEnd.Col
+	}
+	l.Row += sh.newEnd.Row - sh.oldEnd.Row
+	l.Offset += sh.delta
+	return l
+}
+
+// node returns a copy of the node with every location shifted.
+func (sh shift) node(n *node) node {
+	c := node{start: sh.loc(n.start), end: sh.loc(n.end)}
+	if n.arrayNodes != nil {
+		c.arrayNodes = make([]node, len(n.arrayNodes))
+		for i := range n.arrayNodes {
+			c.arrayNodes[i] = sh.node(&n.arrayNodes[i])
+		}
+	}
+	if n.objectNodes != nil {
+		c.objectNodes = make([]nodeKeyValue, len(n.objectNodes))
+		for i := range n.objectNodes {
+			c.objectNodes[i] = sh.keyValue(&n.objectNodes[i])
+		}
+	}
+	return c
+}
+
+func (sh shift) keyValue(kv *nodeKeyValue) nodeKeyValue {
+	c := *kv
+	c.keyStart = sh.loc(kv.keyStart)
+	c.keyEnd = sh.loc(kv.keyEnd)
+	c.node = sh.node(&kv.node)
+	return c
+}
+
+// replaceAt returns copies of the value and node with the value at path replaced by the output.
+// Only the containers along the path are copied. Nodes that end before the edit are shared and
+// nodes after it are shifted.
+func replaceAt(v Value, n *node, path []int, o output, sh shift) (Value, node) {
+	if len(path) == 0 {
+		return o.value, o.node
+	}
+	i := path[0]
+	c := node{start: sh.loc(n.start), end: sh.loc(n.end)}
+	switch vv := v.(type) {
+	case Array:
+		a := make(Array, len(vv))
+		copy(a, vv)
+		c.arrayNodes = make([]node, len(n.arrayNodes))
+		copy(c.arrayNodes[:i], n.arrayNodes[:i])
+		a[i], c.arrayNodes[i] = replaceAt(vv[i], &n.arrayNodes[i], path[1:], o, sh)
+		for j := i + 1; j < len(n.arrayNodes); j++ {
+			c.arrayNodes[j] = sh.node(&n.arrayNodes[j])
+		}
+		return a, c
+	case Object:
+		var obj Object
+		obj.init()
+		c.objectNodes = make([]nodeKeyValue, len(n.objectNodes))
+		copy(c.objectNodes[:i], n.objectNodes[:i])
+		for j, e := range vv.Entries() {
+			switch {
+			case j == i:
+				kv := n.objectNodes[j]
+				nv, nn := replaceAt(e.Value, &kv.node, path[1:], o, sh)
+				kv.node = nn
+				c.objectNodes[j] = kv
+				obj.Add(e.Key, nv)
+			case j > i:
+				c.objectNodes[j] = sh.keyValue(&n.objectNodes[j])
+				obj.Add(e.Key, e.Value)
+			default:
+				obj.Add(e.Key, e.Value)
+			}
+		}
+		return obj, c
+	}
+	return v, c
+}
@@ -0,0 +1,74 @@
+package genjson
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDocumentEdit(t *testing.T) {
+	const src = "{\n  \"a\": [1, 22, {\"b\": \"c\"}],\n  \"d\": true, \"a\": null\n}\n"
+	tests := []struct {
+		name       string
+		start, end int
+		text       string
+		wantErr    bool
+	}{
+		{name: "number", start: 13, end: 15, text: "333"},
+		{name: "number-append", start: 15, end: 15, text: "4"},
+		{name: "string", start: 24, end: 25, text: "x\\ny"},
+		{name: "newlines", start: 12, end: 12, text: "\n\n  "},
+		{name: "remove-newline", start: 29, end: 32, text: ""},
+		{name: "add-element", start: 15, end: 15, text: ", 5"},
+		{name: "add-entry", start: 52, end: 52, text: ", \"e\": {}"},
+		{name: "replace-type", start: 37, end: 41, text: "[false]"},
+		{name: "root", start: 0, end: len(src), text: "[1]"},
+		{name: "whitespace", start: 0, end: 0, text: "  "},
+		{name: "invalid", start: 10, end: 11, text: ",", wantErr: true},
+		{name: "invalid-root", start: 4, end: 5, text: "", wantErr: true},
+	}
+	doc, err := DeserializeDocument([]byte(src))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := doc.Edit(tt.start, tt.end, []byte(tt.text))
+			if err != nil != tt.wantErr {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if err != nil {
+				return
+			}
+			want, err := DeserializeDocument(got.Source())
+			if err != nil {
+				t.Fatalf("unexpected error for full parse %v", err)
+			}
+			if !reflect.DeepEqual(got.value, want.value) {
+				t.Errorf("unexpected value %s != %s", Serialize(got.value), Serialize(want.value))
+			}
+			if !reflect.DeepEqual(got.node, want.node) {
+				t.Errorf("unexpected nodes %+v != %+v", got.node, want.node)
+			}
+		})
+	}
+	if string(doc.Source()) != src {
+		t.Errorf("original document modified")
+	}
+
+	// Values outside of the edited value are shared with the original document.
+	edited, err := doc.Edit(10, 11, []byte("7"))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	a, _ := doc.Value().(Object).Get("a")
+	ea, _ := edited.Value().(Object).Get("a")
+	if a.(Array)[2].(Object).m != ea.(Array)[2].(Object).m {
+		t.Errorf("unedited value was not shared")
+	}
+
+	var ere EditRangeError
+	if _, err := doc.Edit(5, 4, nil); !errors.As(err, &ere) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,394 @@
+package genjson
+
+import (
+	"encoding/base64"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// encodeFlushSize is the size of the buffered output at which Marshaler.Write and Serializer.Write
+// write to their writer.
+const encodeFlushSize = 32 << 10
+
+// Write writes the json encoding of v to w. The output is the same as that of Marshal, but
+// go values are written as they are visited instead of being converted into a Value first, which
+// avoids allocating the intermediate tree. Values that implement Value are written as is.
+//
+// The Serializer options Comments, Progress, SortKeys, KeyLess, OmitNullKeys and EmptyAsNull
+// depend on the complete Value and are supported by converting v into a Value first, as Marshal
+// does.
+//
+// Output is written to w in chunks as it is produced, so w may have received partial output when
+// an error is returned.
+func (m *Marshaler) Write(w io.Writer, v any) error {
+	s := &m.Serializer
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	if s.Comments != nil || s.Progress != nil || s.keyLess() != nil || s.OmitNullKeys || s.EmptyAsNull {
+		b, err := m.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	}
+	rv := reflect.ValueOf(v)
+	if s.RequireContainer {
+		if t := m.topLevelType(rv); t != TypeArray && t != TypeObject {
+			return ErrTopLevelScalar
+		}
+	}
+	// Values are written in chunks as well as go values.
+	s2 := *s
+	s2.out = &serializeOutput{w: w}
+	e := encodeState{m: m, s: &s2, w: w, buf: make([]byte, 0, 4096)}
+	e.buf = s.appendPrefix(e.buf)
+	if err := e.value(rv, 0); err != nil {
+		return err
+	}
+	if s2.out.err != nil {
+		return s2.out.err
+	}
+	return e.flush()
+}
+
+// Write writes the serialized value to w. The output is the same as that of Serialize, but it is
+// written to w in chunks of about 32KiB between the elements of arrays and objects, so that the
+// complete output is never held in memory. The value is checked first, and nothing is written if
+// Check fails. An error is also returned if writing fails, in which case w may have received
+// partial output.
+func (s *Serializer) Write(w io.Writer, v Value) error {
+	if err := s.Check(v); err != nil {
+		return err
+	}
+	s2 := *s
+	s2.out = &serializeOutput{w: w}
+	var buf []byte
+	if a, ok := s.Allocator.(*Arena); ok {
+		// The buffer is not kept, so the unused part of the arena is used without reserving it.
+		buf = a.rest()
+	} else {
+		buf = s2.allocator().Bytes(4096)
+	}
+	buf = s2.appendValue(buf, v)
+	if s2.out.err != nil {
+		return s2.out.err
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// serializeOutput is the writer of a Serializer writing its output as it is produced.
+type serializeOutput struct {
+	w io.Writer
+	// err is the first error returned by w. Serialization stops once it is set.
+	err error
+}
+
+// flush writes bb to out once it has grown to encodeFlushSize and returns the buffer to continue
+// serializing into. It does nothing if out is not set.
+func (s *Serializer) flush(bb []byte) []byte {
+	if s.out == nil || s.out.err != nil || len(bb) < encodeFlushSize {
+		return bb
+	}
+	if _, err := s.out.w.Write(bb); err != nil {
+		s.out.err = err
+		return bb
+	}
+	if s.state != nil {
+		// Keep the number of bytes reported to Progress.
+		s.state.start -= len(bb)
+	}
+	return bb[:0]
+}
+
+// topLevelType returns the json type that rv is written as.
+func (m *Marshaler) topLevelType(rv reflect.Value) Type {
+	for rv.IsValid() && (rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface) && !rv.Type().Implements(valueType) {
+		if rv.IsNil() {
+			return TypeNull
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return TypeNull
+	}
+	if rv.Type().Implements(valueType) {
+		if rv.Kind() == reflect.Interface && rv.IsNil() {
+			return TypeNull
+		}
+		return TypeOf(rv.Interface().(Value))
+	}
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map:
+		if rv.IsNil() {
+			return TypeNull
+		}
+		if rv.Kind() == reflect.Map {
+			return TypeObject
+		}
+		return TypeArray
+	case reflect.Array:
+		return TypeArray
+	case reflect.Struct:
+		return TypeObject
+	}
+	// Scalars are not containers, their exact type does not matter.
+	return TypeNull
+}
+
+// encodeState is the state of a single call to Marshaler.Write.
+type encodeState struct {
+	m   *Marshaler
+	s   *Serializer
+	w   io.Writer
+	buf []byte
+	// path is the path of the value being written, for errors.
+	path Path
+	// cycles detects circular references, as when marshaling.
+	cycles cycleDetector
+}
+
+func (e *encodeState) flush() error {
+	_, err := e.w.Write(e.buf)
+	e.buf = e.buf[:0]
+	return err
+}
+
+// value writes rv following the same rules as Marshaler.marshalValue.
+func (e *encodeState) value(rv reflect.Value, level int) error {
+	if e.s.out.err != nil {
+		return e.s.out.err
+	}
+	if len(e.buf) >= encodeFlushSize {
+		if err := e.flush(); err != nil {
+			return err
+		}
+	}
+	if !rv.IsValid() {
+		e.buf = Null{}.append(e.s, level, e.buf)
+		return nil
+	}
+	if rv.Type().Implements(valueType) {
+		if rv.Kind() == reflect.Interface && rv.IsNil() {
+			e.buf = Null{}.append(e.s, level, e.buf)
+			return nil
+		}
+		v := rv.Interface().(Value)
+		// checkIntegers reports the path of numbers relative to v.
+		switch err := e.s.checkIntegers(v).(type) {
+		case nil:
+		case FractionalNumberError:
+			err.Path = e.path.Append(err.Path...)
+			return err
+		case IntegerRangeError:
+			err.Path = e.path.Append(err.Path...)
+			return err
+		default:
+			return err
+		}
+		e.buf = v.append(e.s, level, e.buf)
+		return nil
+	}
+	switch rv.Kind() {
+	case reflect.Bool:
+		e.buf = Bool(rv.Bool()).append(e.s, level, e.buf)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := rv.Int()
+		n := Number{Integer: uint64(i)}
+		if i < 0 {
+			n = Number{Integer: uint64(-i), IsNeg: true}
+		}
+		e.buf = n.append(e.s, level, e.buf)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.buf = Number{Integer: rv.Uint()}.append(e.s, level, e.buf)
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return UnsupportedValueError{Value: rv}
+		}
+		n := floatNumber(f)
+		if e.s.IntegerOnly {
+			if err := checkInteger(n, e.path); err != nil {
+				return err
+			}
+		}
+		e.buf = n.append(e.s, level, e.buf)
+	case reflect.String:
+		e.buf = e.s.appendColorString(e.buf, colorString, rv.String())
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			e.buf = Null{}.append(e.s, level, e.buf)
+			return nil
+		}
+		if rv.Kind() == reflect.Interface {
+			return e.value(rv.Elem(), level)
+		}
+		if err := e.cycles.enter(rv); err != nil {
+			return err
+		}
+		defer e.cycles.leave(rv)
+		return e.value(rv.Elem(), level)
+	case reflect.Slice:
+		if rv.IsNil() {
+			e.buf = Null{}.append(e.s, level, e.buf)
+			return nil
+		}
+		if e.m.isBase64(rv) {
+			e.buf = e.s.appendColorString(e.buf, colorString, base64.StdEncoding.EncodeToString(rv.Bytes()))
+			return nil
+		}
+		if err := e.cycles.enter(rv); err != nil {
+			return err
+		}
+		defer e.cycles.leave(rv)
+		return e.array(rv, level)
+	case reflect.Array:
+		return e.array(rv, level)
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return UnsupportedTypeError{Type: rv.Type()}
+		}
+		if rv.IsNil() {
+			e.buf = Null{}.append(e.s, level, e.buf)
+			return nil
+		}
+		if err := e.cycles.enter(rv); err != nil {
+			return err
+		}
+		defer e.cycles.leave(rv)
+		return e.mapObject(rv, level)
+	case reflect.Struct:
+		return e.structObject(rv, level)
+	default:
+		return UnsupportedTypeError{Type: rv.Type()}
+	}
+	return nil
+}
+
+func (e *encodeState) array(rv reflect.Value, level int) error {
+	e.buf = e.s.appendColored(e.buf, colorPunctuation, "[")
+	n := rv.Len()
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			e.buf = e.s.appendColored(e.buf, colorPunctuation, ",")
+		}
+		e.buf = appendIndent(e.s, level+1, e.buf)
+		e.path = append(e.path, Index(i))
+		if err := e.value(rv.Index(i), level+1); err != nil {
+			return err
+		}
+		e.path = e.path[:len(e.path)-1]
+	}
+	if n > 0 {
+		e.buf = appendIndent(e.s, level, e.buf)
+	}
+	e.buf = e.s.appendColored(e.buf, colorPunctuation, "]")
+	return nil
+}
+
+// mapObject writes a map with string keys as an object with sorted keys, like marshalMap.
+func (e *encodeState) mapObject(rv reflect.Value, level int) error {
+	keys := rv.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	e.buf = e.s.appendColored(e.buf, colorPunctuation, "{")
+	for i, k := range keys {
+		if err := e.member(i, k.String(), rv.MapIndex(k), level); err != nil {
+			return err
+		}
+	}
+	return e.endObject(len(keys), level)
+}
+
+func (e *encodeState) structObject(rv reflect.Value, level int) error {
+	e.buf = e.s.appendColored(e.buf, colorPunctuation, "{")
+	n, err := e.fields(rv, level)
+	if err != nil {
+		return err
+	}
+	return e.endObject(n, level)
+}
+
+// fields writes the exported fields of the struct in the order of marshalFields and returns the
+// number of members written.
+func (e *encodeState) fields(rv reflect.Value, level int) (int, error) {
+	n := 0
+	for _, f := range cachedStructFields(rv.Type()) {
+		fv, ok := fieldValue(rv, f.index)
+		if !ok {
+			continue
+		}
+		if f.tag.omitEmpty || e.m.OmitEmpty {
+			empty, err := e.m.isEmpty(fv)
+			if err != nil {
+				return n, err
+			}
+			if empty {
+				continue
+			}
+		}
+		if err := e.member(n, f.name, fv, level); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// member writes the i-th member of an object.
+func (e *encodeState) member(i int, key string, rv reflect.Value, level int) error {
+	if i > 0 {
+		e.buf = e.s.appendColored(e.buf, colorPunctuation, ",")
+	}
+	e.buf = appendIndent(e.s, level+1, e.buf)
+	e.buf = e.s.appendColorString(e.buf, colorKey, key)
+	e.buf = e.s.appendColored(e.buf, colorPunctuation, ":")
+	e.buf = appendRepeat(e.buf, " ", e.s.KeyValueGap)
+	e.path = append(e.path, Key(key))
+	if err := e.value(rv, level+1); err != nil {
+		return err
+	}
+	e.path = e.path[:len(e.path)-1]
+	return nil
+}
+
+func (e *encodeState) endObject(n, level int) error {
+	if n > 0 {
+		e.buf = appendIndent(e.s, level, e.buf)
+	}
+	e.buf = e.s.appendColored(e.buf, colorPunctuation, "}")
+	return nil
+}
+
+// isEmpty reports whether rv is marshaled as an empty value, see IsEmpty.
+func (m *Marshaler) isEmpty(rv reflect.Value) (bool, error) {
+	if !rv.Type().Implements(valueType) {
+		switch rv.Kind() {
+		case reflect.Bool:
+			return !rv.Bool(), nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return rv.Int() == 0, nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return rv.Uint() == 0, nil
+		case reflect.Float32, reflect.Float64:
+			return rv.Float() == 0, nil
+		case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+			return rv.Len() == 0, nil
+		case reflect.Pointer, reflect.Interface:
+			if rv.IsNil() {
+				return true, nil
+			}
+		}
+	}
+	// The emptiness of other values, such as structs, depends on how they are marshaled.
+	v, err := m.marshalValue(rv, &cycleDetector{})
+	if err != nil {
+		return false, err
+	}
+	return IsEmpty(v), nil
+}
@@ -0,0 +1,110 @@
+package genjson
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type encodeTest struct {
+	marshalStructTest
+	Ptr   *marshalEmbedded `json:"ptr"`
+	Empty struct{}         `json:"empty,omitempty"`
+	Map   map[string]any   `json:"map"`
+	Value Value            `json:"value"`
+	Big   uint64           `json:"big"`
+	Esc   string           `json:"esc"`
+}
+
+func TestMarshalerWrite(t *testing.T) {
+	v := encodeTest{
+		marshalStructTest: marshalStructTest{marshalEmbedded: marshalEmbedded{E: 1}, A: -2, C: []bool{true, false}},
+		Ptr:               &marshalEmbedded{E: 3},
+		Map:               map[string]any{"b": []int{}, "a": 1.5, "c": nil},
+		Value:             MustDeserializeString(`{"x": [1, {}]}`),
+		Big:               math.MaxUint64,
+		Esc:               "a\"` ",
+	}
+	serializers := map[string]Serializer{
+		"compact": {},
+		"pretty":  {Indent: 2, KeyValueGap: 1, Prefix: 1},
+		"tabs":    {IndentString: "\t"},
+		"options": {UnsafeIntegersAsStrings: true, EscapeBackticks: true, IntegerOnly: true},
+		"sorted":  {SortKeys: true, EmptyAsNull: true, OmitNullKeys: true},
+	}
+	for name, s := range serializers {
+		t.Run(name, func(t *testing.T) {
+			m := Marshaler{Serializer: s, OmitEmpty: name == "options"}
+			if name == "options" {
+				v.Map["a"] = 2.0
+				defer func() { v.Map["a"] = 1.5 }()
+			}
+			want, err := m.Marshal(v)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			var buf bytes.Buffer
+			if err := m.Write(&buf, v); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if buf.String() != string(want) {
+				t.Errorf("unexpected output\n%s\n%s", buf.String(), want)
+			}
+		})
+	}
+}
+
+// Write flushes large output as it goes and writes the same output as Marshal.
+func TestMarshalerWriteLarge(t *testing.T) {
+	v := make([]string, 10000)
+	for i := range v {
+		v[i] = strings.Repeat("a", i%50)
+	}
+	var buf bytes.Buffer
+	if err := (&Marshaler{}).Write(&buf, v); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	want, _ := Marshal(v)
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("unexpected output")
+	}
+}
+
+func TestMarshalerWriteErrors(t *testing.T) {
+	m := Marshaler{Serializer: Serializer{IntegerOnly: true, RequireContainer: true}}
+	tests := []struct {
+		name string
+		in   any
+		want error
+	}{
+		{name: "scalar", in: 1, want: ErrTopLevelScalar},
+		{name: "nil-slice", in: []int(nil), want: ErrTopLevelScalar},
+		{
+			name: "fractional",
+			in:   map[string]any{"a": []float64{1, 1.5}},
+			want: FractionalNumberError{Path: Path{Key("a"), Index(1)}, Number: floatNumber(1.5)},
+		},
+		{
+			name: "fractional-value",
+			in:   []Value{MustDeserializeString(`{"b": 2.5}`)},
+			want: FractionalNumberError{Path: Path{Index(0), Key("b")}, Number: floatNumber(2.5)},
+		},
+		{name: "nan", in: []float64{math.NaN()}, want: UnsupportedValueError{}},
+		{name: "chan", in: []chan int{nil}, want: UnsupportedTypeError{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := m.Write(&bytes.Buffer{}, tt.in)
+			if reflect.TypeOf(err) != reflect.TypeOf(tt.want) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			var fne FractionalNumberError
+			if errors.As(err, &fne) && !reflect.DeepEqual(fne, tt.want) {
+				t.Errorf("unexpected error %#v", fne)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package genjson
+
+import (
+	"bytes"
+	"hash/fnv"
+	"math"
+	"sort"
+)
+
+// DuplicateKeyMode controls how Equal compares objects with duplicate keys.
+type DuplicateKeyMode int8
+
+const (
+	// DuplicateKeysAll compares every entry of a duplicated key, in order, so {"a": 1, "a": 2}
+	// is not equal to {"a": 2, "a": 1} or {"a": 2}.
+	DuplicateKeysAll DuplicateKeyMode = iota
+	// DuplicateKeysLast only compares the last entry of each key, as unmarshaling does.
+	DuplicateKeysLast
+	// DuplicateKeysFirst only compares the first entry of each key, as Object.Get does.
+	DuplicateKeysFirst
+)
+
+// Equaler contains the options used when comparing values. The zero Equaler is used by Equal and
+// Hash, and for the equality of Array.Contains, Unique, Union and Intersect, Diff, Patch tests and
+// filter comparisons.
+type Equaler struct {
+	// OrderedKeys makes objects equal only if their keys are in the same order. By default only
+	// the order of the entries of duplicate keys matters. With DuplicateKeysLast and
+	// DuplicateKeysFirst, keys are ordered by their first entry.
+	OrderedKeys bool
+	// ExactNumbers makes integers and floats unequal, so that 1 and 1.0 are different. By default
+	// numbers are equal if they have the same value, regardless of how they were written.
+	ExactNumbers bool
+	// DuplicateKeys controls which entries of duplicate keys are compared.
+	DuplicateKeys DuplicateKeyMode
+}
+
+var defEqualer Equaler
+
+// Equal reports whether the values are equal according to the options. Arrays are equal if their
+// elements are equal in order, and extensions if they serialize to the same json. Unlike
+// reflect.DeepEqual, objects are compared by their entries rather than their internal
+// representation, so objects built in different ways compare as expected.
+func (eq *Equaler) Equal(a, b Value) bool {
+	switch a := a.(type) {
+	case Null:
+		_, ok := b.(Null)
+		return ok
+	case Bool:
+		bb, ok := b.(Bool)
+		return ok && a == bb
+	case String:
+		bs, ok := b.(String)
+		return ok && a == bs
+	case Number:
+		bn, ok := b.(Number)
+		return ok && eq.numbersEqual(a, bn)
+	case Array:
+		ba, ok := b.(Array)
+		if !ok || len(a) != len(ba) {
+			return false
+		}
+		for i := range a {
+			if !eq.Equal(a[i], ba[i]) {
+				return false
+			}
+		}
+		return true
+	case Object:
+		bo, ok := b.(Object)
+		return ok && eq.objectsEqual(a, bo)
+	}
+	if _, ok := b.(Extension); !ok {
+		return false
+	}
+	return bytes.Equal(equalitySerializer.Serialize(a), equalitySerializer.Serialize(b))
+}
+
+// Hash returns a hash of the value that is consistent with Equal: values that are equal according
+// to the options have the same hash. It is intended for hash tables of values, such as for
+// deduplication. The hash is not cryptographic and may change between versions of the package.
+func (eq *Equaler) Hash(v Value) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write(eq.appendKey(nil, v))
+	return h.Sum64()
+}
+
+// Hash returns the hash of the value for the default Equaler. See Equaler.Hash.
+func Hash(v Value) uint64 {
+	return defEqualer.Hash(v)
+}
+
+// Equal reports whether the values are equal with the default Equaler. See Equaler.Equal.
+func Equal(a, b Value) bool {
+	return defEqualer.Equal(a, b)
+}
+
+func (eq *Equaler) numbersEqual(a, b Number) bool {
+	if a.IsFloat != b.IsFloat {
+		if eq.ExactNumbers {
+			return false
+		}
+		// A float can only equal an integer if it is integral. The conversion is exact, so large
+		// integers are not rounded.
+		var aok, bok bool
+		a, aok = a.integral()
+		b, bok = b.integral()
+		if !aok || !bok {
+			return false
+		}
+	}
+	if a.IsFloat {
+		return a.float64() == b.float64()
+	}
+	return a.Integer == b.Integer && (a.IsNeg == b.IsNeg || a.Integer == 0)
+}
+
+func (eq *Equaler) objectsEqual(a, b Object) bool {
+	ae, be := eq.entries(a), eq.entries(b)
+	if len(ae) != len(be) {
+		return false
+	}
+	if eq.OrderedKeys {
+		for i := range ae {
+			if ae[i].Key != be[i].Key || !eq.Equal(ae[i].Value, be[i].Value) {
+				return false
+			}
+		}
+		return true
+	}
+	// The entries of a key are in order, so matching entries by key and ordinal compares them in
+	// order.
+	bv := make(map[entryKey]Value, len(be))
+	for _, e := range be {
+		bv[entryKey{e.Key, e.Ordinal}] = e.Value
+	}
+	for _, e := range ae {
+		v, ok := bv[entryKey{e.Key, e.Ordinal}]
+		if !ok || !eq.Equal(e.Value, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// entries returns the entries of the object that are compared. With DuplicateKeysLast and
+// DuplicateKeysFirst there is one entry per key, at the position of its first entry.
+func (eq *Equaler) entries(o Object) []Entry {
+	entries := o.Entries()
+	if eq.DuplicateKeys == DuplicateKeysAll {
+		return entries
+	}
+	out := make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		if e.Ordinal > 0 {
+			continue
+		}
+		if eq.DuplicateKeys == DuplicateKeysLast {
+			all, _ := o.GetAll(e.Key)
+			e.Value = all[len(all)-1]
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// appendKey appends an encoding of the value to buf that is the same for values that are equal
+// according to the options, and different otherwise. It is the input of Hash.
+func (eq *Equaler) appendKey(buf []byte, v Value) []byte {
+	switch v := v.(type) {
+	case Null:
+		return append(buf, 'n')
+	case Bool:
+		if v {
+			return append(buf, 't')
+		}
+		return append(buf, 'f')
+	case String:
+		return appendKeyString(append(buf, 's'), string(v))
+	case Number:
+		if !eq.ExactNumbers {
+			if i, ok := v.integral(); ok {
+				v = i
+			}
+		}
+		if !v.IsFloat {
+			neg := byte(0)
+			if v.IsNeg && v.Integer != 0 {
+				neg = 1
+			}
+			return appendKeyUint(append(buf, 'i', neg), v.Integer)
+		}
+		f := v.float64()
+		if f == 0 {
+			// -0 and 0 are equal.
+			f = 0
+		}
+		return appendKeyUint(append(buf, 'd'), math.Float64bits(f))
+	case Array:
+		buf = appendKeyUint(append(buf, 'a'), uint64(len(v)))
+		for _, elem := range v {
+			buf = eq.appendKey(buf, elem)
+		}
+		return buf
+	case Object:
+		entries := eq.entries(v)
+		if !eq.OrderedKeys {
+			// The sort is stable so that the entries of duplicate keys stay in order.
+			entries = append([]Entry(nil), entries...)
+			sort.SliceStable(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+		}
+		buf = appendKeyUint(append(buf, 'o'), uint64(len(entries)))
+		for _, e := range entries {
+			buf = eq.appendKey(appendKeyString(buf, e.Key), e.Value)
+		}
+		return buf
+	}
+	return appendKeyString(append(buf, 'x'), string(equalitySerializer.Serialize(v)))
+}
+
+func appendKeyString(buf []byte, s string) []byte {
+	return append(appendKeyUint(buf, uint64(len(s))), s...)
+}
+
+func appendKeyUint(buf []byte, v uint64) []byte {
+	return append(buf, byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32), byte(v>>24), byte(v>>16),
+		byte(v>>8), byte(v))
+}
@@ -0,0 +1,84 @@
+package genjson
+
+import "testing"
+
+func TestEqual(t *testing.T) {
+	ordered := Equaler{OrderedKeys: true}
+	exact := Equaler{ExactNumbers: true}
+	last := Equaler{DuplicateKeys: DuplicateKeysLast}
+	first := Equaler{DuplicateKeys: DuplicateKeysFirst}
+	orderedLast := Equaler{OrderedKeys: true, DuplicateKeys: DuplicateKeysLast}
+	tests := []struct {
+		name string
+		eq   Equaler
+		a, b string
+		want bool
+	}{
+		{name: "scalars", a: `"a"`, b: `"a"`, want: true},
+		{name: "different-types", a: `"1"`, b: `1`, want: false},
+		{name: "null", a: `null`, b: `null`, want: true},
+		{name: "bools", a: `true`, b: `false`, want: false},
+		{name: "integer-float", a: `1`, b: `1.0`, want: true},
+		{name: "integer-exponent", a: `100`, b: `1e2`, want: true},
+		{name: "negative-integer-float", a: `-3`, b: `-3.0`, want: true},
+		{name: "sign", a: `3`, b: `-3`, want: false},
+		{name: "negative-zero", a: `-0`, b: `0.0`, want: true},
+		{name: "fractional", a: `1`, b: `1.5`, want: false},
+		{name: "float-exponent", a: `0.0015`, b: `1.5e-3`, want: true},
+		{name: "large-float", a: `1e30`, b: `1000000000000000000000000000000.0`, want: true},
+		{name: "large-integer", a: `9007199254740993`, b: `9007199254740992.0`, want: false},
+		{name: "exact-numbers", eq: exact, a: `1`, b: `1.0`, want: false},
+		{name: "exact-floats", eq: exact, a: `1.0`, b: `1e0`, want: true},
+		{name: "arrays", a: `[1, [2, {"a": 3}]]`, b: `[1.0, [2, {"a": 3}]]`, want: true},
+		{name: "array-order", a: `[1, 2]`, b: `[2, 1]`, want: false},
+		{name: "array-length", a: `[1, 2]`, b: `[1, 2, 3]`, want: false},
+		{name: "key-order", a: `{"a": 1, "b": 2}`, b: `{"b": 2, "a": 1}`, want: true},
+		{name: "ordered-keys", eq: ordered, a: `{"a": 1, "b": 2}`, b: `{"b": 2, "a": 1}`, want: false},
+		{name: "ordered-keys-equal", eq: ordered, a: `{"a": 1, "b": {"c": 2, "d": 3}}`, b: `{"a": 1, "b": {"c": 2, "d": 3}}`, want: true},
+		{name: "missing-key", a: `{"a": 1}`, b: `{"a": 1, "b": 2}`, want: false},
+		{name: "different-keys", a: `{"a": 1}`, b: `{"b": 1}`, want: false},
+		{name: "duplicate-keys", a: `{"a": 1, "b": 0, "a": 2}`, b: `{"a": 1, "a": 2, "b": 0}`, want: true},
+		{name: "duplicate-key-order", a: `{"a": 1, "a": 2}`, b: `{"a": 2, "a": 1}`, want: false},
+		{name: "duplicate-key-count", a: `{"a": 1, "a": 1}`, b: `{"a": 1}`, want: false},
+		{name: "duplicate-keys-last", eq: last, a: `{"a": 1, "a": 2}`, b: `{"a": 2}`, want: true},
+		{name: "duplicate-keys-last-differ", eq: last, a: `{"a": 1, "a": 2}`, b: `{"a": 1}`, want: false},
+		{name: "duplicate-keys-first", eq: first, a: `{"a": 1, "a": 2}`, b: `{"a": 1}`, want: true},
+		{name: "ordered-duplicate-keys-last", eq: orderedLast, a: `{"a": 1, "b": 2, "a": 3}`, b: `{"a": 3, "b": 2}`, want: true},
+		{name: "ordered-duplicate-keys-last-order", eq: orderedLast, a: `{"a": 1, "b": 2, "a": 3}`, b: `{"b": 2, "a": 3}`, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := MustDeserializeString(tt.a), MustDeserializeString(tt.b)
+			if got := tt.eq.Equal(a, b); got != tt.want {
+				t.Errorf("Equal(%s, %s) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if got := tt.eq.Equal(b, a); got != tt.want {
+				t.Errorf("Equal(%s, %s) = %v, want %v", tt.b, tt.a, got, tt.want)
+			}
+			// Equal values must have the same hash, and the unequal values here differ in hash.
+			if got := tt.eq.Hash(a) == tt.eq.Hash(b); got != tt.want {
+				t.Errorf("Hash(%s) == Hash(%s) is %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEqualBuiltObjects(t *testing.T) {
+	// Objects built in different ways have different internal representations.
+	var built Object
+	built.Add("b", Number{Integer: 2})
+	built.Add("a", String("x"))
+	built.Delete("b")
+	built.Add("b", Number{Float: 2, IsFloat: true})
+	if !Equal(built, MustDeserializeString(`{"a": "x", "b": 2}`)) {
+		t.Errorf("expected built object to be equal")
+	}
+	ext := Extension{Type: &ExtensionType{Name: "raw", Append: func(_ *Serializer, _ int, bb []byte, data any) []byte {
+		return append(bb, data.(string)...)
+	}}}
+	a, b := ext, ext
+	a.Data, b.Data = "[1]", "[1]"
+	if !Equal(a, b) || Equal(a, Array{Number{Integer: 1}}) {
+		t.Errorf("unexpected extension equality")
+	}
+}
@@ -0,0 +1,32 @@
+package genjson
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// ETag returns a strong entity tag for the value, suitable for the ETag http header. It is derived
+// from a hash of the same encoding as Hash, so values that are Equal have the same tag regardless
+// of the formatting, key order and number spelling of the source json, such as 1, 1.0 and 1e0.
+func ETag(v Value) string {
+	sum := sha256.Sum256(defEqualer.appendKey(nil, v))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// MatchETag reports whether the If-None-Match header value matches the entity tag using the weak
+// comparison of RFC 7232. A server should respond with 304 Not Modified if it does.
+func MatchETag(ifNoneMatch, etag string) bool {
+	ifNoneMatch = strings.TrimSpace(ifNoneMatch)
+	if ifNoneMatch == "*" {
+		return true
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package genjson
+
+import (
+	"testing"
+)
+
+func TestETag(t *testing.T) {
+	a, err := Deserialize([]byte(`{"a": 1, "b": [true]}`))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	b, err := Deserialize([]byte(`{ "b" : [ true ], "a" : 1 }`))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if ETag(a) != ETag(b) {
+		t.Errorf("equivalent values have different etags %s %s", ETag(a), ETag(b))
+	}
+	if ETag(a) == ETag(Array{}) {
+		t.Errorf("different values have the same etag")
+	}
+	want := ETag(MustDeserializeString(`{"a": 1}`))
+	for _, data := range []string{`{"a": 1.0}`, `{"a": 1e0}`, `{"a": 10E-1}`} {
+		if v := MustDeserializeString(data); ETag(v) != want {
+			t.Errorf("%s: equal values have different etags %s %s", data, ETag(v), want)
+		}
+	}
+	if ETag(MustDeserializeString(`{"a": 1.5}`)) == want {
+		t.Errorf("different numbers have the same etag")
+	}
+
+	etag := ETag(a)
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{header: "*", want: true},
+		{header: etag, want: true},
+		{header: `"other", ` + etag, want: true},
+		{header: "W/" + etag, want: true},
+		{header: `"other"`, want: false},
+		{header: "", want: false},
+	}
+	for _, tt := range tests {
+		if got := MatchETag(tt.header, etag); got != tt.want {
+			t.Errorf("unexpected match for %q %v != %v", tt.header, got, tt.want)
+		}
+	}
+}
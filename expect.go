@@ -0,0 +1,236 @@
+package genjson
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Expectation checks the shape of a value without a full schema. It is created by Expect and
+// narrowed to nested values by Key, Index and Each, while Object, Array, String, Number, Bool and
+// Null check the type of the values it applies to. Expectations derived from the same call to
+// Expect share their result, so several keys of an object can be checked by keeping the
+// Expectation of the object:
+//
+//	item := Expect(v).Object().Key("items").Array().Each().Object()
+//	item.Key("id").String()
+//	item.Key("count").Number()
+//	if err := item.Err(); err != nil {
+//		...
+//	}
+//
+// Only the first failed check is reported, later checks are skipped.
+type Expectation struct {
+	targets []expectTarget
+	state   *expectState
+}
+
+type expectTarget struct {
+	v    Value
+	n    *node // Optional. Used for location data.
+	path Path
+}
+
+type expectState struct {
+	err error
+}
+
+// Expect returns an Expectation for v. Errors report the path of the failed value.
+func Expect(v Value) *Expectation {
+	return &Expectation{
+		targets: []expectTarget{{v: v}},
+		state:   &expectState{},
+	}
+}
+
+// Expect returns an Expectation for the value of the document. Errors report the path and the
+// location of the failed value.
+func (d *Document) Expect() *Expectation {
+	return &Expectation{
+		targets: []expectTarget{{v: d.value, n: &d.node}},
+		state:   &expectState{},
+	}
+}
+
+// Err returns the first failed check of the expectation, or nil if all checks passed. The error
+// is an ExpectError.
+func (e *Expectation) Err() error {
+	return e.state.err
+}
+
+// Object checks that the values are objects.
+func (e *Expectation) Object() *Expectation {
+	return e.is(TypeObject)
+}
+
+// Array checks that the values are arrays.
+func (e *Expectation) Array() *Expectation {
+	return e.is(TypeArray)
+}
+
+// String checks that the values are strings.
+func (e *Expectation) String() *Expectation {
+	return e.is(TypeString)
+}
+
+// Number checks that the values are numbers.
+func (e *Expectation) Number() *Expectation {
+	return e.is(TypeNumber)
+}
+
+// Bool checks that the values are booleans.
+func (e *Expectation) Bool() *Expectation {
+	return e.is(TypeBool)
+}
+
+// Null checks that the values are null.
+func (e *Expectation) Null() *Expectation {
+	return e.is(TypeNull)
+}
+
+// Key checks that the values are objects containing key and returns an Expectation for the
+// values of the key. The first entry is used for duplicate keys.
+func (e *Expectation) Key(key string) *Expectation {
+	return e.key(key, false)
+}
+
+// OptionalKey is like Key, but objects without the key are not an error. The returned
+// Expectation only applies to the objects that contain the key.
+func (e *Expectation) OptionalKey(key string) *Expectation {
+	return e.key(key, true)
+}
+
+func (e *Expectation) key(key string, optional bool) *Expectation {
+	return e.narrow(TypeObject, func(t expectTarget, next []expectTarget) ([]expectTarget, error) {
+		for i, entry := range t.v.(Object).Entries() {
+			if entry.Key != key {
+				continue
+			}
+			child := expectTarget{v: entry.Value, path: append(t.path.Append(), Key(key))}
+			if t.n != nil {
+				child.n = &t.n.objectNodes[i].node
+			}
+			return append(next, child), nil
+		}
+		if optional {
+			return next, nil
+		}
+		return next, MissingKeyError{Key: key}
+	})
+}
+
+// Index checks that the values are arrays with an element at index i and returns an Expectation
+// for the elements.
+func (e *Expectation) Index(i int) *Expectation {
+	return e.narrow(TypeArray, func(t expectTarget, next []expectTarget) ([]expectTarget, error) {
+		a := t.v.(Array)
+		if i < 0 || i >= len(a) {
+			return next, IndexOutOfRangeError{Index: i, Len: len(a)}
+		}
+		return append(next, t.elem(i)), nil
+	})
+}
+
+// Each checks that the values are arrays and returns an Expectation for all of their elements.
+func (e *Expectation) Each() *Expectation {
+	return e.narrow(TypeArray, func(t expectTarget, next []expectTarget) ([]expectTarget, error) {
+		for i := range t.v.(Array) {
+			next = append(next, t.elem(i))
+		}
+		return next, nil
+	})
+}
+
+func (t expectTarget) elem(i int) expectTarget {
+	child := expectTarget{v: t.v.(Array)[i], path: append(t.path.Append(), Index(i))}
+	if t.n != nil {
+		child.n = &t.n.arrayNodes[i]
+	}
+	return child
+}
+
+// is checks that every value has type typ.
+func (e *Expectation) is(typ Type) *Expectation {
+	return e.narrow(typ, func(t expectTarget, next []expectTarget) ([]expectTarget, error) {
+		return append(next, t), nil
+	})
+}
+
+// narrow checks that every value has type typ and replaces them with the values returned by f.
+// After the first failure, the returned Expectation applies to no values.
+func (e *Expectation) narrow(typ Type, f func(t expectTarget, next []expectTarget) ([]expectTarget, error)) *Expectation {
+	next := &Expectation{state: e.state}
+	if e.state.err != nil {
+		return next
+	}
+	for _, t := range e.targets {
+		var err error
+		if got := TypeOf(t.v); got != typ {
+			err = TypeError{Want: typ, Got: got}
+		} else {
+			next.targets, err = f(t, next.targets)
+		}
+		if err != nil {
+			ee := ExpectError{Path: t.path.Append(), Err: err}
+			if t.n != nil {
+				loc := t.n.start
+				ee.Loc = &loc
+			}
+			e.state.err = ee
+			next.targets = nil
+			return next
+		}
+	}
+	return next
+}
+
+// ---------------- errors ----------------
+
+// ExpectError is returned by Expectation.Err for a failed check.
+type ExpectError struct {
+	// Path is the path of the value that failed the check.
+	Path Path
+	// Loc is the location of the value that failed the check. It is set if the Expectation was
+	// created by Document.Expect.
+	Loc *Loc
+	// Err is a TypeError, a MissingKeyError or an IndexOutOfRangeError.
+	Err error
+}
+
+func (e ExpectError) Error() string {
+	sb := strings.Builder{}
+	sb.WriteString("unexpected value")
+	if len(e.Path) > 0 {
+		sb.WriteString(" ")
+		sb.WriteString(e.Path.String())
+	}
+	if e.Loc != nil {
+		sb.WriteString(" ")
+		sb.WriteString(locString(e.Loc))
+	}
+	sb.WriteString(": ")
+	sb.WriteString(e.Err.Error())
+	return sb.String()
+}
+
+func (e ExpectError) Unwrap() error {
+	return e.Err
+}
+
+// MissingKeyError is the cause of an ExpectError for an object without a required key.
+type MissingKeyError struct {
+	Key string
+}
+
+func (e MissingKeyError) Error() string {
+	return fmt.Sprintf("missing key %q", e.Key)
+}
+
+// IndexOutOfRangeError is the cause of an ExpectError for an array without a required index.
+type IndexOutOfRangeError struct {
+	Index int
+	Len   int
+}
+
+func (e IndexOutOfRangeError) Error() string {
+	return fmt.Sprintf("index %d out of range for array of length %d", e.Index, e.Len)
+}
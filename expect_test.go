@@ -0,0 +1,97 @@
+package genjson
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpect(t *testing.T) {
+	src := `{
+  "items": [
+    {"id": "a", "count": 1},
+    {"id": 2, "count": 2}
+  ],
+  "name": "x"
+}`
+	doc, err := DeserializeDocument([]byte(src))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	tests := []struct {
+		name   string
+		expect func(e *Expectation) *Expectation
+		want   error
+	}{
+		{
+			name: "ok",
+			expect: func(e *Expectation) *Expectation {
+				o := e.Object()
+				o.Key("name").String()
+				o.Key("items").Index(0).Object().Key("id").String()
+				o.OptionalKey("missing").Number()
+				return o.Key("items").Each().Object().Key("count").Number()
+			},
+		},
+		{
+			name: "type",
+			expect: func(e *Expectation) *Expectation {
+				return e.Object().Key("items").Array().Each().Object().Key("id").String()
+			},
+			want: ExpectError{
+				Path: Path{Key("items"), Index(1), Key("id")},
+				Loc:  &Loc{Row: 4, Col: 12, Offset: 55},
+				Err:  TypeError{Want: TypeString, Got: TypeNumber},
+			},
+		},
+		{
+			name: "missing",
+			expect: func(e *Expectation) *Expectation {
+				return e.Key("items").Each().Key("name")
+			},
+			want: ExpectError{
+				Path: Path{Key("items"), Index(0)},
+				Loc:  &Loc{Row: 3, Col: 5, Offset: 19},
+				Err:  MissingKeyError{Key: "name"},
+			},
+		},
+		{
+			name: "index",
+			expect: func(e *Expectation) *Expectation {
+				return e.Key("items").Index(2)
+			},
+			want: ExpectError{
+				Path: Path{Key("items")},
+				Loc:  &Loc{Row: 2, Col: 12, Offset: 13},
+				Err:  IndexOutOfRangeError{Index: 2, Len: 2},
+			},
+		},
+		{
+			name: "first-error",
+			expect: func(e *Expectation) *Expectation {
+				e.Array()
+				return e.Object().Key("name").Number()
+			},
+			want: ExpectError{
+				Path: Path{},
+				Loc:  &Loc{Row: 1, Col: 1},
+				Err:  TypeError{Want: TypeArray, Got: TypeObject},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.expect(doc.Expect()).Err()
+			if !reflect.DeepEqual(err, tt.want) {
+				t.Fatalf("unexpected error %v != %v", err, tt.want)
+			}
+			err = tt.expect(Expect(doc.Value())).Err()
+			if ee, ok := tt.want.(ExpectError); ok {
+				ee.Loc = nil
+				tt.want = ee
+			}
+			if !reflect.DeepEqual(err, tt.want) {
+				t.Errorf("unexpected error without locations %v != %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package genjson
+
+import (
+	"bytes"
+)
+
+// AppendMember adds a member to the serialized object at the end of buf and returns the extended
+// buffer. The closing brace is rewound and the member written in its place, so that records can
+// be built incrementally without parsing or re-serializing what has already been written. buf must
+// end with an object, optionally followed by whitespace, which is kept after the new closing
+// brace. If the object was written by s, the member is indented to match it. The Colors, Comments
+// and Progress options of s are ignored.
+//
+// ErrNotContainer is returned if buf does not end with an object or array, and a TypeError if it
+// ends with an array. buf is not modified if an error is returned. Duplicate keys are not
+// detected.
+func (s *Serializer) AppendMember(buf []byte, key string, v Value) ([]byte, error) {
+	if s.OmitNullKeys && s.isNull(v) {
+		return buf, nil
+	}
+	return s.extend(buf, TypeObject, v, func(s *Serializer, bb []byte) []byte {
+		bb = appendString(s, bb, key)
+		bb = append(bb, ':')
+		bb = appendRepeat(bb, " ", s.KeyValueGap)
+		return s.appendLevel(bb, v, 1)
+	})
+}
+
+// AppendElement adds an element to the serialized array at the end of buf and returns the
+// extended buffer. It is the array counterpart of AppendMember.
+func (s *Serializer) AppendElement(buf []byte, v Value) ([]byte, error) {
+	return s.extend(buf, TypeArray, v, func(s *Serializer, bb []byte) []byte {
+		return s.appendLevel(bb, v, 1)
+	})
+}
+
+// AppendMember adds a member to the serialized object at the end of buf with the default
+// Serializer. See Serializer.AppendMember.
+func AppendMember(buf []byte, key string, v Value) ([]byte, error) {
+	return defSerializer.AppendMember(buf, key, v)
+}
+
+// AppendElement adds an element to the serialized array at the end of buf with the default
+// Serializer. See Serializer.AppendElement.
+func AppendElement(buf []byte, v Value) ([]byte, error) {
+	return defSerializer.AppendElement(buf, v)
+}
+
+// extend rewinds the container of type typ at the end of buf and calls f to append v as a new
+// entry before writing the closer back.
+func (s *Serializer) extend(buf []byte, typ Type, v Value, f func(s *Serializer, bb []byte) []byte) ([]byte, error) {
+	if err := s.Validate(); err != nil {
+		return buf, err
+	}
+	if err := s.checkIntegers(v); err != nil {
+		return buf, err
+	}
+	end := len(bytes.TrimRight(buf, spaceChars)) - 1
+	if end < 0 {
+		return buf, ErrNotContainer
+	}
+	var (
+		opener, closer byte
+		got            Type
+	)
+	switch buf[end] {
+	case '}':
+		opener, closer, got = '{', '}', TypeObject
+	case ']':
+		opener, closer, got = '[', ']', TypeArray
+	default:
+		return buf, ErrNotContainer
+	}
+	if got != typ {
+		return buf, TypeError{Want: typ, Got: got}
+	}
+
+	s2 := *s
+	s2.Colors, s2.Comments, s2.Progress = nil, nil, nil
+	s2.state, s2.out = nil, nil
+
+	// Remove the line break written by s before the closer, so that the prefix of the line is not
+	// mistaken for the last entry.
+	body := buf[:end]
+	if s2.multiline() {
+		var scratch [64]byte
+		if closing := appendIndent(&s2, 0, scratch[:0]); bytes.HasSuffix(body, closing) {
+			body = body[:len(body)-len(closing)]
+		}
+	}
+	body = bytes.TrimRight(body, spaceChars)
+	if len(body) == 0 {
+		return buf, ErrNotContainer
+	}
+	empty := body[len(body)-1] == opener
+
+	// The whitespace after the closer is overwritten by the new entry, so it is copied first.
+	var tailBuf [8]byte
+	tail := append(tailBuf[:0], buf[end+1:]...)
+	bb := body
+	if !empty {
+		bb = append(bb, ',')
+	}
+	bb = appendIndent(&s2, 1, bb)
+	bb = f(&s2, bb)
+	bb = appendIndent(&s2, 0, bb)
+	bb = append(bb, closer)
+	return append(bb, tail...), nil
+}
+
+// spaceChars are the json whitespace characters.
+const spaceChars = " \t\r\n"
@@ -0,0 +1,81 @@
+package genjson
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAppendMember(t *testing.T) {
+	serializers := []Serializer{
+		{},
+		{KeyValueGap: 1},
+		{Indent: 2, KeyValueGap: 1},
+		{IndentString: "\t", PrefixString: " \t"},
+	}
+	for _, s := range serializers {
+		s := s
+		want := MustDeserializeString(`{"a": 1, "b": [], "c": {"d": "e"}}`).(Object)
+		buf := s.Serialize(Object{})
+		var err error
+		for _, e := range want.Entries() {
+			if buf, err = s.AppendMember(buf, e.Key, e.Value); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+		}
+		if got := string(buf); got != string(s.Serialize(want)) {
+			t.Errorf("unexpected output for %+v\n%s\n%s", s, got, s.Serialize(want))
+		}
+
+		arr := Array{Number{Integer: 1}, Object{}, String("x")}
+		buf = s.Serialize(Array{})
+		for _, v := range arr {
+			if buf, err = s.AppendElement(buf, v); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+		}
+		if got := string(buf); got != string(s.Serialize(arr)) {
+			t.Errorf("unexpected output for %+v\n%s\n%s", s, got, s.Serialize(arr))
+		}
+	}
+}
+
+func TestAppendMemberBuffer(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`{}`, `{"k":true}`},
+		{"{ }\n", "{\"k\":true}\n"},
+		{`{"a":"}"}`, `{"a":"}","k":true}`},
+		{"{\"a\": 1\n}  \r\n", "{\"a\": 1,\"k\":true}  \r\n"},
+		{`[1,{"a":2}]`, ``},
+		{`"}"`, ``},
+		{`}`, ``},
+		{``, ``},
+	}
+	for _, tt := range tests {
+		got, err := AppendMember([]byte(tt.in), "k", Bool(true))
+		switch {
+		case tt.want == "" && err == nil:
+			t.Errorf("expected an error for %q", tt.in)
+		case tt.want == "" && string(got) != tt.in:
+			t.Errorf("buffer modified on error %q", got)
+		case tt.want != "" && err != nil:
+			t.Errorf("unexpected error for %q: %v", tt.in, err)
+		case tt.want != "" && string(got) != tt.want:
+			t.Errorf("unexpected output for %q\n%q\n%q", tt.in, got, tt.want)
+		}
+	}
+
+	_, err := AppendMember([]byte(`[]`), "k", Null{})
+	if want := (TypeError{Want: TypeObject, Got: TypeArray}); !errors.Is(err, want) {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := AppendElement([]byte(`1`), Null{}); !errors.Is(err, ErrNotContainer) {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	s := Serializer{OmitNullKeys: true}
+	if got, _ := s.AppendMember([]byte(`{}`), "k", Null{}); string(got) != `{}` {
+		t.Errorf("unexpected output %s", got)
+	}
+}
@@ -0,0 +1,78 @@
+package genjson
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Extension is a Value holding user data that is not part of the json data model, such as a
+// comment or a placeholder that is filled in later. Extensions are never produced by parsing. How
+// an extension is serialized and unmarshaled is defined by its Type.
+type Extension struct {
+	Type *ExtensionType
+	Data any
+}
+
+// ExtensionType defines the behavior of Extension values. Extension types are usually declared
+// once as package level variables and shared by all values of the type.
+type ExtensionType struct {
+	// Name identifies the extension type in errors.
+	Name string
+	// Append appends the serialized data to bb and returns the extended buffer. level is the
+	// nesting level of the value, for use with multiline output. The output is not validated, so
+	// it is up to Append to write valid json if it is required. If Append is nil, the extension
+	// is serialized as null.
+	Append func(s *Serializer, level int, bb []byte, data any) []byte
+	// Unmarshal stores the data in v. Returned errors are wrapped in an UnmarshalError unless
+	// they already are one. If Unmarshal is nil, unmarshaling into anything other than an empty
+	// interface fails with an UnsupportedExtensionError. Empty interfaces are always set to the
+	// data itself.
+	Unmarshal func(s UnmarshalState, data any, v reflect.Value) error
+}
+
+func (Extension) isValue() {}
+
+var _ Value = Extension{}
+
+// appendsNull reports whether the extension is serialized as null.
+func (e Extension) appendsNull() bool {
+	return e.Type == nil || e.Type.Append == nil
+}
+
+func (e Extension) append(s *Serializer, level int, bb []byte) []byte {
+	if e.appendsNull() {
+		return Null{}.append(s, level, bb)
+	}
+	s.progress(bb)
+	return e.Type.Append(s, level, bb, e.Data)
+}
+
+func (e Extension) unmarshal(s *UnmarshalState, v reflect.Value) error {
+	if e.Type == nil || e.Type.Unmarshal == nil {
+		return unmarshalError(s, UnsupportedExtensionError{Type: e.Type, ValueType: v.Type()})
+	}
+	if err := e.Type.Unmarshal(*s, e.Data, v); err != nil {
+		if ue, ok := err.(UnmarshalError); ok {
+			return ue
+		}
+		return unmarshalError(s, err)
+	}
+	return nil
+}
+
+// ---------------- errors ----------------
+
+// UnsupportedExtensionError is returned when unmarshaling an Extension whose type does not
+// define Unmarshal.
+type UnsupportedExtensionError struct {
+	Type      *ExtensionType
+	ValueType reflect.Type
+}
+
+func (e UnsupportedExtensionError) Error() string {
+	name := "<nil>"
+	if e.Type != nil {
+		name = e.Type.Name
+	}
+	return fmt.Sprintf("extension %s cannot be unmarshaled into go type %s", name, e.ValueType)
+}
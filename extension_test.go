@@ -0,0 +1,85 @@
+package genjson
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// placeholder is an extension naming a variable, written as the string "${name}".
+var placeholder = &ExtensionType{
+	Name: "placeholder",
+	Append: func(s *Serializer, level int, bb []byte, data any) []byte {
+		return appendString(s, bb, "${"+data.(string)+"}")
+	},
+	Unmarshal: func(s UnmarshalState, data any, v reflect.Value) error {
+		if v.Kind() != reflect.String {
+			return errors.New("placeholder must be unmarshaled into a string")
+		}
+		v.SetString("${" + data.(string) + "}")
+		return nil
+	},
+}
+
+// hidden is an extension without hooks.
+var hidden = &ExtensionType{Name: "hidden"}
+
+func TestExtensionSerialize(t *testing.T) {
+	var o Object
+	o.Add("a", Extension{Type: placeholder, Data: "name"})
+	o.Add("b", Array{integer(1), Extension{Type: hidden}})
+	o.Add("c", Extension{Type: hidden})
+	tests := []struct {
+		name string
+		s    Serializer
+		want string
+	}{
+		{name: "compact", want: `{"a":"${name}","b":[1,null],"c":null}`},
+		{name: "omit-null", s: Serializer{OmitNullKeys: true}, want: `{"a":"${name}","b":[1,null]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(tt.s.Serialize(o)); got != tt.want {
+				t.Errorf("unexpected output %s != %s", got, tt.want)
+			}
+		})
+	}
+	if got := TypeOf(Extension{}); got != TypeExtension {
+		t.Errorf("unexpected type %v", got)
+	}
+}
+
+func TestExtensionUnmarshal(t *testing.T) {
+	var u Unmarshaler
+	v := Array{Extension{Type: placeholder, Data: "name"}, Extension{Type: hidden, Data: 1}}
+
+	var strs []any
+	if err := u.UnmarshalValue(v, &strs); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if want := []any{"name", 1}; !reflect.DeepEqual(strs, want) {
+		t.Errorf("unexpected value %v != %v", strs, want)
+	}
+
+	var s [1]string
+	if err := u.UnmarshalValue(v[:1], &s); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if s[0] != "${name}" {
+		t.Errorf("unexpected value %q", s[0])
+	}
+
+	var ints []int
+	err := u.UnmarshalValue(v, &ints)
+	var ue UnmarshalError
+	if !errors.As(err, &ue) || !reflect.DeepEqual(ue.Field, Path{Index(0)}) {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	var strs2 []string
+	err = u.UnmarshalValue(v, &strs2)
+	var uee UnsupportedExtensionError
+	if !errors.As(err, &uee) || uee.Type != hidden {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,421 @@
+package genjson
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Filter is a compiled filter expression that selects values, such as the elements of an array.
+// It is created by CompileFilter and may be used concurrently.
+type Filter struct {
+	expr string
+	eval filterExpr
+}
+
+// filterExpr reports whether a value matches a compiled expression.
+type filterExpr func(v Value) bool
+
+// filterOperand returns the value of an operand for v, or false if it refers to a missing value.
+type filterOperand func(v Value) (Value, bool)
+
+// CompileFilter compiles a filter expression, such as:
+//
+//	price > 10 && tags contains "new"
+//	!(status == "deleted" || archived) && owner.name != null
+//
+// Operands are literals or fields of the value being filtered. Literals are json numbers, double
+// quoted strings, true, false and null. Fields are written as dot paths, see ParseDotPath, and @
+// refers to the value itself. The operators are, in increasing order of precedence:
+//
+//	||                         either side matches
+//	&&                         both sides match
+//	!                          the operand does not match
+//	== !=                      the operands are equal or not, as with Array.Contains
+//	< <= > >=                  numbers by value and strings by their bytes
+//	contains                   an array contains a value or a string contains a substring
+//
+// Parentheses group expressions. An operand on its own matches if it exists and is not null or
+// false. Comparisons with a missing field do not match, except for != which matches, and
+// comparisons of values of different types only match for !=.
+func CompileFilter(expr string) (*Filter, error) {
+	p := filterParser{expr: expr}
+	p.next()
+	eval, err := p.or()
+	if err == nil && p.tok.kind != filterEOF {
+		err = p.errorf("unexpected %s", p.tok)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Filter{expr: expr, eval: eval}, nil
+}
+
+// MustCompileFilter compiles a filter expression, panicking if it is invalid. It is intended for
+// expressions that are known to be valid, such as constants.
+func MustCompileFilter(expr string) *Filter {
+	f, err := CompileFilter(expr)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// String returns the source of the filter.
+func (f *Filter) String() string {
+	return f.expr
+}
+
+// Match reports whether v matches the filter.
+func (f *Filter) Match(v Value) bool {
+	return f.eval(v)
+}
+
+// Apply returns the elements of a that match the filter, in order. The elements are not copied.
+func (f *Filter) Apply(a Array) Array {
+	out := Array{}
+	for _, v := range a {
+		if f.eval(v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Where returns the elements of the array that match the filter expression. See CompileFilter for
+// the syntax. Filters that are applied repeatedly should be compiled once with CompileFilter.
+func (a Array) Where(expr string) (Array, error) {
+	f, err := CompileFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	return f.Apply(a), nil
+}
+
+// ---------------- parser ----------------
+
+type filterTokenKind int8
+
+const (
+	filterEOF filterTokenKind = iota
+	filterOperator
+	filterLiteral
+	filterField
+	filterParen
+)
+
+type filterToken struct {
+	kind filterTokenKind
+	text string
+	// value is the value of a literal.
+	value Value
+	// offset is the byte offset of the token in the expression.
+	offset int
+}
+
+func (t filterToken) String() string {
+	if t.kind == filterEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// filterParser is a recursive descent parser of filter expressions. tok is the current token.
+type filterParser struct {
+	expr string
+	pos  int
+	tok  filterToken
+	err  error
+}
+
+func (p *filterParser) errorf(format string, args ...any) error {
+	return FilterSyntaxError{Expr: p.expr, Offset: p.tok.offset, Msg: fmt.Sprintf(format, args...)}
+}
+
+// next reads the next token. Lexical errors are stored in p.err and reported as the current token
+// is consumed.
+func (p *filterParser) next() {
+	p.err = nil
+	for p.pos < len(p.expr) && isSpace(p.expr[p.pos]) {
+		p.pos++
+	}
+	start := p.pos
+	tok := func(kind filterTokenKind, end int) {
+		p.tok = filterToken{kind: kind, text: p.expr[start:end], offset: start}
+		p.pos = end
+	}
+	if start == len(p.expr) {
+		tok(filterEOF, start)
+		return
+	}
+	rest := p.expr[start:]
+	for _, op := range []string{"||", "&&", "==", "!=", "<=", ">=", "<", ">", "!"} {
+		if strings.HasPrefix(rest, op) {
+			tok(filterOperator, start+len(op))
+			return
+		}
+	}
+	switch c := rest[0]; {
+	case c == '(' || c == ')':
+		tok(filterParen, start+1)
+	case c == '"':
+		end := start + 1
+		for ; end < len(p.expr) && p.expr[end] != '"'; end++ {
+			if p.expr[end] == '\\' {
+				end++
+			}
+		}
+		if end < len(p.expr) {
+			end++
+		}
+		tok(filterLiteral, clampLen(end, p.expr))
+		p.tok.value, p.err = parseFilterLiteral(p.tok.text)
+	case c == '-' || ('0' <= c && c <= '9'):
+		end := start + 1
+		for ; end < len(p.expr) && strings.IndexByte("0123456789.eE+-", p.expr[end]) >= 0; end++ {
+		}
+		tok(filterLiteral, end)
+		p.tok.value, p.err = parseFilterLiteral(p.tok.text)
+	case isFilterFieldByte(c):
+		end := start
+		for ; end < len(p.expr) && (isFilterFieldByte(p.expr[end]) || p.expr[end] == '\\'); end++ {
+			if p.expr[end] == '\\' {
+				end++
+			}
+		}
+		tok(filterField, clampLen(end, p.expr))
+		switch p.tok.text {
+		case "true":
+			p.tok.kind, p.tok.value = filterLiteral, Bool(true)
+		case "false":
+			p.tok.kind, p.tok.value = filterLiteral, Bool(false)
+		case "null":
+			p.tok.kind, p.tok.value = filterLiteral, Null{}
+		case "contains":
+			p.tok.kind = filterOperator
+		}
+	default:
+		tok(filterOperator, start+1)
+		p.err = fmt.Errorf("unexpected character %q", c)
+	}
+}
+
+// clampLen returns i, or the length of s if i is beyond the end of s.
+func clampLen(i int, s string) int {
+	if i > len(s) {
+		return len(s)
+	}
+	return i
+}
+
+// parseFilterLiteral parses a number or string literal, which must be a single json value.
+func parseFilterLiteral(text string) (Value, error) {
+	o, err := deserialize(unsafeBytes(text))
+	if err != nil {
+		return nil, fmt.Errorf("invalid literal %s: %v", text, err)
+	}
+	if o.node.end.Offset != len(text) {
+		return nil, fmt.Errorf("invalid literal %s", text)
+	}
+	return o.value, nil
+}
+
+func isFilterFieldByte(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') ||
+		c == '_' || c == '.' || c == '@' || c >= 0x80
+}
+
+// accept consumes the current token if it is the operator or parenthesis op.
+func (p *filterParser) accept(op string) bool {
+	if (p.tok.kind == filterOperator || p.tok.kind == filterParen) && p.tok.text == op {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) or() (filterExpr, error) {
+	left, err := p.and()
+	for err == nil && p.accept("||") {
+		var right filterExpr
+		if right, err = p.and(); err == nil {
+			l := left
+			left = func(v Value) bool { return l(v) || right(v) }
+		}
+	}
+	return left, err
+}
+
+func (p *filterParser) and() (filterExpr, error) {
+	left, err := p.unary()
+	for err == nil && p.accept("&&") {
+		var right filterExpr
+		if right, err = p.unary(); err == nil {
+			l := left
+			left = func(v Value) bool { return l(v) && right(v) }
+		}
+	}
+	return left, err
+}
+
+func (p *filterParser) unary() (filterExpr, error) {
+	if p.accept("!") {
+		e, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(v Value) bool { return !e(v) }, nil
+	}
+	if p.accept("(") {
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, p.errorf("expected \")\", found %s", p.tok)
+		}
+		return e, nil
+	}
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	op := p.tok.text
+	cmp, ok := filterComparisons[op]
+	if p.tok.kind != filterOperator || !ok {
+		return func(v Value) bool {
+			lv, ok := left(v)
+			return ok && isTruthy(lv)
+		}, nil
+	}
+	p.next()
+	right, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	return func(v Value) bool {
+		lv, lok := left(v)
+		rv, rok := right(v)
+		if !lok || !rok {
+			return op == "!="
+		}
+		return cmp(lv, rv)
+	}, nil
+}
+
+func (p *filterParser) operand() (filterOperand, error) {
+	if p.err != nil {
+		return nil, p.errorf("%v", p.err)
+	}
+	tok := p.tok
+	switch tok.kind {
+	case filterLiteral:
+		p.next()
+		return func(Value) (Value, bool) { return tok.value, true }, nil
+	case filterField:
+		p.next()
+		if tok.text == "@" {
+			return func(v Value) (Value, bool) { return v, true }, nil
+		}
+		path, err := ParseDotPath(strings.TrimPrefix(tok.text, "@."))
+		if err != nil {
+			return nil, FilterSyntaxError{Expr: p.expr, Offset: tok.offset, Msg: err.Error()}
+		}
+		return func(v Value) (Value, bool) {
+			fv, err := path.Get(v)
+			return fv, err == nil
+		}, nil
+	}
+	return nil, p.errorf("expected a field or literal, found %s", tok)
+}
+
+// isTruthy reports whether an operand on its own matches.
+func isTruthy(v Value) bool {
+	switch v := v.(type) {
+	case Null:
+		return false
+	case Bool:
+		return bool(v)
+	}
+	return true
+}
+
+// filterComparisons are the comparison operators of filter expressions.
+var filterComparisons = map[string]func(a, b Value) bool{
+	"==": Equal,
+	"!=": func(a, b Value) bool { return !Equal(a, b) },
+	"<":  func(a, b Value) bool { c, ok := compareOrdered(a, b); return ok && c < 0 },
+	"<=": func(a, b Value) bool { c, ok := compareOrdered(a, b); return ok && c <= 0 },
+	">":  func(a, b Value) bool { c, ok := compareOrdered(a, b); return ok && c > 0 },
+	">=": func(a, b Value) bool { c, ok := compareOrdered(a, b); return ok && c >= 0 },
+	"contains": func(a, b Value) bool {
+		switch a := a.(type) {
+		case Array:
+			return a.Contains(b)
+		case String:
+			s, ok := b.(String)
+			return ok && strings.Contains(string(a), string(s))
+		}
+		return false
+	},
+}
+
+// compareOrdered compares two numbers or two strings. ok is false for other values. Integers are
+// compared exactly, as by Equal, and only numbers with a float are compared as float64.
+func compareOrdered(a, b Value) (c int, ok bool) {
+	switch a := a.(type) {
+	case Number:
+		b, ok := b.(Number)
+		if !ok {
+			return 0, false
+		}
+		if !a.IsFloat && !b.IsFloat {
+			return compareIntegers(a, b), true
+		}
+		af, bf := a.float64(), b.float64()
+		switch {
+		case af < bf:
+			return -1, true
+		case af > bf:
+			return 1, true
+		}
+		return 0, true
+	case String:
+		b, ok := b.(String)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(string(a), string(b)), true
+	}
+	return 0, false
+}
+
+// compareIntegers compares two integer numbers. -0 is equal to 0.
+func compareIntegers(a, b Number) int {
+	aneg, bneg := a.IsNeg && a.Integer != 0, b.IsNeg && b.Integer != 0
+	switch {
+	case aneg != bneg:
+		if aneg {
+			return -1
+		}
+		return 1
+	case a.Integer == b.Integer:
+		return 0
+	case (a.Integer < b.Integer) != aneg:
+		return -1
+	}
+	return 1
+}
+
+// ---------------- errors ----------------
+
+// FilterSyntaxError is returned by CompileFilter for an invalid expression.
+type FilterSyntaxError struct {
+	Expr string
+	// Offset is the byte offset of the error in Expr.
+	Offset int
+	Msg    string
+}
+
+func (e FilterSyntaxError) Error() string {
+	return fmt.Sprintf("invalid filter %q at offset %d: %s", e.Expr, e.Offset, e.Msg)
+}
@@ -0,0 +1,116 @@
+package genjson
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestArrayWhere(t *testing.T) {
+	items := MustDeserializeString(`[
+		{"id": 1, "price": 5, "tags": ["new"], "owner": {"name": "ann"}},
+		{"id": 2, "price": 12.5, "tags": ["new", "sale"], "status": "deleted"},
+		{"id": 3, "price": 20, "tags": [], "archived": true, "owner": {"name": null}},
+		{"id": 4, "price": "11", "title": "a \"new\" item", "a.b": 1}
+	]`).(Array)
+	tests := []struct {
+		expr string
+		want []int
+	}{
+		{`price > 10`, []int{2, 3}},
+		{`price > 10 && tags contains "new"`, []int{2}},
+		{`price >= 12.5 || id == 1`, []int{1, 2, 3}},
+		{`price < 1e1`, []int{1}},
+		{`price <= -1`, nil},
+		{`price == 20.0`, []int{3}},
+		{`price == 1.25e1`, []int{2}},
+		{`price > "10"`, []int{4}},
+		{`status != "deleted"`, []int{1, 3, 4}},
+		{`!(status == "deleted" || archived)`, []int{1, 4}},
+		{`!archived && !status`, []int{1, 4}},
+		{`archived`, []int{3}},
+		{`owner.name`, []int{1}},
+		{`owner.name == null`, []int{3}},
+		{`tags.1 == "sale"`, []int{2}},
+		{`title contains "\"new\""`, []int{4}},
+		{`a\.b == 1`, []int{4}},
+		{`@.id == 2 || @ contains "x"`, []int{2}},
+		{`true`, []int{1, 2, 3, 4}},
+		{`(id > 1) && (id < 4 && price > 15)`, []int{3}},
+		{`id > 1 && id < 4 || id == 1`, []int{1, 2, 3}},
+		{`id == 1 || id > 1 && id < 3`, []int{1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := items.Where(tt.expr)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			var ids []int
+			for _, v := range got {
+				ids = append(ids, GetIntOr(v, 0, "id"))
+			}
+			if len(ids) != len(tt.want) {
+				t.Fatalf("unexpected ids %v != %v", ids, tt.want)
+			}
+			for i := range ids {
+				if ids[i] != tt.want[i] {
+					t.Fatalf("unexpected ids %v != %v", ids, tt.want)
+				}
+			}
+		})
+	}
+
+	numbers := MustDeserializeString(`[1, 5, 10]`).(Array)
+	f := MustCompileFilter(`@ > 2`)
+	if got := f.Apply(numbers); len(got) != 2 || !f.Match(Number{Integer: 3}) || f.Match(String("3")) {
+		t.Errorf("unexpected result %v", got)
+	}
+
+	// Integers beyond the precision of float64 are compared exactly.
+	large := MustDeserializeString(`[{"id": 9007199254740993}, {"id": 9007199254740992}, {"id": -9007199254740993}, {"id": -0}]`).(Array)
+	for expr, want := range map[string]int{
+		`id >= 9007199254740993`:  1,
+		`id < 9007199254740993`:   3,
+		`id > -9007199254740992`:  3,
+		`id <= -9007199254740993`: 1,
+		`id >= 0`:                 3,
+		`id < 0`:                  1,
+		`id > 9007199254740992.0`: 0,
+	} {
+		if got := MustCompileFilter(expr).Apply(large); len(got) != want {
+			t.Errorf("%s: unexpected result %v", expr, got)
+		}
+	}
+}
+
+func TestCompileFilterErrors(t *testing.T) {
+	tests := []struct {
+		expr   string
+		offset int
+	}{
+		{``, 0},
+		{`price >`, 7},
+		{`price > 10 &&`, 13},
+		{`(price > 10`, 11},
+		{`price > 10)`, 10},
+		{`price # 10`, 6},
+		{`price > "10`, 8},
+		{`price > 1.2.3`, 8},
+		{`price 10`, 6},
+		{`tags.# > 1`, 5},
+		{`@len > 1`, 0},
+		{`== 1`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := CompileFilter(tt.expr)
+			var se FilterSyntaxError
+			if !errors.As(err, &se) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if se.Offset != tt.offset {
+				t.Errorf("unexpected offset %d: %v", se.Offset, err)
+			}
+		})
+	}
+}
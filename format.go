@@ -0,0 +1,38 @@
+package genjson
+
+// FormatRange reformats the smallest value of the document that contains the range between start
+// and end, leaving the rest of the source untouched. Only the Offset of the locations is used. The
+// value is indented according to its depth in the document. If s is nil, the default Serializer
+// is used. Like Serialize, it writes negative options as 0.
+func FormatRange(doc *Document, start, end Loc, s *Serializer) []byte {
+	if s == nil {
+		s = &defSerializer
+	}
+	v, n, depth := enclosing(doc.value, &doc.node, start.Offset, end.Offset, 0)
+	out := make([]byte, 0, len(doc.src))
+	out = append(out, doc.src[:n.start.Offset]...)
+	out = s.appendLevel(out, v, depth)
+	out = append(out, doc.src[n.end.Offset:]...)
+	return out
+}
+
+// enclosing returns the deepest value containing the range, along with its node and depth.
+func enclosing(v Value, n *node, start, end, depth int) (Value, *node, int) {
+	switch v := v.(type) {
+	case Array:
+		for i, elem := range v {
+			en := &n.arrayNodes[i]
+			if en.start.Offset <= start && end <= en.end.Offset {
+				return enclosing(elem, en, start, end, depth+1)
+			}
+		}
+	case Object:
+		for i, e := range v.Entries() {
+			kv := &n.objectNodes[i]
+			if kv.start.Offset <= start && end <= kv.end.Offset {
+				return enclosing(e.Value, &kv.node, start, end, depth+1)
+			}
+		}
+	}
+	return v, n, depth
+}
@@ -0,0 +1,46 @@
+package genjson
+
+import (
+	"testing"
+)
+
+func TestFormatRange(t *testing.T) {
+	src := "{\n  \"a\":   [1,2],\n  \"b\" :{\"c\":true}\n}"
+	doc, err := DeserializeDocument([]byte(src))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	s := &Serializer{Indent: 2, KeyValueGap: 1}
+	tests := []struct {
+		name       string
+		start, end int
+		want       string
+	}{
+		{
+			name:  "array",
+			start: 13,
+			end:   14,
+			want:  "{\n  \"a\":   [\n    1,\n    2\n  ],\n  \"b\" :{\"c\":true}\n}",
+		},
+		{
+			name:  "nested-object",
+			start: 26,
+			end:   34,
+			want:  "{\n  \"a\":   [1,2],\n  \"b\" :{\n    \"c\": true\n  }\n}",
+		},
+		{
+			name:  "root",
+			start: 0,
+			end:   len(src),
+			want:  "{\n  \"a\": [\n    1,\n    2\n  ],\n  \"b\": {\n    \"c\": true\n  }\n}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FormatRange(doc, Loc{Offset: tt.start}, Loc{Offset: tt.end}, s)
+			if string(got) != tt.want {
+				t.Errorf("unexpected output\n%s\n%s", got, tt.want)
+			}
+		})
+	}
+}
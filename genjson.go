@@ -2,11 +2,22 @@
 // by first deserializing a byte slice into a Value type. This is less efficient, but allows for
 // perfectly describing json data without having any compromises for go specific implementation
 // details.
+//
+// # API stability
+//
+// The v1 surface of the package is the Value types, the Deserializer, Serializer, Marshaler and
+// Unmarshaler with their package level wrappers, Document, Decoder and Encoder, and the options
+// accepted by NewDeserializer, NewSerializer, NewMarshaler and NewUnmarshaler. It follows semantic
+// versioning: new options and methods may be added, but existing identifiers keep their meaning.
+// Identifiers marked as deprecated remain available until the next major version and are thin
+// wrappers around their replacements.
 package genjson
 
 import (
 	"container/list"
+	"errors"
 	"reflect"
+	"sort"
 )
 
 type Type int8
@@ -18,6 +29,8 @@ const (
 	TypeString Type = iota
 	TypeArray  Type = iota
 	TypeObject Type = iota
+	// TypeExtension is the type of Extension values. It is not a json type.
+	TypeExtension Type = iota
 )
 
 func (t Type) String() string {
@@ -34,13 +47,55 @@ func (t Type) String() string {
 		return "array"
 	case TypeObject:
 		return "object"
+	case TypeExtension:
+		return "extension"
 	}
 	return ""
 }
 
+// TypeOf returns the json type of the value. A nil Value is reported as TypeNull.
+func TypeOf(v Value) Type {
+	switch v.(type) {
+	case Bool:
+		return TypeBool
+	case Number:
+		return TypeNumber
+	case String:
+		return TypeString
+	case Array:
+		return TypeArray
+	case Object:
+		return TypeObject
+	case Extension:
+		return TypeExtension
+	}
+	return TypeNull
+}
+
+// IsEmpty reports whether the value is empty: null, false, 0, an empty string, an empty array or
+// an empty object. These are the values omitted by the omitempty tag option.
+func IsEmpty(v Value) bool {
+	switch v := v.(type) {
+	case nil, Null:
+		return true
+	case Bool:
+		return !bool(v)
+	case Number:
+		return v.Integer == 0 && v.Float == 0
+	case String:
+		return v == ""
+	case Array:
+		return len(v) == 0
+	case Object:
+		return v.Len() == 0
+	}
+	return false
+}
+
 type (
 	// Value describes a json value. It is only implemented by types in this package. Picture it
-	// as a set type from other languages.
+	// as a set type from other languages. Values that are not json values can be added to a tree
+	// with Extension.
 	Value interface {
 		isValue()
 		append(*Serializer, int, []byte) []byte
@@ -57,17 +112,32 @@ type (
 		Integer uint64
 		IsFloat bool
 		IsNeg   bool
+		// IsExp is set for floats written in exponent notation, such as 1e10. They are serialized
+		// in exponent notation too, with a lowercase e and without a + sign or leading zeros in
+		// the exponent.
+		IsExp bool
 	}
 	// String represents a string json value.
 	String string
 	// Array represents an array json value.
 	Array []Value
-	// Object represents an object json value.
+	// Object represents an object json value. The zero Object is an empty object that is ready to
+	// use. Copies of an Object share their entries once the Object has been initialized by Set or
+	// Add, but entries added to a copy of the zero Object are not visible in the original.
 	Object struct {
 		m *orderedDuplicateMap[string, Value]
 	}
 )
 
+// MaxSafeInteger is the largest integer that can be represented exactly by a javascript number.
+const MaxSafeInteger = 1<<53 - 1
+
+// IsSafeInteger reports whether the number can be represented exactly by a javascript number. Only
+// integers are checked, floats are always reported as safe.
+func (n Number) IsSafeInteger() bool {
+	return n.IsFloat || n.Integer <= MaxSafeInteger
+}
+
 func integer(i uint64) Number {
 	return Number{Integer: i}
 }
@@ -106,6 +176,20 @@ func (o Object) Get(key string) (Value, bool) {
 	return o.m.get(key)
 }
 
+// GetBytes is like Get but takes the key as bytes, such as a slice of a message being routed. It
+// does not allocate.
+func (o Object) GetBytes(key []byte) (Value, bool) {
+	if o.m == nil {
+		return nil, false
+	}
+	// The conversion in the index expression does not allocate.
+	e := o.m.m[string(key)]
+	if len(e) == 0 {
+		return nil, false
+	}
+	return e[0].value, true
+}
+
 // GetAll returns all entries matching the provided key.
 func (o Object) GetAll(key string) ([]Value, bool) {
 	return o.m.getAll(key)
@@ -133,19 +217,106 @@ func (o Object) Delete(key string) {
 	o.m.remove(key)
 }
 
-// Delete removes any entries matching the key from the object.
+// ToMultiMap groups the entries of the object by key. Duplicate keys are preserved in the order
+// they were added.
+func (o Object) ToMultiMap() map[string][]Value {
+	m := make(map[string][]Value, o.Len())
+	iter := o.Iter()
+	for k, v, ok := iter.Next(); ok; k, v, ok = iter.Next() {
+		m[k] = append(m[k], v)
+	}
+	return m
+}
+
+// FromMultiMap creates an object from grouped entries. As maps are unordered, keys are added in
+// lexicographical order. The values of each key are added in slice order.
+func FromMultiMap(m map[string][]Value) Object {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var o Object
+	o.init()
+	for _, k := range keys {
+		for _, v := range m[k] {
+			o.Add(k, v)
+		}
+	}
+	return o
+}
+
+// NewObject creates an object from entries, added in order. The Index and Ordinal of the entries
+// are ignored, so duplicate keys are kept.
+func NewObject(entries ...Entry) Object {
+	var o Object
+	o.init()
+	for _, e := range entries {
+		o.Add(e.Key, e.Value)
+	}
+	return o
+}
+
+// Entry is a single key value pair of an object.
+type Entry struct {
+	Key   string
+	Value Value
+	// Index is the position of the entry in insertion order.
+	Index int
+	// Ordinal is the number of entries with the same key before this one. It is 0 for keys that
+	// are not duplicated.
+	Ordinal int
+}
+
+// Entries returns the entries of the object in insertion order.
+func (o Object) Entries() []Entry {
+	entries := make([]Entry, 0, o.Len())
+	ordinals := make(map[string]int)
+	iter := o.Iter()
+	for k, v, ok := iter.Next(); ok; k, v, ok = iter.Next() {
+		entries = append(entries, Entry{
+			Key:     k,
+			Value:   v,
+			Index:   len(entries),
+			Ordinal: ordinals[k],
+		})
+		ordinals[k]++
+	}
+	return entries
+}
+
+// Iter returns an iterator over the entries of the object in insertion order.
 func (o Object) Iter() *ObjectIterator {
 	return &ObjectIterator{iter: o.m.iter()}
 }
 
+// IterSnapshot returns an iterator over a copy of the entries of the object in insertion order.
+// Unlike Iter, entries may be added to or removed from the object during iteration. The iterator
+// returns the entries as they were when IterSnapshot was called.
+func (o Object) IterSnapshot() *ObjectIterator {
+	return &ObjectIterator{iter: o.m.clone().iter()}
+}
+
 type ObjectIterator struct {
 	iter *orderedDuplicateMapIterator[string, Value]
 }
 
-func (o *ObjectIterator) Next() (string, Value, bool) {
+// Next returns the next entry of the object. ok is false once all entries have been returned or
+// the object was modified in a way that prevents iteration from continuing, in which case Err
+// returns ErrObjectModified.
+func (o *ObjectIterator) Next() (key string, value Value, ok bool) {
 	return o.iter.next()
 }
 
+// Err returns the error that stopped iteration, if any.
+func (o *ObjectIterator) Err() error {
+	return o.iter.err
+}
+
+// ErrObjectModified is returned by ObjectIterator.Err when the entry the iterator was positioned
+// on is removed from the object during iteration.
+var ErrObjectModified = errors.New("object modified during iteration")
+
 type orderedDuplicateMap[K comparable, V any] struct {
 	// Linked list of keys in insertion order.
 	keys *list.List
@@ -173,7 +344,26 @@ func (o *orderedDuplicateMap[K, V]) iter() *orderedDuplicateMapIterator[K, V] {
 	return &iter
 }
 
+// clone returns a copy of the map that does not share any state with the original.
+func (o *orderedDuplicateMap[K, V]) clone() *orderedDuplicateMap[K, V] {
+	if o == nil {
+		return nil
+	}
+	c := &orderedDuplicateMap[K, V]{
+		keys: list.New(),
+		m:    make(map[K][]orderedDuplicateMapEntry[V], len(o.m)),
+	}
+	iter := o.iter()
+	for k, v, ok := iter.next(); ok; k, v, ok = iter.next() {
+		c.add(k, v)
+	}
+	return c
+}
+
 func (o *orderedDuplicateMap[K, V]) getAll(k K) ([]V, bool) {
+	if o == nil {
+		return nil, false
+	}
 	e := o.m[k]
 	if len(e) == 0 {
 		return nil, false
@@ -186,6 +376,10 @@ func (o *orderedDuplicateMap[K, V]) getAll(k K) ([]V, bool) {
 }
 
 func (o *orderedDuplicateMap[K, V]) get(k K) (V, bool) {
+	if o == nil {
+		var empty V
+		return empty, false
+	}
 	e := o.m[k]
 	if len(e) == 0 {
 		var empty V
@@ -210,6 +404,9 @@ func (o *orderedDuplicateMap[K, V]) set(k K, v V) {
 
 // remove removes all entries matching the key from the map
 func (o *orderedDuplicateMap[K, V]) remove(k K) {
+	if o == nil {
+		return
+	}
 	for _, e := range o.m[k] {
 		o.keys.Remove(e.key)
 	}
@@ -222,14 +419,15 @@ type orderedDuplicateMapEntry[V any] struct {
 }
 
 type orderedDuplicateMapIterator[K comparable, V any] struct {
-	e *list.Element
-	m map[K][]orderedDuplicateMapEntry[V]
+	e   *list.Element
+	m   map[K][]orderedDuplicateMapEntry[V]
+	err error
 }
 
 func (o *orderedDuplicateMapIterator[K, V]) next() (K, V, bool) {
+	var emptyK K
+	var emptyV V
 	if o.e == nil {
-		var emptyK K
-		var emptyV V
 		return emptyK, emptyV, false
 	}
 
@@ -241,5 +439,7 @@ func (o *orderedDuplicateMapIterator[K, V]) next() (K, V, bool) {
 			return key, e.value, true
 		}
 	}
-	panic("illegal map state")
+	o.e = nil
+	o.err = ErrObjectModified
+	return emptyK, emptyV, false
 }
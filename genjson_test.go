@@ -3,6 +3,8 @@ package genjson
 import (
 	"bytes"
 	_ "embed"
+	"errors"
+	"reflect"
 	"testing"
 )
 
@@ -24,3 +26,207 @@ func TestRoundTrip(t *testing.T) {
 		t.Errorf("json round trip error %q != %q", tes, data)
 	}
 }
+
+func TestMultiMap(t *testing.T) {
+	var o Object
+	o.Add("b", integer(1))
+	o.Add("a", integer(2))
+	o.Add("b", integer(3))
+
+	m := o.ToMultiMap()
+	want := map[string][]Value{
+		"a": {integer(2)},
+		"b": {integer(1), integer(3)},
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Fatalf("unexpected multimap %v != %v", m, want)
+	}
+
+	got := Serialize(FromMultiMap(m))
+	if string(got) != `{"a":2,"b":1,"b":3}` {
+		t.Errorf("unexpected object %s", got)
+	}
+}
+
+func TestEntries(t *testing.T) {
+	var o Object
+	o.Add("a", integer(1))
+	o.Add("b", integer(2))
+	o.Add("a", integer(3))
+
+	want := []Entry{
+		{Key: "a", Value: integer(1), Index: 0, Ordinal: 0},
+		{Key: "b", Value: integer(2), Index: 1, Ordinal: 0},
+		{Key: "a", Value: integer(3), Index: 2, Ordinal: 1},
+	}
+	if got := o.Entries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected entries %+v != %+v", got, want)
+	}
+}
+
+func TestIsEmpty(t *testing.T) {
+	var o Object
+	o.Add("a", Null{})
+	tests := []struct {
+		value Value
+		want  bool
+	}{
+		{nil, true},
+		{Null{}, true},
+		{Bool(false), true},
+		{Bool(true), false},
+		{integer(0), true},
+		{float(0), true},
+		{Number{IsNeg: true}, true},
+		{integer(1), false},
+		{float(0.1), false},
+		{String(""), true},
+		{String("a"), false},
+		{Array{}, true},
+		{Array{Null{}}, false},
+		{Object{}, true},
+		{o, false},
+	}
+	for _, tt := range tests {
+		if got := IsEmpty(tt.value); got != tt.want {
+			t.Errorf("unexpected result for %#v %v != %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestObjectIterModified(t *testing.T) {
+	o := MustDeserializeString(`{"a": 1, "b": 2, "c": 3}`).(Object)
+	iter := o.Iter()
+	var keys []string
+	for k, _, ok := iter.Next(); ok; k, _, ok = iter.Next() {
+		keys = append(keys, k)
+		if k == "a" {
+			o.Delete("b")
+		}
+	}
+	if !errors.Is(iter.Err(), ErrObjectModified) {
+		t.Errorf("unexpected error %v", iter.Err())
+	}
+	if !reflect.DeepEqual(keys, []string{"a"}) {
+		t.Errorf("unexpected keys %v", keys)
+	}
+}
+
+func TestZeroObject(t *testing.T) {
+	var o Object
+	if v, ok := o.Get("a"); ok || v != nil {
+		t.Errorf("unexpected get %v %v", v, ok)
+	}
+	if v, ok := o.GetAll("a"); ok || v != nil {
+		t.Errorf("unexpected get all %v %v", v, ok)
+	}
+	o.Delete("a")
+	if o.Len() != 0 {
+		t.Errorf("unexpected len %d", o.Len())
+	}
+	if _, _, ok := o.Iter().Next(); ok {
+		t.Errorf("unexpected entry")
+	}
+	if len(o.Entries()) != 0 || len(o.ToMultiMap()) != 0 {
+		t.Errorf("unexpected entries")
+	}
+	if !IsEmpty(o) || TypeOf(o) != TypeObject {
+		t.Errorf("unexpected zero object classification")
+	}
+	if got := string(Serialize(o)); got != "{}" {
+		t.Errorf("unexpected serialization %s", got)
+	}
+
+	type embedded struct {
+		Object
+	}
+	var e embedded
+	e.Add("a", Bool(true))
+	e.Set("b", Null{})
+	if v, ok := e.Get("a"); !ok || v != Bool(true) || e.Len() != 2 {
+		t.Errorf("unexpected embedded object %v %v %d", v, ok, e.Len())
+	}
+}
+
+func TestObjectIterSnapshot(t *testing.T) {
+	o := MustDeserializeString(`{"a": 1, "b": 2, "a": 3, "c": 4}`).(Object)
+	iter := o.IterSnapshot()
+	var keys []string
+	for k, _, ok := iter.Next(); ok; k, _, ok = iter.Next() {
+		keys = append(keys, k)
+		o.Delete(k)
+		o.Add(k+k, Null{})
+	}
+	if iter.Err() != nil {
+		t.Errorf("unexpected error %v", iter.Err())
+	}
+	if want := []string{"a", "b", "a", "c"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("unexpected keys %v", keys)
+	}
+	if got := string(Serialize(o)); got != `{"aa":null,"bb":null,"aa":null,"cc":null}` {
+		t.Errorf("unexpected object %s", got)
+	}
+
+	var zero Object
+	if _, _, ok := zero.IterSnapshot().Next(); ok {
+		t.Errorf("unexpected entry")
+	}
+}
+
+func TestObjectGetBytes(t *testing.T) {
+	o := MustDeserializeString(`{"type": "a", "id": 1, "type": "b"}`).(Object)
+	v, ok := o.GetBytes([]byte("type"))
+	if !ok || v != String("a") {
+		t.Errorf("unexpected value %v %v", v, ok)
+	}
+	if _, ok := o.GetBytes([]byte("missing")); ok {
+		t.Errorf("unexpected value for a missing key")
+	}
+	if _, ok := (Object{}).GetBytes([]byte("type")); ok {
+		t.Errorf("unexpected value in the zero object")
+	}
+	key := []byte("id")
+	if allocs := testing.AllocsPerRun(100, func() {
+		o.GetBytes(key)
+		o.Get("type")
+	}); allocs != 0 {
+		t.Errorf("expected no allocations but got %v", allocs)
+	}
+}
+
+var routeMessage = []byte(`{"type": "order.created", "id": 12345, "payload": {"items": [1, 2, 3], "total": 9.99}}`)
+
+func BenchmarkObjectGet(b *testing.B) {
+	o := MustDeserialize(routeMessage).(Object)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		o.Get("type")
+	}
+}
+
+func BenchmarkObjectGetBytes(b *testing.B) {
+	o := MustDeserialize(routeMessage).(Object)
+	key := []byte("type")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		o.GetBytes(key)
+	}
+}
+
+// BenchmarkRouteByKey parses a message and looks up the key it is routed by.
+func BenchmarkRouteByKey(b *testing.B) {
+	key := []byte("type")
+	b.ReportAllocs()
+	b.SetBytes(int64(len(routeMessage)))
+	for i := 0; i < b.N; i++ {
+		v, err := Deserialize(routeMessage)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, ok := v.(Object).GetBytes(key); !ok {
+			b.Fatal("missing key")
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package genjson
+
+import (
+	"math"
+)
+
+// GetOr returns the value at path within v, or def if the path does not exist. Paths are resolved
+// as by GetPath.
+func GetOr(v Value, def Value, path ...string) Value {
+	got, err := GetPath(v, path)
+	if err != nil {
+		return def
+	}
+	return got
+}
+
+// GetStringOr returns the string at path within v, or def if the path does not exist or the value
+// is not a string.
+func GetStringOr(v Value, def string, path ...string) string {
+	if s, ok := GetOr(v, nil, path...).(String); ok {
+		return string(s)
+	}
+	return def
+}
+
+// GetIntOr returns the integer at path within v, or def if the path does not exist or the value is
+// not a number that can be represented exactly by an int.
+func GetIntOr(v Value, def int, path ...string) int {
+	if n, ok := GetOr(v, nil, path...).(Number); ok {
+		if i, ok := n.int(); ok {
+			return i
+		}
+	}
+	return def
+}
+
+// GetFloatOr returns the number at path within v as a float64, or def if the path does not exist
+// or the value is not a number.
+func GetFloatOr(v Value, def float64, path ...string) float64 {
+	if n, ok := GetOr(v, nil, path...).(Number); ok {
+		return n.float64()
+	}
+	return def
+}
+
+// GetBoolOr returns the boolean at path within v, or def if the path does not exist or the value
+// is not a boolean.
+func GetBoolOr(v Value, def bool, path ...string) bool {
+	if b, ok := GetOr(v, nil, path...).(Bool); ok {
+		return bool(b)
+	}
+	return def
+}
+
+// int returns the number as an int if it is an integer within the range of int.
+func (n Number) int() (int, bool) {
+	i, ok := n.integral()
+	if !ok {
+		return 0, false
+	}
+	if i.IsNeg {
+		if i.Integer > -math.MinInt {
+			return 0, false
+		}
+		return int(-i.Integer), true
+	}
+	if i.Integer > math.MaxInt {
+		return 0, false
+	}
+	return int(i.Integer), true
+}
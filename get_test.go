@@ -0,0 +1,66 @@
+package genjson
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetOr(t *testing.T) {
+	v := MustDeserializeString(`{
+		"server": {"host": "localhost", "port": 8080, "ratio": 0.5, "debug": true},
+		"users": [{"name": "a"}, {"name": "b", "age": 2.0}],
+		"big": 18446744073709551615,
+		"neg": -42
+	}`)
+	def := String("default")
+	tests := []struct {
+		path []string
+		want Value
+	}{
+		{path: []string{"server", "host"}, want: String("localhost")},
+		{path: []string{"users", "1", "name"}, want: String("b")},
+		{path: []string{"users", "2", "name"}, want: def},
+		{path: []string{"server", "missing"}, want: def},
+		{path: []string{"server", "host", "x"}, want: def},
+		{path: nil, want: v},
+	}
+	for _, tt := range tests {
+		if got := GetOr(v, def, tt.path...); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: unexpected value %v != %v", tt.path, got, tt.want)
+		}
+	}
+
+	if got := GetStringOr(v, "x", "server", "host"); got != "localhost" {
+		t.Errorf("unexpected string %q", got)
+	}
+	if got := GetStringOr(v, "x", "server", "port"); got != "x" {
+		t.Errorf("unexpected string %q", got)
+	}
+	if got := GetBoolOr(v, false, "server", "debug"); !got {
+		t.Errorf("unexpected bool %v", got)
+	}
+	if got := GetFloatOr(v, 1, "server", "ratio"); got != 0.5 {
+		t.Errorf("unexpected float %v", got)
+	}
+	if got := GetFloatOr(v, 1, "server", "host"); got != 1 {
+		t.Errorf("unexpected float %v", got)
+	}
+
+	ints := []struct {
+		path []string
+		want int
+	}{
+		{path: []string{"server", "port"}, want: 8080},
+		{path: []string{"users", "1", "age"}, want: 2},
+		{path: []string{"server", "ratio"}, want: -1},
+		{path: []string{"server", "host"}, want: -1},
+		{path: []string{"users", "0", "age"}, want: -1},
+		{path: []string{"big"}, want: -1},
+		{path: []string{"neg"}, want: -42},
+	}
+	for _, tt := range ints {
+		if got := GetIntOr(v, -1, tt.path...); got != tt.want {
+			t.Errorf("%v: unexpected int %d != %d", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package genjson
+
+import (
+	"strconv"
+	"strings"
+)
+
+// GoSnippet returns go source declaring a variable with the name that holds the value, for use in
+// tests and fixtures. The json is written in a raw string literal using the serializer, or an
+// indented serializer if s is nil. Backticks in strings are always escaped.
+func GoSnippet(name string, v Value, s *Serializer) string {
+	ss := Serializer{Indent: 1, KeyValueGap: 1}
+	if s != nil {
+		ss = *s
+	}
+	ss.EscapeBackticks = true
+	sb := strings.Builder{}
+	sb.WriteString("var ")
+	sb.WriteString(name)
+	sb.WriteString(" = genjson.MustParseString(`")
+	sb.Write(ss.Serialize(v))
+	sb.WriteString("`)\n")
+	return sb.String()
+}
+
+// ToGoLiteral returns a go expression that constructs the value, for converting captured payloads
+// into test fixtures. Unlike GoSnippet, the value is built directly rather than parsed, so the
+// output reproduces the value exactly, including the representation of numbers. Objects are built
+// with NewObject. Extension values cannot be written and are replaced with nil.
+func ToGoLiteral(v Value) string {
+	return string(appendGoLiteral(nil, v, 0))
+}
+
+func appendGoLiteral(bb []byte, v Value, level int) []byte {
+	switch v := v.(type) {
+	case Null:
+		return append(bb, "genjson.Null{}"...)
+	case Bool:
+		bb = append(bb, "genjson.Bool("...)
+		bb = strconv.AppendBool(bb, bool(v))
+		return append(bb, ')')
+	case Number:
+		return appendGoNumber(bb, v)
+	case String:
+		bb = append(bb, "genjson.String("...)
+		bb = strconv.AppendQuote(bb, string(v))
+		return append(bb, ')')
+	case Array:
+		if len(v) == 0 {
+			return append(bb, "genjson.Array{}"...)
+		}
+		bb = append(bb, "genjson.Array{\n"...)
+		for _, elem := range v {
+			bb = appendGoIndent(bb, level+1)
+			bb = appendGoLiteral(bb, elem, level+1)
+			bb = append(bb, ",\n"...)
+		}
+		bb = appendGoIndent(bb, level)
+		return append(bb, '}')
+	case Object:
+		if v.Len() == 0 {
+			return append(bb, "genjson.Object{}"...)
+		}
+		bb = append(bb, "genjson.NewObject(\n"...)
+		for _, e := range v.Entries() {
+			bb = appendGoIndent(bb, level+1)
+			bb = append(bb, "genjson.Entry{Key: "...)
+			bb = strconv.AppendQuote(bb, e.Key)
+			bb = append(bb, ", Value: "...)
+			bb = appendGoLiteral(bb, e.Value, level+1)
+			bb = append(bb, "},\n"...)
+		}
+		bb = appendGoIndent(bb, level)
+		return append(bb, ')')
+	}
+	return append(bb, "nil"...)
+}
+
+// appendGoNumber appends a composite literal of the number with only the fields that are set.
+func appendGoNumber(bb []byte, n Number) []byte {
+	bb = append(bb, "genjson.Number{"...)
+	var fields []string
+	if n.IsFloat {
+		fields = append(fields, "Float: "+strconv.FormatFloat(n.Float, 'g', -1, 64))
+	}
+	if n.Integer != 0 {
+		fields = append(fields, "Integer: "+strconv.FormatUint(n.Integer, 10))
+	}
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{
+		{"IsFloat", n.IsFloat},
+		{"IsNeg", n.IsNeg},
+		{"IsExp", n.IsExp},
+	} {
+		if f.set {
+			fields = append(fields, f.name+": true")
+		}
+	}
+	bb = append(bb, strings.Join(fields, ", ")...)
+	return append(bb, '}')
+}
+
+func appendGoIndent(bb []byte, level int) []byte {
+	for i := 0; i < level; i++ {
+		bb = append(bb, '\t')
+	}
+	return bb
+}
+
+// GoString implements fmt.GoStringer with ToGoLiteral.
+func (n Null) GoString() string { return ToGoLiteral(n) }
+
+// GoString implements fmt.GoStringer with ToGoLiteral.
+func (b Bool) GoString() string { return ToGoLiteral(b) }
+
+// GoString implements fmt.GoStringer with ToGoLiteral.
+func (n Number) GoString() string { return ToGoLiteral(n) }
+
+// GoString implements fmt.GoStringer with ToGoLiteral.
+func (st String) GoString() string { return ToGoLiteral(st) }
+
+// GoString implements fmt.GoStringer with ToGoLiteral.
+func (a Array) GoString() string { return ToGoLiteral(a) }
+
+// GoString implements fmt.GoStringer with ToGoLiteral.
+func (o Object) GoString() string { return ToGoLiteral(o) }
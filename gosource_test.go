@@ -0,0 +1,56 @@
+package genjson
+
+import (
+	"fmt"
+	"go/format"
+	"testing"
+)
+
+func TestGoSnippet(t *testing.T) {
+	v := MustDeserializeString("{\"cmd\": \"echo `date`\"}")
+	got := GoSnippet("fixture", v, &Serializer{})
+	want := "var fixture = genjson.MustParseString(`{\"cmd\":\"echo \\u0060date\\u0060\"}`)\n"
+	if got != want {
+		t.Errorf("unexpected snippet %q != %q", got, want)
+	}
+}
+
+func TestMustParseStringPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic")
+		}
+	}()
+	MustParseString("[")
+}
+
+func TestToGoLiteral(t *testing.T) {
+	v := MustDeserializeString(`{"a": [null, true, 1, -2.5, 1e3, "x\"y"], "b": {}, "a": []}`)
+	want := `genjson.NewObject(
+	genjson.Entry{Key: "a", Value: genjson.Array{
+		genjson.Null{},
+		genjson.Bool(true),
+		genjson.Number{Integer: 1},
+		genjson.Number{Float: 2.5, IsFloat: true, IsNeg: true},
+		genjson.Number{Float: 1000, IsFloat: true, IsExp: true},
+		genjson.String("x\"y"),
+	}},
+	genjson.Entry{Key: "b", Value: genjson.Object{}},
+	genjson.Entry{Key: "a", Value: genjson.Array{}},
+)`
+	got := ToGoLiteral(v)
+	if got != want {
+		t.Fatalf("unexpected literal\n%s\n%s", got, want)
+	}
+	if got := fmt.Sprintf("%#v", v); got != want {
+		t.Errorf("unexpected go string\n%s", got)
+	}
+	src := "package p\n\nvar v = " + got + "\n"
+	formatted, err := format.Source([]byte(src))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if string(formatted) != src {
+		t.Errorf("literal is not formatted\n%s", formatted)
+	}
+}
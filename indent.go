@@ -0,0 +1,31 @@
+package genjson
+
+// Compact returns the serialization of v without any whitespace. It is the same as Serialize with
+// the default Serializer.
+func Compact(v Value) []byte {
+	return defSerializer.Serialize(v)
+}
+
+// Indent returns the serialization of v with each element of an array or object on a new line.
+// Every line starts with prefix followed by one copy of indent per level of nesting, and keys are
+// separated from their values by a space, as with json.MarshalIndent. If indent is empty, the
+// output is written on a single line.
+func Indent(v Value, prefix, indent string) []byte {
+	s := Serializer{IndentString: indent, PrefixString: prefix, KeyValueGap: 1}
+	return s.Serialize(v)
+}
+
+// CompactBytes re-serializes the json text in data without any whitespace. Unlike Minify, numbers
+// and strings are rewritten in their canonical form. data must contain a single json value,
+// optionally surrounded by whitespace.
+func CompactBytes(data []byte) ([]byte, error) {
+	o, err := deserialize(data)
+	if err != nil {
+		return nil, err
+	}
+	end := o.node.end
+	if d := skipSpace(deserializer{b: data, idx: end.Offset, row: end.Row, col: end.Col}); d.idx < len(data) {
+		return nil, InvalidTokenError{Token: data[d.idx], Row: d.row, Col: d.col}
+	}
+	return Compact(o.value), nil
+}
@@ -0,0 +1,45 @@
+package genjson
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCompactIndent(t *testing.T) {
+	v := MustDeserializeString(`{"a": [1, {"b": "c"}], "d": {}}`)
+	if got, want := string(Compact(v)), `{"a":[1,{"b":"c"}],"d":{}}`; got != want {
+		t.Errorf("unexpected compact output\n%s\n%s", got, want)
+	}
+	tests := []struct {
+		prefix, indent string
+		want           string
+	}{
+		{indent: "  ", want: "{\n  \"a\": [\n    1,\n    {\n      \"b\": \"c\"\n    }\n  ],\n  \"d\": {}\n}"},
+		{prefix: "> ", indent: "\t", want: "> {\n> \t\"a\": [\n> \t\t1,\n> \t\t{\n> \t\t\t\"b\": \"c\"\n> \t\t}\n> \t],\n> \t\"d\": {}\n> }"},
+		{want: `{"a": [1,{"b": "c"}],"d": {}}`},
+	}
+	for _, tt := range tests {
+		if got := string(Indent(v, tt.prefix, tt.indent)); got != tt.want {
+			t.Errorf("unexpected indent output for %q %q\n%s\n%s", tt.prefix, tt.indent, got, tt.want)
+		}
+	}
+}
+
+func TestCompactBytes(t *testing.T) {
+	got, err := CompactBytes([]byte(" {\n  \"a\": [1.50, \"\\u0041\"]\n}\n"))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if want := `{"a":[1.5,"A"]}`; string(got) != want {
+		t.Errorf("unexpected output %s != %s", got, want)
+	}
+	for _, in := range []string{"", "[1,", "[1] [2]"} {
+		if _, err := CompactBytes([]byte(in)); err == nil {
+			t.Errorf("expected an error for %q", in)
+		}
+	}
+	var ite InvalidTokenError
+	if _, err := CompactBytes([]byte("1 x")); !errors.As(err, &ite) || ite.Col != 3 {
+		t.Errorf("unexpected error %v", err)
+	}
+}
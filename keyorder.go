@@ -0,0 +1,96 @@
+package genjson
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// keyLess returns the order of object keys, or nil if keys are written in their original order.
+func (s *Serializer) keyLess() func(a, b string) bool {
+	if s.KeyLess != nil {
+		return s.KeyLess
+	}
+	if s.SortKeys {
+		return byteKeyLess
+	}
+	return nil
+}
+
+func byteKeyLess(a, b string) bool {
+	return a < b
+}
+
+// NaturalKeyLess orders keys the way humans expect, comparing runs of digits by their numeric
+// value so that item2 sorts before item10. Other characters are compared byte by byte. Keys that
+// only differ by leading zeros are ordered by byte order.
+func NaturalKeyLess(a, b string) bool {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		ca, cb := a[i], b[j]
+		if !isDigit(ca) || !isDigit(cb) {
+			if ca != cb {
+				return ca < cb
+			}
+			i++
+			j++
+			continue
+		}
+		// Compare the runs of digits by value: ignoring leading zeros, the longer run is larger and
+		// runs of the same length compare lexically.
+		ai, bj := i, j
+		for i < len(a) && isDigit(a[i]) {
+			i++
+		}
+		for j < len(b) && isDigit(b[j]) {
+			j++
+		}
+		da := strings.TrimLeft(a[ai:i], "0")
+		db := strings.TrimLeft(b[bj:j], "0")
+		if len(da) != len(db) {
+			return len(da) < len(db)
+		}
+		if da != db {
+			return da < db
+		}
+	}
+	if len(a)-i != len(b)-j {
+		return len(a)-i < len(b)-j
+	}
+	return a < b
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// FoldKeyLess orders keys case-insensitively, using simple unicode case folding. Keys that only
+// differ by case are ordered by byte order, so that the order is deterministic.
+func FoldKeyLess(a, b string) bool {
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		ra, na := utf8.DecodeRuneInString(a[i:])
+		rb, nb := utf8.DecodeRuneInString(b[j:])
+		if fa, fb := foldRune(ra), foldRune(rb); fa != fb {
+			return fa < fb
+		}
+		i += na
+		j += nb
+	}
+	if !strings.EqualFold(a, b) {
+		// One key is a prefix of the other.
+		return utf8.RuneCountInString(a) < utf8.RuneCountInString(b)
+	}
+	return a < b
+}
+
+// foldRune returns the smallest rune of the case folding orbit of r, so that runes that are equal
+// under case folding map to the same rune.
+func foldRune(r rune) rune {
+	folded := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		if f < folded {
+			folded = f
+		}
+	}
+	return folded
+}
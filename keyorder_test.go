@@ -0,0 +1,66 @@
+package genjson
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestKeyLess(t *testing.T) {
+	tests := []struct {
+		name string
+		less func(a, b string) bool
+		keys []string
+	}{
+		{
+			name: "natural",
+			less: NaturalKeyLess,
+			keys: []string{"", "01", "1", "2", "10", "a", "item", "item01", "item1", "item2", "item2b", "item10", "item10a", "x9y", "x10"},
+		},
+		{
+			name: "fold",
+			less: FoldKeyLess,
+			keys: []string{"", "A", "a", "ab", "b", "Ba", "bA", "ba", "Éa", "éb", "ÿ"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := append([]string(nil), tt.keys...)
+			for i, j := 0, len(got)-1; i < j; i, j = i+1, j-1 {
+				got[i], got[j] = got[j], got[i]
+			}
+			sort.Slice(got, func(i, j int) bool {
+				return tt.less(got[i], got[j])
+			})
+			if !reflect.DeepEqual(got, tt.keys) {
+				t.Errorf("unexpected order\n%q\n%q", got, tt.keys)
+			}
+			for _, k := range tt.keys {
+				if tt.less(k, k) {
+					t.Errorf("%q is less than itself", k)
+				}
+			}
+		})
+	}
+}
+
+func TestSerializeKeyLess(t *testing.T) {
+	v := MustDeserializeString(`{"b10": 1, "B2": 2, "a": {"z": 3, "Y": 4}, "b2": 5}`)
+	tests := []struct {
+		name string
+		s    Serializer
+		want string
+	}{
+		{name: "unsorted", s: Serializer{}, want: `{"b10":1,"B2":2,"a":{"z":3,"Y":4},"b2":5}`},
+		{name: "sorted", s: Serializer{SortKeys: true}, want: `{"B2":2,"a":{"Y":4,"z":3},"b10":1,"b2":5}`},
+		{name: "natural", s: Serializer{KeyLess: NaturalKeyLess}, want: `{"B2":2,"a":{"Y":4,"z":3},"b2":5,"b10":1}`},
+		{name: "fold", s: Serializer{KeyLess: FoldKeyLess}, want: `{"a":{"Y":4,"z":3},"b10":1,"B2":2,"b2":5}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(tt.s.Serialize(v)); got != tt.want {
+				t.Errorf("unexpected output\n%s\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,301 @@
+package genjson
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// Reserialize converts src into dst as if src was marshaled to json and then unmarshaled into dst.
+// No intermediate json bytes are produced. This is useful for converting between types with
+// compatible json representations and for deep copies that respect json tags.
+func Reserialize(src any, dst any) error {
+	v, err := MarshalValue(src)
+	if err != nil {
+		return err
+	}
+	return defaultUnmarshaler.UnmarshalValue(v, dst)
+}
+
+// Marshaler converts go values into json. Structs are converted into objects using the json tags
+// of their fields, maps with string keys into objects with sorted keys and slices and arrays into
+// arrays. Values that implement Value are used as is.
+type Marshaler struct {
+	// Serializer controls the formatting of the output.
+	Serializer Serializer
+	// OmitEmpty omits every empty struct field, as if it was tagged with omitempty.
+	OmitEmpty bool
+	// Base64Bytes marshals byte slices as base64 encoded strings, as encoding/json does, instead
+	// of arrays of numbers.
+	Base64Bytes bool
+}
+
+var defaultMarshaler Marshaler
+
+// Marshal returns the json encoding of v using the default Marshaler.
+func Marshal(v any) ([]byte, error) {
+	return defaultMarshaler.Marshal(v)
+}
+
+// MarshalValue converts v into a Value using the default Marshaler.
+func MarshalValue(v any) (Value, error) {
+	return defaultMarshaler.MarshalValue(v)
+}
+
+// MarshalValue converts v into a Value without serializing it, e.g. to sort, patch or redact the
+// value before it is serialized. It is the inverse of Unmarshaler.UnmarshalValue. The Serializer
+// of the Marshaler is not used.
+func (m *Marshaler) MarshalValue(v any) (Value, error) {
+	return m.marshalValue(reflect.ValueOf(v), &cycleDetector{})
+}
+
+// Marshal returns the json encoding of v. An error is returned if v contains a value that cannot
+// be represented as json, such as a channel or NaN, or if the serializer rejects the value.
+func (m *Marshaler) Marshal(v any) ([]byte, error) {
+	value, err := m.MarshalValue(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Serializer.Check(value); err != nil {
+		return nil, err
+	}
+	return m.Serializer.Serialize(value), nil
+}
+
+var valueType = reflect.TypeOf((*Value)(nil)).Elem()
+
+// marshalValue converts a go value into a Value. c detects circular references through pointers,
+// maps and slices.
+func (m *Marshaler) marshalValue(rv reflect.Value, c *cycleDetector) (Value, error) {
+	if !rv.IsValid() {
+		return Null{}, nil
+	}
+	if rv.Type().Implements(valueType) {
+		if rv.Kind() == reflect.Interface && rv.IsNil() {
+			return Null{}, nil
+		}
+		return rv.Interface().(Value), nil
+	}
+	switch rv.Kind() {
+	case reflect.Bool:
+		return Bool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := rv.Int()
+		if i < 0 {
+			return Number{Integer: uint64(-i), IsNeg: true}, nil
+		}
+		return Number{Integer: uint64(i)}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return Number{Integer: rv.Uint()}, nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, UnsupportedValueError{Value: rv}
+		}
+		return floatNumber(f), nil
+	case reflect.String:
+		return String(rv.String()), nil
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return Null{}, nil
+		}
+		if rv.Kind() == reflect.Interface {
+			return m.marshalValue(rv.Elem(), c)
+		}
+		if err := c.enter(rv); err != nil {
+			return nil, err
+		}
+		defer c.leave(rv)
+		return m.marshalValue(rv.Elem(), c)
+	case reflect.Slice:
+		if rv.IsNil() {
+			return Null{}, nil
+		}
+		if m.isBase64(rv) {
+			return String(base64.StdEncoding.EncodeToString(rv.Bytes())), nil
+		}
+		if err := c.enter(rv); err != nil {
+			return nil, err
+		}
+		defer c.leave(rv)
+		return m.marshalArray(rv, c)
+	case reflect.Array:
+		return m.marshalArray(rv, c)
+	case reflect.Map:
+		if rv.IsNil() {
+			return Null{}, nil
+		}
+		if err := c.enter(rv); err != nil {
+			return nil, err
+		}
+		defer c.leave(rv)
+		return m.marshalMap(rv, c)
+	case reflect.Struct:
+		return m.marshalStruct(rv, c)
+	default:
+		return nil, UnsupportedTypeError{Type: rv.Type()}
+	}
+}
+
+// isBase64 reports whether the slice is marshaled as a base64 encoded string.
+func (m *Marshaler) isBase64(rv reflect.Value) bool {
+	return m.Base64Bytes && rv.Type().Elem().Kind() == reflect.Uint8
+}
+
+func (m *Marshaler) marshalArray(rv reflect.Value, c *cycleDetector) (Value, error) {
+	a := make(Array, rv.Len())
+	for i := range a {
+		v, err := m.marshalValue(rv.Index(i), c)
+		if err != nil {
+			return nil, err
+		}
+		a[i] = v
+	}
+	return a, nil
+}
+
+// marshalMap converts a map with string keys into an Object. Keys are added in sorted order so that
+// the output is deterministic.
+func (m *Marshaler) marshalMap(rv reflect.Value, c *cycleDetector) (Value, error) {
+	if rv.Type().Key().Kind() != reflect.String {
+		return nil, UnsupportedTypeError{Type: rv.Type()}
+	}
+	keys := rv.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	var o Object
+	o.init()
+	for _, k := range keys {
+		v, err := m.marshalValue(rv.MapIndex(k), c)
+		if err != nil {
+			return nil, err
+		}
+		o.Add(k.String(), v)
+	}
+	return o, nil
+}
+
+func (m *Marshaler) marshalStruct(rv reflect.Value, c *cycleDetector) (Value, error) {
+	var o Object
+	o.init()
+	if err := m.marshalFields(rv, &o, c); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// marshalFields adds the exported fields of the struct to the object. The fields of embedded
+// structs without a json name are added as if they were fields of the outer struct, see
+// typeFields.
+func (m *Marshaler) marshalFields(rv reflect.Value, o *Object, c *cycleDetector) error {
+	for _, f := range cachedStructFields(rv.Type()) {
+		fv, ok := fieldValue(rv, f.index)
+		if !ok {
+			continue
+		}
+		v, err := m.marshalValue(fv, c)
+		if err != nil {
+			return err
+		}
+		if (f.tag.omitEmpty || m.OmitEmpty) && IsEmpty(v) {
+			continue
+		}
+		o.Add(f.name, v)
+	}
+	return nil
+}
+
+// cycleDepth is the nesting of pointers, maps and slices after which marshaling starts tracking
+// them to detect circular references. Values that are not deeply nested are never tracked.
+const cycleDepth = 1000
+
+// cycleDetector detects circular references between go values while they are marshaled.
+type cycleDetector struct {
+	depth int
+	// seen contains the pointers, maps and slices being marshaled once depth exceeds cycleDepth.
+	seen map[cycleKey]struct{}
+}
+
+// cycleKey identifies a pointer, map or slice. Slices sharing an array are only the same if they
+// have the same length, and pointers to a struct and to its first field are told apart by type.
+type cycleKey struct {
+	ptr uintptr
+	len int
+	typ reflect.Type
+}
+
+func newCycleKey(rv reflect.Value) cycleKey {
+	k := cycleKey{ptr: rv.Pointer(), typ: rv.Type()}
+	if rv.Kind() == reflect.Slice {
+		k.len = rv.Len()
+	}
+	return k
+}
+
+// enter is called before marshaling the value a pointer, map or slice refers to and returns a
+// CycleError if it is already being marshaled.
+func (c *cycleDetector) enter(rv reflect.Value) error {
+	c.depth++
+	if c.depth <= cycleDepth {
+		return nil
+	}
+	k := newCycleKey(rv)
+	if _, ok := c.seen[k]; ok {
+		return CycleError{Type: rv.Type()}
+	}
+	if c.seen == nil {
+		c.seen = make(map[cycleKey]struct{})
+	}
+	c.seen[k] = struct{}{}
+	return nil
+}
+
+// leave is called once the value rv refers to has been marshaled.
+func (c *cycleDetector) leave(rv reflect.Value) {
+	if c.depth > cycleDepth {
+		delete(c.seen, newCycleKey(rv))
+	}
+	c.depth--
+}
+
+// ---------------- errors ----------------
+
+type UnsupportedTypeError struct {
+	Type reflect.Type
+}
+
+func (e UnsupportedTypeError) Error() string {
+	return fmt.Sprintf("go type %s cannot be represented as json", e.Type)
+}
+
+type UnsupportedValueError struct {
+	Value reflect.Value
+}
+
+func (e UnsupportedValueError) Error() string {
+	return fmt.Sprintf("go value %v cannot be represented as json", e.Value)
+}
+
+// CycleError is returned when marshaling a value that refers to itself, such as a struct with a
+// pointer to itself or a map that contains itself.
+type CycleError struct {
+	// Type is the type of the pointer, map or slice through which the value refers to itself.
+	Type reflect.Type
+}
+
+func (e CycleError) Error() string {
+	return fmt.Sprintf("go value of type %s contains a circular reference", e.Type)
+}
+
+// ---------------- errors end ----------------
+
+// floatNumber converts f into a floating point Number.
+func floatNumber(f float64) Number {
+	if f < 0 {
+		return Number{Float: -f, IsFloat: true, IsNeg: true}
+	}
+	return Number{Float: f, IsFloat: true}
+}
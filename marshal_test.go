@@ -0,0 +1,212 @@
+package genjson
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+)
+
+type marshalEmbedded struct {
+	E int
+}
+
+type marshalStructTest struct {
+	marshalEmbedded
+	A       int    `json:"a"`
+	B       string `json:"b,omitempty"`
+	C       []bool
+	Skip    int `json:"-"`
+	private int
+}
+
+type marshalSelfEmbedded struct {
+	*marshalSelfEmbedded
+	N int
+}
+
+type marshalPromoted struct {
+	X int
+	*marshalEmbedded
+	E int `json:"e"`
+	marshalSelfEmbedded
+}
+
+func TestMarshalValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      any
+		want    string
+		wantErr bool
+	}{
+		{name: "nil", in: nil, want: `null`},
+		{name: "bool", in: true, want: `true`},
+		{name: "int", in: -3, want: `-3`},
+		{name: "uint", in: uint8(3), want: `3`},
+		{name: "float", in: 1.5, want: `1.5`},
+		{name: "string", in: "a", want: `"a"`},
+		{name: "nil-slice", in: []int(nil), want: `null`},
+		{name: "slice", in: []int{1, 2}, want: `[1,2]`},
+		{name: "array", in: [2]string{"a", "b"}, want: `["a","b"]`},
+		{name: "map", in: map[string]int{"b": 2, "a": 1}, want: `{"a":1,"b":2}`},
+		{name: "pointer", in: &[]int{1}, want: `[1]`},
+		{name: "value", in: Array{String("a")}, want: `["a"]`},
+		{
+			name: "struct",
+			in:   marshalStructTest{marshalEmbedded: marshalEmbedded{E: 1}, A: 2, C: []bool{true}, Skip: 3, private: 4},
+			want: `{"E":1,"a":2,"C":[true]}`,
+		},
+		{
+			// Promoted fields are written in place of their embedded struct, and structs that embed
+			// themselves are only promoted once.
+			name: "promoted",
+			in: marshalPromoted{X: 1, marshalEmbedded: &marshalEmbedded{E: 2}, E: 3,
+				marshalSelfEmbedded: marshalSelfEmbedded{N: 4, marshalSelfEmbedded: &marshalSelfEmbedded{N: 5}}},
+			want: `{"X":1,"E":2,"e":3,"N":4}`,
+		},
+		{name: "nil-embedded", in: marshalPromoted{X: 1}, want: `{"X":1,"e":0,"N":0}`},
+		{name: "int-map", in: map[int]int{1: 1}, wantErr: true},
+		{name: "chan", in: make(chan int), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := MarshalValue(tt.in)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if err != nil {
+				return
+			}
+			if got := string(Serialize(v)); got != tt.want {
+				t.Errorf("unexpected output %s != %s", got, tt.want)
+			}
+			// Marshaler.Write visits the go value directly and must agree with MarshalValue.
+			var buf bytes.Buffer
+			if err := (&Marshaler{}).Write(&buf, tt.in); err != nil || buf.String() != tt.want {
+				t.Errorf("unexpected Write output %s != %s %v", buf.String(), tt.want, err)
+			}
+		})
+	}
+}
+
+func TestMarshalerMarshalValue(t *testing.T) {
+	m := Marshaler{OmitEmpty: true}
+	v, err := m.MarshalValue(marshalStructTest{A: 1, C: []bool{}})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if got := string(Serialize(v)); got != `{"a":1}` {
+		t.Errorf("unexpected output %s", got)
+	}
+	v, err = SetPath(v, []string{"a"}, String("redacted"))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if got := string(Serialize(v)); got != `{"a":"redacted"}` {
+		t.Errorf("unexpected output %s", got)
+	}
+}
+
+type marshalCycle struct {
+	Next *marshalCycle
+}
+
+func TestMarshalCycles(t *testing.T) {
+	ptr := &marshalCycle{}
+	ptr.Next = ptr
+	m := map[string]any{}
+	m["m"] = m
+	s := []any{nil}
+	s[0] = s
+	for name, in := range map[string]any{"pointer": ptr, "map": m, "slice": s} {
+		t.Run(name, func(t *testing.T) {
+			var ce CycleError
+			if _, err := Marshal(in); !errors.As(err, &ce) {
+				t.Errorf("unexpected marshal error %v", err)
+			}
+			if err := new(Marshaler).Write(io.Discard, in); !errors.As(err, &ce) {
+				t.Errorf("unexpected Write error %v", err)
+			}
+		})
+	}
+
+	// Deep values without cycles, and values referred to more than once, are not cycles.
+	var list *marshalCycle
+	for i := 0; i < 2*cycleDepth; i++ {
+		list = &marshalCycle{Next: list}
+	}
+	shared := &marshalCycle{}
+	for _, in := range []any{list, []*marshalCycle{shared, shared}} {
+		if _, err := Marshal(in); err != nil {
+			t.Errorf("unexpected marshal error %v", err)
+		}
+		if err := new(Marshaler).Write(io.Discard, in); err != nil {
+			t.Errorf("unexpected Write error %v", err)
+		}
+	}
+}
+
+func TestReserialize(t *testing.T) {
+	type celsius float64
+	var dst []celsius
+	if err := Reserialize([]int8{-1, 20}, &dst); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if want := []celsius{-1, 20}; !reflect.DeepEqual(dst, want) {
+		t.Errorf("unexpected result %v != %v", dst, want)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		name      string
+		marshaler Marshaler
+		in        any
+		want      string
+		wantErr   bool
+	}{
+		{
+			name: "struct",
+			in:   marshalStructTest{A: 1, C: []bool{false}},
+			want: `{"E":0,"a":1,"C":[false]}`,
+		},
+		{
+			name:      "omit-empty",
+			marshaler: Marshaler{OmitEmpty: true},
+			in:        marshalStructTest{A: 1},
+			want:      `{"a":1}`,
+		},
+		{
+			name:      "indent",
+			marshaler: Marshaler{Serializer: Serializer{Indent: 2, KeyValueGap: 1}},
+			in:        map[string][]int{"b": {1}, "a": nil},
+			want:      "{\n  \"a\": null,\n  \"b\": [\n    1\n  ]\n}",
+		},
+		{name: "nan", in: math.NaN(), wantErr: true},
+		{name: "inf", in: []float64{math.Inf(1)}, wantErr: true},
+		{
+			name:      "integer-only",
+			marshaler: Marshaler{Serializer: Serializer{IntegerOnly: true}},
+			in:        1.5,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.marshaler.Marshal(tt.in)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("unexpected output %s != %s", got, tt.want)
+			}
+		})
+	}
+
+	got, err := Marshal([]any{1, "a", nil})
+	if err != nil || string(got) != `[1,"a",null]` {
+		t.Errorf("unexpected result %s %v", got, err)
+	}
+}
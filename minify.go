@@ -0,0 +1,121 @@
+package genjson
+
+import (
+	"errors"
+)
+
+// ErrUnterminatedComment is returned by Minify when a block comment is not closed.
+var ErrUnterminatedComment = errors.New("unterminated comment")
+
+// Minifier contains the options used when minifying json.
+type Minifier struct {
+	// AllowComments accepts // line comments and /* */ block comments outside of strings, as
+	// written by Serializer.Comments. They are removed from the output.
+	AllowComments bool
+}
+
+var defMinifier Minifier
+
+// Minify removes the whitespace outside of strings from data, along with comments if
+// AllowComments is set. It returns the minified json together with a SourceMap from the output
+// back to data. data must contain a single valid json value; errors report locations in data.
+func (mi *Minifier) Minify(data []byte) ([]byte, SourceMap, error) {
+	src := data
+	if mi.AllowComments {
+		var err error
+		if src, err = blankComments(data); err != nil {
+			return nil, SourceMap{}, err
+		}
+	}
+	o, err := deserialize(src)
+	if err != nil {
+		return nil, SourceMap{}, err
+	}
+	end := o.node.end
+	if d := skipSpace(deserializer{b: src, idx: end.Offset, row: end.Row, col: end.Col}); d.idx < len(src) {
+		return nil, SourceMap{}, InvalidTokenError{Token: src[d.idx], Row: d.row, Col: d.col}
+	}
+
+	var sm SourceMap
+	out := make([]byte, 0, end.Offset-o.node.start.Offset)
+	loc := o.node.start
+	inString, escaped := false, false
+	for ; loc.Offset < end.Offset; loc.Offset++ {
+		b := src[loc.Offset]
+		if inString || !isSpace(b) {
+			sm.add(len(out), "", loc)
+			out = append(out, b)
+		}
+		switch {
+		case escaped:
+			escaped = false
+		case inString && b == '\\':
+			escaped = true
+		case b == '"':
+			inString = !inString
+		}
+		if b == '\n' {
+			loc.Row++
+			loc.Col = 1
+		} else {
+			loc.Col++
+		}
+	}
+	return out, sm, nil
+}
+
+// Minify minifies data with the default Minifier. See Minifier.Minify.
+func Minify(data []byte) ([]byte, SourceMap, error) {
+	return defMinifier.Minify(data)
+}
+
+// blankComments returns a copy of data with every comment outside of strings replaced by spaces.
+// Newlines are kept so that the locations of the remaining bytes do not change.
+func blankComments(data []byte) ([]byte, error) {
+	comments, err := commentRanges(data)
+	if err != nil {
+		return nil, err
+	}
+	out := append([]byte(nil), data...)
+	for _, c := range comments {
+		for i := c[0]; i < c[1]; i++ {
+			if out[i] != '\n' {
+				out[i] = ' '
+			}
+		}
+	}
+	return out, nil
+}
+
+// commentRanges returns the start and exclusive end offsets of every comment outside of strings
+// in data. Line comments end before their newline.
+func commentRanges(data []byte) ([][2]int, error) {
+	var comments [][2]int
+	inString, escaped := false, false
+	for i := 0; i < len(data); i++ {
+		b := data[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inString && b == '\\':
+			escaped = true
+		case b == '"':
+			inString = !inString
+		case !inString && b == '/' && i+1 < len(data) && data[i+1] == '/':
+			start := i
+			for ; i < len(data) && data[i] != '\n'; i++ {
+			}
+			comments = append(comments, [2]int{start, i})
+		case !inString && b == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := i + 2
+			for ; end+1 < len(data) && !(data[end] == '*' && data[end+1] == '/'); end++ {
+			}
+			if end+1 >= len(data) {
+				return nil, ErrUnterminatedComment
+			}
+			comments = append(comments, [2]int{i, end + 2})
+			i = end + 1
+		}
+	}
+	return comments, nil
+}
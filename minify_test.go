@@ -0,0 +1,66 @@
+package genjson
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMinify(t *testing.T) {
+	src := "{\n  \"a b\": [1, 2],\n  \"c\": \"x\\\" y\"\n}\n"
+	out, sm, err := Minify([]byte(src))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if want := `{"a b":[1,2],"c":"x\" y"}`; string(out) != want {
+		t.Fatalf("unexpected output %s != %s", out, want)
+	}
+	tests := []struct {
+		offset int
+		want   Loc
+	}{
+		{offset: 0, want: Loc{Row: 1, Col: 1, Offset: 0}},
+		{offset: 1, want: Loc{Row: 2, Col: 3, Offset: 4}},
+		{offset: 4, want: Loc{Row: 2, Col: 6, Offset: 7}},
+		{offset: 8, want: Loc{Row: 2, Col: 11, Offset: 12}},
+		{offset: 10, want: Loc{Row: 2, Col: 14, Offset: 15}},
+		{offset: 13, want: Loc{Row: 3, Col: 3, Offset: 21}},
+		{offset: 20, want: Loc{Row: 3, Col: 11, Offset: 29}},
+		{offset: 24, want: Loc{Row: 4, Col: 1, Offset: 34}},
+	}
+	for _, tt := range tests {
+		if got := sm.Loc(tt.offset); got != tt.want {
+			t.Errorf("unexpected location for offset %d: %+v != %+v", tt.offset, got, tt.want)
+		}
+	}
+}
+
+func TestMinifyComments(t *testing.T) {
+	src := "// header\n[1, /* two */ 2, \"/* not a comment */\" // end\n]"
+	mi := Minifier{AllowComments: true}
+	out, sm, err := mi.Minify([]byte(src))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if want := `[1,2,"/* not a comment */"]`; string(out) != want {
+		t.Fatalf("unexpected output %s != %s", out, want)
+	}
+	if got, want := sm.Loc(3), (Loc{Row: 2, Col: 15, Offset: 24}); got != want {
+		t.Errorf("unexpected location %+v != %+v", got, want)
+	}
+
+	if _, _, err := Minify([]byte(src)); err == nil {
+		t.Errorf("expected an error for comments without AllowComments")
+	}
+	if _, _, err := mi.Minify([]byte("[1 /* 2 ]")); !errors.Is(err, ErrUnterminatedComment) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestMinifyTrailing(t *testing.T) {
+	_, _, err := Minify([]byte("[1]\n  2"))
+	want := InvalidTokenError{Token: '2', Row: 2, Col: 3}
+	if !reflect.DeepEqual(err, want) {
+		t.Errorf("unexpected error %v != %v", err, want)
+	}
+}
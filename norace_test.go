@@ -0,0 +1,5 @@
+//go:build !race
+
+package genjson
+
+const raceEnabled = false
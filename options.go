@@ -0,0 +1,305 @@
+package genjson
+
+import "fmt"
+
+// Option configures a Deserializer, Serializer, Marshaler or Unmarshaler created by
+// NewDeserializer, NewSerializer, NewMarshaler or NewUnmarshaler. Each option sets the exported
+// field of the same name, so that options and struct literals can be used interchangeably. An
+// option applies to every type that has the field; the formatting options of a Marshaler apply to
+// its Serializer.
+//
+// There is an option for every exported field. Progress and ProgressInterval, and
+// CheckPrecisionLoss and MaxPrecisionLoss, are set together by WithProgress and
+// WithMaxPrecisionLoss.
+type Option struct {
+	name         string
+	deserializer func(ds *Deserializer)
+	serializer   func(s *Serializer)
+	marshaler    func(m *Marshaler)
+	unmarshaler  func(u *Unmarshaler)
+}
+
+// NewDeserializer returns a Deserializer configured by the options. An OptionError is returned for
+// options that do not apply to deserializing.
+func NewDeserializer(opts ...Option) (*Deserializer, error) {
+	ds := &Deserializer{}
+	for _, opt := range opts {
+		if opt.deserializer == nil {
+			return nil, OptionError{Option: opt.name, Target: "Deserializer"}
+		}
+		opt.deserializer(ds)
+	}
+	return ds, nil
+}
+
+// NewSerializer returns a Serializer configured by the options. An OptionError is returned for
+// options that do not apply to serializing and an InvalidOptionError if the resulting Serializer is
+// invalid.
+func NewSerializer(opts ...Option) (*Serializer, error) {
+	s := &Serializer{}
+	for _, opt := range opts {
+		if opt.serializer == nil {
+			return nil, OptionError{Option: opt.name, Target: "Serializer"}
+		}
+		opt.serializer(s)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewMarshaler returns a Marshaler configured by the options. Serializer options configure the
+// formatting of its output. An OptionError is returned for options that do not apply to
+// marshaling and an InvalidOptionError if the resulting Serializer is invalid.
+func NewMarshaler(opts ...Option) (*Marshaler, error) {
+	m := &Marshaler{}
+	for _, opt := range opts {
+		switch {
+		case opt.marshaler != nil:
+			opt.marshaler(m)
+		case opt.serializer != nil:
+			opt.serializer(&m.Serializer)
+		default:
+			return nil, OptionError{Option: opt.name, Target: "Marshaler"}
+		}
+	}
+	if err := m.Serializer.Validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// NewUnmarshaler returns an Unmarshaler configured by the options. An OptionError is returned for
+// options that do not apply to unmarshaling.
+func NewUnmarshaler(opts ...Option) (*Unmarshaler, error) {
+	u := &Unmarshaler{}
+	for _, opt := range opts {
+		if opt.unmarshaler == nil {
+			return nil, OptionError{Option: opt.name, Target: "Unmarshaler"}
+		}
+		opt.unmarshaler(u)
+	}
+	return u, nil
+}
+
+// ---------------- deserializer options ----------------
+
+// WithParseNumber sets Deserializer.ParseNumber.
+func WithParseNumber(parse func(raw []byte) (Value, error)) Option {
+	return Option{name: "WithParseNumber", deserializer: func(ds *Deserializer) { ds.ParseNumber = parse }}
+}
+
+// WithTransformString sets Deserializer.TransformString.
+func WithTransformString(transform func(s string, isKey bool) (string, error)) Option {
+	return Option{name: "WithTransformString", deserializer: func(ds *Deserializer) { ds.TransformString = transform }}
+}
+
+// WithNormalizeNewlines sets Deserializer.NormalizeNewlines.
+func WithNormalizeNewlines() Option {
+	return Option{name: "WithNormalizeNewlines", deserializer: func(ds *Deserializer) { ds.NormalizeNewlines = true }}
+}
+
+// WithStripBOM sets Deserializer.StripBOM.
+func WithStripBOM() Option {
+	return Option{name: "WithStripBOM", deserializer: func(ds *Deserializer) { ds.StripBOM = true }}
+}
+
+// WithRequireContainer sets the RequireContainer field of a Deserializer or Serializer.
+func WithRequireContainer() Option {
+	return Option{
+		name:         "WithRequireContainer",
+		deserializer: func(ds *Deserializer) { ds.RequireContainer = true },
+		serializer:   func(s *Serializer) { s.RequireContainer = true },
+	}
+}
+
+// WithAllocator sets the Allocator field of a Deserializer or Serializer.
+func WithAllocator(a Allocator) Option {
+	return Option{
+		name:         "WithAllocator",
+		deserializer: func(ds *Deserializer) { ds.Allocator = a },
+		serializer:   func(s *Serializer) { s.Allocator = a },
+	}
+}
+
+// ---------------- serializer options ----------------
+
+// WithIndent sets Serializer.Indent.
+func WithIndent(n int) Option {
+	return Option{name: "WithIndent", serializer: func(s *Serializer) { s.Indent = n }}
+}
+
+// WithPrefix sets Serializer.Prefix.
+func WithPrefix(n int) Option {
+	return Option{name: "WithPrefix", serializer: func(s *Serializer) { s.Prefix = n }}
+}
+
+// WithIndentString sets Serializer.IndentString.
+func WithIndentString(indent string) Option {
+	return Option{name: "WithIndentString", serializer: func(s *Serializer) { s.IndentString = indent }}
+}
+
+// WithPrefixString sets Serializer.PrefixString.
+func WithPrefixString(prefix string) Option {
+	return Option{name: "WithPrefixString", serializer: func(s *Serializer) { s.PrefixString = prefix }}
+}
+
+// WithKeyValueGap sets Serializer.KeyValueGap.
+func WithKeyValueGap(n int) Option {
+	return Option{name: "WithKeyValueGap", serializer: func(s *Serializer) { s.KeyValueGap = n }}
+}
+
+// WithSortKeys sets Serializer.SortKeys.
+func WithSortKeys() Option {
+	return Option{name: "WithSortKeys", serializer: func(s *Serializer) { s.SortKeys = true }}
+}
+
+// WithKeyLess sets Serializer.KeyLess.
+func WithKeyLess(less func(a, b string) bool) Option {
+	return Option{name: "WithKeyLess", serializer: func(s *Serializer) { s.KeyLess = less }}
+}
+
+// WithEscapeHTML sets Serializer.EscapeHTML.
+func WithEscapeHTML() Option {
+	return Option{name: "WithEscapeHTML", serializer: func(s *Serializer) { s.EscapeHTML = true }}
+}
+
+// WithEscapeNonASCII sets Serializer.EscapeNonASCII.
+func WithEscapeNonASCII() Option {
+	return Option{name: "WithEscapeNonASCII", serializer: func(s *Serializer) { s.EscapeNonASCII = true }}
+}
+
+// WithCanonicalStrings sets Serializer.CanonicalStrings.
+func WithCanonicalStrings() Option {
+	return Option{name: "WithCanonicalStrings", serializer: func(s *Serializer) { s.CanonicalStrings = true }}
+}
+
+// WithEscapeBackticks sets Serializer.EscapeBackticks.
+func WithEscapeBackticks() Option {
+	return Option{name: "WithEscapeBackticks", serializer: func(s *Serializer) { s.EscapeBackticks = true }}
+}
+
+// WithUnsafeIntegersAsStrings sets Serializer.UnsafeIntegersAsStrings.
+func WithUnsafeIntegersAsStrings() Option {
+	return Option{name: "WithUnsafeIntegersAsStrings", serializer: func(s *Serializer) { s.UnsafeIntegersAsStrings = true }}
+}
+
+// WithOmitNullKeys sets Serializer.OmitNullKeys.
+func WithOmitNullKeys() Option {
+	return Option{name: "WithOmitNullKeys", serializer: func(s *Serializer) { s.OmitNullKeys = true }}
+}
+
+// WithEmptyAsNull sets Serializer.EmptyAsNull.
+func WithEmptyAsNull() Option {
+	return Option{name: "WithEmptyAsNull", serializer: func(s *Serializer) { s.EmptyAsNull = true }}
+}
+
+// WithIntegerOnly sets Serializer.IntegerOnly.
+func WithIntegerOnly() Option {
+	return Option{name: "WithIntegerOnly", serializer: func(s *Serializer) { s.IntegerOnly = true }}
+}
+
+// WithColors sets Serializer.Colors.
+func WithColors(c *Colors) Option {
+	return Option{name: "WithColors", serializer: func(s *Serializer) { s.Colors = c }}
+}
+
+// WithComments sets Serializer.Comments.
+func WithComments(comments func(path Path, v Value) []Comment) Option {
+	return Option{name: "WithComments", serializer: func(s *Serializer) { s.Comments = comments }}
+}
+
+// WithCommentStyle sets Serializer.CommentStyle.
+func WithCommentStyle(style CommentStyle) Option {
+	return Option{name: "WithCommentStyle", serializer: func(s *Serializer) { s.CommentStyle = style }}
+}
+
+// WithProgress sets Serializer.Progress and Serializer.ProgressInterval.
+func WithProgress(interval int, progress func(bytes, values int)) Option {
+	return Option{name: "WithProgress", serializer: func(s *Serializer) {
+		s.Progress = progress
+		s.ProgressInterval = interval
+	}}
+}
+
+// ---------------- marshaler options ----------------
+
+// WithOmitEmpty sets Marshaler.OmitEmpty.
+func WithOmitEmpty() Option {
+	return Option{name: "WithOmitEmpty", marshaler: func(m *Marshaler) { m.OmitEmpty = true }}
+}
+
+// WithBase64Bytes sets the Base64Bytes field of a Marshaler or Unmarshaler.
+func WithBase64Bytes() Option {
+	return Option{
+		name:        "WithBase64Bytes",
+		marshaler:   func(m *Marshaler) { m.Base64Bytes = true },
+		unmarshaler: func(u *Unmarshaler) { u.Base64Bytes = true },
+	}
+}
+
+// ---------------- unmarshaler options ----------------
+
+// WithSliceWorkers sets Unmarshaler.SliceWorkers.
+func WithSliceWorkers(n int) Option {
+	return Option{name: "WithSliceWorkers", unmarshaler: func(u *Unmarshaler) { u.SliceWorkers = n }}
+}
+
+// WithParallelSliceMinLen sets Unmarshaler.ParallelSliceMinLen.
+func WithParallelSliceMinLen(n int) Option {
+	return Option{name: "WithParallelSliceMinLen", unmarshaler: func(u *Unmarshaler) { u.ParallelSliceMinLen = n }}
+}
+
+// WithMaxPrecisionLoss sets Unmarshaler.CheckPrecisionLoss and Unmarshaler.MaxPrecisionLoss.
+func WithMaxPrecisionLoss(max float64) Option {
+	return Option{name: "WithMaxPrecisionLoss", unmarshaler: func(u *Unmarshaler) {
+		u.CheckPrecisionLoss = true
+		u.MaxPrecisionLoss = max
+	}}
+}
+
+// WithNumberMode sets Unmarshaler.NumberMode.
+func WithNumberMode(mode NumberMode) Option {
+	return Option{name: "WithNumberMode", unmarshaler: func(u *Unmarshaler) { u.NumberMode = mode }}
+}
+
+// WithDisallowDuplicateKeys sets Unmarshaler.DisallowDuplicateKeys.
+func WithDisallowDuplicateKeys() Option {
+	return Option{name: "WithDisallowDuplicateKeys", unmarshaler: func(u *Unmarshaler) { u.DisallowDuplicateKeys = true }}
+}
+
+// WithDisallowUnknownFields sets Unmarshaler.DisallowUnknownFields.
+func WithDisallowUnknownFields() Option {
+	return Option{name: "WithDisallowUnknownFields", unmarshaler: func(u *Unmarshaler) { u.DisallowUnknownFields = true }}
+}
+
+// WithMergeMapValues sets Unmarshaler.MergeMapValues.
+func WithMergeMapValues() Option {
+	return Option{name: "WithMergeMapValues", unmarshaler: func(u *Unmarshaler) { u.MergeMapValues = true }}
+}
+
+// WithHook registers a decode hook with an Unmarshaler, as Unmarshaler.RegisterHook does.
+func WithHook(name string, hook DecodeHook) Option {
+	return Option{name: "WithHook", unmarshaler: func(u *Unmarshaler) { u.RegisterHook(name, hook) }}
+}
+
+// WithMiddleware adds middleware to an Unmarshaler, as Unmarshaler.Use does.
+func WithMiddleware(mw ...Middleware) Option {
+	return Option{name: "WithMiddleware", unmarshaler: func(u *Unmarshaler) { u.Use(mw...) }}
+}
+
+// ---------------- errors ----------------
+
+// OptionError is returned by the New functions for an option that does not apply to the type
+// being created.
+type OptionError struct {
+	// Option is the name of the function that created the option.
+	Option string
+	// Target is the name of the type being created.
+	Target string
+}
+
+func (e OptionError) Error() string {
+	return fmt.Sprintf("option %s does not apply to %s", e.Option, e.Target)
+}
@@ -0,0 +1,93 @@
+package genjson
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNewWithOptions(t *testing.T) {
+	ds, err := NewDeserializer(WithStripBOM(), WithRequireContainer())
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !ds.StripBOM || !ds.RequireContainer || ds.NormalizeNewlines {
+		t.Errorf("unexpected deserializer %+v", ds)
+	}
+
+	s, err := NewSerializer(WithIndent(2), WithKeyValueGap(1), WithSortKeys())
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	want := Serializer{Indent: 2, KeyValueGap: 1, SortKeys: true}
+	if got, want := string(s.Serialize(MustDeserializeString(`{"b":1,"a":[]}`))), string(want.Serialize(MustDeserializeString(`{"b":1,"a":[]}`))); got != want {
+		t.Errorf("unexpected output\n%s\n%s", got, want)
+	}
+
+	m, err := NewMarshaler(WithOmitEmpty(), WithBase64Bytes(), WithIndentString("\t"))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !m.OmitEmpty || !m.Base64Bytes || m.Serializer.IndentString != "\t" {
+		t.Errorf("unexpected marshaler %+v", m)
+	}
+
+	u, err := NewUnmarshaler(WithBase64Bytes(), WithDisallowUnknownFields(), WithNumberMode(NumberModeInt64WhenIntegral))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !u.Base64Bytes || !u.DisallowUnknownFields || u.NumberMode != NumberModeInt64WhenIntegral {
+		t.Errorf("unexpected unmarshaler %+v", u)
+	}
+}
+
+func TestSerializerOptions(t *testing.T) {
+	colors := &Colors{}
+	s, err := NewSerializer(
+		WithPrefix(1), WithEscapeBackticks(), WithUnsafeIntegersAsStrings(), WithEmptyAsNull(),
+		WithIntegerOnly(), WithColors(colors), WithCommentStyle(CommentBlock), WithOmitNullKeys(),
+		WithComments(func(Path, Value) []Comment { return nil }), WithProgress(10, func(int, int) {}),
+		WithPrefixString("  "), WithKeyLess(NaturalKeyLess), WithEscapeHTML(), WithEscapeNonASCII(),
+		WithAllocator(HeapAllocator{}), WithCanonicalStrings(),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if s.Prefix != 1 || !s.EscapeBackticks || !s.UnsafeIntegersAsStrings || !s.EmptyAsNull || !s.IntegerOnly ||
+		s.Colors != colors || s.CommentStyle != CommentBlock || !s.OmitNullKeys || s.Comments == nil ||
+		s.Progress == nil || s.ProgressInterval != 10 || s.PrefixString != "  " || s.KeyLess == nil ||
+		!s.EscapeHTML || !s.EscapeNonASCII || s.Allocator != (HeapAllocator{}) || !s.CanonicalStrings {
+		t.Errorf("unexpected serializer %+v", s)
+	}
+}
+
+func TestUnmarshalerOptions(t *testing.T) {
+	u, err := NewUnmarshaler(
+		WithSliceWorkers(4), WithParallelSliceMinLen(100), WithMaxPrecisionLoss(0.5),
+		WithDisallowDuplicateKeys(), WithMergeMapValues(),
+		WithHook("double", func(_ UnmarshalState, v Value) (Value, error) { return v, nil }),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if u.SliceWorkers != 4 || u.ParallelSliceMinLen != 100 || !u.CheckPrecisionLoss || u.MaxPrecisionLoss != 0.5 ||
+		!u.DisallowDuplicateKeys || !u.MergeMapValues || u.hooks["double"] == nil {
+		t.Errorf("unexpected unmarshaler %+v", u)
+	}
+}
+
+func TestNewWithOptionsErrors(t *testing.T) {
+	if _, err := NewDeserializer(WithIndent(2)); !reflect.DeepEqual(err, OptionError{Option: "WithIndent", Target: "Deserializer"}) {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := NewUnmarshaler(WithStripBOM()); !reflect.DeepEqual(err, OptionError{Option: "WithStripBOM", Target: "Unmarshaler"}) {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := NewMarshaler(WithMergeMapValues()); !errors.As(err, &OptionError{}) {
+		t.Errorf("unexpected error %v", err)
+	}
+	var ioe InvalidOptionError
+	if _, err := NewSerializer(WithIndent(-1)); !errors.As(err, &ioe) || ioe.Option != "Indent" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package genjson
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ElementError is an error returned for a single element of an array.
+type ElementError struct {
+	Index int
+	Err   error
+}
+
+func (e ElementError) Error() string {
+	return fmt.Sprintf("element %d: %v", e.Index, e.Err)
+}
+
+func (e ElementError) Unwrap() error {
+	return e.Err
+}
+
+// ElementErrors is a list of errors for elements of an array, ordered by index.
+type ElementErrors []ElementError
+
+func (e ElementErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, ee := range e {
+		msgs[i] = ee.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ForEachParallel calls fn for every element of the array using the number of worker goroutines.
+// See ForEachParallelContext.
+func ForEachParallel(a Array, workers int, fn func(i int, v Value) error) error {
+	return ForEachParallelContext(context.Background(), a, workers, func(_ context.Context, i int, v Value) error {
+		return fn(i, v)
+	})
+}
+
+// ForEachParallelContext calls fn for every element of the array using the number of worker
+// goroutines. Once fn returns an error, the context passed to fn is cancelled and no further
+// elements are processed. All errors returned by fn are returned as ElementErrors. If ctx is
+// cancelled before all elements are processed and fn did not fail, ctx.Err() is returned.
+func ForEachParallelContext(ctx context.Context, a Array, workers int, fn func(ctx context.Context, i int, v Value) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(a) {
+		workers = len(a)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		next = int64(-1)
+		done int64
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs ElementErrors
+	)
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= len(a) {
+					return
+				}
+				if err := fn(ctx, i, a[i]); err != nil {
+					mu.Lock()
+					errs = append(errs, ElementError{Index: i, Err: err})
+					mu.Unlock()
+					cancel()
+				}
+				atomic.AddInt64(&done, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		sort.Slice(errs, func(i, j int) bool { return errs[i].Index < errs[j].Index })
+		return errs
+	}
+	if int(done) < len(a) {
+		return ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package genjson
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func TestForEachParallel(t *testing.T) {
+	a := make(Array, 100)
+	for i := range a {
+		a[i] = integer(uint64(i))
+	}
+	var sum int64
+	err := ForEachParallel(a, 8, func(i int, v Value) error {
+		atomic.AddInt64(&sum, int64(v.(Number).Integer))
+		return nil
+	})
+	if err != nil || sum != 4950 {
+		t.Errorf("unexpected result %d %v", sum, err)
+	}
+
+	errBad := errors.New("bad")
+	err = ForEachParallel(a, 1, func(i int, v Value) error {
+		if i == 10 {
+			return errBad
+		}
+		return nil
+	})
+	var errs ElementErrors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Index != 10 || errs[0].Err != errBad {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestForEachParallelContextCancel(t *testing.T) {
+	a := make(Array, 100)
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls int64
+	err := ForEachParallelContext(ctx, a, 1, func(ctx context.Context, i int, v Value) error {
+		if atomic.AddInt64(&calls, 1) == 5 {
+			cancel()
+		}
+		return nil
+	})
+	if err != context.Canceled || calls != 5 {
+		t.Errorf("unexpected result %d %v", calls, err)
+	}
+}
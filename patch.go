@@ -0,0 +1,271 @@
+package genjson
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ErrPatchTestFailed is the cause of a PatchError for a test operation whose value does not match.
+var ErrPatchTestFailed = errors.New("test failed")
+
+// Patch applies a json patch (RFC 6902) to doc and returns the patched value. patch must be an
+// array of operations, each an object with an "op" member of add, remove, replace, move, copy or
+// test and the "path", "from" and "value" members the operation requires. Paths are json pointers
+// and array indexes must be written without leading zeros; - refers to the end of an array.
+//
+// The operations are applied in order and the patch fails as a whole if any of them fails, in which
+// case the error is a PatchError. doc is not modified: like SetPath, only the objects and arrays
+// along the patched paths are copied and all other values are shared with doc. Values are compared
+// by test as with Array.Contains, so that the order of object keys and the formatting of numbers
+// do not matter.
+func Patch(doc Value, patch Value) (Value, error) {
+	ops, err := RequireArray(patch)
+	if err != nil {
+		return nil, err
+	}
+	for i, op := range ops {
+		name, err := applyPatchOp(&doc, op)
+		if err != nil {
+			return nil, PatchError{Index: i, Op: name, Err: err}
+		}
+	}
+	return doc, nil
+}
+
+// applyPatchOp applies a single operation to doc, returning the name of the operation.
+func applyPatchOp(doc *Value, opv Value) (string, error) {
+	op, err := RequireObject(opv)
+	if err != nil {
+		return "", err
+	}
+	namev, ok := op.Get("op")
+	if !ok {
+		return "", MissingKeyError{Key: "op"}
+	}
+	name, err := RequireString(namev)
+	if err != nil {
+		return "", fmt.Errorf("op: %w", err)
+	}
+	path, err := patchPointer(op, "path")
+	if err != nil {
+		return string(name), err
+	}
+	value := func() (Value, error) {
+		v, ok := op.Get("value")
+		if !ok {
+			return nil, MissingKeyError{Key: "value"}
+		}
+		return v, nil
+	}
+
+	var v Value
+	switch name {
+	case "add":
+		if v, err = value(); err == nil {
+			*doc, err = patchAdd(*doc, path, v)
+		}
+	case "remove":
+		*doc, err = patchUpdate(*doc, path, false, nil)
+	case "replace":
+		if v, err = value(); err == nil {
+			*doc, err = patchUpdate(*doc, path, true, v)
+		}
+	case "move", "copy":
+		var from Pointer
+		if from, err = patchPointer(op, "from"); err != nil {
+			break
+		}
+		if v, err = patchGet(*doc, from); err != nil {
+			err = fmt.Errorf("from: %w", err)
+			break
+		}
+		moved := *doc
+		if name == "move" {
+			if len(from) < len(path) && isPointerPrefix(from, path) {
+				err = fmt.Errorf("cannot move %s into itself", from)
+				break
+			}
+			if moved, err = patchUpdate(moved, from, false, nil); err != nil {
+				break
+			}
+		}
+		*doc, err = patchAdd(moved, path, v)
+	case "test":
+		var want, got Value
+		if want, err = value(); err != nil {
+			break
+		}
+		if got, err = patchGet(*doc, path); err == nil && !Equal(got, want) {
+			err = ErrPatchTestFailed
+		}
+	default:
+		err = fmt.Errorf("unknown operation %q", string(name))
+	}
+	return string(name), err
+}
+
+// patchPointer returns the pointer member of an operation.
+func patchPointer(op Object, key string) (Pointer, error) {
+	v, ok := op.Get(key)
+	if !ok {
+		return nil, MissingKeyError{Key: key}
+	}
+	s, err := RequireString(v)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	return ParsePointer(string(s))
+}
+
+// patchGet returns the value at p.
+func patchGet(doc Value, p Pointer) (Value, error) {
+	if len(p) == 0 {
+		return doc, nil
+	}
+	parent, c, err := patchParent(doc, p)
+	if err != nil {
+		return nil, err
+	}
+	step, err := patchStep(c, p[len(p)-1])
+	if err != nil {
+		return nil, PathError{Path: parent.Append(Key(p[len(p)-1])), Err: err}
+	}
+	v, _, _ := childAt(c, step)
+	return v, nil
+}
+
+// patchAdd adds v at p. Elements are inserted into arrays and members of objects are replaced.
+func patchAdd(doc Value, p Pointer, v Value) (Value, error) {
+	if len(p) == 0 {
+		return v, nil
+	}
+	parent, _, err := patchParent(doc, p)
+	if err != nil {
+		return nil, err
+	}
+	token := p[len(p)-1]
+	return updatePath(doc, parent, nil, false, func(c Value, _ bool) (Value, bool, error) {
+		switch c := c.(type) {
+		case Object:
+			return c.cloneWith(token, v, true), true, nil
+		case Array:
+			i := len(c)
+			if token != "-" {
+				var err error
+				if i, err = patchIndex(token, len(c)+1); err != nil {
+					return nil, false, PathError{Path: parent.Append(Key(token)), Err: err}
+				}
+			}
+			out := make(Array, 0, len(c)+1)
+			out = append(out, c[:i]...)
+			out = append(out, v)
+			return append(out, c[i:]...), true, nil
+		}
+		return nil, false, PathError{Path: parent.Append(Key(token)), Err: ErrNotContainer}
+	})
+}
+
+// patchUpdate replaces the existing value at p with v, or removes it if keep is false.
+func patchUpdate(doc Value, p Pointer, keep bool, v Value) (Value, error) {
+	if len(p) == 0 {
+		if !keep {
+			return nil, errors.New("cannot remove the root value")
+		}
+		return v, nil
+	}
+	parent, c, err := patchParent(doc, p)
+	if err != nil {
+		return nil, err
+	}
+	step, err := patchStep(c, p[len(p)-1])
+	if err != nil {
+		return nil, PathError{Path: parent.Append(Key(p[len(p)-1])), Err: err}
+	}
+	return updatePath(doc, append(parent, step), nil, false, func(Value, bool) (Value, bool, error) {
+		return v, keep, nil
+	})
+}
+
+// patchParent resolves the path to the parent of the value at p, which must exist, and returns it
+// together with the parent. Unlike Path.Get, tokens are interpreted strictly as in RFC 6901: keys
+// must match exactly and indexes must be canonical integers.
+func patchParent(doc Value, p Pointer) (Path, Value, error) {
+	path := make(Path, 0, len(p))
+	v := doc
+	for _, token := range p[:len(p)-1] {
+		step, err := patchStep(v, token)
+		if err != nil {
+			return nil, nil, PathError{Path: path.Append(Key(token)), Err: err}
+		}
+		path = append(path, step)
+		v, _, _ = childAt(v, step)
+	}
+	return path, v, nil
+}
+
+// patchStep returns the step for an existing child of v.
+func patchStep(v Value, token string) (PathStep, error) {
+	switch v := v.(type) {
+	case Object:
+		if _, ok := v.Get(token); !ok {
+			return PathStep{}, ErrPathNotFound
+		}
+		return Key(token), nil
+	case Array:
+		i, err := patchIndex(token, len(v))
+		if err != nil {
+			return PathStep{}, err
+		}
+		return Index(i), nil
+	}
+	return PathStep{}, ErrNotContainer
+}
+
+// patchIndex parses an array index, which must be less than n.
+func patchIndex(token string, n int) (int, error) {
+	if token == "" || (token[0] == '0' && len(token) > 1) || token[0] == '+' || token[0] == '-' {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if i >= n {
+		return 0, ErrPathNotFound
+	}
+	return i, nil
+}
+
+// isPointerPrefix reports whether every token of prefix is the same as the token of p at the same
+// position.
+func isPointerPrefix(prefix, p Pointer) bool {
+	for i, token := range prefix {
+		if p[i] != token {
+			return false
+		}
+	}
+	return true
+}
+
+// ---------------- errors ----------------
+
+// PatchError is returned by Patch when an operation cannot be applied.
+type PatchError struct {
+	// Index is the index of the operation within the patch.
+	Index int
+	// Op is the name of the operation, if it has one.
+	Op  string
+	Err error
+}
+
+func (e PatchError) Error() string {
+	if e.Op == "" {
+		return fmt.Sprintf("patch operation %d: %v", e.Index, e.Err)
+	}
+	return fmt.Sprintf("patch operation %d (%s): %v", e.Index, e.Op, e.Err)
+}
+
+func (e PatchError) Unwrap() error {
+	return e.Err
+}
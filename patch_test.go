@@ -0,0 +1,106 @@
+package genjson
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		// The examples of RFC 6902 appendix A.
+		{name: "add-member", doc: `{"foo": "bar"}`, patch: `[{"op": "add", "path": "/baz", "value": "qux"}]`, want: `{"foo": "bar", "baz": "qux"}`},
+		{name: "add-element", doc: `{"foo": ["bar", "baz"]}`, patch: `[{"op": "add", "path": "/foo/1", "value": "qux"}]`, want: `{"foo": ["bar", "qux", "baz"]}`},
+		{name: "remove-member", doc: `{"baz": "qux", "foo": "bar"}`, patch: `[{"op": "remove", "path": "/baz"}]`, want: `{"foo": "bar"}`},
+		{name: "remove-element", doc: `{"foo": ["bar", "qux", "baz"]}`, patch: `[{"op": "remove", "path": "/foo/1"}]`, want: `{"foo": ["bar", "baz"]}`},
+		{name: "replace", doc: `{"baz": "qux", "foo": "bar"}`, patch: `[{"op": "replace", "path": "/baz", "value": "boo"}]`, want: `{"baz": "boo", "foo": "bar"}`},
+		{name: "move-member", doc: `{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`, patch: `[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`, want: `{"foo": {"bar": "baz"}, "qux": {"corge": "grault", "thud": "fred"}}`},
+		{name: "move-element", doc: `{"foo": ["all", "grass", "cows", "eat"]}`, patch: `[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`, want: `{"foo": ["all", "cows", "eat", "grass"]}`},
+		{name: "test", doc: `{"baz": "qux", "foo": ["a", 2, "c"]}`, patch: `[{"op": "test", "path": "/baz", "value": "qux"}, {"op": "test", "path": "/foo/1", "value": 2}]`, want: `{"baz": "qux", "foo": ["a", 2, "c"]}`},
+		{name: "add-nested", doc: `{"foo": "bar"}`, patch: `[{"op": "add", "path": "/child", "value": {"grandchild": {}}}]`, want: `{"foo": "bar", "child": {"grandchild": {}}}`},
+		{name: "ignore-unknown-members", doc: `{"foo": "bar"}`, patch: `[{"op": "add", "path": "/baz", "value": "qux", "xyz": 123}]`, want: `{"foo": "bar", "baz": "qux"}`},
+		{name: "add-to-array-end", doc: `{"foo": ["bar"]}`, patch: `[{"op": "add", "path": "/foo/-", "value": ["abc", "def"]}]`, want: `{"foo": ["bar", ["abc", "def"]]}`},
+		{name: "escaped-keys", doc: `{"/": 9, "~1": 10}`, patch: `[{"op": "test", "path": "/~01", "value": 10}, {"op": "remove", "path": "/~1"}]`, want: `{"~1": 10}`},
+		{name: "test-unordered-object", doc: `{"a": {"x": 1, "y": 2.0}}`, patch: `[{"op": "test", "path": "/a", "value": {"y": 2, "x": 1}}]`, want: `{"a": {"x": 1, "y": 2.0}}`},
+		{name: "test-number-formats", doc: `{"a": 0.0015, "b": [100]}`, patch: `[{"op": "test", "path": "/a", "value": 1.5e-3}, {"op": "test", "path": "/b", "value": [1e2]}]`, want: `{"a": 0.0015, "b": [100]}`},
+
+		{name: "add-existing-member", doc: `{"a": 1}`, patch: `[{"op": "add", "path": "/a", "value": 2}]`, want: `{"a": 2}`},
+		{name: "add-root", doc: `{"a": 1}`, patch: `[{"op": "add", "path": "", "value": [1]}]`, want: `[1]`},
+		{name: "add-array-end-index", doc: `[1]`, patch: `[{"op": "add", "path": "/1", "value": 2}]`, want: `[1, 2]`},
+		{name: "replace-root", doc: `1`, patch: `[{"op": "replace", "path": "", "value": null}]`, want: `null`},
+		{name: "copy", doc: `{"a": {"b": [1]}}`, patch: `[{"op": "copy", "from": "/a/b", "path": "/c"}, {"op": "add", "path": "/c/-", "value": 2}]`, want: `{"a": {"b": [1]}, "c": [1, 2]}`},
+		{name: "move-to-same-path", doc: `{"a": 1}`, patch: `[{"op": "move", "from": "/a", "path": "/a"}]`, want: `{"a": 1}`},
+		{name: "sequence", doc: `{}`, patch: `[{"op": "add", "path": "/a", "value": []}, {"op": "add", "path": "/a/0", "value": 1}, {"op": "add", "path": "/a/0", "value": 0}]`, want: `{"a": [0, 1]}`},
+		{name: "empty-key", doc: `{"": 1}`, patch: `[{"op": "replace", "path": "/", "value": 2}]`, want: `{"": 2}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := MustDeserializeString(tt.doc)
+			before := string(Serialize(doc))
+			got, err := Patch(doc, MustDeserializeString(tt.patch))
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if want := MustDeserializeString(tt.want); string(Serialize(got)) != string(Serialize(want)) {
+				t.Errorf("unexpected result %s != %s", Serialize(got), Serialize(want))
+			}
+			if after := string(Serialize(doc)); after != before {
+				t.Errorf("document modified %s", after)
+			}
+		})
+	}
+}
+
+func TestPatchErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		patch   string
+		index   int
+		wantErr error
+	}{
+		{name: "add-missing-parent", doc: `{"foo": "bar"}`, patch: `[{"op": "add", "path": "/baz/bat", "value": "qux"}]`, wantErr: ErrPathNotFound},
+		{name: "test-failed", doc: `{"baz": "qux"}`, patch: `[{"op": "test", "path": "/baz", "value": "bar"}]`, wantErr: ErrPatchTestFailed},
+		{name: "test-string-number", doc: `{"/": 9}`, patch: `[{"op": "test", "path": "/~1", "value": "9"}]`, wantErr: ErrPatchTestFailed},
+		{name: "add-out-of-range", doc: `{"bar": [1, 2]}`, patch: `[{"op": "add", "path": "/bar/8", "value": "5"}]`, wantErr: ErrPathNotFound},
+		{name: "remove-missing", doc: `{"a": 1}`, patch: `[{"op": "test", "path": "/a", "value": 1}, {"op": "remove", "path": "/b"}]`, index: 1, wantErr: ErrPathNotFound},
+		{name: "replace-missing", doc: `[1]`, patch: `[{"op": "replace", "path": "/1", "value": 2}]`, wantErr: ErrPathNotFound},
+		{name: "move-missing-from", doc: `{}`, patch: `[{"op": "move", "from": "/a", "path": "/b"}]`, wantErr: ErrPathNotFound},
+		{name: "scalar-parent", doc: `{"a": 1}`, patch: `[{"op": "add", "path": "/a/b", "value": 2}]`, wantErr: ErrNotContainer},
+		{name: "ordinal-key", doc: `{"a": 1}`, patch: `[{"op": "remove", "path": "/a#0"}]`, wantErr: ErrPathNotFound},
+		{name: "invalid-pointer", doc: `{}`, patch: `[{"op": "add", "path": "a", "value": 1}]`, wantErr: ErrInvalidPointer},
+		{name: "missing-value", doc: `{}`, patch: `[{"op": "add", "path": "/a"}]`, wantErr: MissingKeyError{Key: "value"}},
+		{name: "missing-op", doc: `{}`, patch: `[{"path": "/a"}]`, wantErr: MissingKeyError{Key: "op"}},
+		{name: "op-type", doc: `{}`, patch: `[{"op": 1, "path": "/a"}]`, wantErr: TypeError{Want: TypeString, Got: TypeNumber}},
+		{name: "not-object", doc: `{}`, patch: `[1]`, wantErr: TypeError{Want: TypeObject, Got: TypeNumber}},
+		{name: "remove-root", doc: `{}`, patch: `[{"op": "remove", "path": ""}]`},
+		{name: "unknown-op", doc: `{}`, patch: `[{"op": "merge", "path": ""}]`},
+		{name: "leading-zero", doc: `[1, 2]`, patch: `[{"op": "remove", "path": "/01"}]`},
+		{name: "signed-index", doc: `[1, 2]`, patch: `[{"op": "remove", "path": "/+1"}]`},
+		{name: "dash-remove", doc: `[1, 2]`, patch: `[{"op": "remove", "path": "/-"}]`},
+		{name: "move-into-child", doc: `{"a": {"b": {}}}`, patch: `[{"op": "move", "from": "/a", "path": "/a/b/c"}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Patch(MustDeserializeString(tt.doc), MustDeserializeString(tt.patch))
+			var pe PatchError
+			if !errors.As(err, &pe) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if pe.Index != tt.index {
+				t.Errorf("unexpected index %d", pe.Index)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+
+	if _, err := Patch(Object{}, Object{}); !errors.Is(err, TypeError{Want: TypeArray, Got: TypeObject}) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package genjson
+
+import (
+	"strconv"
+	"strings"
+)
+
+// PathStep is a single step of a Path. It is either an object key or an array index. Key steps
+// may select a single entry of duplicate keys by its ordinal, see Entry.Ordinal.
+type PathStep struct {
+	Key        string
+	Index      int
+	IsIndex    bool
+	Ordinal    int
+	HasOrdinal bool
+}
+
+// Key returns the step to the value of an object key.
+func Key(key string) PathStep {
+	return PathStep{Key: key}
+}
+
+// KeyAt returns the step to the value of the entry of an object key with the given ordinal. The
+// ordinal of the first entry of a key is 0.
+func KeyAt(key string, ordinal int) PathStep {
+	return PathStep{Key: key, Ordinal: ordinal, HasOrdinal: true}
+}
+
+// Index returns the step to the element of an array at index i.
+func Index(i int) PathStep {
+	return PathStep{Index: i, IsIndex: true}
+}
+
+// token returns the step as an unescaped pointer token.
+func (ps PathStep) token() string {
+	switch {
+	case ps.IsIndex:
+		return strconv.Itoa(ps.Index)
+	case ps.HasOrdinal:
+		return ps.Key + "#" + strconv.Itoa(ps.Ordinal)
+	}
+	return ps.Key
+}
+
+// Path is the location of a value within another value, as a sequence of object keys and array
+// indexes. The empty path refers to the root value.
+type Path []PathStep
+
+// String returns the path in dot notation, e.g. users[0].name. Keys that are not made up of
+// letters, digits, underscores and dashes are written as quoted json strings in brackets, e.g.
+// ["a.b"], so that the path is unambiguous. Ordinals are written after their key, e.g. a#1.
+func (p Path) String() string {
+	var sb strings.Builder
+	for i, step := range p {
+		switch {
+		case step.IsIndex:
+			sb.WriteByte('[')
+			sb.WriteString(strconv.Itoa(step.Index))
+			sb.WriteByte(']')
+		case plainKey(step.Key):
+			if i > 0 {
+				sb.WriteByte('.')
+			}
+			sb.WriteString(step.Key)
+		default:
+			sb.WriteByte('[')
+			sb.Write(appendString(&defSerializer, nil, step.Key))
+			sb.WriteByte(']')
+		}
+		if step.HasOrdinal && !step.IsIndex {
+			sb.WriteByte('#')
+			sb.WriteString(strconv.Itoa(step.Ordinal))
+		}
+	}
+	return sb.String()
+}
+
+// Pointer returns the path as a json pointer. Ordinals are written as key#N.
+func (p Path) Pointer() Pointer {
+	ptr := make(Pointer, len(p))
+	for i, step := range p {
+		ptr[i] = step.token()
+	}
+	return ptr
+}
+
+// Append returns a new path with the steps appended.
+func (p Path) Append(steps ...PathStep) Path {
+	c := make(Path, 0, len(p)+len(steps))
+	c = append(c, p...)
+	return append(c, steps...)
+}
+
+func plainKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		if r != '_' && r != '-' && !('a' <= r && r <= 'z') && !('A' <= r && r <= 'Z') && !('0' <= r && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,147 @@
+package genjson
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPathString(t *testing.T) {
+	tests := []struct {
+		path Path
+		want string
+	}{
+		{path: nil, want: ""},
+		{path: Path{Key("users"), Index(0), Key("name")}, want: "users[0].name"},
+		{path: Path{Index(1), Key("a_b-c")}, want: "[1].a_b-c"},
+		{path: Path{Key("a.b"), Key("0"), Key("")}, want: `["a.b"].0[""]`},
+		{path: Path{Key(`"q"`)}, want: `["\"q\""]`},
+		{path: Path{KeyAt("a", 1), KeyAt("b.c", 0)}, want: `a#1["b.c"]#0`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.path.String(); got != tt.want {
+				t.Errorf("unexpected string %s != %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathPointer(t *testing.T) {
+	p := Path{Key("a/b"), Index(2), Key("~"), KeyAt("d", 1)}
+	if got := p.Pointer().String(); got != "/a~1b/2/~0/d#1" {
+		t.Errorf("unexpected pointer %s", got)
+	}
+}
+
+func TestPathErrorPath(t *testing.T) {
+	v := MustDeserializeString(`{"a": [{"1": true}]}`)
+	_, err := GetPath(v, []string{"a", "0", "1", "x"})
+	var pe PathError
+	if !errors.As(err, &pe) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if want := (Path{Key("a"), Index(0), Key("1"), Key("x")}); !reflect.DeepEqual(pe.Path, want) {
+		t.Errorf("unexpected path %s", pe.Path)
+	}
+	_, err = SetPath(v, []string{"a", "1", "b"}, Null{})
+	if !errors.As(err, &pe) || !reflect.DeepEqual(pe.Path, Path{Key("a"), Index(1)}) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestPathOrdinal(t *testing.T) {
+	root := MustDeserializeString(`{"a": 1, "b": {"c": 2}, "a": 3, "x#1": 4, "a": 5}`)
+	tests := []struct {
+		name    string
+		path    []string
+		want    Value
+		wantErr error
+	}{
+		{name: "first", path: []string{"a"}, want: integer(1)},
+		{name: "ordinal", path: []string{"a#1"}, want: integer(3)},
+		{name: "last", path: []string{"a#2"}, want: integer(5)},
+		{name: "exact key", path: []string{"x#1"}, want: integer(4)},
+		{name: "nested", path: []string{"b#0", "c"}, want: integer(2)},
+		{name: "out of range", path: []string{"a#3"}, wantErr: ErrPathNotFound},
+		{name: "missing key", path: []string{"y#0"}, wantErr: ErrPathNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetPath(root, tt.path)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unexpected value %s", Serialize(got))
+			}
+		})
+	}
+
+	v, err := Path{KeyAt("a", 1)}.Set(root, Null{})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if got, want := string(Serialize(v)), `{"a":1,"b":{"c":2},"a":null,"x#1":4,"a":5}`; got != want {
+		t.Errorf("unexpected value %s != %s", got, want)
+	}
+	v, err = Path{KeyAt("a", 0)}.Delete(v)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if got, want := string(Serialize(v)), `{"b":{"c":2},"a":null,"x#1":4,"a":5}`; got != want {
+		t.Errorf("unexpected value %s != %s", got, want)
+	}
+	if _, err := (Path{KeyAt("b", 1)}).Set(root, Null{}); !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	_, err = MustParsePointer("/a#4").Get(root)
+	var pe PathError
+	if !errors.As(err, &pe) || !reflect.DeepEqual(pe.Path, Path{KeyAt("a", 4)}) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestPathTypedSteps(t *testing.T) {
+	// Unlike the tokens of GetPath, the steps of a Path are applied as they are.
+	root := MustDeserializeString(`{"a": [1, 2], "b": 3, "b": 4, "0": 5}`)
+	tests := []struct {
+		name    string
+		path    Path
+		want    Value
+		wantErr error
+	}{
+		{name: "index", path: Path{Key("a"), Index(1)}, want: integer(2)},
+		{name: "key-on-array", path: Path{Key("a"), Key("1")}, wantErr: ErrPathNotFound},
+		{name: "index-on-object", path: Path{Index(0)}, wantErr: ErrPathNotFound},
+		{name: "numeric-key", path: Path{Key("0")}, want: integer(5)},
+		{name: "ordinal-syntax", path: Path{Key("b#1")}, wantErr: ErrPathNotFound},
+		{name: "ordinal", path: Path{KeyAt("b", 1)}, want: integer(4)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.path.Get(root)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unexpected value %s", Serialize(got))
+			}
+		})
+	}
+
+	if _, err := (Path{Key("a"), Key("0")}).Set(root, Null{}); !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := (Path{Key("a"), Key("0")}).Delete(root); !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("unexpected error %v", err)
+	}
+	v, err := (Path{Key("b#1")}).Set(root, Null{})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if got, want := string(Serialize(v)), `{"a":[1,2],"b":3,"b":4,"0":5,"b#1":null}`; got != want {
+		t.Errorf("unexpected value %s != %s", got, want)
+	}
+}
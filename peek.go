@@ -0,0 +1,193 @@
+package genjson
+
+import "bytes"
+
+// PeekKey returns the value of key in the top-level object of data using the default Deserializer.
+// See Deserializer.PeekKey.
+func PeekKey(data []byte, key string) (Value, error) {
+	return defDeserializer.PeekKey(data, key)
+}
+
+// PeekKey returns the value of key in the top-level object of data without parsing the rest of
+// the document, e.g. so that a message router can dispatch on a "type" member before deciding
+// whether to decode the whole message. The members before the key are skipped by matching their
+// brackets and strings, so only their structure is validated, and the document is not read past
+// the value of the key. The first member is used for duplicate keys.
+//
+// A PathError wrapping ErrPathNotFound is returned if the object does not contain the key, and a
+// TypeError if the top-level value is not an object.
+func (ds *Deserializer) PeekKey(data []byte, key string) (Value, error) {
+	d := skipSpace(newDeserializer(data, ds))
+	d, c, br := read(d)
+	if !br.OK {
+		return nil, ErrEmptyInput
+	}
+	if c != '{' {
+		v, err := ds.Deserialize(data)
+		if err != nil {
+			return nil, err
+		}
+		return nil, TypeError{Want: TypeObject, Got: TypeOf(v)}
+	}
+	for first := true; ; first = false {
+		d = skipSpace(d)
+		if _, c, br := read(d); first && br.OK && c == '}' {
+			return nil, PathError{Path: Path{Key(key)}, Err: ErrPathNotFound}
+		}
+		var (
+			match bool
+			err   error
+		)
+		if d, match, err = peekMatchKey(d, key); err != nil {
+			return nil, err
+		}
+		if d, err = peekByte(skipSpace(d), ':'); err != nil {
+			return nil, err
+		}
+		if match {
+			_, o, err := deserializeNext(skipSpace(d))
+			if err != nil {
+				return nil, err
+			}
+			return o.value, nil
+		}
+		if d, err = skipValue(skipSpace(d)); err != nil {
+			return nil, err
+		}
+		d = skipSpace(d)
+		nd, c, br := read(d)
+		switch {
+		case !br.OK:
+			return nil, ErrUnexpectedEndOfInput
+		case c == '}':
+			return nil, PathError{Path: Path{Key(key)}, Err: ErrPathNotFound}
+		case c != ',':
+			return nil, errNoMatch(d)
+		}
+		d = nd
+	}
+}
+
+// peekMatchKey reads an object key and reports whether it is key.
+func peekMatchKey(d deserializer, key string) (deserializer, bool, error) {
+	d, k, err := readKey(d)
+	if err != nil {
+		return d, false, err
+	}
+	return d, string(k) == key, nil
+}
+
+// readKey reads an object key. Keys without escape sequences are returned without decoding them
+// unless the options transform strings, in which case the returned bytes alias the input.
+func readKey(d deserializer) (deserializer, []byte, error) {
+	start := d
+	d, c, br := read(d)
+	if !br.OK {
+		return d, nil, ErrUnexpectedEndOfInput
+	}
+	if c != '"' {
+		return d, nil, errNoMatch(start)
+	}
+	d, escaped, err := skipString(d)
+	if err != nil {
+		return d, nil, err
+	}
+	if !escaped && plainStrings(start.opts) {
+		return d, start.b[start.idx+1 : d.idx-1], nil
+	}
+	_, s, cr := stringHookParser(rawStringParser(), true)(start)
+	if cr.Err != nil {
+		return d, nil, cr.Err
+	}
+	return d, unsafeBytes(s), nil
+}
+
+// plainStrings reports whether the options leave strings without escape sequences unchanged.
+func plainStrings(opts *Deserializer) bool {
+	return opts == nil || (opts.TransformString == nil && !opts.NormalizeNewlines && !opts.StripBOM)
+}
+
+// peekByte reads the byte b.
+func peekByte(d deserializer, b byte) (deserializer, error) {
+	nd, c, br := read(d)
+	if !br.OK {
+		return d, ErrUnexpectedEndOfInput
+	}
+	if c != b {
+		return d, errNoMatch(d)
+	}
+	return nd, nil
+}
+
+// skipString skips the rest of a string after its opening quote and reports whether it contains
+// escape sequences. The escape sequences are not validated.
+func skipString(d deserializer) (deserializer, bool, error) {
+	escaped := false
+	for {
+		i := bytes.IndexAny(d.b[d.idx:], "\"\\\n")
+		if i < 0 {
+			return d, escaped, ErrUnmatchedQuote
+		}
+		d.col += i
+		d.idx += i
+		var c byte
+		d, c, _ = read(d)
+		switch c {
+		case '"':
+			return d, escaped, nil
+		case '\\':
+			escaped = true
+			nd, _, br := read(d)
+			if !br.OK {
+				return d, escaped, ErrUnmatchedQuote
+			}
+			d = nd
+		}
+	}
+}
+
+// skipValue skips a value by matching its brackets and strings. Scalars are only checked to be
+// made of the bytes allowed in literals and numbers.
+func skipValue(d deserializer) (deserializer, error) {
+	// stack holds the closing brackets of the open containers.
+	var stackBuf [32]byte
+	stack := stackBuf[:0]
+	for {
+		nd, c, br := read(d)
+		if !br.OK {
+			return d, ErrUnexpectedEndOfInput
+		}
+		switch {
+		case c == '{' || c == '[':
+			// The closing brackets follow the opening brackets by two in ascii.
+			stack = append(stack, c+2)
+			d = nd
+		case c == '}' || c == ']':
+			if len(stack) == 0 || stack[len(stack)-1] != c {
+				return d, errNoMatch(d)
+			}
+			stack = stack[:len(stack)-1]
+			d = nd
+		case c == '"':
+			var err error
+			if d, _, err = skipString(nd); err != nil {
+				return d, err
+			}
+		case isLiteralByte(c):
+			d = skipWhile(nd, isLiteralByte)
+		case len(stack) > 0 && (isSpace(c) || c == ',' || c == ':'):
+			d = nd
+			continue
+		default:
+			return d, errNoMatch(d)
+		}
+		if len(stack) == 0 {
+			return d, nil
+		}
+	}
+}
+
+// isLiteralByte matches the bytes of true, false, null and numbers.
+func isLiteralByte(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') || c == '-' || c == '+' || c == '.' || c == 'E'
+}
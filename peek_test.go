@@ -0,0 +1,64 @@
+package genjson
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPeekKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		key     string
+		want    Value
+		wantErr error
+	}{
+		{name: "first", data: `{"type": "a", "id": 1}`, key: "type", want: String("a")},
+		{
+			name: "skips-members",
+			data: `{"a": {"b": [1, {"c": "}]"}], "d": null}, "e": "x\"y", "f": -1.5e3, "g": [], "type": {"k": true}}`,
+			key:  "type",
+			want: NewObject(Entry{Key: "k", Value: Bool(true)}),
+		},
+		{name: "whitespace", data: " \n{ \"a\" :\n1 ,\n \"type\"\t: [ 2 ] }", key: "type", want: Array{integer(2)}},
+		{name: "escaped-key", data: `{"a\"b": 0, "t\u0079pe": 1}`, key: "type", want: integer(1)},
+		{name: "duplicate", data: `{"type": 1, "type": 2}`, key: "type", want: integer(1)},
+		{name: "invalid-rest", data: `{"type": 1, "a": [}`, key: "type", want: integer(1)},
+		{name: "missing", data: `{"a": 1}`, key: "type", wantErr: ErrPathNotFound},
+		{name: "empty-object", data: `{}`, key: "type", wantErr: ErrPathNotFound},
+		{name: "not-object", data: `[1]`, key: "type", wantErr: TypeError{Want: TypeObject, Got: TypeArray}},
+		{name: "empty", data: ` `, key: "type", wantErr: ErrEmptyInput},
+		{name: "truncated", data: `{"a": [1, 2`, key: "type", wantErr: ErrUnexpectedEndOfInput},
+		{name: "unterminated-string", data: `{"a": "b`, key: "type", wantErr: ErrUnmatchedQuote},
+		{name: "mismatched", data: `{"a": [1}, "type": 1}`, key: "type", wantErr: InvalidTokenError{Token: '}', Row: 1, Col: 9}},
+		{name: "missing-colon", data: `{"a" 1}`, key: "type", wantErr: InvalidTokenError{Token: '1', Row: 1, Col: 6}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PeekKey([]byte(tt.data), tt.key)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("unexpected error %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(Serialize(got), Serialize(tt.want)) {
+				t.Errorf("unexpected value %s", Serialize(got))
+			}
+		})
+	}
+}
+
+func BenchmarkPeekKey(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(routeMessage)))
+	for i := 0; i < b.N; i++ {
+		if _, err := PeekKey(routeMessage, "type"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package genjson
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrPathNotFound is returned when a path does not exist in a value.
+	ErrPathNotFound = errors.New("path not found")
+	// ErrNotContainer is returned when a path traverses a value that is not an object or array.
+	ErrNotContainer = errors.New("value is not an object or array")
+)
+
+// PathError is returned when a path cannot be resolved. Path is the prefix of the path that
+// failed.
+type PathError struct {
+	Path Path
+	Err  error
+}
+
+func (e PathError) Error() string {
+	return fmt.Sprintf("path %s: %v", e.Path, e.Err)
+}
+
+func (e PathError) Unwrap() error {
+	return e.Err
+}
+
+// GetPath returns the value at path. Tokens applied to arrays are treated as indexes if they are
+// valid integers. The first entry is used for duplicate object keys, unless a token of the form
+// key#N is not itself a key of its object, in which case it selects the entry of key with ordinal
+// N, see Entry.Ordinal.
+func GetPath(root Value, path []string) (Value, error) {
+	return tokenPath(path).get(root, true)
+}
+
+// SetPath returns a copy of root with the value at path set to v. The final key of the path is
+// added to its object if it does not exist. Array elements are addressed by their index.
+//
+// Only the objects and arrays along the path are copied, all other values are shared with root.
+// This makes deriving modified values from large trees cheap, but shared values must not be
+// modified in place by either tree.
+func SetPath(root Value, path []string, v Value) (Value, error) {
+	return tokenPath(path).set(root, v, true)
+}
+
+// DeletePath returns a copy of root with the value at path removed. For objects, all entries
+// matching the final key are removed. Array elements after a removed element are shifted down.
+// Like SetPath, only the objects and arrays along the path are copied.
+func DeletePath(root Value, path []string) (Value, error) {
+	return tokenPath(path).delete(root, true)
+}
+
+// tokenPath converts path tokens into key steps. They are resolved against the value they are
+// applied to by resolveToken.
+func tokenPath(tokens []string) Path {
+	p := make(Path, len(tokens))
+	for i, token := range tokens {
+		p[i] = Key(token)
+	}
+	return p
+}
+
+// Get returns the value at the path within root. Steps are applied as they are: key steps only
+// select entries of objects, with the first entry of duplicate keys selected by a step without an
+// ordinal, and index steps only select elements of arrays.
+func (p Path) Get(root Value) (Value, error) {
+	return p.get(root, false)
+}
+
+// get returns the value at the path. If tokens is set, the steps are path tokens resolved by
+// resolveToken.
+func (p Path) get(root Value, tokens bool) (Value, error) {
+	v := root
+	at := make(Path, 0, len(p))
+	for _, step := range p {
+		if tokens {
+			step = resolveToken(v, step)
+		}
+		at = append(at, step)
+		child, exists, err := childAt(v, step)
+		if err != nil {
+			return nil, PathError{Path: at, Err: err}
+		}
+		if !exists {
+			return nil, PathError{Path: at, Err: ErrPathNotFound}
+		}
+		v = child
+	}
+	return v, nil
+}
+
+// Set returns a copy of root with the value at the path set to v. The final key of the path is
+// added to its object if it does not exist. A final key step without an ordinal replaces the first
+// entry of duplicate keys and drops the others, while a step with an ordinal only replaces the
+// selected entry. Like SetPath, only the objects and arrays along the path are copied.
+func (p Path) Set(root Value, v Value) (Value, error) {
+	return p.set(root, v, false)
+}
+
+func (p Path) set(root Value, v Value, tokens bool) (Value, error) {
+	return updatePath(root, p, nil, tokens, func(Value, bool) (Value, bool, error) {
+		return v, true, nil
+	})
+}
+
+// Delete returns a copy of root with the value at the path removed. A final key step without an
+// ordinal removes all entries of duplicate keys, while a step with an ordinal only removes the
+// selected entry. Like SetPath, only the objects and arrays along the path are copied.
+func (p Path) Delete(root Value) (Value, error) {
+	return p.delete(root, false)
+}
+
+func (p Path) delete(root Value, tokens bool) (Value, error) {
+	return updatePath(root, p, nil, tokens, func(_ Value, exists bool) (Value, bool, error) {
+		if !exists {
+			return nil, false, ErrPathNotFound
+		}
+		return nil, false, nil
+	})
+}
+
+// resolveToken returns the key step of a path token as it applies to v. Tokens are converted into
+// index steps for arrays and tokens of the form key#N into ordinal steps for objects that do not
+// contain the key.
+func resolveToken(v Value, step PathStep) PathStep {
+	switch v := v.(type) {
+	case Array:
+		if i, err := strconv.Atoi(step.Key); err == nil {
+			return Index(i)
+		}
+	case Object:
+		if _, ok := v.Get(step.Key); ok {
+			return step
+		}
+		i := strings.LastIndexByte(step.Key, '#')
+		if i < 0 {
+			return step
+		}
+		n, err := strconv.Atoi(step.Key[i+1:])
+		if err != nil || n < 0 {
+			return step
+		}
+		if _, ok := v.Get(step.Key[:i]); ok {
+			return KeyAt(step.Key[:i], n)
+		}
+	}
+	return step
+}
+
+// childAt returns the child of v selected by the resolved step. An error is returned if v is not
+// a container.
+func childAt(v Value, step PathStep) (Value, bool, error) {
+	switch v := v.(type) {
+	case Object:
+		if step.IsIndex {
+			return nil, false, nil
+		}
+		if !step.HasOrdinal {
+			child, ok := v.Get(step.Key)
+			return child, ok, nil
+		}
+		values, _ := v.GetAll(step.Key)
+		if step.Ordinal >= len(values) {
+			return nil, false, nil
+		}
+		return values[step.Ordinal], true, nil
+	case Array:
+		if !step.IsIndex || step.Index < 0 || step.Index >= len(v) {
+			return nil, false, nil
+		}
+		return v[step.Index], true, nil
+	default:
+		return nil, false, ErrNotContainer
+	}
+}
+
+// updatePath copies the containers along the path, calling update with the current value at the
+// end of the path. update returns the new value and whether it should be kept. at is the resolved
+// path to v. If tokens is set, the steps are path tokens resolved by resolveToken.
+func updatePath(v Value, path Path, at Path, tokens bool, update func(v Value, exists bool) (Value, bool, error)) (Value, error) {
+	if len(path) == 0 {
+		nv, _, err := update(v, true)
+		return nv, err
+	}
+	depth := len(at)
+	step := path[depth]
+	if tokens {
+		step = resolveToken(v, step)
+	}
+	last := depth == len(path)-1
+	at = at.Append(step)
+	pathErr := func(err error) error {
+		return PathError{Path: at, Err: err}
+	}
+	child, exists, err := childAt(v, step)
+	if err != nil {
+		return nil, pathErr(err)
+	}
+	// Only new object keys can be added.
+	if _, isArray := v.(Array); !exists && (!last || isArray || step.IsIndex || step.HasOrdinal) {
+		return nil, pathErr(ErrPathNotFound)
+	}
+	var (
+		nv   Value
+		keep = true
+	)
+	if last {
+		nv, keep, err = update(child, exists)
+	} else {
+		nv, err = updatePath(child, path, at, tokens, update)
+	}
+	if err != nil {
+		if _, ok := err.(PathError); ok {
+			return nil, err
+		}
+		return nil, pathErr(err)
+	}
+	switch v := v.(type) {
+	case Object:
+		if step.HasOrdinal {
+			return v.cloneWithOrdinal(step.Key, step.Ordinal, nv, keep), nil
+		}
+		return v.cloneWith(step.Key, nv, keep), nil
+	default:
+		a := v.(Array)
+		i := step.Index
+		c := make(Array, 0, len(a))
+		c = append(c, a[:i]...)
+		if keep {
+			c = append(c, nv)
+		}
+		return append(c, a[i+1:]...), nil
+	}
+}
+
+// cloneWith returns a new object with the same entries, except that the first entry matching the
+// key is replaced by v, in place, and any further entries matching the key are dropped. If keep is
+// false, all matching entries are dropped. The values are not cloned.
+func (o Object) cloneWith(key string, v Value, keep bool) Object {
+	var c Object
+	c.init()
+	found := false
+	iter := o.Iter()
+	for k, vv, ok := iter.Next(); ok; k, vv, ok = iter.Next() {
+		if k != key {
+			c.Add(k, vv)
+			continue
+		}
+		if keep && !found {
+			c.Add(k, v)
+		}
+		found = true
+	}
+	if keep && !found {
+		c.Add(key, v)
+	}
+	return c
+}
+
+// cloneWithOrdinal returns a new object with the same entries, except that the entry of the key
+// with the given ordinal is replaced by v, or dropped if keep is false.
+func (o Object) cloneWithOrdinal(key string, ordinal int, v Value, keep bool) Object {
+	var c Object
+	c.init()
+	n := 0
+	iter := o.Iter()
+	for k, vv, ok := iter.Next(); ok; k, vv, ok = iter.Next() {
+		if k == key {
+			match := n == ordinal
+			n++
+			if match && !keep {
+				continue
+			}
+			if match {
+				vv = v
+			}
+		}
+		c.Add(k, vv)
+	}
+	return c
+}
@@ -0,0 +1,80 @@
+package genjson
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSetPath(t *testing.T) {
+	root, err := Deserialize([]byte(`{"a": {"b": [1, 2]}, "c": {"d": true}}`))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	before := string(Serialize(root))
+
+	tests := []struct {
+		name    string
+		path    []string
+		value   Value
+		want    string
+		wantErr error
+	}{
+		{name: "replace", path: []string{"a", "b", "1"}, value: integer(3), want: `{"a":{"b":[1,3]},"c":{"d":true}}`},
+		{name: "add", path: []string{"a", "e"}, value: Null{}, want: `{"a":{"b":[1,2],"e":null},"c":{"d":true}}`},
+		{name: "root", path: nil, value: Null{}, want: `null`},
+		{name: "missing", path: []string{"x", "y"}, value: Null{}, wantErr: ErrPathNotFound},
+		{name: "index", path: []string{"a", "b", "2"}, value: Null{}, wantErr: ErrPathNotFound},
+		{name: "scalar", path: []string{"c", "d", "e"}, value: Null{}, wantErr: ErrNotContainer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := SetPath(root, tt.path, tt.value)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if err == nil && string(Serialize(v)) != tt.want {
+				t.Errorf("unexpected value %s != %s", Serialize(v), tt.want)
+			}
+			if after := string(Serialize(root)); after != before {
+				t.Errorf("root was modified %s", after)
+			}
+		})
+	}
+}
+
+func TestSetPathSharing(t *testing.T) {
+	root, err := Deserialize([]byte(`{"a": {"b": 1}, "c": {"d": true}}`))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	v, err := SetPath(root, []string{"a", "b"}, integer(2))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	oldC, _ := root.(Object).Get("c")
+	newC, _ := v.(Object).Get("c")
+	if oldC.(Object).m != newC.(Object).m {
+		t.Errorf("untouched values should be shared")
+	}
+}
+
+func TestDeletePath(t *testing.T) {
+	root, err := Deserialize([]byte(`{"a": [1, 2, 3], "b": 1}`))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	v, err := DeletePath(root, []string{"a", "1"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	v, err = DeletePath(v, []string{"b"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if got := string(Serialize(v)); got != `{"a":[1,3]}` {
+		t.Errorf("unexpected value %s", got)
+	}
+	if _, err := DeletePath(root, []string{"x"}); !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package genjson
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidPointer is returned when a string is not a valid json pointer.
+var ErrInvalidPointer = errors.New("invalid json pointer")
+
+// Pointer is a json pointer (RFC 6901). Each element is an unescaped reference token, so a Pointer
+// can be used anywhere a path is expected, such as GetPath and SetPath.
+type Pointer []string
+
+// ParsePointer parses a json pointer such as /a/0/b~1c. The empty string is the pointer to the
+// whole document.
+func ParsePointer(s string) (Pointer, error) {
+	if s == "" {
+		return Pointer{}, nil
+	}
+	if s[0] != '/' {
+		return nil, fmt.Errorf("%w %q: must start with /", ErrInvalidPointer, s)
+	}
+	tokens := strings.Split(s[1:], "/")
+	p := make(Pointer, len(tokens))
+	for i, token := range tokens {
+		t, err := UnescapePointerToken(token)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %v", ErrInvalidPointer, s, err)
+		}
+		p[i] = t
+	}
+	return p, nil
+}
+
+// MustParsePointer parses a json pointer, panicking if it is invalid. It is intended for
+// pointers that are known to be valid, such as constants.
+func MustParsePointer(s string) Pointer {
+	p, err := ParsePointer(s)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// String returns the pointer with every token escaped.
+func (p Pointer) String() string {
+	var sb strings.Builder
+	for _, token := range p {
+		sb.WriteByte('/')
+		sb.WriteString(EscapePointerToken(token))
+	}
+	return sb.String()
+}
+
+// Append returns a new pointer with the unescaped tokens appended.
+func (p Pointer) Append(tokens ...string) Pointer {
+	c := make(Pointer, 0, len(p)+len(tokens))
+	c = append(c, p...)
+	return append(c, tokens...)
+}
+
+// Get returns the value the pointer refers to within root. Like GetPath, a token of the form key#N
+// that is not a key of its object selects the entry of key with ordinal N.
+func (p Pointer) Get(root Value) (Value, error) {
+	return GetPath(root, p)
+}
+
+// EscapePointerToken escapes a key for use as a json pointer reference token, replacing ~ with ~0
+// and / with ~1.
+func EscapePointerToken(s string) string {
+	if !strings.ContainsAny(s, "~/") {
+		return s
+	}
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+// UnescapePointerToken unescapes a json pointer reference token. An error is returned if the
+// token contains a ~ that is not followed by 0 or 1.
+func UnescapePointerToken(s string) (string, error) {
+	if !strings.Contains(s, "~") {
+		return s, nil
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '~' {
+			sb.WriteByte(s[i])
+			continue
+		}
+		if i+1 == len(s) || (s[i+1] != '0' && s[i+1] != '1') {
+			return "", fmt.Errorf("invalid escape sequence at offset %d", i)
+		}
+		if s[i+1] == '0' {
+			sb.WriteByte('~')
+		} else {
+			sb.WriteByte('/')
+		}
+		i++
+	}
+	return sb.String(), nil
+}
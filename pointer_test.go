@@ -0,0 +1,74 @@
+package genjson
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParsePointer(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Pointer
+		wantErr bool
+	}{
+		{in: "", want: Pointer{}},
+		{in: "/", want: Pointer{""}},
+		{in: "/a/0", want: Pointer{"a", "0"}},
+		{in: "/a~1b/m~0n/~01", want: Pointer{"a/b", "m~n", "~1"}},
+		{in: "a", wantErr: true},
+		{in: "/a~", wantErr: true},
+		{in: "/a~2", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParsePointer(tt.in)
+			if err != nil != tt.wantErr {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if err != nil {
+				if !errors.Is(err, ErrInvalidPointer) {
+					t.Errorf("unexpected error %v", err)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unexpected pointer %q != %q", got, tt.want)
+			}
+			if got.String() != tt.in {
+				t.Errorf("unexpected string %s != %s", got.String(), tt.in)
+			}
+		})
+	}
+}
+
+func TestPointerGet(t *testing.T) {
+	v := MustDeserializeString(`{"a/b": [1, {"m~n": true}], "": 2}`)
+	tests := []struct {
+		pointer string
+		want    Value
+		wantErr error
+	}{
+		{pointer: "", want: v},
+		{pointer: "/", want: integer(2)},
+		{pointer: "/a~1b/1/m~0n", want: Bool(true)},
+		{pointer: "/a~1b/2", wantErr: ErrPathNotFound},
+		{pointer: "/a~1b/0/x", wantErr: ErrNotContainer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pointer, func(t *testing.T) {
+			got, err := MustParsePointer(tt.pointer).Get(v)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unexpected value %s", Serialize(got))
+			}
+		})
+	}
+
+	p := MustParsePointer("/a").Append("b/c", "~")
+	if p.String() != "/a/b~1c/~0" {
+		t.Errorf("unexpected pointer %s", p)
+	}
+}
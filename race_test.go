@@ -0,0 +1,7 @@
+//go:build race
+
+package genjson
+
+// raceEnabled reports whether the tests are built with the race detector, which drops pooled
+// values so that allocation counts are not reliable.
+const raceEnabled = true
@@ -0,0 +1,70 @@
+package genjson
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// defaultRegistry holds the hooks and implementations used by every Unmarshaler that does not
+// register its own. Unlike the registries of an Unmarshaler, it may be modified concurrently with
+// its use, so that libraries can register their types in init functions.
+var defaultRegistry = struct {
+	mu              sync.RWMutex
+	hooks           map[string]DecodeHook
+	implementations map[reflect.Type]reflect.Type
+}{
+	hooks:           make(map[string]DecodeHook),
+	implementations: make(map[reflect.Type]reflect.Type),
+}
+
+// RegisterDefaultHook registers a named DecodeHook for all Unmarshalers. An Unmarshaler uses its
+// own hook, registered with Unmarshaler.RegisterHook, in preference to a default hook of the same
+// name. RegisterDefaultHook is safe for concurrent use.
+func RegisterDefaultHook(name string, hook DecodeHook) {
+	defaultRegistry.mu.Lock()
+	defer defaultRegistry.mu.Unlock()
+	defaultRegistry.hooks[name] = hook
+}
+
+// RegisterDefaultImplementation registers the concrete type of impl to be used by all
+// Unmarshalers when unmarshaling into the interface type I. An Unmarshaler uses its own
+// implementation, registered with RegisterImplementation, in preference to a default one.
+// RegisterDefaultImplementation is safe for concurrent use.
+func RegisterDefaultImplementation[I any](impl I) {
+	iface := interfaceType[I]()
+	defaultRegistry.mu.Lock()
+	defer defaultRegistry.mu.Unlock()
+	defaultRegistry.implementations[iface] = reflect.TypeOf(impl)
+}
+
+// hook returns the named hook of the Unmarshaler, falling back to the default registry.
+func (u *Unmarshaler) hook(name string) (DecodeHook, bool) {
+	if hook, ok := u.hooks[name]; ok {
+		return hook, true
+	}
+	defaultRegistry.mu.RLock()
+	defer defaultRegistry.mu.RUnlock()
+	hook, ok := defaultRegistry.hooks[name]
+	return hook, ok
+}
+
+// implementation returns the implementation of the interface type registered with the
+// Unmarshaler, falling back to the default registry.
+func (u *Unmarshaler) implementation(iface reflect.Type) (reflect.Type, bool) {
+	if impl, ok := u.implementations[iface]; ok {
+		return impl, true
+	}
+	defaultRegistry.mu.RLock()
+	defer defaultRegistry.mu.RUnlock()
+	impl, ok := defaultRegistry.implementations[iface]
+	return impl, ok
+}
+
+func interfaceType[I any]() reflect.Type {
+	iface := reflect.TypeOf((*I)(nil)).Elem()
+	if iface.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("genjson: %s is not an interface type", iface))
+	}
+	return iface
+}
@@ -0,0 +1,60 @@
+package genjson
+
+import (
+	"sync"
+	"testing"
+)
+
+type defaultShape interface {
+	area() float64
+}
+
+func TestDefaultRegistry(t *testing.T) {
+	// The default registry is global, so the registrations are removed to not affect other tests.
+	t.Cleanup(func() {
+		defaultRegistry.mu.Lock()
+		defer defaultRegistry.mu.Unlock()
+		delete(defaultRegistry.implementations, interfaceType[defaultShape]())
+		delete(defaultRegistry.hooks, "test-default")
+	})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		RegisterDefaultImplementation[defaultShape](square(0))
+	}()
+	go func() {
+		defer wg.Done()
+		RegisterDefaultHook("test-default", func(_ UnmarshalState, v Value) (Value, error) {
+			return String("default"), nil
+		})
+	}()
+	wg.Wait()
+
+	var u Unmarshaler
+	var s defaultShape
+	if err := u.Unmarshal([]byte(`2`), &s); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if s != square(2) {
+		t.Errorf("unexpected value %#v", s)
+	}
+	RegisterImplementation[defaultShape](&u, new(radius))
+	if err := u.Unmarshal([]byte(`2`), &s); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if r, ok := s.(*radius); !ok || *r != 2 {
+		t.Errorf("unexpected value %#v", s)
+	}
+
+	st := &UnmarshalState{u: &u}
+	if v, err := applyHook(st, "test-default", Null{}); err != nil || v != String("default") {
+		t.Errorf("unexpected result %v %v", v, err)
+	}
+	u.RegisterHook("test-default", func(_ UnmarshalState, v Value) (Value, error) {
+		return String("override"), nil
+	})
+	if v, err := applyHook(st, "test-default", Null{}); err != nil || v != String("override") {
+		t.Errorf("unexpected result %v %v", v, err)
+	}
+}
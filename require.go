@@ -0,0 +1,79 @@
+package genjson
+
+import (
+	"fmt"
+)
+
+// TypeError is returned by the Require functions when a value is not of the required type.
+type TypeError struct {
+	Want Type
+	Got  Type
+	// Loc is the location of the value. It is set by Document.Require.
+	Loc *Loc
+}
+
+func (e TypeError) Error() string {
+	if e.Loc != nil {
+		return fmt.Sprintf("expected json %s but got %s at %s", e.Want, e.Got, locString(e.Loc))
+	}
+	return fmt.Sprintf("expected json %s but got %s", e.Want, e.Got)
+}
+
+// Require returns the value at path within the document, resolved as by Path.Get, or an error if
+// it is not of type want. The TypeError reports the location of the value.
+func (d *Document) Require(path Path, want Type) (Value, error) {
+	v, n, err := d.lookup(path)
+	if err != nil {
+		return nil, err
+	}
+	if got := TypeOf(v); got != want {
+		loc := n.start
+		return nil, TypeError{Want: want, Got: got, Loc: &loc}
+	}
+	return v, nil
+}
+
+// RequireObject returns the value as an Object or a TypeError if it is not one.
+func RequireObject(v Value) (Object, error) {
+	o, ok := v.(Object)
+	if !ok {
+		return Object{}, TypeError{Want: TypeObject, Got: TypeOf(v)}
+	}
+	return o, nil
+}
+
+// RequireArray returns the value as an Array or a TypeError if it is not one.
+func RequireArray(v Value) (Array, error) {
+	a, ok := v.(Array)
+	if !ok {
+		return nil, TypeError{Want: TypeArray, Got: TypeOf(v)}
+	}
+	return a, nil
+}
+
+// RequireString returns the value as a String or a TypeError if it is not one.
+func RequireString(v Value) (String, error) {
+	s, ok := v.(String)
+	if !ok {
+		return "", TypeError{Want: TypeString, Got: TypeOf(v)}
+	}
+	return s, nil
+}
+
+// RequireNumber returns the value as a Number or a TypeError if it is not one.
+func RequireNumber(v Value) (Number, error) {
+	n, ok := v.(Number)
+	if !ok {
+		return Number{}, TypeError{Want: TypeNumber, Got: TypeOf(v)}
+	}
+	return n, nil
+}
+
+// RequireBool returns the value as a Bool or a TypeError if it is not one.
+func RequireBool(v Value) (Bool, error) {
+	b, ok := v.(Bool)
+	if !ok {
+		return false, TypeError{Want: TypeBool, Got: TypeOf(v)}
+	}
+	return b, nil
+}
@@ -0,0 +1,60 @@
+package genjson
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRequire(t *testing.T) {
+	if _, err := RequireObject(Object{}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := RequireArray(Array{}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if s, err := RequireString(String("a")); err != nil || s != "a" {
+		t.Errorf("unexpected result %q %v", s, err)
+	}
+
+	_, err := RequireObject(Array{})
+	if err != (TypeError{Want: TypeObject, Got: TypeArray}) {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err.Error() != "expected json object but got array" {
+		t.Errorf("unexpected error message %q", err)
+	}
+	_, err = RequireString(nil)
+	if err != (TypeError{Want: TypeString, Got: TypeNull}) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestDocumentRequire(t *testing.T) {
+	d, err := DeserializeDocument([]byte("{\n  \"a\": [1, {\"b\": \"x\"}],\n  \"a\": null\n}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := d.Require(Path{Key("a"), Index(1), Key("b")}, TypeString)
+	if err != nil || v != String("x") {
+		t.Errorf("unexpected result %v %v", v, err)
+	}
+
+	_, err = d.Require(Path{Key("a"), Index(0)}, TypeString)
+	want := TypeError{Want: TypeString, Got: TypeNumber, Loc: &Loc{Row: 2, Col: 9, Offset: 10}}
+	var te TypeError
+	if !errors.As(err, &te) || te.Want != want.Want || te.Got != want.Got || te.Loc == nil || *te.Loc != *want.Loc {
+		t.Fatalf("unexpected error %#v", err)
+	}
+	if err.Error() != "expected json string but got number at 2:9" {
+		t.Errorf("unexpected error message %q", err)
+	}
+
+	_, err = d.Require(Path{KeyAt("a", 1)}, TypeArray)
+	if !errors.As(err, &te) || te.Got != TypeNull || te.Loc == nil || te.Loc.Row != 3 {
+		t.Errorf("unexpected error %v", err)
+	}
+	_, err = d.Require(Path{Key("b")}, TypeArray)
+	if !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package genjson
+
+import "unicode"
+
+// TokenType is the type of a SemanticToken.
+type TokenType int8
+
+const (
+	TokenKey TokenType = iota
+	TokenString
+	TokenNumber
+	TokenBool
+	TokenNull
+	TokenPunctuation
+)
+
+func (t TokenType) String() string {
+	switch t {
+	case TokenKey:
+		return "key"
+	case TokenString:
+		return "string"
+	case TokenNumber:
+		return "number"
+	case TokenBool:
+		return "bool"
+	case TokenNull:
+		return "null"
+	case TokenPunctuation:
+		return "punctuation"
+	}
+	return ""
+}
+
+// SemanticToken is a typed range of a Document. Start is inclusive and End is exclusive.
+type SemanticToken struct {
+	Type  TokenType
+	Start Loc
+	End   Loc
+}
+
+// SemanticTokens returns the tokens of the document in source order, for use in syntax
+// highlighting. Whitespace is not included.
+func (d *Document) SemanticTokens() []SemanticToken {
+	var tokens []SemanticToken
+	return d.appendTokens(tokens, d.value, &d.node)
+}
+
+func (d *Document) appendTokens(tokens []SemanticToken, v Value, n *node) []SemanticToken {
+	switch v := v.(type) {
+	case Null:
+		return append(tokens, SemanticToken{TokenNull, n.start, n.end})
+	case Bool:
+		return append(tokens, SemanticToken{TokenBool, n.start, n.end})
+	case Number:
+		return append(tokens, SemanticToken{TokenNumber, n.start, n.end})
+	case String:
+		return append(tokens, SemanticToken{TokenString, n.start, n.end})
+	case Array:
+		tokens = d.appendPunctuation(tokens, n.start)
+		last := d.advance(n.start, n.start.Offset+1)
+		for i, elem := range v {
+			en := &n.arrayNodes[i]
+			if i > 0 {
+				tokens = d.appendPunctuation(tokens, d.next(last))
+			}
+			tokens = d.appendTokens(tokens, elem, en)
+			last = en.end
+		}
+		return d.appendPunctuation(tokens, d.next(last))
+	case Object:
+		tokens = d.appendPunctuation(tokens, n.start)
+		last := d.advance(n.start, n.start.Offset+1)
+		for i, e := range v.Entries() {
+			kv := &n.objectNodes[i]
+			if i > 0 {
+				tokens = d.appendPunctuation(tokens, d.next(last))
+			}
+			tokens = append(tokens, SemanticToken{TokenKey, kv.keyStart, kv.keyEnd})
+			tokens = d.appendPunctuation(tokens, d.next(kv.keyEnd))
+			tokens = d.appendTokens(tokens, e.Value, &kv.node)
+			last = kv.end
+		}
+		return d.appendPunctuation(tokens, d.next(last))
+	}
+	return tokens
+}
+
+func (d *Document) appendPunctuation(tokens []SemanticToken, start Loc) []SemanticToken {
+	return append(tokens, SemanticToken{TokenPunctuation, start, d.advance(start, start.Offset+1)})
+}
+
+// next returns the location of the first non whitespace byte at or after from.
+func (d *Document) next(from Loc) Loc {
+	offset := from.Offset
+	for offset < len(d.src) && unicode.IsSpace(rune(d.src[offset])) {
+		offset++
+	}
+	return d.advance(from, offset)
+}
@@ -0,0 +1,36 @@
+package genjson
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSemanticTokens(t *testing.T) {
+	src := "{\"a\": [1, true],\n \"b\" : null, \"c\": \"s\"}"
+	doc, err := DeserializeDocument([]byte(src))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var got []string
+	for _, tok := range doc.SemanticTokens() {
+		got = append(got, tok.Type.String()+":"+src[tok.Start.Offset:tok.End.Offset])
+	}
+	want := []string{
+		"punctuation:{",
+		`key:"a"`, "punctuation::",
+		"punctuation:[", "number:1", "punctuation:,", "bool:true", "punctuation:]",
+		"punctuation:,",
+		`key:"b"`, "punctuation::", "null:null",
+		"punctuation:,",
+		`key:"c"`, "punctuation::", `string:"s"`,
+		"punctuation:}",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("unexpected tokens\n%v\n%v", got, want)
+	}
+
+	toks := doc.SemanticTokens()
+	if b := toks[9]; b.Start != (Loc{Row: 2, Col: 2, Offset: 18}) {
+		t.Errorf("unexpected location %+v", b.Start)
+	}
+}
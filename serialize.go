@@ -1,98 +1,415 @@
 package genjson
 
 import (
+	"bytes"
+	"fmt"
+	"math"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
 func (Null) append(s *Serializer, level int, bb []byte) []byte {
-	return append(bb, "null"...)
+	s.progress(bb)
+	return s.appendColored(bb, colorLiteral, "null")
 }
 
 func (b Bool) append(s *Serializer, level int, bb []byte) []byte {
-	return append(bb, strconv.FormatBool(bool(b))...)
+	s.progress(bb)
+	return s.appendColored(bb, colorLiteral, strconv.FormatBool(bool(b)))
 }
 
 func (n Number) append(s *Serializer, level int, bb []byte) []byte {
+	s.progress(bb)
+	if s.IntegerOnly {
+		if i, ok := n.integral(); ok {
+			n = i
+		}
+	}
+	if s.UnsafeIntegersAsStrings && !n.IsSafeInteger() {
+		bb = s.startColor(bb, colorString)
+		bb = append(bb, '"')
+		bb = n.appendNumber(bb)
+		bb = append(bb, '"')
+		return s.endColor(bb, colorString)
+	}
+	bb = s.startColor(bb, colorNumber)
+	bb = n.appendNumber(bb)
+	return s.endColor(bb, colorNumber)
+}
+
+func (n Number) appendNumber(bb []byte) []byte {
 	if n.IsNeg {
 		bb = append(bb, '-')
 	}
+	if n.IsFloat && n.IsExp {
+		start := len(bb)
+		bb = strconv.AppendFloat(bb, n.Float, 'e', -1, 64)
+		// Remove the plus sign and the leading zeros of the exponent, e.g. 1e+05 becomes 1e5.
+		i := start + bytes.IndexByte(bb[start:], 'e') + 1
+		if bb[i] == '-' {
+			i++
+		}
+		digits := bytes.TrimLeft(bb[i:], "+")
+		exp := bytes.TrimLeft(digits, "0")
+		if len(exp) == 0 {
+			exp = digits[len(digits)-1:]
+		}
+		return append(bb[:i], exp...)
+	}
 	if n.IsFloat {
-		s := strconv.FormatFloat(n.Float, 'f', -1, 64)
-		if !strings.Contains(s, ".") {
-			s += ".0"
+		start := len(bb)
+		bb = strconv.AppendFloat(bb, n.Float, 'f', -1, 64)
+		if bytes.IndexByte(bb[start:], '.') < 0 {
+			bb = append(bb, ".0"...)
+		}
+		return bb
+	}
+	return strconv.AppendUint(bb, n.Integer, 10)
+}
+
+func (st String) append(s *Serializer, level int, bb []byte) []byte {
+	s.progress(bb)
+	return s.appendColorString(bb, colorString, string(st))
+}
+
+// appendString appends the json string literal of str. With CanonicalStrings, printable runes are
+// written as is and only characters that must be escaped are, U+2028 and U+2029 are also escaped
+// as they are not valid in javascript string literals and invalid utf-8 is replaced with U+FFFD.
+// Otherwise the characters that strconv.Quote escapes are escaped as it does. In both cases,
+// EscapeNonASCII escapes every non-ascii rune and EscapeHTML escapes <, > and &.
+func appendString(s *Serializer, bb []byte, str string) []byte {
+	bb = append(bb, '"')
+	start := 0
+	for i := 0; i < len(str); {
+		if b := str[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' && !(b == '`' && s.EscapeBackticks) && !(s.EscapeHTML && isHTMLSpecial(b)) &&
+				(b != 0x7f || s.CanonicalStrings) {
+				i++
+				continue
+			}
+			bb = append(bb, str[start:i]...)
+			switch b {
+			case '"', '\\':
+				bb = append(bb, '\\', b)
+			case '\b':
+				bb = append(bb, `\b`...)
+			case '\f':
+				bb = append(bb, `\f`...)
+			case '\n':
+				bb = append(bb, `\n`...)
+			case '\r':
+				bb = append(bb, `\r`...)
+			case '\t':
+				bb = append(bb, `\t`...)
+			default:
+				if s.CanonicalStrings || isHTMLSpecial(b) || b == '`' {
+					bb = appendUnicodeEscape(bb, rune(b))
+				} else {
+					bb = appendQuoted(bb, str[i:i+1])
+				}
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(str[i:])
+		if !s.CanonicalStrings && !s.EscapeNonASCII && ((r == utf8.RuneError && size == 1) || !strconv.IsPrint(r)) {
+			bb = append(bb, str[start:i]...)
+			bb = appendQuoted(bb, str[i:i+size])
+			i += size
+			start = i
+			continue
 		}
-		return append(bb, s...)
+		if (r == utf8.RuneError && size == 1) || r == '\u2028' || r == '\u2029' || s.EscapeNonASCII {
+			bb = append(bb, str[start:i]...)
+			if r1, r2 := utf16.EncodeRune(r); r1 != utf8.RuneError {
+				bb = appendUnicodeEscape(bb, r1)
+				bb = appendUnicodeEscape(bb, r2)
+			} else {
+				bb = appendUnicodeEscape(bb, r)
+			}
+			i += size
+			start = i
+			continue
+		}
+		i += size
 	}
-	return append(bb, strconv.FormatUint(n.Integer, 10)...)
+	bb = append(bb, str[start:]...)
+	return append(bb, '"')
 }
 
-func (s String) append(_ *Serializer, level int, bb []byte) []byte {
-	return appendString(bb, string(s))
+// appendQuoted appends str quoted by strconv.Quote without the surrounding quotes.
+func appendQuoted(bb []byte, str string) []byte {
+	start := len(bb)
+	bb = strconv.AppendQuote(bb, str)
+	return append(bb[:start], bb[start+1:len(bb)-1]...)
 }
 
-func appendString(bb []byte, s string) []byte {
-	return append(bb, strconv.Quote(s)...)
+// isHTMLSpecial reports whether b is escaped by EscapeHTML.
+func isHTMLSpecial(b byte) bool {
+	return b == '<' || b == '>' || b == '&'
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendUnicodeEscape appends the \uXXXX escape of a rune in the basic multilingual plane.
+func appendUnicodeEscape(bb []byte, r rune) []byte {
+	return append(bb, '\\', 'u', hexDigits[r>>12&0xf], hexDigits[r>>8&0xf], hexDigits[r>>4&0xf], hexDigits[r&0xf])
 }
 
 func (a Array) append(s *Serializer, level int, bb []byte) []byte {
-	bb = append(bb, "["...)
+	s.progress(bb)
+	if s.EmptyAsNull && len(a) == 0 {
+		return s.appendColored(bb, colorLiteral, "null")
+	}
+	bb = s.appendColored(bb, colorPunctuation, "[")
+	var after []Comment
 	for i, v := range a {
+		bb = s.flush(bb)
+		if s.stopped() {
+			return bb
+		}
 		if i > 0 {
-			bb = append(bb, ","...)
+			bb = s.appendColored(bb, colorPunctuation, ",")
 		}
+		bb = s.appendAfterComments(bb, after)
+		s.push(Index(i))
+		var before []Comment
+		before, after = s.comments(v)
 		bb = appendIndent(s, level+1, bb)
+		bb = s.appendBeforeComments(bb, level+1, before)
 		bb = v.append(s, level+1, bb)
+		s.pop()
 	}
+	bb = s.appendAfterComments(bb, after)
 	if len(a) > 0 {
 		bb = appendIndent(s, level, bb)
 	}
-	return append(bb, "]"...)
+	return s.appendColored(bb, colorPunctuation, "]")
+}
+
+// objectMember is a member of an object being serialized.
+type objectMember struct {
+	key   string
+	value Value
 }
 
+// objectMembersPool holds the slices that Object.append collects members into before sorting and
+// writing them, so that serializing into a reused buffer does not allocate.
+var objectMembersPool = sync.Pool{New: func() any { return new([]objectMember) }}
+
 func (o Object) append(s *Serializer, level int, bb []byte) []byte {
-	bb = append(bb, "{"...)
-	type keyValue struct {
-		key   string
-		value Value
+	members := objectMembersPool.Get().(*[]objectMember)
+	bb = o.appendMembers(s, level, bb, members)
+	// Drop the references to the values before the slice is reused.
+	for i := range *members {
+		(*members)[i] = objectMember{}
 	}
-	keys := make([]keyValue, 0, o.Len())
+	*members = (*members)[:0]
+	objectMembersPool.Put(members)
+	return bb
+}
+
+// appendMembers serializes the object, collecting its members into the scratch slice.
+func (o Object) appendMembers(s *Serializer, level int, bb []byte, scratch *[]objectMember) []byte {
+	s.progress(bb)
+	keys := (*scratch)[:0]
 	iter := o.Iter()
 	for k, v, ok := iter.Next(); ok; k, v, ok = iter.Next() {
-		keys = append(keys, keyValue{
+		if s.OmitNullKeys && s.isNull(v) {
+			continue
+		}
+		keys = append(keys, objectMember{
 			key:   k,
 			value: v,
 		})
 	}
-	if s.SortKeys {
-		sort.Slice(keys, func(i, j int) bool {
-			return keys[i].key < keys[j].key
+	*scratch = keys
+	if s.EmptyAsNull && len(keys) == 0 {
+		return s.appendColored(bb, colorLiteral, "null")
+	}
+	bb = s.appendColored(bb, colorPunctuation, "{")
+	if less := s.keyLess(); less != nil {
+		sort.SliceStable(keys, func(i, j int) bool {
+			return less(keys[i].key, keys[j].key)
 		})
 	}
+	var after []Comment
 	for i, k := range keys {
+		bb = s.flush(bb)
+		if s.stopped() {
+			return bb
+		}
 		if i > 0 {
-			bb = append(bb, ","...)
+			bb = s.appendColored(bb, colorPunctuation, ",")
 		}
-
-		i++
+		bb = s.appendAfterComments(bb, after)
+		s.push(Key(k.key))
+		var before []Comment
+		before, after = s.comments(k.value)
 		bb = appendIndent(s, level+1, bb)
-		bb = appendString(bb, k.key)
-		bb = append(bb, ":"...)
-		bb = append(bb, strings.Repeat(" ", s.KeyValueGap)...)
+		bb = s.appendBeforeComments(bb, level+1, before)
+		bb = s.appendColorString(bb, colorKey, k.key)
+		bb = s.appendColored(bb, colorPunctuation, ":")
+		bb = appendRepeat(bb, " ", s.KeyValueGap)
 		bb = k.value.append(s, level+1, bb)
+		s.pop()
 	}
+	bb = s.appendAfterComments(bb, after)
 	if len(keys) > 0 {
 		bb = appendIndent(s, level, bb)
 	}
-	return append(bb, "}"...)
+	return s.appendColored(bb, colorPunctuation, "}")
+}
+
+// isNull reports whether the value is serialized as null.
+func (s *Serializer) isNull(v Value) bool {
+	switch v := v.(type) {
+	case Null:
+		return true
+	case Extension:
+		return v.appendsNull()
+	case Array:
+		return s.EmptyAsNull && len(v) == 0
+	case Object:
+		if !s.EmptyAsNull {
+			return false
+		}
+		if !s.OmitNullKeys {
+			return v.Len() == 0
+		}
+		iter := v.Iter()
+		for _, vv, ok := iter.Next(); ok; _, vv, ok = iter.Next() {
+			if !s.isNull(vv) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// stopped reports whether done has been closed or writing to out has failed.
+func (s *Serializer) stopped() bool {
+	if s.out != nil && s.out.err != nil {
+		return true
+	}
+	if s.done == nil {
+		return false
+	}
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *Serializer) push(step PathStep) {
+	if s.Comments != nil && s.state != nil {
+		s.state.path = append(s.state.path, step)
+	}
+}
+
+func (s *Serializer) pop() {
+	if s.Comments != nil && s.state != nil {
+		s.state.path = s.state.path[:len(s.state.path)-1]
+	}
+}
+
+// comments returns the comments of the value at the current path, split by position.
+func (s *Serializer) comments(v Value) (before, after []Comment) {
+	if s.Comments == nil || s.state == nil {
+		return nil, nil
+	}
+	for _, c := range s.Comments(s.state.path.Append(), v) {
+		if c.Position == CommentAfter {
+			after = append(after, c)
+		} else {
+			before = append(before, c)
+		}
+	}
+	return before, after
+}
+
+func (s *Serializer) blockComments() bool {
+	return s.CommentStyle == CommentBlock || !s.multiline()
+}
+
+// appendBeforeComments appends the comments, each followed by a new line at the level.
+func (s *Serializer) appendBeforeComments(bb []byte, level int, comments []Comment) []byte {
+	for _, c := range comments {
+		bb = s.appendComment(bb, level, c.Text)
+		if !s.multiline() {
+			bb = append(bb, ' ')
+		}
+		bb = appendIndent(s, level, bb)
+	}
+	return bb
+}
+
+// appendAfterComments appends the comments to the current line.
+func (s *Serializer) appendAfterComments(bb []byte, comments []Comment) []byte {
+	for _, c := range comments {
+		bb = append(bb, ' ')
+		bb = s.appendComment(bb, -1, c.Text)
+	}
+	return bb
+}
+
+// appendComment appends a single comment. Line comments containing new lines are written as
+// multiple line comments at the level, or as a block comment if level is negative as the comment
+// is followed by other content on the same line.
+func (s *Serializer) appendComment(bb []byte, level int, text string) []byte {
+	if s.blockComments() || (level < 0 && strings.Contains(text, "\n")) {
+		bb = append(bb, "/* "...)
+		bb = append(bb, strings.ReplaceAll(text, "*/", "* /")...)
+		return append(bb, " */"...)
+	}
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			bb = appendIndent(s, level, bb)
+		}
+		bb = append(bb, "// "...)
+		bb = append(bb, line...)
+	}
+	return bb
 }
 
 func appendIndent(s *Serializer, level int, bb []byte) []byte {
-	if s.Indent != 0 {
+	if s.multiline() {
 		bb = append(bb, "\n"...)
-		bb = append(bb, strings.Repeat(" ", s.Prefix)...)
-		bb = append(bb, strings.Repeat(" ", s.Indent*level)...)
+		bb = s.appendPrefix(bb)
+		if s.IndentString != "" {
+			bb = appendRepeat(bb, s.IndentString, level)
+		} else {
+			bb = appendRepeat(bb, " ", s.Indent*level)
+		}
+	}
+	return bb
+}
+
+// multiline reports whether values are written on separate lines.
+func (s *Serializer) multiline() bool {
+	return s.Indent > 0 || s.IndentString != ""
+}
+
+// appendPrefix appends the prefix written at the start of every line.
+func (s *Serializer) appendPrefix(bb []byte) []byte {
+	if s.PrefixString != "" {
+		return append(bb, s.PrefixString...)
+	}
+	return appendRepeat(bb, " ", s.Prefix)
+}
+
+// appendRepeat appends n copies of str without allocating a temporary string.
+func appendRepeat(bb []byte, str string, n int) []byte {
+	for i := 0; i < n; i++ {
+		bb = append(bb, str...)
 	}
 	return bb
 }
@@ -102,18 +419,351 @@ type Serializer struct {
 	Prefix      int
 	KeyValueGap int
 	SortKeys    bool
+	// KeyLess, if set, sorts the keys of objects in the order it defines instead of the byte order
+	// used by SortKeys, which it implies. It must be a strict weak ordering, such as NaturalKeyLess
+	// or FoldKeyLess. Entries with equal keys keep their relative order.
+	KeyLess func(a, b string) bool
+	// IndentString, if set, is written once per level instead of Indent spaces, and values are
+	// written on separate lines even if Indent is 0. It must only contain json whitespace, see
+	// Validate.
+	IndentString string
+	// PrefixString, if set, is written at the start of every line instead of Prefix spaces. Like
+	// IndentString, it must only contain json whitespace.
+	PrefixString string
+	// Progress, if set, is called every ProgressInterval values with the number of bytes written
+	// and values serialized so far. It is called once more when serialization finishes. This
+	// allows long running serialization of large values to report progress.
+	Progress func(bytes, values int)
+	// ProgressInterval is the number of values between calls to Progress. If 0, a default of 1000
+	// is used.
+	ProgressInterval int
+	// UnsafeIntegersAsStrings writes integers that cannot be represented exactly by a javascript
+	// number as strings. See MaxSafeInteger.
+	UnsafeIntegersAsStrings bool
+	// EscapeBackticks escapes backticks in strings as \u0060 so that the output can be pasted
+	// into a go raw string literal.
+	EscapeBackticks bool
+	// Colors, if set, highlights the elements of the output with ANSI escape sequences for display
+	// in a terminal, e.g. DefaultColors. The output is not valid json.
+	Colors *Colors
+	// EscapeHTML escapes <, > and & in strings as \u003c, \u003e and \u0026 so that the output can
+	// be embedded in html, as encoding/json does by default.
+	EscapeHTML bool
+	// EscapeNonASCII escapes every non-ascii rune in strings as \uXXXX, using a surrogate pair for
+	// runes outside of the basic multilingual plane, so that the output is pure ascii.
+	EscapeNonASCII bool
+	// CanonicalStrings writes strings with canonical json escaping: printable runes are written as
+	// is, control characters are escaped as \uXXXX and invalid utf-8 is replaced with U+FFFD. By
+	// default strings are escaped as strconv.Quote does, which also escapes non-printable runes and
+	// may use go escapes, such as \x00 and \a, that are not valid json.
+	CanonicalStrings bool
+	// Comments, if set, is called for every value with its path and returns the comments written
+	// with the value. Comments are written with CommentStyle, except that block comments are
+	// always used if values are not written on separate lines.
+	Comments func(path Path, v Value) []Comment
+	// CommentStyle is the style of the comments returned by Comments.
+	CommentStyle CommentStyle
+	// OmitNullKeys omits object entries whose value is serialized as null, instead of writing
+	// "key": null.
+	OmitNullKeys bool
+	// EmptyAsNull writes empty arrays and objects as null.
+	EmptyAsNull bool
+	// IntegerOnly writes floats without a fractional part as integers, so that integral values
+	// are never written with a trailing .0. Floats with a fractional part are not allowed and are
+	// reported by Check, which Write and the other writers call. Serialize and Append write them
+	// unchanged, so values from untrusted sources should be checked first.
+	IntegerOnly bool
+	// RequireContainer rejects top-level values that are not objects or arrays, as required by
+	// RFC 4627. Like IntegerOnly, it is enforced by Check and the writers and not by Serialize.
+	RequireContainer bool
+	// Allocator, if set, provides the buffers returned by Serialize and used by Write. See
+	// Allocator.
+	Allocator Allocator
+
+	state *serializeState
+	// done, if set, stops serialization between the elements of arrays and objects once it is
+	// closed. The output is incomplete in that case.
+	done <-chan struct{}
+	// out, if set, receives the output in chunks between the elements of arrays and objects, see
+	// Write.
+	out *serializeOutput
+}
+
+// serializeState is the state of a single call to Serialize.
+type serializeState struct {
+	values int
+	// start is the length of the buffer before serialization started.
+	start int
+	// path is the path of the value being serialized. It is only maintained if Comments is set.
+	path Path
 }
 
+// CommentStyle is the syntax used to write comments.
+type CommentStyle int8
+
+const (
+	// CommentLine writes comments starting with //.
+	CommentLine CommentStyle = iota
+	// CommentBlock writes comments enclosed by /* and */.
+	CommentBlock
+)
+
+// CommentPosition is the position of a comment relative to the value it is attached to.
+type CommentPosition int8
+
+const (
+	// CommentBefore writes the comment on the line before the value, or before its key for object
+	// entries.
+	CommentBefore CommentPosition = iota
+	// CommentAfter writes the comment at the end of the line of the value.
+	CommentAfter
+)
+
+// Comment is a comment attached to a value when serializing. Output containing comments is not
+// valid json, but is accepted by parsers of json with comments such as JSONC and JSON5.
+type Comment struct {
+	Text     string
+	Position CommentPosition
+}
+
+const defaultProgressInterval = 1000
+
 var defSerializer Serializer
 
+// InvalidOptionError is returned by Serializer.Validate for options with invalid values.
+type InvalidOptionError struct {
+	Option string
+	Value  int
+}
+
+func (e InvalidOptionError) Error() string {
+	return fmt.Sprintf("invalid serializer option %s: %d must not be negative", e.Option, e.Value)
+}
+
+// InvalidWhitespaceError is returned by Serializer.Validate for an IndentString or PrefixString
+// that contains characters other than json whitespace.
+type InvalidWhitespaceError struct {
+	Option string
+	Value  string
+}
+
+func (e InvalidWhitespaceError) Error() string {
+	return fmt.Sprintf("invalid serializer option %s: %q must only contain json whitespace", e.Option, e.Value)
+}
+
+// Validate checks that the options of the serializer are valid. The methods that return errors,
+// such as Write and Check, return this error if the serializer is invalid. Serialize and Append
+// cannot return errors, so they write negative options as 0 and IndentString and PrefixString as
+// they are.
+func (s *Serializer) Validate() error {
+	for _, opt := range []struct {
+		name  string
+		value int
+	}{
+		{"Indent", s.Indent},
+		{"Prefix", s.Prefix},
+		{"KeyValueGap", s.KeyValueGap},
+		{"ProgressInterval", s.ProgressInterval},
+	} {
+		if opt.value < 0 {
+			return InvalidOptionError{Option: opt.name, Value: opt.value}
+		}
+	}
+	for _, opt := range []struct {
+		name  string
+		value string
+	}{
+		{"IndentString", s.IndentString},
+		{"PrefixString", s.PrefixString},
+	} {
+		if strings.Trim(opt.value, spaceChars) != "" {
+			return InvalidWhitespaceError{Option: opt.name, Value: opt.value}
+		}
+	}
+	return nil
+}
+
+// FractionalNumberError is returned by Serializer.Check for a number with a fractional part when
+// the serializer only allows integers.
+type FractionalNumberError struct {
+	// Path is the path of the number within the checked value.
+	Path   Path
+	Number Number
+}
+
+func (e FractionalNumberError) Error() string {
+	return fmt.Sprintf("number %s at path %s is not an integer",
+		e.Number.appendNumber(nil),
+		e.Path)
+}
+
+// IntegerRangeError is returned by Serializer.Check for an integral float that is too large to be
+// written as an integer when the serializer only allows integers.
+type IntegerRangeError struct {
+	// Path is the path of the number within the checked value.
+	Path   Path
+	Number Number
+}
+
+func (e IntegerRangeError) Error() string {
+	return fmt.Sprintf("number %s at path %s is out of the range of integers",
+		e.Number.appendNumber(nil),
+		e.Path)
+}
+
+// Check checks that the options of the serializer are valid and that the value can be serialized
+// according to them. This rejects top-level values that are not objects or arrays with
+// ErrTopLevelScalar if RequireContainer is set, and floats with a fractional part with a
+// FractionalNumberError if IntegerOnly is set. Integral floats too large to be written as integers
+// are rejected with an IntegerRangeError.
+//
+// Write, Encoder, AppendMember and AppendElement check their values, while Serialize and Append
+// do not.
+func (s *Serializer) Check(v Value) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	if s.RequireContainer {
+		switch v.(type) {
+		case Array, Object:
+		default:
+			return ErrTopLevelScalar
+		}
+	}
+	return s.checkIntegers(v)
+}
+
+// checkIntegers checks that every number of the value can be written as an integer if IntegerOnly
+// is set.
+func (s *Serializer) checkIntegers(v Value) error {
+	if !s.IntegerOnly {
+		return nil
+	}
+	var check func(v Value, path Path) error
+	check = func(v Value, path Path) error {
+		switch v := v.(type) {
+		case Number:
+			return checkInteger(v, path)
+		case Array:
+			for i, elem := range v {
+				if err := check(elem, append(path, Index(i))); err != nil {
+					return err
+				}
+			}
+		case Object:
+			iter := v.Iter()
+			for k, vv, ok := iter.Next(); ok; k, vv, ok = iter.Next() {
+				if err := check(vv, append(path, Key(k))); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return check(v, nil)
+}
+
+// checkInteger returns an error if the number at path cannot be written as an integer.
+func checkInteger(n Number, path Path) error {
+	if _, ok := n.integral(); ok {
+		return nil
+	}
+	if n.Float == math.Trunc(n.Float) {
+		return IntegerRangeError{Path: path.Append(), Number: n}
+	}
+	return FractionalNumberError{Path: path.Append(), Number: n}
+}
+
+// integral returns the number as an integer if it does not have a fractional part and is within
+// the range of uint64.
+func (n Number) integral() (Number, bool) {
+	if !n.IsFloat {
+		return n, true
+	}
+	if n.Float != math.Trunc(n.Float) || n.Float >= math.MaxUint64 {
+		return n, false
+	}
+	i := Number{Integer: uint64(n.Float)}
+	i.IsNeg = n.IsNeg && i.Integer != 0
+	return i, true
+}
+
 func (s *Serializer) Serialize(v Value) []byte {
-	buf := make([]byte, 0, 1024)
-	buf = append(buf, strings.Repeat(" ", s.Prefix)...)
-	buf = v.append(s, 0, buf)
+	if a, ok := s.Allocator.(*Arena); ok {
+		buf := s.appendValue(a.rest(), v)
+		a.reserve(buf)
+		return buf[:len(buf):len(buf)]
+	}
+	buf := s.appendValue(s.allocator().Bytes(1024), v)
 	buf = buf[:len(buf):len(buf)]
 	return buf
 }
 
+// MustSerialize is like Serialize but panics if Check fails, such as for a fractional number with
+// IntegerOnly set. It is intended for tests and fixtures where the value is known to be valid.
+func (s *Serializer) MustSerialize(v Value) []byte {
+	if err := s.Check(v); err != nil {
+		panic(err)
+	}
+	return s.Serialize(v)
+}
+
+// Append appends the serialized value to dst and returns the extended buffer. Unlike Serialize,
+// it only allocates when dst does not have enough capacity, so that hot paths can reuse a buffer
+// across calls:
+//
+//	buf = s.Append(buf[:0], v)
+//
+// The guarantee is amortised: the scratch space used for objects is pooled and the pool may be
+// emptied by a garbage collection, after which the next call allocates it again.
+//
+// Sorting keys with SortKeys or KeyLess, and tracking the path with Comments or Progress, still
+// allocate.
+func (s *Serializer) Append(dst []byte, v Value) []byte {
+	return s.appendValue(dst, v)
+}
+
+func (s *Serializer) appendValue(buf []byte, v Value) []byte {
+	buf = s.appendPrefix(buf)
+	return s.appendLevel(buf, v, 0)
+}
+
+// appendLevel appends the value as if it was nested level deep. The serializer must be valid.
+func (s *Serializer) appendLevel(buf []byte, v Value, level int) []byte {
+	if s.Progress != nil || s.Comments != nil {
+		// Copy the serializer so that the state is local to this call.
+		s2 := *s
+		s2.state = &serializeState{start: len(buf)}
+		s = &s2
+	}
+	var before, after []Comment
+	if level == 0 {
+		before, after = s.comments(v)
+		buf = s.appendBeforeComments(buf, level, before)
+	}
+	buf = v.append(s, level, buf)
+	buf = s.appendAfterComments(buf, after)
+	if s.Progress != nil {
+		s.Progress(len(buf)-s.state.start, s.state.values)
+	}
+	return buf
+}
+
+// progress records that a value is being serialized, calling the Progress callback if required.
+func (s *Serializer) progress(bb []byte) {
+	if s.Progress == nil {
+		return
+	}
+	s.state.values++
+	interval := s.ProgressInterval
+	if interval <= 0 {
+		interval = defaultProgressInterval
+	}
+	if s.state.values%interval == 0 {
+		s.Progress(len(bb)-s.state.start, s.state.values)
+	}
+}
+
 func Serialize(v Value) []byte {
 	return defSerializer.Serialize(v)
 }
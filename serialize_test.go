@@ -0,0 +1,430 @@
+package genjson
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSerializeString(t *testing.T) {
+	tests := []struct {
+		name  string
+		s     Serializer
+		value String
+		want  string
+	}{
+		{name: "empty", value: "", want: `""`},
+		{name: "plain", value: "abc", want: `"abc"`},
+		{name: "quotes", value: `a"b\c`, want: `"a\"b\\c"`},
+		{name: "short-escapes", value: "\b\f\n\r\t", want: `"\b\f\n\r\t"`},
+		{name: "control", value: "\x00\x07\x1f\x7f", want: `"\x00\a\x1f\x7f"`},
+		{name: "control-canonical", s: Serializer{CanonicalStrings: true}, value: "\x00\x07\x1f\x7f", want: `"\u0000\u0007\u001f` + "\x7f" + `"`},
+		{name: "slash", value: "a/b", want: `"a/b"`},
+		{name: "non-ascii", value: "héllo 世界 😀", want: `"héllo 世界 😀"`},
+		{name: "non-printable", value: "a\u00a0\U000e0001", want: `"a\u00a0\U000e0001"`},
+		{name: "non-printable-canonical", s: Serializer{CanonicalStrings: true}, value: "a\u00a0", want: "\"a\u00a0\""},
+		{name: "line-separators", value: "\u2028\u2029", want: `"\u2028\u2029"`},
+		{name: "line-separators-canonical", s: Serializer{CanonicalStrings: true}, value: "\u2028\u2029", want: `"\u2028\u2029"`},
+		{name: "invalid-utf8", value: "a\xffb", want: `"a\xffb"`},
+		{name: "invalid-utf8-canonical", s: Serializer{CanonicalStrings: true}, value: "a\xffb", want: `"a\ufffdb"`},
+		{
+			name:  "escape-non-ascii",
+			s:     Serializer{EscapeNonASCII: true, CanonicalStrings: true},
+			value: "héllo 世界 😀\u2028\x7f\xff\n",
+			want:  `"h\u00e9llo \u4e16\u754c \ud83d\ude00\u2028` + "\x7f" + `\ufffd\n"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(tt.s.Serialize(tt.value)); got != tt.want {
+				t.Errorf("unexpected output %s != %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSerializeProgress(t *testing.T) {
+	type call struct{ bytes, values int }
+	var calls []call
+	s := Serializer{
+		Progress: func(bytes, values int) {
+			calls = append(calls, call{bytes, values})
+		},
+		ProgressInterval: 2,
+	}
+	// 1 array + 3 numbers.
+	out := s.Serialize(Array{integer(1), integer(2), integer(3)})
+	want := []call{{1, 2}, {5, 4}, {len(out), 4}}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("unexpected progress calls %v != %v", calls, want)
+	}
+}
+
+func TestAppend(t *testing.T) {
+	buf := []byte("data=")
+	buf = (&Serializer{}).Append(buf, Array{integer(1)})
+	buf = (&Serializer{Indent: 1}).Append(buf, Array{integer(2)})
+	if want := "data=[1][\n 2\n]"; string(buf) != want {
+		t.Errorf("unexpected output %q != %q", buf, want)
+	}
+}
+
+func TestSerializerAppend(t *testing.T) {
+	s := Serializer{Indent: 1}
+	var v Value = Array{
+		integer(1), String("a"), Bool(true), Null{}, floatNumber(1.5), Number{Float: 2.5e-10, IsFloat: true, IsExp: true},
+		MustDeserializeString(`{"a": [1, {"b": null}], "c": {}}`),
+	}
+	buf := s.Append(nil, v)
+	if want := string(s.Serialize(v)); string(buf) != want {
+		t.Fatalf("unexpected output %q != %q", buf, want)
+	}
+	if raceEnabled {
+		t.Skip("allocations are not counted with the race detector")
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		buf = s.Append(buf[:0], v)
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations when reusing the buffer but got %v", allocs)
+	}
+}
+
+func TestSerializerValidate(t *testing.T) {
+	if err := (&Serializer{Indent: 2}).Validate(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	s := Serializer{KeyValueGap: -1}
+	if err := s.Validate(); err != (InvalidOptionError{Option: "KeyValueGap", Value: -1}) {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := s.Write(io.Discard, Null{}); err != (InvalidOptionError{Option: "KeyValueGap", Value: -1}) {
+		t.Errorf("unexpected write error %v", err)
+	}
+	if err := (&Serializer{IndentString: "\t \r\n", PrefixString: " "}).Validate(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := (&Serializer{IndentString: "--"}).Validate(); err != (InvalidWhitespaceError{Option: "IndentString", Value: "--"}) {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := (&Serializer{PrefixString: "\v"}).Validate(); err != (InvalidWhitespaceError{Option: "PrefixString", Value: "\v"}) {
+		t.Errorf("unexpected error %v", err)
+	}
+	// Methods that cannot return errors write negative options as 0 rather than panicking.
+	v := MustDeserializeString(`{"a": [1]}`)
+	s = Serializer{Indent: -1, Prefix: -2, KeyValueGap: -1, ProgressInterval: -1, Progress: func(int, int) {}}
+	if got := string(s.Serialize(v)); got != `{"a":[1]}` {
+		t.Errorf("unexpected output %s", got)
+	}
+	if got := string(s.Append(nil, v)); got != `{"a":[1]}` {
+		t.Errorf("unexpected output %s", got)
+	}
+	doc, _ := DeserializeDocument([]byte(`{"a": [1,  2]}`))
+	if got := string(FormatRange(doc, Loc{Offset: 6}, Loc{Offset: 13}, &s)); got != `{"a": [1,2]}` {
+		t.Errorf("unexpected output %s", got)
+	}
+}
+
+func TestSerializeNullOptions(t *testing.T) {
+	v := MustDeserializeString(`{"a": null, "b": [], "c": {"d": null}, "e": [null, {}], "f": 1}`)
+	tests := []struct {
+		name       string
+		serializer Serializer
+		want       string
+	}{
+		{
+			name: "default",
+			want: `{"a":null,"b":[],"c":{"d":null},"e":[null,{}],"f":1}`,
+		},
+		{
+			name:       "omit-null-keys",
+			serializer: Serializer{OmitNullKeys: true},
+			want:       `{"b":[],"c":{},"e":[null,{}],"f":1}`,
+		},
+		{
+			name:       "empty-as-null",
+			serializer: Serializer{EmptyAsNull: true},
+			want:       `{"a":null,"b":null,"c":{"d":null},"e":[null,null],"f":1}`,
+		},
+		{
+			name:       "both",
+			serializer: Serializer{OmitNullKeys: true, EmptyAsNull: true},
+			want:       `{"e":[null,null],"f":1}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(tt.serializer.Serialize(v)); got != tt.want {
+				t.Errorf("unexpected output %s != %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSerializeIntegerOnly(t *testing.T) {
+	s := Serializer{IntegerOnly: true}
+	v := MustDeserializeString(`{"a": 1.0, "b": -2.0, "c": -0.0, "d": [3, 1000.0]}`)
+	if err := s.Check(v); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if got := string(s.Serialize(v)); got != `{"a":1,"b":-2,"c":0,"d":[3,1000]}` {
+		t.Errorf("unexpected output %s", got)
+	}
+
+	err := s.Check(MustDeserializeString(`{"a": [1, 2.5]}`))
+	var fne FractionalNumberError
+	if !errors.As(err, &fne) || !reflect.DeepEqual(fne.Path, Path{Key("a"), Index(1)}) {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := (&Serializer{}).Check(MustDeserializeString(`2.5`)); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// Integral floats beyond uint64 are integers, but cannot be written as one.
+	err = s.Check(Array{floatNumber(1e20)})
+	var ire IntegerRangeError
+	if !errors.As(err, &ire) || !reflect.DeepEqual(ire.Path, Path{Index(0)}) {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// The writers check their values and write nothing if the check fails.
+	var buf bytes.Buffer
+	if err := s.Write(&buf, Array{floatNumber(2.5)}); !errors.As(err, &fne) || buf.Len() != 0 {
+		t.Errorf("unexpected result %q %v", buf.String(), err)
+	}
+	obj := []byte(`{"a":1}`)
+	if got, err := s.AppendMember(obj, "b", floatNumber(2.5)); !errors.As(err, &fne) || string(got) != `{"a":1}` {
+		t.Errorf("unexpected result %s %v", got, err)
+	}
+	if _, err := s.AppendElement([]byte(`[]`), floatNumber(1e20)); !errors.As(err, &ire) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestSerializeRequireContainer(t *testing.T) {
+	s := Serializer{RequireContainer: true}
+	if err := s.Check(Array{integer(1)}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := s.Check(integer(1)); err != ErrTopLevelScalar {
+		t.Errorf("unexpected error %v", err)
+	}
+	var out bytes.Buffer
+	if err := s.Write(&out, integer(1)); err != ErrTopLevelScalar || out.Len() != 0 {
+		t.Errorf("unexpected result %q %v", out.String(), err)
+	}
+
+	m := Marshaler{Serializer: s}
+	if _, err := m.Marshal("a"); err != ErrTopLevelScalar {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// Values written by an array encoder are elements of its array.
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.Serializer = s
+	if err := enc.Encode(integer(1)); err != ErrTopLevelScalar {
+		t.Errorf("unexpected error %v", err)
+	}
+	enc.Separator = SeparatorArray
+	if err := enc.Encode(integer(1)); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestSerializeComments(t *testing.T) {
+	v := MustDeserializeString(`{"port": 8080, "hosts": ["a", "b"], "tls": {}}`)
+	comments := func(path Path, v Value) []Comment {
+		switch path.String() {
+		case "":
+			return []Comment{{Text: "generated config"}}
+		case "port":
+			return []Comment{{Text: "listen port\nmust be free"}, {Text: "default", Position: CommentAfter}}
+		case "hosts[1]":
+			return []Comment{{Text: "backup", Position: CommentAfter}}
+		case "tls":
+			return []Comment{{Text: "tls */ settings"}}
+		}
+		return nil
+	}
+	tests := []struct {
+		name       string
+		serializer Serializer
+		want       string
+	}{
+		{
+			name:       "line",
+			serializer: Serializer{Indent: 2, KeyValueGap: 1, Comments: comments},
+			want: `// generated config
+{
+  // listen port
+  // must be free
+  "port": 8080, // default
+  "hosts": [
+    "a",
+    "b" // backup
+  ],
+  // tls */ settings
+  "tls": {}
+}`,
+		},
+		{
+			name:       "block",
+			serializer: Serializer{Indent: 2, Comments: comments, CommentStyle: CommentBlock},
+			want: `/* generated config */
+{
+  /* listen port
+must be free */
+  "port":8080, /* default */
+  "hosts":[
+    "a",
+    "b" /* backup */
+  ],
+  /* tls * / settings */
+  "tls":{}
+}`,
+		},
+		{
+			name:       "compact",
+			serializer: Serializer{Comments: comments},
+			want:       `/* generated config */ {/* listen port` + "\n" + `must be free */ "port":8080, /* default */"hosts":["a","b" /* backup */],/* tls * / settings */ "tls":{}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(tt.serializer.Serialize(v)); got != tt.want {
+				t.Errorf("unexpected output\n%s\n!=\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSerializeIndentString(t *testing.T) {
+	v := MustDeserializeString(`{"a": [1, {}], "b": {"c": null}}`)
+	tests := []struct {
+		name string
+		s    Serializer
+		want string
+	}{
+		{
+			name: "tabs",
+			s:    Serializer{IndentString: "\t", KeyValueGap: 1},
+			want: "{\n\t\"a\": [\n\t\t1,\n\t\t{}\n\t],\n\t\"b\": {\n\t\t\"c\": null\n\t}\n}",
+		},
+		{
+			name: "prefix-string",
+			s:    Serializer{IndentString: " \t", PrefixString: "\t\t", Indent: 8},
+			want: "\t\t{\n\t\t \t\"a\":[\n\t\t \t \t1,\n\t\t \t \t{}\n\t\t \t],\n\t\t \t\"b\":{\n\t\t \t \t\"c\":null\n\t\t \t}\n\t\t}",
+		},
+		{
+			name: "prefix-string-compact",
+			s:    Serializer{PrefixString: "\t"},
+			want: "\t{\"a\":[1,{}],\"b\":{\"c\":null}}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(tt.s.Serialize(v)); got != tt.want {
+				t.Errorf("unexpected output %q != %q", got, tt.want)
+			}
+			var buf bytes.Buffer
+			if err := (&Marshaler{Serializer: tt.s}).Write(&buf, v); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("unexpected Marshaler.Write output %q != %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+// countWriter records the writes made to it.
+type countWriter struct {
+	bytes.Buffer
+	writes int
+	max    int
+	// fail, if non-zero, fails the write with that number.
+	fail int
+}
+
+func (w *countWriter) Write(b []byte) (int, error) {
+	w.writes++
+	if w.writes == w.fail {
+		return 0, errors.New("write failed")
+	}
+	if len(b) > w.max {
+		w.max = len(b)
+	}
+	return w.Buffer.Write(b)
+}
+
+func TestSerializerWrite(t *testing.T) {
+	large := make(Array, 5000)
+	for i := range large {
+		var o Object
+		o.Add("id", integer(uint64(i)))
+		o.Add("name", String(strings.Repeat("x", i%40)))
+		o.Add("tags", Array{String("a"), Null{}})
+		large[i] = o
+	}
+	serializers := map[string]Serializer{
+		"compact":  {},
+		"pretty":   {Indent: 2, KeyValueGap: 1, Prefix: 1},
+		"options":  {SortKeys: true, OmitNullKeys: true, EscapeNonASCII: true},
+		"comments": {Indent: 2, Comments: func(path Path, v Value) []Comment { return []Comment{{Text: path.String()}} }},
+	}
+	for name, s := range serializers {
+		t.Run(name, func(t *testing.T) {
+			var w countWriter
+			if err := s.Write(&w, large); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			want := s.Serialize(large)
+			if !bytes.Equal(w.Bytes(), want) {
+				t.Fatalf("unexpected output")
+			}
+			if w.writes < 2 || w.max >= len(want) {
+				t.Errorf("expected chunked writes but got %d writes of at most %d bytes", w.writes, w.max)
+			}
+		})
+	}
+	t.Run("progress", func(t *testing.T) {
+		var gotBytes int
+		s := Serializer{Progress: func(bytes, values int) { gotBytes = bytes }}
+		var w countWriter
+		if err := s.Write(&w, large); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if gotBytes != w.Len() {
+			t.Errorf("unexpected progress %d for %d bytes", gotBytes, w.Len())
+		}
+	})
+	t.Run("serialize-to", func(t *testing.T) {
+		var w countWriter
+		if err := new(Marshaler).Write(&w, []Value{large}); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if w.writes < 2 || !bytes.Equal(w.Bytes(), Serialize(Array{large})) {
+			t.Errorf("unexpected output in %d writes", w.writes)
+		}
+	})
+	t.Run("write-error", func(t *testing.T) {
+		w := countWriter{fail: 2}
+		if err := defSerializer.Write(&w, large); err == nil || err.Error() != "write failed" {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if w.writes != 2 {
+			t.Errorf("expected serialization to stop after the failed write but got %d writes", w.writes)
+		}
+	})
+	t.Run("invalid", func(t *testing.T) {
+		s := Serializer{Indent: -1}
+		var ioe InvalidOptionError
+		if err := s.Write(&countWriter{}, large); !errors.As(err, &ioe) {
+			t.Errorf("unexpected error %v", err)
+		}
+	})
+}
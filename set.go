@@ -0,0 +1,78 @@
+package genjson
+
+// equalitySerializer serializes extensions for comparison, as their data is opaque. Object keys
+// are sorted, keeping the order of duplicate keys, and floats without a fractional part are
+// written as integers.
+var equalitySerializer = Serializer{SortKeys: true, IntegerOnly: true, CanonicalStrings: true}
+
+// valueSet is a set of values using the equality of Equal.
+type valueSet map[uint64][]Value
+
+// add adds the value to the set, reporting whether it was not already present.
+func (s valueSet) add(v Value) bool {
+	if s.has(v) {
+		return false
+	}
+	h := Hash(v)
+	s[h] = append(s[h], v)
+	return true
+}
+
+func (s valueSet) has(v Value) bool {
+	for _, elem := range s[Hash(v)] {
+		if Equal(elem, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains reports whether the array contains a value equal to v. Values are compared with Equal,
+// so objects are equal if they have the same entries, regardless of the order of different keys,
+// and numbers are equal if they have the same value, so 1, 1.0 and 1e0 are equal.
+func (a Array) Contains(v Value) bool {
+	for _, elem := range a {
+		if Equal(elem, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unique returns the elements of the array with duplicates removed, keeping the first of each set
+// of equal elements. See Contains for the definition of equality.
+func (a Array) Unique() Array {
+	return a.Union(nil)
+}
+
+// Union returns the unique elements of a followed by the unique elements of b that are not in a.
+// See Contains for the definition of equality.
+func (a Array) Union(b Array) Array {
+	seen := make(valueSet, len(a)+len(b))
+	out := Array{}
+	for _, arr := range []Array{a, b} {
+		for _, elem := range arr {
+			if seen.add(elem) {
+				out = append(out, elem)
+			}
+		}
+	}
+	return out
+}
+
+// Intersect returns the unique elements of a that are also in b, in the order of a. See Contains
+// for the definition of equality.
+func (a Array) Intersect(b Array) Array {
+	inB := make(valueSet, len(b))
+	for _, elem := range b {
+		inB.add(elem)
+	}
+	seen := make(valueSet, len(a))
+	out := Array{}
+	for _, elem := range a {
+		if inB.has(elem) && seen.add(elem) {
+			out = append(out, elem)
+		}
+	}
+	return out
+}
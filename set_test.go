@@ -0,0 +1,38 @@
+package genjson
+
+import "testing"
+
+func TestArraySetOperations(t *testing.T) {
+	a := MustDeserializeString(`[1, {"a": 1, "b": [2]}, "x", 1.0, {"b": [2], "a": 1}, null]`).(Array)
+	b := MustDeserializeString(`[null, 2, {"b": [2], "a": 1.0}, "y"]`).(Array)
+	tests := []struct {
+		name string
+		got  Value
+		want string
+	}{
+		{name: "unique", got: a.Unique(), want: `[1,{"a":1,"b":[2]},"x",null]`},
+		{name: "union", got: a.Union(b), want: `[1,{"a":1,"b":[2]},"x",null,2,"y"]`},
+		{name: "intersect", got: a.Intersect(b), want: `[{"a":1,"b":[2]},null]`},
+		{name: "intersect-empty", got: a.Intersect(nil), want: `[]`},
+		{name: "unique-empty", got: Array(nil).Unique(), want: `[]`},
+		{name: "unique-number-formats", got: MustDeserializeString(`[0.0015, 1.5e-3, 15e-4, 2, 2e0, -0, 0.0]`).(Array).Unique(), want: `[0.0015,2,-0]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(Serialize(tt.got)); got != tt.want {
+				t.Errorf("unexpected result %s != %s", got, tt.want)
+			}
+		})
+	}
+
+	for _, v := range []Value{Number{Float: 1, IsFloat: true}, MustDeserializeString(`{"b":[2],"a":1e0}`), Null{}} {
+		if !a.Contains(v) {
+			t.Errorf("expected %s to be contained", Serialize(v))
+		}
+	}
+	for _, v := range []Value{String("y"), MustDeserializeString(`{"a": 1}`), Bool(false)} {
+		if a.Contains(v) {
+			t.Errorf("expected %s not to be contained", Serialize(v))
+		}
+	}
+}
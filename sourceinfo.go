@@ -0,0 +1,146 @@
+package genjson
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Encoding is the character encoding of a json source.
+type Encoding int8
+
+const (
+	// EncodingUTF8 is utf-8, the only encoding permitted for json exchanged between systems by
+	// RFC 8259 and the only encoding accepted by the deserializer.
+	EncodingUTF8 Encoding = iota
+	EncodingUTF16LE
+	EncodingUTF16BE
+	EncodingUTF32LE
+	EncodingUTF32BE
+)
+
+func (e Encoding) String() string {
+	switch e {
+	case EncodingUTF16LE:
+		return "utf-16le"
+	case EncodingUTF16BE:
+		return "utf-16be"
+	case EncodingUTF32LE:
+		return "utf-32le"
+	case EncodingUTF32BE:
+		return "utf-32be"
+	}
+	return "utf-8"
+}
+
+// unitSize returns the size in bytes of a code unit of the encoding.
+func (e Encoding) unitSize() int {
+	switch e {
+	case EncodingUTF16LE, EncodingUTF16BE:
+		return 2
+	case EncodingUTF32LE, EncodingUTF32BE:
+		return 4
+	}
+	return 1
+}
+
+// SourceInfo describes the source of a json value, for reporting and for tools deciding how to
+// process an input, such as whether to stream or read it whole.
+type SourceInfo struct {
+	// Size is the length of the source in bytes, including any byte order mark.
+	Size int
+	// Encoding is the detected encoding of the source.
+	Encoding Encoding
+	// BOM reports whether the source starts with a byte order mark.
+	BOM bool
+	// Lines is the number of lines in the source. A line feed ends a line, so that a final line
+	// feed does not start a new line. The empty source has no lines.
+	Lines int
+}
+
+// InspectSource returns information about data without deserializing it. The encoding is
+// identified by a byte order mark if there is one, or else from the pattern of zero bytes at the
+// start of data as described by RFC 4627, which relies on json text starting with an ascii
+// character.
+func InspectSource(data []byte) SourceInfo {
+	info := SourceInfo{Size: len(data)}
+	info.Encoding, info.BOM = detectEncoding(data)
+	info.Lines = countLines(data, info.Encoding)
+	return info
+}
+
+// SourceInfo returns information about the source the document was deserialized from. For an
+// edited document, it describes the edited source.
+func (d *Document) SourceInfo() SourceInfo {
+	return InspectSource(d.src)
+}
+
+func detectEncoding(b []byte) (Encoding, bool) {
+	switch {
+	case bytes.HasPrefix(b, []byte{0x00, 0x00, 0xFE, 0xFF}):
+		return EncodingUTF32BE, true
+	case bytes.HasPrefix(b, []byte{0xFF, 0xFE, 0x00, 0x00}):
+		return EncodingUTF32LE, true
+	case bytes.HasPrefix(b, []byte{0xFE, 0xFF}):
+		return EncodingUTF16BE, true
+	case bytes.HasPrefix(b, []byte{0xFF, 0xFE}):
+		return EncodingUTF16LE, true
+	case bytes.HasPrefix(b, []byte(bom)):
+		return EncodingUTF8, true
+	}
+	if len(b) >= 4 {
+		switch {
+		case b[0] == 0 && b[1] == 0 && b[2] == 0 && b[3] != 0:
+			return EncodingUTF32BE, false
+		case b[0] != 0 && b[1] == 0 && b[2] == 0 && b[3] == 0:
+			return EncodingUTF32LE, false
+		}
+	}
+	if len(b) >= 2 {
+		switch {
+		case b[0] == 0 && b[1] != 0:
+			return EncodingUTF16BE, false
+		case b[0] != 0 && b[1] == 0:
+			return EncodingUTF16LE, false
+		}
+	}
+	return EncodingUTF8, false
+}
+
+// countLines counts the lines of b, which is in the encoding e. Line feeds are matched as whole
+// code units, so that bytes of other characters are not mistaken for them.
+func countLines(b []byte, e Encoding) int {
+	if len(b) == 0 {
+		return 0
+	}
+	size := e.unitSize()
+	lf, last := 0, false
+	if size == 1 {
+		lf = bytes.Count(b, []byte{'\n'})
+		last = b[len(b)-1] == '\n'
+	} else {
+		for i := 0; i+size <= len(b); i += size {
+			last = codeUnit(b[i:i+size], e) == '\n'
+			if last {
+				lf++
+			}
+		}
+	}
+	if last {
+		return lf
+	}
+	return lf + 1
+}
+
+func codeUnit(u []byte, e Encoding) uint32 {
+	switch e {
+	case EncodingUTF16LE:
+		return uint32(binary.LittleEndian.Uint16(u))
+	case EncodingUTF16BE:
+		return uint32(binary.BigEndian.Uint16(u))
+	case EncodingUTF32LE:
+		return binary.LittleEndian.Uint32(u)
+	case EncodingUTF32BE:
+		return binary.BigEndian.Uint32(u)
+	}
+	return uint32(u[0])
+}
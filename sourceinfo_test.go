@@ -0,0 +1,60 @@
+package genjson
+
+import (
+	"testing"
+	"unicode/utf16"
+)
+
+func TestInspectSource(t *testing.T) {
+	utf16le := func(s string, bom bool) string {
+		var b []byte
+		if bom {
+			b = append(b, 0xFF, 0xFE)
+		}
+		for _, u := range utf16.Encode([]rune(s)) {
+			b = append(b, byte(u), byte(u>>8))
+		}
+		return string(b)
+	}
+	tests := []struct {
+		name string
+		data string
+		want SourceInfo
+	}{
+		{name: "empty", data: "", want: SourceInfo{}},
+		{name: "one-line", data: `{"a": 1}`, want: SourceInfo{Size: 8, Lines: 1}},
+		{name: "final-newline", data: "[1]\n", want: SourceInfo{Size: 4, Lines: 1}},
+		{name: "lines", data: "{\r\n\"a\": 1\n}", want: SourceInfo{Size: 11, Lines: 3}},
+		{name: "blank-lines", data: "\n\n1", want: SourceInfo{Size: 3, Lines: 3}},
+		{name: "utf8-bom", data: "\xEF\xBB\xBF[1]", want: SourceInfo{Size: 6, BOM: true, Lines: 1}},
+		{name: "utf16le-bom", data: utf16le("[1]\n", true), want: SourceInfo{Size: 10, Encoding: EncodingUTF16LE, BOM: true, Lines: 1}},
+		{name: "utf16le", data: utf16le("[\n਀]", false), want: SourceInfo{Size: 8, Encoding: EncodingUTF16LE, Lines: 2}},
+		{name: "utf16be", data: "\x00[\x00]", want: SourceInfo{Size: 4, Encoding: EncodingUTF16BE, Lines: 1}},
+		{name: "utf16be-bom", data: "\xFE\xFF\x00\n", want: SourceInfo{Size: 4, Encoding: EncodingUTF16BE, BOM: true, Lines: 1}},
+		{name: "utf32le", data: "1\x00\x00\x00", want: SourceInfo{Size: 4, Encoding: EncodingUTF32LE, Lines: 1}},
+		{name: "utf32be-bom", data: "\x00\x00\xFE\xFF\x00\x00\x00\n\x00\x00\x00\n", want: SourceInfo{Size: 12, Encoding: EncodingUTF32BE, BOM: true, Lines: 2}},
+		{name: "utf32le-bom", data: "\xFF\xFE\x00\x001\x00\x00\x00", want: SourceInfo{Size: 8, Encoding: EncodingUTF32LE, BOM: true, Lines: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := InspectSource([]byte(tt.data)); got != tt.want {
+				t.Errorf("unexpected info %+v != %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocumentSourceInfo(t *testing.T) {
+	ds := Deserializer{StripBOM: true}
+	doc, err := ds.DeserializeDocument([]byte("\xEF\xBB\xBF{\n  \"a\": [1, 2]\n}\n"))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	want := SourceInfo{Size: 21, Encoding: EncodingUTF8, BOM: true, Lines: 3}
+	if got := doc.SourceInfo(); got != want {
+		t.Errorf("unexpected info %+v != %+v", got, want)
+	}
+	if got, want := want.Encoding.String(), "utf-8"; got != want {
+		t.Errorf("unexpected encoding name %s", got)
+	}
+}
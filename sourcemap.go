@@ -0,0 +1,143 @@
+package genjson
+
+import (
+	"sort"
+)
+
+// SourceMap maps offsets in the output of a transformation, such as Minify, back to locations in
+// its input, so that errors raised against the output can be traced back to the source. Maps of
+// consecutive transformations are combined with ComposeSourceMaps.
+//
+// The zero SourceMap maps every offset to itself. Maps for other transformations are built with
+// Add.
+type SourceMap struct {
+	// segments are the runs of output bytes that were copied from contiguous input bytes, in
+	// output order.
+	segments []sourceSegment
+}
+
+type sourceSegment struct {
+	offset int
+	source string
+	src    Loc
+}
+
+// Add records that the output bytes starting at offset were copied from the bytes starting at src
+// in the named source, up to the offset of the next call to Add. Offsets must be added in
+// increasing order, and a single call must not cover a newline in the source. source is
+// optional and only needed for transformations that combine several inputs.
+func (m *SourceMap) Add(offset int, source string, src Loc) {
+	m.add(offset, source, src)
+}
+
+// add is Add, merging the segment into the previous one if it continues it.
+func (m *SourceMap) add(offset int, source string, src Loc) {
+	if n := len(m.segments); n > 0 {
+		last := m.segments[n-1]
+		if src.Offset-last.src.Offset == offset-last.offset &&
+			src.Row == last.src.Row &&
+			source == last.source {
+			return
+		}
+	}
+	m.segments = append(m.segments, sourceSegment{offset: offset, source: source, src: src})
+}
+
+// Loc returns the location in the input of the byte at offset in the output. Offsets past the
+// end of the output are mapped past the last byte of the input that was copied.
+func (m SourceMap) Loc(offset int) Loc {
+	_, loc := m.Lookup(offset)
+	return loc
+}
+
+// Lookup is like Loc, but also returns the name of the source the byte was copied from.
+func (m SourceMap) Lookup(offset int) (string, Loc) {
+	i := m.segment(offset)
+	if i < 0 {
+		return "", Loc{Row: 1, Col: 1 + offset, Offset: offset}
+	}
+	seg := m.segments[i]
+	// Segments never contain newlines, so the row of the segment is the row of every byte in it.
+	delta := offset - seg.offset
+	loc := seg.src
+	loc.Col += delta
+	loc.Offset += delta
+	return seg.source, loc
+}
+
+// segment returns the index of the segment containing offset, or -1 if it is before the first
+// segment.
+func (m SourceMap) segment(offset int) int {
+	return sort.Search(len(m.segments), func(i int) bool {
+		return m.segments[i].offset > offset
+	}) - 1
+}
+
+// ComposeSourceMaps combines the source maps of consecutive transformations into a single map
+// from the output of the last transformation to the input of the first. maps are in the order
+// the transformations were applied. Source names are taken from the first map that is not the
+// zero SourceMap.
+func ComposeSourceMaps(maps ...SourceMap) SourceMap {
+	var m SourceMap
+	for i, next := range maps {
+		if i == 0 {
+			m = next
+			continue
+		}
+		m = composeSourceMaps(m, next)
+	}
+	return m
+}
+
+// TranslateLoc translates loc, a location in the output of the last of a chain of
+// transformations, back to the input of the first. maps are in the order the transformations
+// were applied. Only the Offset of loc is used. It is equivalent to looking up the offset in the
+// result of ComposeSourceMaps, without building the composed map.
+func TranslateLoc(loc Loc, maps ...SourceMap) (string, Loc) {
+	var source string
+	for i := len(maps) - 1; i >= 0; i-- {
+		if len(maps[i].segments) > 0 {
+			source, loc = maps[i].Lookup(loc.Offset)
+		}
+	}
+	return source, loc
+}
+
+// composeSourceMaps returns the map of prev followed by next.
+func composeSourceMaps(prev, next SourceMap) SourceMap {
+	if len(prev.segments) == 0 {
+		return next
+	}
+	if len(next.segments) == 0 {
+		return prev
+	}
+	var m SourceMap
+	if first := next.segments[0].offset; first > 0 {
+		// The zero SourceMap maps offsets before the first segment to themselves.
+		source, loc := prev.Lookup(0)
+		m.add(0, source, loc)
+		m.addBoundaries(prev, 0, first, 0)
+	}
+	for j, seg := range next.segments {
+		start, end := seg.src.Offset, -1
+		if j+1 < len(next.segments) {
+			end = start + next.segments[j+1].offset - seg.offset
+		}
+		source, loc := prev.Lookup(start)
+		m.add(seg.offset, source, loc)
+		m.addBoundaries(prev, start, end, seg.offset)
+	}
+	return m
+}
+
+// addBoundaries adds the segments of prev that start within the input range [start, end) of a
+// segment of the next transformation at output offset. An end of -1 is unbounded.
+func (m *SourceMap) addBoundaries(prev SourceMap, start, end, offset int) {
+	for k := prev.segment(start) + 1; k < len(prev.segments); k++ {
+		b := prev.segments[k]
+		if end >= 0 && b.offset >= end {
+			return
+		}
+		m.add(offset+b.offset-start, b.source, b.src)
+	}
+}
@@ -0,0 +1,97 @@
+package genjson
+
+import (
+	"testing"
+)
+
+// addLines records that text, copied from the start of source, was written at offset.
+func addLines(m *SourceMap, offset int, source string, text string) {
+	loc := Loc{Row: 1, Col: 1}
+	for i := 0; i < len(text); i++ {
+		if i == 0 || text[i-1] == '\n' {
+			m.Add(offset+i, source, loc)
+		}
+		if text[i] == '\n' {
+			loc.Row++
+			loc.Col = 1
+		} else {
+			loc.Col++
+		}
+		loc.Offset++
+	}
+}
+
+func TestComposeSourceMaps(t *testing.T) {
+	sources := map[string]string{
+		"a.json": "{\n  \"a\": 1\n}",
+		"b.json": "[true,\n  false]",
+	}
+	// Merge the sources into a single array, recording where each part came from.
+	var merged []byte
+	var mergeMap SourceMap
+	for _, part := range []struct{ source, text string }{
+		{"", "[\n"},
+		{"a.json", sources["a.json"]},
+		{"", ",\n"},
+		{"b.json", sources["b.json"]},
+		{"", "\n]"},
+	} {
+		if part.source == "" {
+			mergeMap.Add(len(merged), "", Loc{Offset: len(merged)})
+		} else {
+			addLines(&mergeMap, len(merged), part.source, part.text)
+		}
+		merged = append(merged, part.text...)
+	}
+	out, minifyMap, err := Minify(merged)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if want := `[{"a":1},[true,false]]`; string(out) != want {
+		t.Fatalf("unexpected output %s != %s", out, want)
+	}
+
+	m := ComposeSourceMaps(mergeMap, minifyMap)
+	for i := range out {
+		source, loc := m.Lookup(i)
+		tsource, tloc := TranslateLoc(Loc{Offset: i}, mergeMap, minifyMap)
+		if source != tsource || loc != tloc {
+			t.Errorf("offset %d: composed %s %+v != translated %s %+v", i, source, loc, tsource, tloc)
+		}
+		if source != "" && sources[source][loc.Offset] != out[i] {
+			t.Errorf("offset %d: %q is not mapped to itself in %s %+v", i, out[i], source, loc)
+		}
+	}
+	tests := []struct {
+		offset int
+		source string
+		loc    Loc
+	}{
+		{offset: 1, source: "a.json", loc: Loc{Row: 1, Col: 1, Offset: 0}},
+		{offset: 2, source: "a.json", loc: Loc{Row: 2, Col: 3, Offset: 4}},
+		{offset: 10, source: "b.json", loc: Loc{Row: 1, Col: 2, Offset: 1}},
+		{offset: 15, source: "b.json", loc: Loc{Row: 2, Col: 3, Offset: 9}},
+	}
+	for _, tt := range tests {
+		if source, loc := m.Lookup(tt.offset); source != tt.source || loc != tt.loc {
+			t.Errorf("offset %d: unexpected location %s %+v != %s %+v", tt.offset, source, loc, tt.source, tt.loc)
+		}
+	}
+}
+
+func TestComposeSourceMapsIdentity(t *testing.T) {
+	var m SourceMap
+	m.Add(2, "x", Loc{Row: 3, Col: 1, Offset: 10})
+	for _, maps := range [][]SourceMap{{m}, {m, {}}, {{}, m}} {
+		c := ComposeSourceMaps(maps...)
+		if got := c.Loc(1); got != (Loc{Row: 1, Col: 2, Offset: 1}) {
+			t.Errorf("unexpected location %+v", got)
+		}
+		if source, got := c.Lookup(3); source != "x" || got != (Loc{Row: 3, Col: 2, Offset: 11}) {
+			t.Errorf("unexpected location %s %+v", source, got)
+		}
+		if source, got := TranslateLoc(Loc{Offset: 3}, maps...); source != "x" || got != (Loc{Row: 3, Col: 2, Offset: 11}) {
+			t.Errorf("unexpected translated location %s %+v", source, got)
+		}
+	}
+}
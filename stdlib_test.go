@@ -0,0 +1,219 @@
+package genjson
+
+import (
+	"encoding/json"
+	"flag"
+	"math"
+	"os"
+	"reflect"
+	"testing"
+)
+
+var stdlibReport = flag.String("stdlib-report", "", "Write the encoding/json divergence report of TestStdlibCompat as json to this file.")
+
+type stdlibTagged struct {
+	A      int    `json:"a"`
+	Empty  string `json:",omitempty"`
+	Skip   int    `json:"-"`
+	Dash   int    `json:"-,"`
+	Plain  bool
+	hidden int
+	stdlibEmbedded
+}
+
+type stdlibEmbedded struct {
+	E string
+}
+
+type stdlibStringOpt struct {
+	N int `json:",string"`
+}
+
+// stdlibMarshalJSON implements json.Marshaler.
+type stdlibMarshalJSON struct{}
+
+func (stdlibMarshalJSON) MarshalJSON() ([]byte, error) {
+	return []byte(`"custom"`), nil
+}
+
+// stdlibCase is a behavior of encoding/json checked against genjson. Marshal cases set marshal,
+// unmarshal cases set input and target.
+type stdlibCase struct {
+	name     string
+	category string
+	marshal  any
+	input    string
+	target   func() any
+	// divergence explains why genjson intentionally differs from encoding/json. It is empty for
+	// compatible behaviors.
+	divergence string
+	// option names the option that makes genjson behave like encoding/json, which compat sets.
+	option string
+	compat func(m *Marshaler, u *Unmarshaler)
+}
+
+// stdlibResult is an entry of the divergence report.
+type stdlibResult struct {
+	Name      string `json:"name"`
+	Category  string `json:"category"`
+	Stdlib    string `json:"stdlib"`
+	Genjson   string `json:"genjson"`
+	Divergent bool   `json:"divergent"`
+	Reason    string `json:"reason,omitempty"`
+	Option    string `json:"option,omitempty"`
+}
+
+const (
+	reasonFloats  = "floats are written with a fractional part and without exponents so that they are read back as floats"
+	reasonUTF8    = "invalid utf-8 is escaped as strconv.Quote does by default, or replaced by an escaped U+FFFD with Serializer.CanonicalStrings, so that the output is valid ascii where the input was not valid text"
+	reasonQuote   = "strings are escaped as strconv.Quote does by default, which uses go escapes that are not valid json for control characters and invalid utf-8"
+	reasonHTML    = "strings are not escaped for html by default"
+	reasonBytes   = "byte slices are arrays of numbers by default"
+	reasonIfaces  = "the json.Marshaler and json.Unmarshaler interfaces are not used; types implement Value or use decode hooks instead"
+	reasonNoMatch = "trailing data after the first value is not an error, as the parser reads values from larger inputs"
+)
+
+var stdlibCases = []stdlibCase{
+	// Struct tags.
+	{name: "tags", category: "tags", marshal: stdlibTagged{A: 1, Dash: 2, Plain: true, stdlibEmbedded: stdlibEmbedded{E: "e"}}},
+	{name: "tags-unmarshal", category: "tags", input: `{"a": 1, "Empty": "x", "Skip": 3, "-": 2, "E": "e"}`, target: func() any { return new(stdlibTagged) }},
+	{name: "fold-field-names", category: "tags", input: `{"PLAIN": true, "e": "x"}`, target: func() any { return new(stdlibTagged) }},
+	{name: "unknown-fields", category: "tags", input: `{"unknown": 1}`, target: func() any { return new(stdlibTagged) }},
+	{
+		name:       "string-option",
+		category:   "tags",
+		marshal:    stdlibStringOpt{N: 1},
+		divergence: "the string tag option is not supported",
+	},
+	{
+		name:       "string-option-unmarshal",
+		category:   "tags",
+		input:      `{"N": "1"}`,
+		target:     func() any { return new(stdlibStringOpt) },
+		divergence: "the string tag option is not supported",
+	},
+
+	// Numbers.
+	{name: "integers", category: "numbers", marshal: []any{0, -1, int64(math.MinInt64), uint64(math.MaxUint64)}},
+	{name: "fraction", category: "numbers", marshal: 1.5},
+	{name: "integral-float", category: "numbers", marshal: 1.0, divergence: reasonFloats},
+	{name: "large-float", category: "numbers", marshal: 1e21, divergence: reasonFloats},
+	{name: "small-float", category: "numbers", marshal: 1e-7, divergence: reasonFloats},
+	{name: "float32", category: "numbers", marshal: float32(0.1), divergence: "float32 values are written with float64 precision"},
+	{name: "nan", category: "numbers", marshal: math.NaN()},
+	{name: "interface-number", category: "numbers", input: `[1, 1.5, 12345678901234567890]`, target: func() any { return new(any) }},
+	{name: "fraction-into-int", category: "numbers", input: `1.5`, target: func() any { return new(int) }},
+	{name: "overflow", category: "numbers", input: `300`, target: func() any { return new(int8) }},
+	{
+		name:       "json-number",
+		category:   "numbers",
+		marshal:    json.Number("12"),
+		divergence: "json.Number has no special meaning and is written as a string",
+	},
+
+	// Strings.
+	{name: "escapes", category: "strings", marshal: "\"\\\n\t\x01  ", divergence: reasonQuote, option: "Serializer.CanonicalStrings", compat: canonicalStrings},
+	{name: "html", category: "strings", marshal: "<a&b>", divergence: reasonHTML, option: "Serializer.EscapeHTML", compat: func(m *Marshaler, u *Unmarshaler) {
+		m.Serializer.EscapeHTML = true
+	}},
+	{name: "invalid-utf8", category: "strings", marshal: "a\xffb", divergence: reasonUTF8},
+	{name: "unicode-escapes", category: "strings", input: `"é😀"`, target: func() any { return new(string) }},
+
+	// Go types.
+	{name: "map", category: "types", marshal: map[string]int{"b": 1, "a": 2}},
+	{name: "nil", category: "types", marshal: []any{[]int(nil), map[string]int(nil), (*int)(nil)}},
+	{name: "int-map-keys", category: "types", marshal: map[int]string{1: "a"}, divergence: "maps must have string keys"},
+	{name: "bytes", category: "types", marshal: []byte("hi"), divergence: reasonBytes, option: "Marshaler.Base64Bytes", compat: func(m *Marshaler, u *Unmarshaler) {
+		m.Base64Bytes = true
+	}},
+	{name: "bytes-unmarshal", category: "types", input: `"aGk="`, target: func() any { return new([]byte) }, divergence: reasonBytes, option: "Unmarshaler.Base64Bytes", compat: func(m *Marshaler, u *Unmarshaler) {
+		u.Base64Bytes = true
+	}},
+	{name: "json-marshaler", category: "types", marshal: stdlibMarshalJSON{}, divergence: reasonIfaces},
+	{name: "channel", category: "types", marshal: make(chan int)},
+	{name: "interface-object", category: "types", input: `{"a": [true, null, "s"]}`, target: func() any { return new(any) }},
+
+	// Errors and edge cases.
+	{name: "empty-input", category: "errors", input: ``, target: func() any { return new(any) }},
+	{name: "syntax-error", category: "errors", input: `[1,]`, target: func() any { return new(any) }},
+	{name: "trailing-data", category: "errors", input: `[1] x`, target: func() any { return new(any) }, divergence: reasonNoMatch},
+	{name: "wrong-type", category: "errors", input: `1`, target: func() any { return new(string) }},
+	{name: "duplicate-keys", category: "errors", input: `{"a": 1, "a": 2}`, target: func() any { return new(map[string]int) }},
+	{
+		name:       "null-into-value",
+		category:   "errors",
+		input:      `null`,
+		target:     func() any { return new(int) },
+		divergence: "null can only be unmarshaled into pointers, interfaces, slices and maps instead of being ignored",
+	},
+}
+
+func canonicalStrings(m *Marshaler, u *Unmarshaler) {
+	m.Serializer.CanonicalStrings = true
+}
+
+// run returns the results of encoding/json and of genjson, and whether they match.
+func (c stdlibCase) run(m *Marshaler, u *Unmarshaler) (std, gen string, match bool) {
+	if c.target == nil {
+		sb, serr := json.Marshal(c.marshal)
+		gb, gerr := m.Marshal(c.marshal)
+		std, gen = resultString(sb, serr), resultString(gb, gerr)
+		return std, gen, (serr == nil) == (gerr == nil) && string(sb) == string(gb)
+	}
+	sv, gv := c.target(), c.target()
+	serr := json.Unmarshal([]byte(c.input), sv)
+	gerr := u.Unmarshal([]byte(c.input), gv)
+	sb, _ := json.Marshal(sv)
+	gb, _ := json.Marshal(gv)
+	std, gen = resultString(sb, serr), resultString(gb, gerr)
+	return std, gen, (serr == nil) == (gerr == nil) && (serr != nil || reflect.DeepEqual(sv, gv))
+}
+
+func resultString(b []byte, err error) string {
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return string(b)
+}
+
+// TestStdlibCompat checks genjson against the documented behaviors of encoding/json. Divergences
+// must be documented by the case, and options that opt into the behavior of encoding/json must
+// remove them. Run with -stdlib-report to write a machine readable report of the results.
+func TestStdlibCompat(t *testing.T) {
+	var report []stdlibResult
+	for _, c := range stdlibCases {
+		t.Run(c.name, func(t *testing.T) {
+			std, gen, match := c.run(&Marshaler{}, &Unmarshaler{})
+			report = append(report, stdlibResult{
+				Name:      c.name,
+				Category:  c.category,
+				Stdlib:    std,
+				Genjson:   gen,
+				Divergent: !match,
+				Reason:    c.divergence,
+				Option:    c.option,
+			})
+			if want := c.divergence == ""; match != want {
+				t.Errorf("expected match %v\nencoding/json: %s\ngenjson:       %s", want, std, gen)
+			}
+			if c.compat != nil {
+				m, u := &Marshaler{}, &Unmarshaler{}
+				c.compat(m, u)
+				if std, gen, match := c.run(m, u); !match {
+					t.Errorf("expected %s to match\nencoding/json: %s\ngenjson:       %s", c.option, std, gen)
+				}
+			}
+		})
+	}
+	if *stdlibReport == "" {
+		return
+	}
+	m := Marshaler{Serializer: Serializer{Indent: 2, KeyValueGap: 1}}
+	b, err := m.Marshal(report)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := os.WriteFile(*stdlibReport, append(b, '\n'), 0o644); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
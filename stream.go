@@ -0,0 +1,563 @@
+package genjson
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"unicode"
+)
+
+// recordSeparator is the byte that precedes each json text in a json text sequence (RFC 7464).
+const recordSeparator = 0x1E
+
+// ErrMissingRecordSeparator is returned when a json text in a json text sequence is not followed by
+// a record separator or the end of the input.
+var ErrMissingRecordSeparator = errors.New("json text is not followed by a record separator")
+
+// decoderReadSize is the minimum free space the Decoder provides to each read.
+const decoderReadSize = 4096
+
+// Decoder reads successive json values from an input stream. The input is read incrementally, so
+// only the value being decoded is held in memory. A value that is split across reads is only
+// parsed once the end of it has been read.
+type Decoder struct {
+	// Deserializer controls the parsing of each value.
+	Deserializer Deserializer
+	// Separator controls how values are separated.
+	Separator Separator
+	// Seq configures the decoder to read json text sequences (RFC 7464) when Separator is
+	// SeparatorNewline.
+	//
+	// Deprecated: Use Separator with SeparatorRecord instead.
+	Seq bool
+	// Unmarshaler is used by Decode. If nil, the default Unmarshaler is used.
+	Unmarshaler *Unmarshaler
+
+	r       io.Reader
+	started bool
+	eof     bool
+	// d is the parser state at the start of the input that has not been decoded. d.b only holds
+	// the input from the start of the current value that has been read from r.
+	d deserializer
+	// tokenStack holds the opening delimiters of the containers opened by Token.
+	tokenStack []byte
+	tokenState tokenState
+	// arrayDone is set once the array of SeparatorArray has been closed.
+	arrayDone bool
+}
+
+// NewDecoder returns a decoder reading from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// DecodeValue reads the next json value from the stream. io.EOF is returned once there are no
+// more values. With SeparatorRecord, an invalid json text is skipped so that decoding can continue
+// with the next text after the error is returned.
+func (dec *Decoder) DecodeValue() (Value, error) {
+	o, err := dec.next()
+	if err != nil {
+		return nil, err
+	}
+	return o.value, nil
+}
+
+// Decode reads the next json value from the stream and unmarshals it into v. io.EOF is returned
+// once there are no more values.
+func (dec *Decoder) Decode(v any) error {
+	o, err := dec.next()
+	if err != nil {
+		return err
+	}
+	u := dec.Unmarshaler
+	if u == nil {
+		u = &defaultUnmarshaler
+	}
+	return u.unmarshal(o.value, &o.node, v)
+}
+
+// More reports whether there is another value in the stream. It also returns true if reading the
+// stream fails, so that the error is returned by the next call to Decode or DecodeValue.
+func (dec *Decoder) More() bool {
+	if err := dec.start(); err != nil {
+		return true
+	}
+	if dec.separator() == SeparatorArray {
+		if err := dec.openArray(); err != nil {
+			return err != io.EOF
+		}
+	}
+	if err := dec.skip(dec.space()); err != nil {
+		return true
+	}
+	if dec.d.idx >= len(dec.d.b) {
+		return false
+	}
+	if len(dec.tokenStack) > 0 {
+		c := dec.d.b[dec.d.idx]
+		return c != ']' && c != '}'
+	}
+	return true
+}
+
+// next parses the next value, reading more input until the value is complete.
+func (dec *Decoder) next() (output, error) {
+	if err := dec.start(); err != nil {
+		return output{}, err
+	}
+	if dec.separator() == SeparatorArray {
+		if err := dec.nextElement(); err != nil {
+			return output{}, err
+		}
+	}
+	inToken := len(dec.tokenStack) > 0
+	if inToken {
+		if err := dec.prepareValue(); err != nil {
+			return output{}, err
+		}
+	}
+	if err := dec.skip(dec.space()); err != nil {
+		return output{}, err
+	}
+	if dec.d.idx >= len(dec.d.b) {
+		if inToken {
+			return output{}, ErrUnexpectedEndOfInput
+		}
+		return output{}, io.EOF
+	}
+	var sc valueScanner
+	for {
+		if !dec.eof && !sc.complete(dec.d.b[dec.d.idx:], dec.separator() == SeparatorRecord) {
+			if err := dec.fill(); err != nil {
+				return output{}, err
+			}
+			continue
+		}
+		d := dec.d
+		partial := false
+		if !dec.eof {
+			d.partial = &partial
+		}
+		arena := dec.Deserializer.arena()
+		var mark int
+		if arena != nil {
+			mark = arena.mark()
+		}
+		d, o, err := deserializeNext(d)
+		if partial {
+			// The result may change with more input, e.g. a number may have more digits.
+			if arena != nil {
+				arena.release(mark)
+			}
+			if err := dec.fill(); err != nil {
+				return output{}, err
+			}
+			continue
+		}
+		d.partial = nil
+		if err != nil {
+			if dec.separator() == SeparatorRecord {
+				if err := dec.skip(isRecord); err != nil {
+					return output{}, err
+				}
+			}
+			return output{}, err
+		}
+		dec.d = d
+		if inToken {
+			dec.tokenState = dec.afterValue()
+			return o, nil
+		}
+		if err := dec.skip(isSpace); err != nil {
+			return output{}, err
+		}
+		if dec.separator() == SeparatorRecord {
+			if _, b, br := read(dec.d); br.OK && b != recordSeparator {
+				if err := dec.skip(isRecord); err != nil {
+					return output{}, err
+				}
+				return output{}, ErrMissingRecordSeparator
+			}
+		}
+		if err := dec.Deserializer.checkTopLevel(o.value); err != nil {
+			return output{}, err
+		}
+		return o, nil
+	}
+}
+
+// valueScanner finds the end of a json value in input that is read incrementally, so that the
+// Decoder parses every value once rather than after every read. Scanning resumes where the last
+// call stopped. The scan only matches brackets and strings; the value is validated by the parser.
+type valueScanner struct {
+	// pos is the offset from the start of the value of the next byte to scan.
+	pos      int
+	depth    int
+	inString bool
+	escaped  bool
+}
+
+// complete scans b, which starts at the start of a value, and reports whether it holds the end of
+// the value. Scalars other than strings end at the first byte that cannot be part of them. Bytes
+// that cannot start or continue a value also complete it, so that the parser reports them. In
+// sequence mode a record separator always completes the value.
+func (sc *valueScanner) complete(b []byte, seq bool) bool {
+	for ; sc.pos < len(b); sc.pos++ {
+		c := b[sc.pos]
+		switch {
+		case sc.inString:
+			switch {
+			case sc.escaped:
+				sc.escaped = false
+			case c == '\\':
+				sc.escaped = true
+			case c == '"':
+				sc.inString = false
+				if sc.depth == 0 {
+					return true
+				}
+			}
+		case seq && c == recordSeparator:
+			return true
+		case c == '"':
+			sc.inString = true
+		case c == '{' || c == '[':
+			sc.depth++
+		case c == '}' || c == ']':
+			sc.depth--
+			if sc.depth <= 0 {
+				return true
+			}
+		case sc.depth == 0 && !isLiteralByte(c):
+			return true
+		}
+	}
+	return false
+}
+
+// separator returns the separator of the values, taking the deprecated Seq into account.
+func (dec *Decoder) separator() Separator {
+	if dec.Seq && dec.Separator == SeparatorNewline {
+		return SeparatorRecord
+	}
+	return dec.Separator
+}
+
+// openArray consumes the start of the array of SeparatorArray if it has not been read yet. io.EOF
+// is returned once the array has been closed or if the stream is empty.
+func (dec *Decoder) openArray() error {
+	if len(dec.tokenStack) > 0 {
+		return nil
+	}
+	if dec.arrayDone {
+		return io.EOF
+	}
+	if err := dec.skip(isSpace); err != nil {
+		return err
+	}
+	if _, c, br := read(dec.d); br.OK && c != '[' {
+		// The stream is not an array, so there are no values to decode after the error.
+		dec.arrayDone = true
+		return errNoMatch(dec.d)
+	}
+	_, err := dec.Token()
+	return err
+}
+
+// nextElement prepares the decoding of the next element of the array of SeparatorArray, returning
+// io.EOF and consuming the end of the array once there are no more elements.
+func (dec *Decoder) nextElement() error {
+	if err := dec.openArray(); err != nil {
+		return err
+	}
+	if len(dec.tokenStack) > 1 || dec.More() {
+		return nil
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	dec.arrayDone = true
+	return io.EOF
+}
+
+// start reads enough input to skip a leading byte order mark.
+func (dec *Decoder) start() error {
+	if dec.started {
+		return nil
+	}
+	for len(dec.d.b) < len(bom) && !dec.eof {
+		if err := dec.fill(); err != nil {
+			return err
+		}
+	}
+	dec.d = newDeserializer(dec.d.b, &dec.Deserializer)
+	dec.started = true
+	return nil
+}
+
+// space returns the predicate matching the bytes between values.
+func (dec *Decoder) space() func(b byte) bool {
+	if dec.separator() == SeparatorRecord {
+		return isSeqSpace
+	}
+	return isSpace
+}
+
+// skip skips the input matching the predicate, reading more input as required.
+func (dec *Decoder) skip(predicate func(b byte) bool) error {
+	for {
+		dec.d = skipWhile(dec.d, predicate)
+		if dec.d.idx < len(dec.d.b) || dec.eof {
+			return nil
+		}
+		if err := dec.fill(); err != nil {
+			return err
+		}
+	}
+}
+
+// fill reads more input from r, discarding the input before d first. The end of the input is
+// recorded in eof rather than returned as an error.
+func (dec *Decoder) fill() error {
+	b := dec.d.b
+	if dec.d.idx > 0 {
+		n := copy(b, b[dec.d.idx:])
+		b = b[:n]
+		dec.d.base += dec.d.idx
+		dec.d.idx = 0
+	}
+	if cap(b)-len(b) < decoderReadSize {
+		nb := make([]byte, len(b), 2*cap(b)+decoderReadSize)
+		copy(nb, b)
+		b = nb
+	}
+	for {
+		n, err := dec.r.Read(b[len(b):cap(b)])
+		b = b[:len(b)+n]
+		dec.d.b = b
+		if errors.Is(err, io.EOF) {
+			dec.eof = true
+			return nil
+		}
+		if n > 0 || err != nil {
+			return err
+		}
+	}
+}
+
+func skipSpace(d deserializer) deserializer {
+	return skipWhile(d, isSpace)
+}
+
+func isSpace(b byte) bool {
+	return unicode.IsSpace(rune(b))
+}
+
+// isSeqSpace matches whitespace and the record separators before json texts in a sequence.
+func isSeqSpace(b byte) bool {
+	return b == recordSeparator || unicode.IsSpace(rune(b))
+}
+
+// isRecord matches the bytes of a record in a json text sequence.
+func isRecord(b byte) bool {
+	return b != recordSeparator
+}
+
+func skipWhile(d deserializer, predicate func(b byte) bool) deserializer {
+	for {
+		d2, b, br := read(d)
+		if !br.OK || !predicate(b) {
+			return d
+		}
+		d = d2
+	}
+}
+
+// Separator controls how the successive values of an Encoder or Decoder are separated.
+type Separator int8
+
+const (
+	// SeparatorNewline writes every value followed by a newline, producing newline delimited json
+	// when the Serializer does not indent. Decoders read values separated by any whitespace.
+	SeparatorNewline Separator = iota
+	// SeparatorRecord writes json text sequences (RFC 7464), where every value is preceded by a
+	// record separator and followed by a newline.
+	SeparatorRecord
+	// SeparatorArray writes every value as an element of a single json array. The array is closed
+	// by Close. Decoders read the elements of a single top-level array one by one, and must not
+	// be used with Token.
+	SeparatorArray
+)
+
+// Encoder writes successive json values to an output stream.
+type Encoder struct {
+	// Serializer controls the formatting of each value.
+	Serializer Serializer
+	// Separator controls how values are separated.
+	Separator Separator
+	// Seq configures the encoder to write json text sequences (RFC 7464) when Separator is
+	// SeparatorNewline.
+	//
+	// Deprecated: Use Separator with SeparatorRecord instead.
+	Seq bool
+
+	w       io.Writer
+	buf     *bufio.Writer
+	n       int
+	written int64
+}
+
+// NewEncoder returns an encoder writing to w. Every call to Encode writes to w directly.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// NewBufferedEncoder returns an encoder writing to w through a buffer of the given size. Written
+// values only become visible to w when the buffer fills up or when Flush or Close is called, so
+// the caller controls when partial output is sent, for example over a socket.
+func NewBufferedEncoder(w io.Writer, size int) *Encoder {
+	return &Encoder{w: w, buf: bufio.NewWriterSize(w, size)}
+}
+
+// separator returns the separator of the values, taking the deprecated Seq into account.
+func (enc *Encoder) separator() Separator {
+	if enc.Seq && enc.Separator == SeparatorNewline {
+		return SeparatorRecord
+	}
+	return enc.Separator
+}
+
+// out returns the writer that values are written to.
+func (enc *Encoder) out() io.Writer {
+	if enc.buf != nil {
+		return enc.buf
+	}
+	return enc.w
+}
+
+// Encode writes the value to the stream. The value is checked with Serializer.Check first, and
+// nothing is written if it fails.
+func (enc *Encoder) Encode(v Value) error {
+	_, err := enc.EncodeContext(context.Background(), v)
+	return err
+}
+
+// EncodeContext is like Encode, but stops once ctx is done and returns the number of bytes written
+// to the stream. The value is written as it is serialized, in chunks of about 32KiB between the
+// elements of arrays and objects as Serializer.Write does, so that the complete output of a large
+// value is never held in memory and a slow writer holds back serialization. ctx is checked between
+// the elements, so that a handler streaming a large value can stop promptly when its client
+// disconnects. Nothing is written if ctx is done before the value is serialized. Otherwise the
+// error of ctx or of the writer is returned as soon as it occurs, and the stream is left
+// incomplete.
+func (enc *Encoder) EncodeContext(ctx context.Context, v Value) (int, error) {
+	s := enc.Serializer
+	if enc.separator() == SeparatorArray {
+		// Values are elements of the array written by the encoder.
+		s.RequireContainer = false
+	}
+	if err := s.Check(v); err != nil {
+		return 0, err
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	w := &encoderWriter{enc: enc}
+	s.done = ctx.Done()
+	s.out = &serializeOutput{w: w}
+	buf := make([]byte, 0, 1024)
+	switch enc.separator() {
+	case SeparatorArray:
+		if enc.n == 0 {
+			buf = s.appendPrefix(buf)
+			buf = append(buf, '[')
+		} else {
+			buf = append(buf, ',')
+		}
+		buf = appendIndent(&s, 1, buf)
+		buf = s.appendLevel(buf, v, 1)
+	case SeparatorRecord:
+		buf = append(buf, recordSeparator)
+		buf = s.appendValue(buf, v)
+		buf = append(buf, '\n')
+	default:
+		buf = s.appendValue(buf, v)
+		buf = append(buf, '\n')
+	}
+	if s.out.err != nil {
+		return w.n, s.out.err
+	}
+	if err := ctx.Err(); err != nil {
+		return w.n, err
+	}
+	if _, err := w.Write(buf); err != nil {
+		return w.n, err
+	}
+	// The value only counts once it has been written, so that the opening bracket of
+	// SeparatorArray is written again if writing the first value fails.
+	enc.n++
+	return w.n, nil
+}
+
+// encoderWriter writes the output of a single value to the stream of an Encoder, counting the
+// bytes written.
+type encoderWriter struct {
+	enc *Encoder
+	n   int
+}
+
+func (w *encoderWriter) Write(b []byte) (int, error) {
+	n, err := w.enc.out().Write(b)
+	w.n += n
+	w.enc.written += int64(n)
+	return n, err
+}
+
+// Written returns the total number of bytes written to the stream, including any bytes still held
+// in the buffer of a buffered encoder.
+func (enc *Encoder) Written() int64 {
+	return enc.written
+}
+
+// Close finishes the stream, writing the end of the array for SeparatorArray, and calls Flush.
+// Close does not close the underlying writer.
+func (enc *Encoder) Close() error {
+	if enc.separator() == SeparatorArray {
+		s := &enc.Serializer
+		if err := s.Validate(); err != nil {
+			return err
+		}
+		var buf []byte
+		if enc.n == 0 {
+			buf = s.appendPrefix(buf)
+			buf = append(buf, '[')
+		} else {
+			buf = appendIndent(s, 0, buf)
+		}
+		buf = append(buf, "]\n"...)
+		n, err := enc.out().Write(buf)
+		enc.written += int64(n)
+		if err != nil {
+			return err
+		}
+	}
+	return enc.Flush()
+}
+
+// Flush writes any buffered data to the underlying writer. If the underlying writer has a Flush
+// method, such as a bufio.Writer or http.Flusher, it is called too.
+func (enc *Encoder) Flush() error {
+	if enc.buf != nil {
+		if err := enc.buf.Flush(); err != nil {
+			return err
+		}
+	}
+	switch f := enc.w.(type) {
+	case interface{ Flush() error }:
+		return f.Flush()
+	case interface{ Flush() }:
+		f.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,429 @@
+package genjson
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDecoder(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		separator Separator
+		want      []Value
+		wantErr   bool
+	}{
+		{
+			name:  "empty",
+			input: "",
+		},
+		{
+			name:  "concatenated",
+			input: "1 \"a\"\n[true]",
+			want:  []Value{integer(1), String("a"), Array{Bool(true)}},
+		},
+		{
+			name:      "seq",
+			input:     "\x1e1\n\x1e\"a\"\n\x1e[true]\n",
+			separator: SeparatorRecord,
+			want:      []Value{integer(1), String("a"), Array{Bool(true)}},
+		},
+		{
+			name:      "seq-empty-records",
+			input:     "\x1e\x1e\n\x1enull\n",
+			separator: SeparatorRecord,
+			want:      []Value{Null{}},
+		},
+		{
+			name:      "seq-invalid-record-skipped",
+			input:     "\x1e[1,\n\x1e2\n",
+			separator: SeparatorRecord,
+			want:      []Value{integer(2)},
+			wantErr:   true,
+		},
+		{
+			name:      "seq-missing-separator",
+			input:     "\x1e1 2\n\x1e3\n",
+			separator: SeparatorRecord,
+			want:      []Value{integer(3)},
+			wantErr:   true,
+		},
+		{
+			name:      "array",
+			input:     " [1, \"a\",\n[true]] ",
+			separator: SeparatorArray,
+			want:      []Value{integer(1), String("a"), Array{Bool(true)}},
+		},
+		{
+			name:      "array-empty",
+			input:     "[ ]",
+			separator: SeparatorArray,
+		},
+		{
+			name:      "array-empty-input",
+			input:     " ",
+			separator: SeparatorArray,
+		},
+		{
+			name:      "array-not-array",
+			input:     "1",
+			separator: SeparatorArray,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testDecoder(t, strings.NewReader(tt.input), tt.separator, tt.want, tt.wantErr)
+		})
+		t.Run(tt.name+"-one-byte", func(t *testing.T) {
+			testDecoder(t, iotest.OneByteReader(strings.NewReader(tt.input)), tt.separator, tt.want, tt.wantErr)
+		})
+	}
+
+	// The deprecated Seq is the same as SeparatorRecord.
+	dec := NewDecoder(strings.NewReader("\x1e1\n"))
+	dec.Seq = true
+	if v, err := dec.DecodeValue(); err != nil || !Equal(v, integer(1)) {
+		t.Errorf("unexpected result %v %v", v, err)
+	}
+	dec = NewDecoder(strings.NewReader("[1, 2]"))
+	dec.Separator = SeparatorArray
+	n := 0
+	for ; dec.More(); n++ {
+		if _, err := dec.DecodeValue(); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	if _, err := dec.DecodeValue(); n != 2 || err != io.EOF {
+		t.Errorf("unexpected elements %d %v", n, err)
+	}
+}
+
+func testDecoder(t *testing.T, r io.Reader, separator Separator, want []Value, wantErr bool) {
+	t.Helper()
+	dec := NewDecoder(r)
+	dec.Separator = separator
+	var (
+		got    []Value
+		gotErr bool
+	)
+	for {
+		v, err := dec.DecodeValue()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			gotErr = true
+			continue
+		}
+		got = append(got, v)
+	}
+	if gotErr != wantErr {
+		t.Errorf("unexpected error state %v", gotErr)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected values %v != %v", got, want)
+	}
+}
+
+// chunkReader returns at most n bytes from every Read, like a slow network connection.
+type chunkReader struct {
+	r     io.Reader
+	n     int
+	reads int
+}
+
+func (cr *chunkReader) Read(p []byte) (int, error) {
+	cr.reads++
+	if len(p) > cr.n {
+		p = p[:cr.n]
+	}
+	return cr.r.Read(p)
+}
+
+func TestDecoderSlowReader(t *testing.T) {
+	// A large value read in small chunks must be parsed once it is complete rather than after every
+	// read, which would take quadratic time.
+	var sb strings.Builder
+	sb.WriteString(`{"items": [`)
+	for i := 0; i < 2000; i++ {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(`{"id": 12345, "name": "item \"x\" [}", "tags": ["a", "b"]}`)
+	}
+	sb.WriteString("]} 2 \"three\" [4]")
+	input := sb.String()
+	want, err := DeserializeString(input[:strings.Index(input, " 2 ")])
+	if err != nil {
+		t.Fatal(err)
+	}
+	cr := &chunkReader{r: strings.NewReader(input), n: 1024}
+	dec := NewDecoder(cr)
+	var got []Value
+	for {
+		v, err := dec.DecodeValue()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		got = append(got, v)
+	}
+	if len(got) != 4 || !Equal(got[0], want) || !Equal(got[1], integer(2)) || !Equal(got[2], String("three")) {
+		t.Fatalf("unexpected values %d", len(got))
+	}
+	if cr.reads < len(input)/1024 {
+		t.Errorf("unexpected number of reads %d", cr.reads)
+	}
+}
+
+func TestDecoderDecode(t *testing.T) {
+	input := "{\"a\": 12345}\n[\"b\", true]\n{\"a\": \"c\"}"
+	dec := NewDecoder(iotest.OneByteReader(strings.NewReader(input)))
+	var s struct{ A int }
+	if !dec.More() {
+		t.Fatalf("expected more values")
+	}
+	if err := dec.Decode(&s); err != nil || s.A != 12345 {
+		t.Fatalf("unexpected result %v %v", s, err)
+	}
+	var a []any
+	if err := dec.Decode(&a); err != nil || !reflect.DeepEqual(a, []any{"b", true}) {
+		t.Fatalf("unexpected result %v %v", a, err)
+	}
+	err := dec.Decode(&s)
+	var ue UnmarshalError
+	if !errors.As(err, &ue) || *ue.Loc != (Loc{Row: 3, Col: 7, Offset: 31}) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if dec.More() {
+		t.Errorf("unexpected more values")
+	}
+	if err := dec.Decode(&s); err != io.EOF {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestDecoderInvalid(t *testing.T) {
+	dec := NewDecoder(iotest.OneByteReader(strings.NewReader(`[1 x 2]`)))
+	_, err := dec.DecodeValue()
+	var ite InvalidTokenError
+	if !errors.As(err, &ite) || ite.Col != 4 {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	dec = NewDecoder(iotest.ErrReader(io.ErrUnexpectedEOF))
+	if !dec.More() {
+		t.Errorf("expected more to report the read error")
+	}
+	if _, err := dec.DecodeValue(); err != io.ErrUnexpectedEOF {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	tests := []struct {
+		name       string
+		separator  Separator
+		seq        bool
+		serializer Serializer
+		values     []Value
+		want       string
+	}{
+		{
+			name:   "newline",
+			values: []Value{integer(1), Array{Null{}}},
+			want:   "1\n[null]\n",
+		},
+		{
+			name:      "record",
+			separator: SeparatorRecord,
+			values:    []Value{integer(1), Array{Null{}}},
+			want:      "\x1e1\n\x1e[null]\n",
+		},
+		{
+			name:   "deprecated-seq",
+			seq:    true,
+			values: []Value{integer(1), Array{Null{}}},
+			want:   "\x1e1\n\x1e[null]\n",
+		},
+		{
+			name:      "array-empty",
+			separator: SeparatorArray,
+			want:      "[]\n",
+		},
+		{
+			name:      "array",
+			separator: SeparatorArray,
+			values:    []Value{integer(1), Array{Null{}}},
+			want:      "[1,[null]]\n",
+		},
+		{
+			name:       "array-indent",
+			separator:  SeparatorArray,
+			serializer: Serializer{Indent: 2},
+			values:     []Value{integer(1), Array{Null{}}},
+			want:       "[\n  1,\n  [\n    null\n  ]\n]\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := bufio.NewWriter(&buf)
+			enc := NewEncoder(w)
+			enc.Separator = tt.separator
+			enc.Seq = tt.seq
+			enc.Serializer = tt.serializer
+			for _, v := range tt.values {
+				if err := enc.Encode(v); err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+			}
+			if err := enc.Close(); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("unexpected output %q != %q", buf.String(), tt.want)
+			}
+			if enc.Written() != int64(len(tt.want)) {
+				t.Errorf("unexpected bytes written %d", enc.Written())
+			}
+		})
+	}
+}
+
+// cancelWriter cancels its context after the first write.
+type cancelWriter struct {
+	bytes.Buffer
+	cancel context.CancelFunc
+}
+
+func (w *cancelWriter) Write(b []byte) (int, error) {
+	w.cancel()
+	return w.Buffer.Write(b)
+}
+
+func TestEncodeContext(t *testing.T) {
+	large := make(Array, encodeFlushSize)
+	for i := range large {
+		large[i] = integer(1)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	if n, err := enc.EncodeContext(ctx, large); n != 0 || !errors.Is(err, context.Canceled) || buf.Len() != 0 {
+		t.Errorf("unexpected result %d %v", n, err)
+	}
+
+	// Cancelled while serializing.
+	ctx, cancel = context.WithCancel(context.Background())
+	enc.Serializer.ProgressInterval = 10
+	enc.Serializer.Progress = func(_, values int) {
+		if values == 10 {
+			cancel()
+		}
+	}
+	if n, err := enc.EncodeContext(ctx, large); n != 0 || !errors.Is(err, context.Canceled) || buf.Len() != 0 {
+		t.Errorf("unexpected result %d %v", n, err)
+	}
+
+	// Cancelled while writing.
+	ctx, cancel = context.WithCancel(context.Background())
+	w := &cancelWriter{cancel: cancel}
+	enc = NewEncoder(w)
+	n, err := enc.EncodeContext(ctx, large)
+	if n < encodeFlushSize || n >= len(Serialize(large)) || !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected result %d %v", n, err)
+	}
+	if w.Len() != n || enc.Written() != int64(n) {
+		t.Errorf("unexpected bytes written %d %d", w.Len(), enc.Written())
+	}
+}
+
+// chunkWriter records the size of every write and fails the first fail writes.
+type chunkWriter struct {
+	bytes.Buffer
+	writes []int
+	fail   int
+}
+
+func (w *chunkWriter) Write(b []byte) (int, error) {
+	if w.fail > 0 {
+		w.fail--
+		return 0, errors.New("write failed")
+	}
+	w.writes = append(w.writes, len(b))
+	return w.Buffer.Write(b)
+}
+
+func TestEncodeStreams(t *testing.T) {
+	large := make(Array, 4*encodeFlushSize)
+	for i := range large {
+		large[i] = String("ab")
+	}
+	w := &chunkWriter{}
+	enc := NewEncoder(w)
+	n, err := enc.EncodeContext(context.Background(), large)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if want := string(Serialize(large)) + "\n"; w.String() != want || n != len(want) {
+		t.Errorf("unexpected output of %d bytes", n)
+	}
+	// The value is written in chunks as it is serialized rather than all at once.
+	if len(w.writes) < 4 {
+		t.Errorf("unexpected writes %v", w.writes)
+	}
+	for _, size := range w.writes {
+		if size > encodeFlushSize+16 {
+			t.Errorf("unexpected write of %d bytes", size)
+		}
+	}
+}
+
+func TestEncodeWriteError(t *testing.T) {
+	// A value whose write fails is not counted, so the next value opens the array.
+	w := &chunkWriter{fail: 1}
+	enc := NewEncoder(w)
+	enc.Separator = SeparatorArray
+	if err := enc.Encode(integer(1)); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if err := enc.Encode(integer(2)); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if w.String() != "[2]\n" {
+		t.Errorf("unexpected output %q", w.String())
+	}
+}
+
+func TestBufferedEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewBufferedEncoder(&buf, 1024)
+	if err := enc.Encode(integer(1)); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("output visible before flush %q", buf.String())
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if buf.String() != "1\n" {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+}
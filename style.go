@@ -0,0 +1,61 @@
+package genjson
+
+import "fmt"
+
+// Style is a named set of Serializer options, allowing formatting to be standardized by name.
+type Style string
+
+const (
+	// StyleCompact writes values without any whitespace.
+	StyleCompact Style = "compact"
+	// StylePretty2 writes every value on its own line, indented by 2 spaces per level.
+	StylePretty2 Style = "pretty2"
+	// StylePretty4Tabs writes every value on its own line, indented by a tab per level.
+	StylePretty4Tabs Style = "pretty4tabs"
+	// StyleCanonical writes values without any whitespace, with sorted keys and with canonical
+	// string escaping, so that equal values are written identically regardless of the key order
+	// and escaping of their source.
+	StyleCanonical Style = "canonical"
+)
+
+// Styles returns every named style.
+func Styles() []Style {
+	return []Style{StyleCompact, StylePretty2, StylePretty4Tabs, StyleCanonical}
+}
+
+// ParseStyle returns the style with the given name.
+func ParseStyle(name string) (Style, error) {
+	for _, st := range Styles() {
+		if string(st) == name {
+			return st, nil
+		}
+	}
+	return "", UnknownStyleError{Name: name}
+}
+
+// Serializer returns the options of the style. Unknown styles return the options of
+// StyleCompact.
+func (st Style) Serializer() Serializer {
+	switch st {
+	case StylePretty2:
+		return Serializer{Indent: 2, KeyValueGap: 1}
+	case StylePretty4Tabs:
+		return Serializer{IndentString: "\t", KeyValueGap: 1}
+	case StyleCanonical:
+		return Serializer{SortKeys: true, CanonicalStrings: true}
+	default:
+		return Serializer{}
+	}
+}
+
+// ---------------- errors ----------------
+
+type UnknownStyleError struct {
+	Name string
+}
+
+func (e UnknownStyleError) Error() string {
+	return fmt.Sprintf("unknown style %q", e.Name)
+}
+
+// ---------------- errors end ----------------
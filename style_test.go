@@ -0,0 +1,36 @@
+package genjson
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStyle(t *testing.T) {
+	v := MustDeserializeString(`{"b": [1], "a": {}}`)
+	tests := []struct {
+		style Style
+		want  string
+	}{
+		{style: StyleCompact, want: `{"b":[1],"a":{}}`},
+		{style: StylePretty2, want: "{\n  \"b\": [\n    1\n  ],\n  \"a\": {}\n}"},
+		{style: StylePretty4Tabs, want: "{\n\t\"b\": [\n\t\t1\n\t],\n\t\"a\": {}\n}"},
+		{style: StyleCanonical, want: `{"a":{},"b":[1]}`},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.style), func(t *testing.T) {
+			st, err := ParseStyle(string(tt.style))
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			s := st.Serializer()
+			if got := string(s.Serialize(v)); got != tt.want {
+				t.Errorf("unexpected output %q != %q", got, tt.want)
+			}
+		})
+	}
+
+	var use UnknownStyleError
+	if _, err := ParseStyle("pretty3"); !errors.As(err, &use) || use.Name != "pretty3" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,223 @@
+package genjson
+
+import "io"
+
+// Token is a single token of a json stream returned by Decoder.Token. Commas and colons are
+// validated but not returned as tokens.
+type Token struct {
+	// Type is the type of the token. Delimiters have the type TokenPunctuation and object keys
+	// have the type TokenKey.
+	Type TokenType
+	// Delim is the delimiter of punctuation tokens: one of [ ] { }.
+	Delim byte
+	// Value is the value of every other token. Keys are Strings.
+	Value Value
+	// Start is the location of the first byte of the token.
+	Start Loc
+	// End is the location after the last byte of the token.
+	End Loc
+}
+
+// tokenState is the position of the Decoder within the containers opened by Token.
+type tokenState int8
+
+const (
+	// tokenValue expects a value, at the top level or within a container.
+	tokenValue tokenState = iota
+	// tokenArrayStart expects the first element of an array or its end.
+	tokenArrayStart
+	// tokenArrayValue expects a comma or the end of an array.
+	tokenArrayValue
+	// tokenObjectStart expects the first key of an object or its end.
+	tokenObjectStart
+	// tokenObjectKey expects the key of an object.
+	tokenObjectKey
+	// tokenObjectColon expects the colon after a key.
+	tokenObjectColon
+	// tokenObjectValue expects a comma or the end of an object.
+	tokenObjectValue
+)
+
+// Token returns the next token of the stream. Arrays and objects are returned as their delimiters
+// with their contents in between, so that a stream can be consumed without materializing its
+// values. io.EOF is returned once there are no more values at the top level.
+//
+// Token may be mixed with Decode and DecodeValue, which then decode the next complete value
+// within the current array or object, e.g. to decode the elements of a large array one by one.
+// Within an object, Decode and DecodeValue may only be used for values and not for keys.
+// Token does not support json text sequences or SeparatorArray.
+func (dec *Decoder) Token() (Token, error) {
+	if err := dec.start(); err != nil {
+		return Token{}, err
+	}
+	for {
+		if err := dec.skip(isSpace); err != nil {
+			return Token{}, err
+		}
+		d, c, br := read(dec.d)
+		if !br.OK {
+			if dec.tokenState == tokenValue && len(dec.tokenStack) == 0 {
+				return Token{}, io.EOF
+			}
+			return Token{}, ErrUnexpectedEndOfInput
+		}
+		switch dec.tokenState {
+		case tokenArrayValue:
+			switch c {
+			case ',':
+				dec.d = d
+				dec.tokenState = tokenValue
+				continue
+			case ']':
+				return dec.closeToken(d, c), nil
+			}
+			return Token{}, errNoMatch(dec.d)
+		case tokenObjectValue:
+			switch c {
+			case ',':
+				dec.d = d
+				dec.tokenState = tokenObjectKey
+				continue
+			case '}':
+				return dec.closeToken(d, c), nil
+			}
+			return Token{}, errNoMatch(dec.d)
+		case tokenObjectColon:
+			if c != ':' {
+				return Token{}, errNoMatch(dec.d)
+			}
+			dec.d = d
+			dec.tokenState = tokenValue
+			continue
+		case tokenArrayStart:
+			if c == ']' {
+				return dec.closeToken(d, c), nil
+			}
+		case tokenObjectStart, tokenObjectKey:
+			if c == '}' && dec.tokenState == tokenObjectStart {
+				return dec.closeToken(d, c), nil
+			}
+			start := dec.d.loc()
+			key, err := parseToken(dec, parserC[string](stringHookParser(rawStringParser(), true)))
+			if err != nil {
+				return Token{}, err
+			}
+			dec.tokenState = tokenObjectColon
+			return Token{Type: TokenKey, Value: String(key), Start: start, End: dec.d.loc()}, nil
+		}
+
+		// A value is expected.
+		switch c {
+		case '[', '{':
+			start := dec.d.loc()
+			dec.d = d
+			dec.tokenStack = append(dec.tokenStack, c)
+			dec.tokenState = tokenArrayStart
+			if c == '{' {
+				dec.tokenState = tokenObjectStart
+			}
+			return Token{Type: TokenPunctuation, Delim: c, Start: start, End: d.loc()}, nil
+		}
+		o, err := parseToken(dec, scalarParser())
+		if err != nil {
+			return Token{}, err
+		}
+		dec.tokenState = dec.afterValue()
+		tok := Token{Value: o.value, Start: o.node.start, End: o.node.end}
+		switch o.value.(type) {
+		case Null:
+			tok.Type = TokenNull
+		case Bool:
+			tok.Type = TokenBool
+		case Number:
+			tok.Type = TokenNumber
+		default:
+			tok.Type = TokenString
+		}
+		return tok, nil
+	}
+}
+
+// closeToken consumes the end delimiter of the innermost container. d is the state after the
+// delimiter.
+func (dec *Decoder) closeToken(d deserializer, c byte) Token {
+	start := dec.d.loc()
+	dec.d = d
+	dec.tokenStack = dec.tokenStack[:len(dec.tokenStack)-1]
+	dec.tokenState = dec.afterValue()
+	return Token{Type: TokenPunctuation, Delim: c, Start: start, End: d.loc()}
+}
+
+// afterValue returns the state after a complete value in the innermost container.
+func (dec *Decoder) afterValue() tokenState {
+	if len(dec.tokenStack) == 0 {
+		return tokenValue
+	}
+	if dec.tokenStack[len(dec.tokenStack)-1] == '[' {
+		return tokenArrayValue
+	}
+	return tokenObjectValue
+}
+
+// prepareValue consumes the separator before a value decoded within a container opened by Token.
+func (dec *Decoder) prepareValue() error {
+	var sep byte
+	switch dec.tokenState {
+	case tokenArrayValue:
+		sep = ','
+	case tokenObjectColon:
+		sep = ':'
+	case tokenObjectStart, tokenObjectKey, tokenObjectValue:
+		// A key is expected, which is not a complete value.
+		if err := dec.skip(isSpace); err != nil {
+			return err
+		}
+		return errNoMatch(dec.d)
+	default:
+		return nil
+	}
+	if err := dec.skip(isSpace); err != nil {
+		return err
+	}
+	d, c, br := read(dec.d)
+	if !br.OK || c != sep {
+		return errNoMatch(dec.d)
+	}
+	dec.d = d
+	dec.tokenState = tokenValue
+	return nil
+}
+
+// parseToken runs p at the current position of the decoder, reading more input until the result
+// of p no longer depends on input that has not been read.
+func parseToken[V any](dec *Decoder, p parserC[V]) (V, error) {
+	for {
+		d := dec.d
+		partial := false
+		if !dec.eof {
+			d.partial = &partial
+		}
+		nd, v, cr := p(d)
+		var err error
+		if !cr.Valid() {
+			err = cr.Err
+			if err == nil {
+				err = errNoMatch(d)
+			}
+		}
+		if partial {
+			if err := dec.fill(); err != nil {
+				var zero V
+				return zero, err
+			}
+			continue
+		}
+		if err != nil {
+			var zero V
+			return zero, err
+		}
+		nd.partial = nil
+		dec.d = nd
+		return v, nil
+	}
+}
@@ -0,0 +1,135 @@
+package genjson
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// tokenString describes a token for comparison in tests.
+func tokenString(tok Token) string {
+	if tok.Type == TokenPunctuation {
+		return string(tok.Delim)
+	}
+	return tok.Type.String() + ":" + string(Serialize(tok.Value))
+}
+
+func TestDecoderToken(t *testing.T) {
+	input := `{"a": [1, "b", true, null], "c": {}, "d": []} 12 [{"e": -1.5}]`
+	want := []string{
+		"{", `key:"a"`, "[", "number:1", `string:"b"`, "bool:true", "null:null", "]",
+		`key:"c"`, "{", "}", `key:"d"`, "[", "]", "}",
+		"number:12",
+		"[", "{", `key:"e"`, "number:-1.5", "}", "]",
+	}
+	for name, r := range map[string]io.Reader{
+		"reader":   strings.NewReader(input),
+		"one-byte": iotest.OneByteReader(strings.NewReader(input)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			dec := NewDecoder(r)
+			var got []string
+			for {
+				tok, err := dec.Token()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				got = append(got, tokenString(tok))
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("unexpected tokens\n%v\n%v", got, want)
+			}
+		})
+	}
+}
+
+func TestDecoderTokenLoc(t *testing.T) {
+	dec := NewDecoder(strings.NewReader("{\n  \"ab\": 10\n}"))
+	var got []Token
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		got = append(got, tok)
+	}
+	want := []Token{
+		{Type: TokenPunctuation, Delim: '{', Start: Loc{1, 1, 0}, End: Loc{1, 2, 1}},
+		{Type: TokenKey, Value: String("ab"), Start: Loc{2, 3, 4}, End: Loc{2, 7, 8}},
+		{Type: TokenNumber, Value: integer(10), Start: Loc{2, 9, 10}, End: Loc{2, 11, 12}},
+		{Type: TokenPunctuation, Delim: '}', Start: Loc{3, 1, 13}, End: Loc{3, 2, 14}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected tokens\n%+v\n%+v", got, want)
+	}
+}
+
+func TestDecoderTokenDecode(t *testing.T) {
+	dec := NewDecoder(iotest.OneByteReader(strings.NewReader(`{"items": [{"A": 1}, {"A": 2}], "n": 3}`)))
+	for _, want := range []string{"{", `key:"items"`, "["} {
+		tok, err := dec.Token()
+		if err != nil || tokenString(tok) != want {
+			t.Fatalf("unexpected token %v %v", tokenString(tok), err)
+		}
+	}
+	var got []int
+	for dec.More() {
+		var item struct{ A int }
+		if err := dec.Decode(&item); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		got = append(got, item.A)
+	}
+	if !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("unexpected items %v", got)
+	}
+	for _, want := range []string{"]", `key:"n"`} {
+		tok, err := dec.Token()
+		if err != nil || tokenString(tok) != want {
+			t.Fatalf("unexpected token %v %v", tokenString(tok), err)
+		}
+	}
+	v, err := dec.DecodeValue()
+	if err != nil || v != integer(3) {
+		t.Fatalf("unexpected value %v %v", v, err)
+	}
+	if tok, err := dec.Token(); err != nil || tokenString(tok) != "}" {
+		t.Fatalf("unexpected token %v %v", tokenString(tok), err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestDecoderTokenErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  error
+	}{
+		{input: `[1 2]`, want: InvalidTokenError{Token: '2', Row: 1, Col: 4}},
+		{input: `{"a" 1}`, want: InvalidTokenError{Token: '1', Row: 1, Col: 6}},
+		{input: `{1: 2}`, want: InvalidTokenError{Token: '1', Row: 1, Col: 2}},
+		{input: `[1,`, want: ErrUnexpectedEndOfInput},
+		{input: `[tru]`, want: InvalidTokenError{Token: 't', Row: 1, Col: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			dec := NewDecoder(iotest.OneByteReader(strings.NewReader(tt.input)))
+			var err error
+			for err == nil {
+				_, err = dec.Token()
+			}
+			if err != tt.want {
+				t.Errorf("unexpected error %v != %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mattpgray/go-genjson"
+)
+
+// runAgg runs the agg subcommand, which aggregates json lines read from files or stdin and
+// returns the exit code.
+func runAgg(args []string) int {
+	fs := flag.NewFlagSet("agg", flag.ExitOnError)
+	var (
+		groupBy = fs.String("group", "", "The dot separated path of the value used to group records e.g. level or req.method.")
+		path    = fs.String("path", "", "The dot separated path of the number summed and compared e.g. latency_ms. If empty, records that are numbers are summarized.")
+		indent  = fs.Int("indent", 4, "The indent of the json output.")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s agg [flags] [file ...]\n\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "Aggregates json lines into a count and, with -path, the sum, min and max of a number for each group.\n")
+		fmt.Fprintf(fs.Output(), "With no files, the json lines are read from stdin.\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	ag := genjson.Aggregator{
+		GroupBy: splitPath(*groupBy),
+		Path:    splitPath(*path),
+	}
+	if fs.NArg() == 0 {
+		if err := ag.Decode(genjson.NewDecoder(os.Stdin)); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			return 1
+		}
+	}
+	for _, file := range fs.Args() {
+		f, err := os.Open(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			return 1
+		}
+		err = ag.Decode(genjson.NewDecoder(f))
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s: %v\n", file, err)
+			return 1
+		}
+	}
+
+	s := genjson.Serializer{Indent: *indent, KeyValueGap: 1}
+	if err := s.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return 2
+	}
+	fmt.Printf("%s\n", s.Serialize(ag.Value()))
+	return 0
+}
+
+func splitPath(path string) []string {
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mattpgray/go-genjson"
+)
+
+// runAST runs the ast subcommand, which prints the syntax tree of a json file and returns the exit
+// code.
+func runAST(args []string) int {
+	fs := flag.NewFlagSet("ast", flag.ExitOnError)
+	indent := fs.Int("indent", 4, "The indent of the json output.")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s ast [flags] [file]\n\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "Prints the syntax tree of the json as json, with the type, location and byte range of every value.\n")
+		fmt.Fprintf(fs.Output(), "With no file, the json is read from stdin.\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	s := genjson.Serializer{Indent: *indent, KeyValueGap: 1}
+	if err := s.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return 2
+	}
+	var (
+		data []byte
+		err  error
+	)
+	if fs.NArg() == 0 {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(fs.Arg(0))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return 1
+	}
+	doc, err := genjson.DeserializeDocument(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return 1
+	}
+	fmt.Printf("%s\n", s.Serialize(genjson.DumpAST(doc)))
+	return 0
+}
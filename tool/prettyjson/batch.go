@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/mattpgray/go-genjson"
+)
+
+// batch formats or validates many files.
+type batch struct {
+	serializer genjson.Serializer
+	// out is the serializer of the output printed to stdout, which may be colored.
+	out     genjson.Serializer
+	newline finalNewline
+	write   bool
+	list    bool
+	// check only validates and lints files, without formatting them.
+	check  bool
+	jobs   int
+	jsSafe bool
+	// duplicates reports duplicate keys as warnings, or as failures if strict is also set.
+	duplicates bool
+	strict     bool
+	fetcher    *fetcher
+	report     *report
+}
+
+// result is the outcome of processing a single file.
+type result struct {
+	// out is written to stdout.
+	out     []byte
+	changed bool
+	lint    []diagnostic
+	err     error
+	done    chan struct{}
+}
+
+// run processes all files matching the arguments and returns the exit code.
+func (b *batch) run(args []string) int {
+	var files []string
+	for _, arg := range args {
+		matches, err := expand(arg)
+		if err != nil {
+			b.report.error(arg, err)
+			continue
+		}
+		files = append(files, matches...)
+	}
+
+	// Files are processed by a pool of workers but results are reported in input order so that the
+	// output is deterministic.
+	results := make([]result, len(files))
+	for i := range results {
+		results[i].done = make(chan struct{})
+	}
+	work := make(chan int)
+	jobs := b.jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+	for j := 0; j < jobs; j++ {
+		go func() {
+			for i := range work {
+				results[i].err = b.process(files[i], &results[i])
+				close(results[i].done)
+			}
+		}()
+	}
+	go func() {
+		for i := range files {
+			work <- i
+		}
+		close(work)
+	}()
+
+	for i, file := range files {
+		<-results[i].done
+		err := results[i].err
+		if err == nil {
+			_, err = os.Stdout.Write(results[i].out)
+		}
+		if err != nil {
+			b.report.error(file, err)
+			continue
+		}
+		for _, d := range results[i].lint {
+			b.report.lint(d)
+		}
+		if b.list && results[i].changed {
+			b.report.unformatted(file)
+		}
+	}
+	return b.report.finish(len(files))
+}
+
+// process formats, validates and lints the file, storing the output that should be written to
+// stdout, whether the formatting differs and any lint diagnostics in r.
+func (b *batch) process(file string, r *result) error {
+	data, err := b.read(file)
+	if err != nil {
+		return err
+	}
+	doc, err := genjson.DeserializeDocument(data)
+	if err != nil {
+		return err
+	}
+	if b.jsSafe {
+		for _, loc := range doc.UnsafeIntegers() {
+			r.lint = append(r.lint, diagnostic{
+				file:    file,
+				row:     loc.Row,
+				col:     loc.Col,
+				rule:    ruleJSSafeInteger,
+				message: "integer cannot be represented exactly by a javascript number",
+			})
+		}
+	}
+	if b.duplicates || b.strict {
+		r.lint = append(r.lint, duplicateKeys(file, doc, b.strict, 0)...)
+	}
+	if b.check {
+		return nil
+	}
+	formatted := b.newline.appendTo(b.serializer.Serialize(doc.Value()), data)
+	r.changed = !bytes.Equal(data, formatted)
+	if b.write && r.changed {
+		if isURL(file) {
+			return errWriteURL
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(file, formatted, info.Mode().Perm())
+	}
+	if !b.list && !b.write && !b.report.json {
+		r.out = formatted
+		if b.out.Colors != nil {
+			r.out = b.newline.appendTo(b.out.Serialize(doc.Value()), data)
+		}
+	}
+	return nil
+}
+
+// read returns the contents of the file, fetching it if it is a url.
+func (b *batch) read(file string) ([]byte, error) {
+	if isURL(file) {
+		return b.fetcher.fetch(file)
+	}
+	return os.ReadFile(file)
+}
+
+// expand returns the files matching the argument, which may be a file, a directory, a glob
+// pattern or a url. Directories are walked for .json files. Glob patterns are resolved relative to
+// their longest directory prefix without meta characters.
+func expand(arg string) ([]string, error) {
+	if isURL(arg) {
+		return []string{arg}, nil
+	}
+	base, pattern := splitPattern(arg)
+	if pattern == "" {
+		info, err := os.Stat(base)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return []string{base}, nil
+		}
+		return walk(os.DirFS(base), base, ".")
+	}
+
+	fsys := os.DirFS(base)
+	matches, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("%s: no matching files", arg)
+	}
+	var files []string
+	for _, match := range matches {
+		info, err := fs.Stat(fsys, match)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, filepath.Join(base, filepath.FromSlash(match)))
+			continue
+		}
+		walked, err := walk(fsys, base, match)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, walked...)
+	}
+	return files, nil
+}
+
+// walk returns the .json files below root in fsys, with base prepended.
+func walk(fsys fs.FS, base, root string) ([]string, error) {
+	var files []string
+	err := fs.WalkDir(fsys, root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && path.Ext(name) == ".json" {
+			files = append(files, filepath.Join(base, filepath.FromSlash(name)))
+		}
+		return nil
+	})
+	return files, err
+}
+
+// splitPattern splits the argument into the directory prefix containing no glob meta characters
+// and the remaining pattern, which is empty if the argument is not a glob pattern.
+func splitPattern(arg string) (string, string) {
+	parts := strings.Split(filepath.ToSlash(arg), "/")
+	for i, part := range parts {
+		if strings.ContainsAny(part, `*?[\`) {
+			base := strings.Join(parts[:i], "/")
+			if base == "" && i > 0 {
+				base = "/"
+			} else if base == "" {
+				base = "."
+			}
+			return filepath.FromSlash(base), strings.Join(parts[i:], "/")
+		}
+	}
+	return arg, ""
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/mattpgray/go-genjson"
+)
+
+// reportFormat registers the -format flag of the validate, lint and diff subcommands.
+func reportFormat(fs *flag.FlagSet) *string {
+	return fs.String("format", "text", "The format of the report, text or json. In json mode, a single json report with the file, row, col, rule and message of every problem is written to stdout.")
+}
+
+// newReport returns the report for the value of the -format flag.
+func newReport(format string) (*report, error) {
+	if format != "text" && format != "json" {
+		return nil, fmt.Errorf("unknown report format %q", format)
+	}
+	return &report{json: format == "json"}, nil
+}
+
+// runValidate runs the validate subcommand, which reports the files that are not valid json and
+// returns the exit code.
+func runValidate(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	var (
+		format = reportFormat(fs)
+		jobs   = fs.Int("j", 1, "The number of files to process in parallel. Results are always reported in argument order.")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s validate [flags] path ...\n\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "Reports the files that cannot be read or are not valid json. Paths are resolved as by the\n")
+		fmt.Fprintf(fs.Output(), "formatter. The exit code is 1 if any file is invalid.\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	return runCheck(fs, *format, batch{jobs: *jobs, check: true})
+}
+
+// runLint runs the lint subcommand, which reports problems in valid json files and returns the
+// exit code.
+func runLint(args []string) int {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	var (
+		format = reportFormat(fs)
+		jobs   = fs.Int("j", 1, "The number of files to process in parallel. Results are always reported in argument order.")
+		jsSafe = fs.Bool("js-safe", true, "Report integers that cannot be represented exactly by javascript numbers.")
+		strict = fs.Bool("strict", false, "Report duplicate keys as failures instead of warnings.")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s lint [flags] path ...\n\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "Reports invalid files, duplicate keys and integers that javascript cannot represent exactly.\n")
+		fmt.Fprintf(fs.Output(), "The exit code is 1 if any problem other than a warning is found.\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	return runCheck(fs, *format, batch{jobs: *jobs, check: true, jsSafe: *jsSafe, duplicates: true, strict: *strict})
+}
+
+// runCheck checks the files of the arguments of fs with b and returns the exit code.
+func runCheck(fs *flag.FlagSet, format string, b batch) int {
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	r, err := newReport(format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return 2
+	}
+	b.report = r
+	b.fetcher = &fetcher{client: &http.Client{Timeout: 30 * time.Second}, maxSize: 10 << 20}
+	return b.run(fs.Args())
+}
+
+// runDiff runs the diff subcommand, which reports the differences between the values of two
+// files and returns the exit code.
+func runDiff(args []string) int {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	format := reportFormat(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s diff [flags] old new\n\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "Reports the values that differ between two json files, ignoring formatting and key order.\n")
+		fmt.Fprintf(fs.Output(), "Removed values are located in the old file and other changes in the new file. The exit code\n")
+		fmt.Fprintf(fs.Output(), "is 1 if the files differ.\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+	r, err := newReport(*format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return 2
+	}
+	files := fs.Args()
+	var docs [2]*genjson.Document
+	for i, file := range files {
+		data, err := os.ReadFile(file)
+		if err == nil {
+			docs[i], err = genjson.DeserializeDocument(data)
+		}
+		if err != nil {
+			r.error(file, err)
+		}
+	}
+	if docs[0] != nil && docs[1] != nil {
+		for _, c := range genjson.DiffChanges(docs[0].Value(), docs[1].Value()) {
+			file, doc := files[1], docs[1]
+			if c.Kind == genjson.ChangeRemoved {
+				file, doc = files[0], docs[0]
+			}
+			// The rule is the kind of change: added, removed or modified.
+			d := diagnostic{file: file, rule: c.Kind.String(), message: c.String()}
+			if loc, err := doc.Loc(c.Path); err == nil {
+				d.row, d.col = loc.Row, loc.Col
+			}
+			r.lint(d)
+		}
+	}
+	return r.finish(len(files))
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mattpgray/go-genjson"
+)
+
+// writeFiles writes the files to a temporary directory and returns their paths in order.
+func writeFiles(t *testing.T, contents ...string) []string {
+	t.Helper()
+	dir := t.TempDir()
+	var files []string
+	for i, c := range contents {
+		file := filepath.Join(dir, string(rune('a'+i))+".json")
+		if err := os.WriteFile(file, []byte(c), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+// jsonDiagnostics returns the file, row, col, rule and message of every diagnostic of a json
+// report, one line each.
+func jsonDiagnostics(t *testing.T, stdout string) []string {
+	t.Helper()
+	v, err := genjson.DeserializeString(stdout)
+	if err != nil {
+		t.Fatalf("invalid report %v\n%s", err, stdout)
+	}
+	diags, err := genjson.RequireArray(genjson.GetOr(v, nil, "diagnostics"))
+	if err != nil {
+		t.Fatalf("invalid report %v\n%s", err, stdout)
+	}
+	var lines []string
+	for _, d := range diags {
+		file := genjson.GetStringOr(d, "", "file")
+		lines = append(lines, strings.Join([]string{
+			filepath.Base(file),
+			string(genjson.Serialize(genjson.GetOr(d, nil, "row"))),
+			string(genjson.Serialize(genjson.GetOr(d, nil, "col"))),
+			genjson.GetStringOr(d, "", "rule"),
+			genjson.GetStringOr(d, "", "message"),
+		}, " "))
+	}
+	return lines
+}
+
+func TestValidate(t *testing.T) {
+	files := writeFiles(t, `{"a": 1}`, "{\n  \"a\": x\n}")
+	stdout, stderr, code := runCommand(t, "", nil, "validate", "-format", "json", files[0], files[1])
+	if code != 1 {
+		t.Fatalf("unexpected exit code %d: %s", code, stderr)
+	}
+	got := jsonDiagnostics(t, stdout)
+	want := []string{"b.json 2 8 syntax 2:8: invalid token 'x'"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected diagnostics\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	stdout, stderr, code = runCommand(t, "", nil, "validate", files[0])
+	if code != 0 || stdout != "" || !strings.Contains(stderr, "1 files processed, 0 failed") {
+		t.Errorf("unexpected result %d %q %s", code, stdout, stderr)
+	}
+	if _, _, code := runCommand(t, "", nil, "validate", "-format", "xml", files[0]); code != 2 {
+		t.Errorf("unexpected exit code %d", code)
+	}
+}
+
+func TestLint(t *testing.T) {
+	files := writeFiles(t, "{\"a\": 1,\n \"a\": 9007199254740993}")
+	stdout, stderr, code := runCommand(t, "", nil, "lint", "-format", "json", files[0])
+	if code != 1 {
+		t.Fatalf("unexpected exit code %d: %s", code, stderr)
+	}
+	got := jsonDiagnostics(t, stdout)
+	want := []string{
+		"a.json 2 7 js-safe-integer integer cannot be represented exactly by a javascript number",
+		`a.json 2 2 duplicate-key duplicate key "a", first defined at 1:2`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected diagnostics\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Duplicate keys are warnings unless -strict is set.
+	_, stderr, code = runCommand(t, "", nil, "lint", "-js-safe=false", files[0])
+	if code != 0 || !strings.Contains(stderr, "WARNING: "+files[0]+":2:2: duplicate key") {
+		t.Errorf("unexpected result %d %s", code, stderr)
+	}
+	if _, stderr, code := runCommand(t, "", nil, "lint", "-js-safe=false", "-strict", files[0]); code != 1 {
+		t.Errorf("unexpected result %d %s", code, stderr)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	files := writeFiles(t, "{\"a\": 1, \"b\": [1, 2]}", "{\n  \"b\": [1],\n  \"a\": 2,\n  \"c\": true\n}")
+	stdout, stderr, code := runCommand(t, "", nil, "diff", "-format", "json", files[0], files[1])
+	if code != 1 {
+		t.Fatalf("unexpected exit code %d: %s", code, stderr)
+	}
+	got := jsonDiagnostics(t, stdout)
+	want := []string{
+		"b.json 3 8 modified ~ a: 1 -> 2",
+		"a.json 1 19 removed - b[1]: 2",
+		"b.json 4 8 added + c: true",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected diagnostics\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if _, stderr, code := runCommand(t, "", nil, "diff", files[0], files[0]); code != 0 {
+		t.Errorf("unexpected result %d %s", code, stderr)
+	}
+	if _, _, code := runCommand(t, "", nil, "diff", files[0]); code != 2 {
+		t.Errorf("unexpected exit code %d", code)
+	}
+}
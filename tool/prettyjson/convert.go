@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mattpgray/go-genjson"
+)
+
+// streamFormats maps the names of the formats supported by the convert subcommand to the encoder
+// separator used to write them.
+var streamFormats = map[string]genjson.Separator{
+	"ndjson": genjson.SeparatorNewline,
+	"seq":    genjson.SeparatorRecord,
+	"array":  genjson.SeparatorArray,
+}
+
+// runConvert runs the convert subcommand, which converts a stream of json values between formats
+// and returns the exit code.
+func runConvert(args []string) int {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	var (
+		from   = fs.String("from", "ndjson", "The format of the input: ndjson, seq or array.")
+		to     = fs.String("to", "array", "The format of the output: ndjson, seq or array.")
+		indent = fs.Int("indent", 0, "The indent of each value. Only valid for array output.")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s convert [flags] [file ...]\n\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "Converts a stream of json values between json lines (ndjson), json text sequences (seq)\n")
+		fmt.Fprintf(fs.Output(), "and the elements of a single json array (array). With no files, the input is read from stdin.\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if _, ok := streamFormats[*from]; !ok {
+		fmt.Fprintf(os.Stderr, "ERROR: unknown input format %q\n", *from)
+		return 2
+	}
+	sep, ok := streamFormats[*to]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "ERROR: unknown output format %q\n", *to)
+		return 2
+	}
+	if *indent != 0 && sep != genjson.SeparatorArray {
+		fmt.Fprintf(os.Stderr, "ERROR: -indent is only valid for array output\n")
+		return 2
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	enc := genjson.NewEncoder(out)
+	enc.Separator = sep
+	enc.Serializer = genjson.Serializer{Indent: *indent}
+	if *indent > 0 {
+		enc.Serializer.KeyValueGap = 1
+	}
+	if err := enc.Serializer.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return 2
+	}
+
+	inputs := fs.Args()
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+	for _, file := range inputs {
+		if err := convertFile(file, *from, enc); err != nil {
+			if file == "-" {
+				file = "<stdin>"
+			}
+			fmt.Fprintf(os.Stderr, "ERROR: %s: %v\n", file, err)
+			return 1
+		}
+	}
+	if err := enc.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// convertFile encodes every value read from the file, or from stdin if file is "-". Array input
+// is a single json array whose elements are decoded and encoded one at a time, so that the array
+// is never held in memory.
+func convertFile(file, from string, enc *genjson.Encoder) error {
+	r := io.Reader(os.Stdin)
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	dec := genjson.NewDecoder(r)
+	dec.Separator = streamFormats[from]
+	for {
+		v, err := dec.DecodeValue()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+	}
+}
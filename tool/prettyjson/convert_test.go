@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		stdin string
+		args  []string
+		want  string
+	}{
+		{
+			name:  "ndjson-to-array",
+			stdin: "{\"a\": 1}\n[2]\n",
+			args:  []string{"-from", "ndjson", "-to", "array"},
+			want:  "[{\"a\":1},[2]]\n",
+		},
+		{
+			name:  "array-to-ndjson",
+			stdin: ` [{"a": 1}, [2], "b"] `,
+			args:  []string{"-from", "array", "-to", "ndjson"},
+			want:  "{\"a\":1}\n[2]\n\"b\"\n",
+		},
+		{
+			name:  "empty-array",
+			stdin: `[]`,
+			args:  []string{"-from", "array", "-to", "ndjson"},
+			want:  "",
+		},
+		{
+			name:  "seq-to-ndjson",
+			stdin: "\x1e1\n\x1e{\"a\": 2}\n",
+			args:  []string{"-from", "seq", "-to", "ndjson"},
+			want:  "1\n{\"a\":2}\n",
+		},
+		{
+			name:  "ndjson-to-seq",
+			stdin: "1\n2\n",
+			args:  []string{"-to", "seq"},
+			want:  "\x1e1\n\x1e2\n",
+		},
+		{
+			name:  "indent",
+			stdin: "{\"a\": 1}\n",
+			args:  []string{"-indent", "2"},
+			want:  "[\n  {\n    \"a\": 1\n  }\n]\n",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, code := runCommand(t, tt.stdin, nil, append([]string{"convert"}, tt.args...)...)
+			if code != 0 || stdout != tt.want {
+				t.Errorf("unexpected result %d %q %s", code, stdout, stderr)
+			}
+		})
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		stdin string
+		args  []string
+		code  int
+	}{
+		{name: "not-array", stdin: `{"a": 1}`, args: []string{"-from", "array"}, code: 1},
+		{name: "truncated-array", stdin: `[1, 2`, args: []string{"-from", "array"}, code: 1},
+		{name: "unknown-from", args: []string{"-from", "csv"}, code: 2},
+		{name: "unknown-to", args: []string{"-to", "csv"}, code: 2},
+		{name: "indent-ndjson", args: []string{"-to", "ndjson", "-indent", "2"}, code: 2},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, stderr, code := runCommand(t, tt.stdin, nil, append([]string{"convert"}, tt.args...)...)
+			if code != tt.code || !strings.HasPrefix(stderr, "ERROR: ") {
+				t.Errorf("unexpected result %d %s", code, stderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/mattpgray/go-genjson"
+)
+
+// runDedup runs the dedup subcommand, which writes the json lines read from files or stdin
+// without duplicate records and returns the exit code.
+func runDedup(args []string) int {
+	fs := flag.NewFlagSet("dedup", flag.ExitOnError)
+	var (
+		key   = fs.String("key", "", "The dot separated path of the value that identifies a record e.g. id or req.id. A backslash escapes a dot in a key. If empty, whole records are compared.")
+		bloom = fs.Int("bloom-bits", 0, "Bound memory by remembering records in a bloom filter of this many bits. Some unique records may be dropped as duplicates, at a rate reported on stderr.")
+		jobs  = fs.Int("j", 1, "The number of files to read in parallel. With more than 1, records of different files are interleaved in the output.")
+	)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s dedup [flags] [file ...]\n\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "Writes json lines without duplicate records, keeping the first occurrence. Records are compared\n")
+		fmt.Fprintf(fs.Output(), "by value, ignoring formatting and key order. With no files, the json lines are read from stdin.\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if *bloom < 0 || *jobs < 1 {
+		fmt.Fprintf(os.Stderr, "ERROR: -bloom-bits must not be negative and -j must be positive\n")
+		return 2
+	}
+	keyPath, err := genjson.ParseDotPath(*key)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: -key: %v\n", err)
+		return 2
+	}
+
+	dd := &genjson.Deduper{KeyPath: keyPath, BloomBits: *bloom}
+	out := bufio.NewWriter(os.Stdout)
+	enc := genjson.NewEncoder(out)
+	var (
+		mu     sync.Mutex
+		unique int
+	)
+	write := func(v genjson.Value) error {
+		mu.Lock()
+		defer mu.Unlock()
+		unique++
+		return enc.Encode(v)
+	}
+
+	inputs := fs.Args()
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+	var (
+		wg     sync.WaitGroup
+		sem    = make(chan struct{}, *jobs)
+		errs   = make([]error, len(inputs))
+		counts = make([]int, len(inputs))
+	)
+	for i, file := range inputs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, file string) {
+			defer func() { <-sem; wg.Done() }()
+			counts[i], errs[i] = dedupFile(dd, file, write)
+		}(i, file)
+	}
+	wg.Wait()
+	if err := out.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return 1
+	}
+
+	code, dups := 0, 0
+	for i, err := range errs {
+		dups += counts[i]
+		if err != nil {
+			file := inputs[i]
+			if file == "-" {
+				file = "<stdin>"
+			}
+			fmt.Fprintf(os.Stderr, "ERROR: %s: %v\n", file, err)
+			code = 1
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%d duplicates removed\n", dups)
+	if *bloom > 0 {
+		fmt.Fprintf(os.Stderr, "estimated false positive rate %.2g\n", dd.BloomFalsePositiveRate(unique))
+	}
+	return code
+}
+
+// dedupFile writes the unique records of the file, or of stdin if file is "-", and returns the
+// number of duplicates.
+func dedupFile(dd *genjson.Deduper, file string, write func(genjson.Value) error) (int, error) {
+	r := io.Reader(os.Stdin)
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+		r = f
+	}
+	return dd.Filter(genjson.NewDecoder(r), write)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDedup(t *testing.T) {
+	input := `{"id": 1, "n": 0.0015, "a.b": "x"}
+{"n": 1.5e-3, "id": 1, "a.b": "x"}
+{"id": 2, "n": 0.0015, "a.b": "y"}
+{"id": 1.0, "n": 2, "a.b": "x"}
+`
+	tests := []struct {
+		name     string
+		args     []string
+		want     string
+		wantDups string
+	}{
+		{
+			name:     "records",
+			want:     "{\"id\":1,\"n\":0.0015,\"a.b\":\"x\"}\n{\"id\":2,\"n\":0.0015,\"a.b\":\"y\"}\n{\"id\":1.0,\"n\":2,\"a.b\":\"x\"}\n",
+			wantDups: "1 duplicates removed",
+		},
+		{
+			name:     "key",
+			args:     []string{"-key", "id"},
+			want:     "{\"id\":1,\"n\":0.0015,\"a.b\":\"x\"}\n{\"id\":2,\"n\":0.0015,\"a.b\":\"y\"}\n",
+			wantDups: "2 duplicates removed",
+		},
+		{
+			name:     "escaped-key",
+			args:     []string{"-key", `a\.b`},
+			want:     "{\"id\":1,\"n\":0.0015,\"a.b\":\"x\"}\n{\"id\":2,\"n\":0.0015,\"a.b\":\"y\"}\n",
+			wantDups: "2 duplicates removed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, code := runCommand(t, input, nil, append([]string{"dedup"}, tt.args...)...)
+			if code != 0 {
+				t.Fatalf("unexpected exit code %d: %s", code, stderr)
+			}
+			if stdout != tt.want {
+				t.Errorf("unexpected output\n%s\nwant\n%s", stdout, tt.want)
+			}
+			if !strings.Contains(stderr, tt.wantDups) {
+				t.Errorf("unexpected stderr %q", stderr)
+			}
+		})
+	}
+}
+
+func TestDedupFiles(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")
+	if err := os.WriteFile(a, []byte("{\"id\": 1}\n{\"id\": 2}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b, []byte("{\"id\": 2}\n{\"id\": 3}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr, code := runCommand(t, "", nil, "dedup", a, b)
+	if code != 0 {
+		t.Fatalf("unexpected exit code %d: %s", code, stderr)
+	}
+	if want := "{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n"; stdout != want {
+		t.Errorf("unexpected output %q", stdout)
+	}
+
+	_, stderr, code = runCommand(t, "", nil, "dedup", filepath.Join(dir, "missing.json"))
+	if code != 1 || !strings.Contains(stderr, "missing.json") {
+		t.Errorf("unexpected result %d %q", code, stderr)
+	}
+}
+
+func TestDedupInvalidFlags(t *testing.T) {
+	for _, args := range [][]string{
+		{"-key", "a.*"},
+		{"-bloom-bits", "-1"},
+		{"-j", "0"},
+	} {
+		if _, stderr, code := runCommand(t, "", nil, append([]string{"dedup"}, args...)...); code != 2 {
+			t.Errorf("%v: unexpected exit code %d: %s", args, code, stderr)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+var (
+	errWriteURL  = errors.New("cannot write the result to a url")
+	errSizeLimit = errors.New("response exceeds the maximum size")
+)
+
+// fetchError is returned when a url cannot be fetched.
+type fetchError struct {
+	url string
+	err error
+}
+
+func (e *fetchError) Error() string {
+	return fmt.Sprintf("GET %s: %v", e.url, e.err)
+}
+
+func (e *fetchError) Unwrap() error {
+	return e.err
+}
+
+// fetcher downloads json from http and https urls.
+type fetcher struct {
+	client *http.Client
+	// maxSize is the maximum size of a response body in bytes.
+	maxSize int64
+}
+
+// isURL reports whether the argument should be fetched rather than read from the file system.
+func isURL(arg string) bool {
+	return strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://")
+}
+
+// fetch returns the body of a successful GET request to url.
+func (f *fetcher) fetch(url string) ([]byte, error) {
+	data, err := f.get(url)
+	if err != nil {
+		return nil, &fetchError{url: url, err: err}
+	}
+	return data, nil
+}
+
+func (f *fetcher) get(url string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, f.maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > f.maxSize {
+		return nil, fmt.Errorf("%w of %d bytes", errSizeLimit, f.maxSize)
+	}
+	return data, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFetch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "application/json" {
+			http.Error(w, "bad accept header", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"a": 1}`))
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`"` + strings.Repeat("a", 20) + `"`))
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := &fetcher{client: &http.Client{Timeout: 100 * time.Millisecond}, maxSize: 10}
+	if data, err := f.fetch(srv.URL + "/ok"); err != nil || string(data) != `{"a": 1}` {
+		t.Errorf("unexpected result %q %v", data, err)
+	}
+	for _, tt := range []struct {
+		path string
+		want string
+	}{
+		{path: "/large", want: "response exceeds the maximum size of 10 bytes"},
+		{path: "/missing", want: "404 Not Found"},
+		{path: "/slow", want: "Client.Timeout exceeded"},
+	} {
+		_, err := f.fetch(srv.URL + tt.path)
+		var fe *fetchError
+		if !errors.As(err, &fe) || fe.url != srv.URL+tt.path || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: unexpected error %v", tt.path, err)
+		}
+	}
+	if _, err := f.fetch(srv.URL + "/large"); !errors.Is(err, errSizeLimit) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestFetchCommand(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/a.json" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"a":[1]}`))
+	}))
+	defer srv.Close()
+
+	stdout, stderr, code := runCommand(t, "", nil, "-indent", "0", srv.URL+"/a.json")
+	if code != 0 || stdout != "{\"a\": [1]}\n" {
+		t.Errorf("unexpected result %d %q %s", code, stdout, stderr)
+	}
+	stdout, stderr, code = runCommand(t, "", nil, "-format", "json", "-max-size", "4", srv.URL+"/a.json", srv.URL+"/b.json")
+	if code != 1 {
+		t.Fatalf("unexpected exit code %d: %s", code, stderr)
+	}
+	got := strings.Join(jsonDiagnostics(t, stdout), "\n")
+	want := "a.json 0 0 io GET " + srv.URL + "/a.json: response exceeds the maximum size of 4 bytes\n" +
+		"b.json 0 0 io GET " + srv.URL + "/b.json: 503 Service Unavailable"
+	if got != want {
+		t.Errorf("unexpected diagnostics\n%s\nwant\n%s", got, want)
+	}
+}
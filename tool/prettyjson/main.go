@@ -1,38 +1,254 @@
 package main
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/mattpgray/go-genjson"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "agg":
+			os.Exit(runAgg(os.Args[2:]))
+		case "convert":
+			os.Exit(runConvert(os.Args[2:]))
+		case "ast":
+			os.Exit(runAST(os.Args[2:]))
+		case "dedup":
+			os.Exit(runDedup(os.Args[2:]))
+		case "validate":
+			os.Exit(runValidate(os.Args[2:]))
+		case "lint":
+			os.Exit(runLint(os.Args[2:]))
+		case "diff":
+			os.Exit(runDiff(os.Args[2:]))
+		}
+	}
 	var (
 		indent   = flag.Int("indent", 4, "The indent of the json. If 0, there will be not newlines in the output.")
 		prefix   = flag.Int("prefix", 0, "The prefix of the json. This can be useful if the output json is being injected into another json file.")
 		keyGap   = flag.Int("key-gap", 1, "Whether to include a space between keys and values in objects.")
 		sortKeys = flag.Bool("sort-keys", false, "Whether to sort keys in the output json")
+		keyOrder = flag.String("key-order", "", "Sort keys in a human friendly order: natural, which compares numbers in keys by value, or fold, which ignores case.")
+		write    = flag.Bool("w", false, "Write the result to the source file instead of stdout. Only valid with file arguments.")
+		list     = flag.Bool("l", false, "List the files whose formatting differs instead of printing the result. Only valid with file arguments.")
+		format   = flag.String("format", "text", "The format of the report for file arguments, text or json. In json mode, formatted output is not printed and a single json report is written to stdout.")
+		jsSafe   = flag.Bool("js-safe", false, "Report integers that cannot be represented exactly by javascript numbers as failures. Only valid with file arguments.")
+		intStr   = flag.Bool("unsafe-int-strings", false, "Write integers that cannot be represented exactly by javascript numbers as strings.")
+		ascii    = flag.Bool("ascii", false, "Escape every non-ascii character in strings as \\uXXXX so that the output is pure ascii.")
+		simplify = flag.Bool("simplify", false, "Write strings with canonical json escaping, only escaping the characters that json requires.")
+		dups     = flag.Bool("dup-keys", false, "Print a warning to stderr for every duplicate key, with its location and the location of the first occurrence of the key.")
+		strict   = flag.Bool("strict", false, "Report duplicate keys as failures.")
+		timeout  = flag.Duration("timeout", 30*time.Second, "The timeout for fetching each url argument.")
+		maxSize  = flag.Int64("max-size", 10<<20, "The maximum size in bytes of the response when fetching each url argument.")
+		jobs     = flag.Int("j", 1, "The number of files to process in parallel. Results are always reported in argument order.")
+		newline  = flag.String("final-newline", "always", "Whether the output ends with a newline: always, never or preserve, which keeps the final newline of the input if it has one.")
+		color    = flag.String("color", "auto", "Whether to highlight the output with ANSI colors: auto, which colors output to a terminal unless NO_COLOR is set to a non-empty value, always or never. Files written with -w are never colored.")
+		style    = flag.String("style", "", "A named formatting style: compact, pretty2, pretty4tabs or canonical. Formatting flags that are set explicitly override the style.")
 	)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] [path ...]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s agg [flags] [file ...]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s convert [flags] [file ...]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s ast [flags] [file]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s dedup [flags] [file ...]\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s validate [flags] path ...\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s lint [flags] path ...\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s diff [flags] old new\n\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "Paths may be files, directories, glob patterns or http(s) urls. Directories are walked for .json files.\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "With no paths, the json is read from stdin. Json lines read from stdin are formatted line by line.\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "Numbers are rewritten from their value. With -simplify, strings only escape what they must.\n\n")
+		flag.PrintDefaults()
+	}
 	flag.Parse()
+	s := genjson.Serializer{
+		Indent:      *indent,
+		KeyValueGap: *keyGap,
+		SortKeys:    *sortKeys,
+		Prefix:      *prefix,
+
+		UnsafeIntegersAsStrings: *intStr,
+		EscapeNonASCII:          *ascii,
+		CanonicalStrings:        *simplify,
+	}
+	if *style != "" {
+		st, err := genjson.ParseStyle(*style)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			os.Exit(2)
+		}
+		s = st.Serializer()
+		s.UnsafeIntegersAsStrings = *intStr
+		s.EscapeNonASCII = *ascii
+		s.CanonicalStrings = s.CanonicalStrings || *simplify
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "indent":
+				// IndentString takes precedence over Indent, so the indent string of the style
+				// is cleared for the explicit indent to apply.
+				s.Indent = *indent
+				s.IndentString = ""
+			case "prefix":
+				s.Prefix = *prefix
+			case "key-gap":
+				s.KeyValueGap = *keyGap
+			case "sort-keys":
+				s.SortKeys = *sortKeys
+			}
+		})
+	}
+	switch *keyOrder {
+	case "":
+	case "natural":
+		s.KeyLess = genjson.NaturalKeyLess
+	case "fold":
+		s.KeyLess = genjson.FoldKeyLess
+	default:
+		fmt.Fprintf(os.Stderr, "ERROR: unknown key order %q\n", *keyOrder)
+		os.Exit(2)
+	}
+	if err := s.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(2)
+	}
+	final, err := parseFinalNewline(*newline)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(2)
+	}
+	colored, err := useColor(*color, os.Getenv("NO_COLOR"), isTerminal(os.Stdout))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(2)
+	}
+	// out is the serializer of the output printed to stdout.
+	out := s
+	if colored {
+		out.Colors = &genjson.DefaultColors
+	}
+
+	if flag.NArg() > 0 {
+		if *format != "text" && *format != "json" {
+			fmt.Fprintf(os.Stderr, "ERROR: unknown report format %q\n", *format)
+			os.Exit(2)
+		}
+		b := batch{
+			serializer: s,
+			out:        out,
+			newline:    final,
+			write:      *write,
+			list:       *list,
+			jobs:       *jobs,
+			jsSafe:     *jsSafe,
+			duplicates: *dups,
+			strict:     *strict,
+			fetcher: &fetcher{
+				client:  &http.Client{Timeout: *timeout},
+				maxSize: *maxSize,
+			},
+			report: &report{json: *format == "json"},
+		}
+		os.Exit(b.run(flag.Args()))
+	}
+
 	data, err := io.ReadAll(os.Stdin)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "ERROR: Could not read from stdin %v\n", err)
 		os.Exit(1)
 	}
-	js, err := genjson.Deserialize(data)
+	switch genjson.DetectFormat(data) {
+	case genjson.FormatNDJSON:
+		os.Exit(formatLines(data, out, final, *dups, *strict))
+	case genjson.FormatJSON5:
+		fmt.Fprintf(os.Stderr, "ERROR: the input looks like JSON5 or JSONC, which is not supported\n")
+		os.Exit(1)
+	}
+	doc, err := genjson.DeserializeDocument(data)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
 		os.Exit(1)
 	}
-	s := genjson.Serializer{
-		Indent:      *indent,
-		KeyValueGap: *keyGap,
-		SortKeys:    *sortKeys,
-		Prefix:      *prefix,
+	if *dups || *strict {
+		r := report{}
+		for _, d := range duplicateKeys("<stdin>", doc, *strict, 0) {
+			r.lint(d)
+		}
+		if r.failed > 0 {
+			os.Exit(1)
+		}
+	}
+	if _, err := os.Stdout.Write(final.appendTo(out.Serialize(doc.Value()), data)); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// formatLines formats json lines, writing each value on its own line regardless of the indent, and
+// returns the exit code. The final newline applies to the last line. Duplicate keys are reported
+// as for a single value, with the row of the line they are on.
+func formatLines(data []byte, s genjson.Serializer, final finalNewline, dups, strict bool) int {
+	s.Indent, s.IndentString = 0, ""
+	s.Prefix, s.PrefixString = 0, ""
+	r := report{}
+	var formatted []byte
+	n := 0
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		doc, err := genjson.DeserializeDocument(line)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: line %d: %v\n", i+1, err)
+			return 1
+		}
+		if dups || strict {
+			for _, d := range duplicateKeys("<stdin>", doc, strict, i) {
+				r.lint(d)
+			}
+		}
+		if n > 0 {
+			formatted = append(formatted, '\n')
+		}
+		formatted = s.Append(formatted, doc.Value())
+		n++
+	}
+	if n > 0 {
+		formatted = final.appendTo(formatted, data)
+	}
+	if _, err := os.Stdout.Write(formatted); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		return 1
+	}
+	if r.failed > 0 {
+		return 1
 	}
-	data2 := s.Serialize(js)
-	fmt.Printf("%s\n", data2)
+	return 0
+}
+
+// useColor reports whether output printed to stdout is colored for the value of the -color flag.
+// In auto mode, output to a terminal is colored unless the NO_COLOR environment variable is set
+// to a non-empty value, as an empty NO_COLOR does not disable color.
+func useColor(mode, noColor string, terminal bool) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		return terminal && noColor == "", nil
+	}
+	return false, fmt.Errorf("unknown color mode %q, want auto, always or never", mode)
+}
+
+// isTerminal reports whether f is a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
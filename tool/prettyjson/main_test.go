@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestMain runs main instead of the tests when the test binary is started by runCommand, so that
+// tests can check the output and exit code of the command.
+func TestMain(m *testing.M) {
+	if os.Getenv("PRETTYJSON_TEST_MAIN") == "1" {
+		os.Args = append([]string{"prettyjson"}, os.Args[1:]...)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runCommand runs the command with the arguments and stdin and returns its stdout, stderr and exit
+// code.
+func runCommand(t *testing.T, stdin string, env []string, args ...string) (string, string, int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(append(os.Environ(), "PRETTYJSON_TEST_MAIN=1"), env...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		t.Fatalf("could not run command: %v", err)
+	}
+	return stdout.String(), stderr.String(), cmd.ProcessState.ExitCode()
+}
+
+func TestSimplify(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"-indent", "0"}, want: `"a\x01"` + "\n"},
+		{args: []string{"-indent", "0", "-simplify"}, want: `"a\u0001"` + "\n"},
+		{args: []string{"-style", "canonical"}, want: `"a\u0001"` + "\n"},
+	} {
+		stdout, stderr, code := runCommand(t, `"a\u0001"`, nil, tt.args...)
+		if code != 0 || stdout != tt.want {
+			t.Errorf("%v: unexpected result %d %q %s", tt.args, code, stdout, stderr)
+		}
+	}
+}
+
+func TestStyleIndent(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"-style", "pretty4tabs"}, want: "[\n\t1\n]\n"},
+		{args: []string{"-style", "pretty4tabs", "-indent", "2"}, want: "[\n  1\n]\n"},
+		{args: []string{"-style", "pretty4tabs", "-indent", "0"}, want: "[1]\n"},
+	} {
+		stdout, stderr, code := runCommand(t, `[1]`, nil, tt.args...)
+		if code != 0 || stdout != tt.want {
+			t.Errorf("%v: unexpected result %d %q %s", tt.args, code, stdout, stderr)
+		}
+	}
+}
+
+func TestLinesDuplicateKeys(t *testing.T) {
+	stdin := "{\"a\": 1}\n\n{\"b\": 1, \"b\": 2}\n"
+	stdout, stderr, code := runCommand(t, stdin, nil, "-dup-keys")
+	if code != 0 || stdout != "{\"a\": 1}\n{\"b\": 1,\"b\": 2}\n" {
+		t.Errorf("unexpected result %d %q %s", code, stdout, stderr)
+	}
+	want := "WARNING: <stdin>:3:10: duplicate key \"b\", first defined at 3:2 (duplicate-key)\n"
+	if stderr != want {
+		t.Errorf("unexpected stderr %q != %q", stderr, want)
+	}
+	_, stderr, code = runCommand(t, stdin, nil, "-strict")
+	if code != 1 || !strings.HasPrefix(stderr, "ERROR: <stdin>:3:10: duplicate key") {
+		t.Errorf("unexpected result %d %s", code, stderr)
+	}
+	if _, stderr, code := runCommand(t, stdin, nil); code != 0 || stderr != "" {
+		t.Errorf("unexpected result %d %s", code, stderr)
+	}
+}
+
+func TestFinalNewline(t *testing.T) {
+	for _, tt := range []struct {
+		policy, stdin, want string
+	}{
+		{policy: "always", stdin: `[1]`, want: "[1]\n"},
+		{policy: "always", stdin: "[1]\r\n", want: "[1]\n"},
+		{policy: "never", stdin: "[1]\n", want: "[1]"},
+		{policy: "preserve", stdin: `[1]`, want: "[1]"},
+		{policy: "preserve", stdin: "[1]\n", want: "[1]\n"},
+		{policy: "preserve", stdin: "[1]\r\n", want: "[1]\r\n"},
+		{policy: "preserve", stdin: "[1]\n\n", want: "[1]\n"},
+		{policy: "never", stdin: "{\"a\": 1}\n{\"b\": 2}\n", want: "{\"a\": 1}\n{\"b\": 2}"},
+		{policy: "preserve", stdin: "{\"a\": 1}\n{\"b\": 2}", want: "{\"a\": 1}\n{\"b\": 2}"},
+	} {
+		stdout, stderr, code := runCommand(t, tt.stdin, nil, "-indent", "0", "-final-newline", tt.policy)
+		if code != 0 || stdout != tt.want {
+			t.Errorf("%s %q: unexpected result %d %q %s", tt.policy, tt.stdin, code, stdout, stderr)
+		}
+	}
+	if _, stderr, code := runCommand(t, `[1]`, nil, "-final-newline", "sometimes"); code != 2 || !strings.Contains(stderr, "unknown final newline policy") {
+		t.Errorf("unexpected result %d %s", code, stderr)
+	}
+}
+
+func TestStdoutWriteError(t *testing.T) {
+	stdout, err := os.OpenFile("/dev/full", os.O_WRONLY, 0)
+	if err != nil {
+		t.Skipf("could not open /dev/full: %v", err)
+	}
+	defer stdout.Close()
+	file := writeFiles(t, `[1]`)[0]
+	for _, tt := range []struct {
+		stdin string
+		args  []string
+	}{
+		{stdin: `[1]`},
+		{stdin: "{\"a\": 1}\n{\"b\": 2}\n"},
+		{args: []string{file}},
+	} {
+		cmd := exec.Command(os.Args[0], tt.args...)
+		cmd.Env = append(os.Environ(), "PRETTYJSON_TEST_MAIN=1")
+		cmd.Stdin = strings.NewReader(tt.stdin)
+		var stderr bytes.Buffer
+		cmd.Stdout, cmd.Stderr = stdout, &stderr
+		_ = cmd.Run()
+		if code := cmd.ProcessState.ExitCode(); code != 1 || !strings.Contains(stderr.String(), "no space left on device") {
+			t.Errorf("%q %v: unexpected result %d %s", tt.stdin, tt.args, code, stderr.String())
+		}
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	for _, tt := range []struct {
+		mode, noColor string
+		terminal      bool
+		want          bool
+	}{
+		{mode: "always", noColor: "1", want: true},
+		{mode: "never", terminal: true, want: false},
+		{mode: "auto", terminal: true, want: true},
+		{mode: "auto", terminal: true, noColor: "1", want: false},
+		{mode: "auto", terminal: false, want: false},
+	} {
+		got, err := useColor(tt.mode, tt.noColor, tt.terminal)
+		if err != nil || got != tt.want {
+			t.Errorf("%+v: unexpected result %v %v", tt, got, err)
+		}
+	}
+	if _, err := useColor("sometimes", "", true); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestColor(t *testing.T) {
+	for _, tt := range []struct {
+		args    []string
+		colored bool
+	}{
+		{args: []string{"-color", "always"}, colored: true},
+		{args: []string{"-color", "never"}},
+		// The output of the command is not a terminal.
+		{args: []string{"-color", "auto"}},
+	} {
+		stdout, stderr, code := runCommand(t, `{"a": [1, true, null]}`, []string{"NO_COLOR="}, tt.args...)
+		if code != 0 || strings.Contains(stdout, "\x1b[") != tt.colored {
+			t.Errorf("%v: unexpected result %d %q %s", tt.args, code, stdout, stderr)
+		}
+	}
+}
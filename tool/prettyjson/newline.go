@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// finalNewline is the policy for the newline written after the formatted json. Other whitespace
+// after the top-level value of the input is never kept.
+type finalNewline int8
+
+const (
+	// newlineAlways ends the output with a newline.
+	newlineAlways finalNewline = iota
+	// newlineNever ends the output with the top-level value.
+	newlineNever
+	// newlinePreserve ends the output with a newline if the input ends with one, keeping a
+	// windows line ending, so that files managed by other tools are left unchanged.
+	newlinePreserve
+)
+
+func parseFinalNewline(s string) (finalNewline, error) {
+	switch s {
+	case "always":
+		return newlineAlways, nil
+	case "never":
+		return newlineNever, nil
+	case "preserve":
+		return newlinePreserve, nil
+	}
+	return 0, fmt.Errorf("unknown final newline policy %q, want always, never or preserve", s)
+}
+
+// appendTo appends the newline required by the policy for the input to the formatted output.
+func (n finalNewline) appendTo(formatted, input []byte) []byte {
+	switch n {
+	case newlineNever:
+		return formatted
+	case newlinePreserve:
+		switch {
+		case bytes.HasSuffix(input, []byte("\r\n")):
+			return append(formatted, '\r', '\n')
+		case bytes.HasSuffix(input, []byte("\n")):
+			return append(formatted, '\n')
+		}
+		return formatted
+	}
+	return append(formatted, '\n')
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/mattpgray/go-genjson"
+)
+
+// Rules reported by diagnostics.
+const (
+	ruleIO     = "io"
+	ruleSyntax = "syntax"
+	ruleFormat = "format"
+	// ruleJSSafeInteger reports integers that javascript cannot represent exactly.
+	ruleJSSafeInteger = "js-safe-integer"
+	// ruleDuplicateKey reports keys that repeat an earlier key of the same object.
+	ruleDuplicateKey = "duplicate-key"
+)
+
+// diagnostic is a single problem found in a file. Row and Col are 0 if the location is unknown.
+// Warnings are reported but are not treated as failures.
+type diagnostic struct {
+	file    string
+	row     int
+	col     int
+	rule    string
+	message string
+	warning bool
+}
+
+func (d diagnostic) severity() string {
+	if d.warning {
+		return "warning"
+	}
+	return "error"
+}
+
+// report collects diagnostics. In text mode, diagnostics are printed as they are added. In json
+// mode, a single json document is written to stdout once processing has finished.
+type report struct {
+	json   bool
+	failed int
+	diags  []diagnostic
+}
+
+func (r *report) error(file string, err error) {
+	d := diagnostic{
+		file:    file,
+		rule:    ruleSyntax,
+		message: err.Error(),
+	}
+	var (
+		pe *fs.PathError
+		fe *fetchError
+	)
+	if errors.As(err, &pe) || errors.As(err, &fe) {
+		d.rule = ruleIO
+	}
+	d.row, d.col = errorLoc(err)
+	r.failed++
+	if r.json {
+		r.diags = append(r.diags, d)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "ERROR: %s: %v\n", file, err)
+}
+
+// lint records a problem found in a valid file. Lint problems are treated as failures unless
+// they are warnings.
+func (r *report) lint(d diagnostic) {
+	if !d.warning {
+		r.failed++
+	}
+	if r.json {
+		r.diags = append(r.diags, d)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s: %s:%d:%d: %s (%s)\n", strings.ToUpper(d.severity()), d.file, d.row, d.col, d.message, d.rule)
+}
+
+func (r *report) unformatted(file string) {
+	if r.json {
+		r.diags = append(r.diags, diagnostic{
+			file:    file,
+			rule:    ruleFormat,
+			message: "file is not formatted",
+		})
+		return
+	}
+	fmt.Println(file)
+}
+
+// finish writes the summary and returns the exit code.
+func (r *report) finish(files int) int {
+	if r.json {
+		diags := make(genjson.Array, 0, len(r.diags))
+		for _, d := range r.diags {
+			var o genjson.Object
+			o.Add("file", genjson.String(d.file))
+			o.Add("row", genjson.Number{Integer: uint64(d.row)})
+			o.Add("col", genjson.Number{Integer: uint64(d.col)})
+			o.Add("rule", genjson.String(d.rule))
+			o.Add("severity", genjson.String(d.severity()))
+			o.Add("message", genjson.String(d.message))
+			diags = append(diags, o)
+		}
+		var o genjson.Object
+		o.Add("files", genjson.Number{Integer: uint64(files)})
+		o.Add("failed", genjson.Number{Integer: uint64(r.failed)})
+		o.Add("diagnostics", diags)
+		s := genjson.Serializer{Indent: 2, KeyValueGap: 1}
+		fmt.Printf("%s\n", s.Serialize(o))
+	} else {
+		fmt.Fprintf(os.Stderr, "%d files processed, %d failed\n", files, r.failed)
+	}
+	if r.failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// duplicateKeys returns a diagnostic for every duplicate key in the document. The diagnostics
+// are warnings unless strict is set. rows is the number of lines of the input before the document.
+func duplicateKeys(file string, doc *genjson.Document, strict bool, rows int) []diagnostic {
+	var diags []diagnostic
+	for _, dup := range doc.DuplicateKeys() {
+		diags = append(diags, diagnostic{
+			file:    file,
+			row:     rows + dup.Loc.Row,
+			col:     dup.Loc.Col,
+			rule:    ruleDuplicateKey,
+			message: fmt.Sprintf("duplicate key %q, first defined at %d:%d", dup.Key, rows+dup.First.Row, dup.First.Col),
+			warning: !strict,
+		})
+	}
+	return diags
+}
+
+// errorLoc returns the location of the error, if known.
+func errorLoc(err error) (int, int) {
+	var (
+		ite genjson.InvalidTokenError
+		ies genjson.InvalidEscapeSequence
+		he  genjson.HookError
+	)
+	switch {
+	case errors.As(err, &ite):
+		return ite.Row, ite.Col
+	case errors.As(err, &ies):
+		return ies.Row, ies.Col
+	case errors.As(err, &he):
+		return he.Row, he.Col
+	}
+	return 0, 0
+}
@@ -0,0 +1,282 @@
+package genjson
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Transformer reshapes values according to a declarative spec, so that ETL style transformations
+// can be configured with data rather than code. It is created by CompileTransform and may be used
+// concurrently. The values passed to Transform are not modified; unchanged values are shared with
+// the result.
+type Transformer struct {
+	ops []transformOp
+}
+
+// transformOp is a single compiled operation of a spec.
+type transformOp func(v Value) (Value, error)
+
+// CompileTransform compiles a transformation spec. The spec is an array of operations that are
+// applied in order, each an object with an "op" member:
+//
+//	{"op": "get", "path": "data.items"}
+//	    Replaces the value with the value at the dot path, or null if it does not exist.
+//	{"op": "select", "keys": ["id", "name"]}
+//	    Keeps only the entries of an object with the keys, in their original order.
+//	{"op": "delete", "keys": ["password"]}
+//	    Removes the entries of an object with the keys.
+//	{"op": "rename", "keys": {"name": "title"}}
+//	    Renames the keys of an object, keeping the position of the entries.
+//	{"op": "default", "values": {"count": 0}}
+//	    Adds the entries to an object for the keys it does not contain.
+//	{"op": "map", "path": "items", "spec": [...]}
+//	    Transforms every element of the array at the dot path with the nested spec. The path is
+//	    optional and defaults to the value itself.
+//
+// Paths use the syntax of ParseDotPath. Errors in the spec are reported as a TransformSpecError.
+func CompileTransform(spec Value) (*Transformer, error) {
+	return compileTransform(spec, nil)
+}
+
+// MustCompileTransform compiles a transformation spec, panicking if it is invalid. It is intended
+// for specs that are known to be valid, such as constants.
+func MustCompileTransform(spec Value) *Transformer {
+	t, err := CompileTransform(spec)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Transform applies the transformation to v. Operations applied to values of the wrong type fail
+// with a TransformError.
+func (t *Transformer) Transform(v Value) (Value, error) {
+	for _, op := range t.ops {
+		var err error
+		if v, err = op(v); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+func compileTransform(spec Value, at Path) (*Transformer, error) {
+	ops, err := RequireArray(spec)
+	if err != nil {
+		return nil, TransformSpecError{Path: at.Append(), Err: err}
+	}
+	t := &Transformer{ops: make([]transformOp, len(ops))}
+	for i, op := range ops {
+		if t.ops[i], err = compileTransformOp(op, at.Append(Index(i))); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+func compileTransformOp(spec Value, at Path) (transformOp, error) {
+	specErr := func(key string, err error) error {
+		return TransformSpecError{Path: at.Append(Key(key)), Err: err}
+	}
+	o, err := RequireObject(spec)
+	if err != nil {
+		return nil, TransformSpecError{Path: at, Err: err}
+	}
+	opv, _ := o.Get("op")
+	opName, err := RequireString(opv)
+	if err != nil {
+		return nil, specErr("op", err)
+	}
+	switch name := string(opName); name {
+	case "get":
+		path, err := transformPath(o)
+		if err != nil {
+			return nil, specErr("path", err)
+		}
+		return func(v Value) (Value, error) {
+			return GetOr(v, Null{}, path...), nil
+		}, nil
+	case "select", "delete":
+		keysv, _ := o.Get("keys")
+		keys, err := transformKeys(keysv)
+		if err != nil {
+			return nil, specErr("keys", err)
+		}
+		keep := name == "select"
+		return objectOp(name, func(obj Object) Object {
+			var out Object
+			out.init()
+			for _, e := range obj.Entries() {
+				if keys[e.Key] == keep {
+					out.Add(e.Key, e.Value)
+				}
+			}
+			return out
+		}), nil
+	case "rename":
+		keysv, _ := o.Get("keys")
+		renames, err := RequireObject(keysv)
+		if err != nil {
+			return nil, specErr("keys", err)
+		}
+		to := make(map[string]string, renames.Len())
+		for _, e := range renames.Entries() {
+			s, err := RequireString(e.Value)
+			if err != nil {
+				return nil, TransformSpecError{Path: at.Append(Key("keys"), Key(e.Key)), Err: err}
+			}
+			to[e.Key] = string(s)
+		}
+		return objectOp(name, func(obj Object) Object {
+			var out Object
+			out.init()
+			for _, e := range obj.Entries() {
+				if k, ok := to[e.Key]; ok {
+					e.Key = k
+				}
+				out.Add(e.Key, e.Value)
+			}
+			return out
+		}), nil
+	case "default":
+		valuesv, _ := o.Get("values")
+		values, err := RequireObject(valuesv)
+		if err != nil {
+			return nil, specErr("values", err)
+		}
+		defaults := values.Entries()
+		return objectOp(name, func(obj Object) Object {
+			var out Object
+			out.init()
+			for _, e := range obj.Entries() {
+				out.Add(e.Key, e.Value)
+			}
+			for _, e := range defaults {
+				if _, ok := out.Get(e.Key); !ok {
+					out.Add(e.Key, e.Value)
+				}
+			}
+			return out
+		}), nil
+	case "map":
+		path, err := transformPath(o)
+		if err != nil {
+			return nil, specErr("path", err)
+		}
+		nested, _ := o.Get("spec")
+		t, err := compileTransform(nested, at.Append(Key("spec")))
+		if err != nil {
+			return nil, err
+		}
+		return func(v Value) (Value, error) {
+			return mapTransform(v, path, t)
+		}, nil
+	}
+	return nil, specErr("op", fmt.Errorf("unknown operation %q", string(opName)))
+}
+
+// transformPath returns the optional path member of an operation.
+func transformPath(o Object) (DotPath, error) {
+	pv, ok := o.Get("path")
+	if !ok {
+		return DotPath{}, nil
+	}
+	s, err := RequireString(pv)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDotPath(string(s))
+}
+
+// transformKeys returns the set of keys in an array of strings.
+func transformKeys(v Value) (map[string]bool, error) {
+	a, err := RequireArray(v)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]bool, len(a))
+	for _, kv := range a {
+		k, err := RequireString(kv)
+		if err != nil {
+			return nil, err
+		}
+		keys[string(k)] = true
+	}
+	return keys, nil
+}
+
+// objectOp returns an operation that applies f to objects and fails for other values.
+func objectOp(name string, f func(Object) Object) transformOp {
+	return func(v Value) (Value, error) {
+		o, err := RequireObject(v)
+		if err != nil {
+			return nil, TransformError{Op: name, Err: err}
+		}
+		return f(o), nil
+	}
+}
+
+// mapTransform applies t to every element of the array at path within v.
+func mapTransform(v Value, path DotPath, t *Transformer) (Value, error) {
+	target, err := path.Get(v)
+	if err != nil {
+		return nil, TransformError{Op: "map", Path: tokenPath(path), Err: err}
+	}
+	a, err := RequireArray(target)
+	if err != nil {
+		return nil, TransformError{Op: "map", Path: tokenPath(path), Err: err}
+	}
+	out := make(Array, len(a))
+	for i, elem := range a {
+		if out[i], err = t.Transform(elem); err != nil {
+			var te TransformError
+			if errors.As(err, &te) {
+				te.Path = append(tokenPath(path).Append(Index(i)), te.Path...)
+				return nil, te
+			}
+			return nil, err
+		}
+	}
+	if len(path) == 0 {
+		return out, nil
+	}
+	return SetPath(v, path, out)
+}
+
+// ---------------- errors ----------------
+
+// TransformSpecError is returned by CompileTransform for an invalid spec.
+type TransformSpecError struct {
+	// Path is the path of the invalid value within the spec.
+	Path Path
+	Err  error
+}
+
+func (e TransformSpecError) Error() string {
+	return fmt.Sprintf("invalid transform spec at %s: %v", e.Path, e.Err)
+}
+
+func (e TransformSpecError) Unwrap() error {
+	return e.Err
+}
+
+// TransformError is returned by Transformer.Transform when an operation cannot be applied.
+type TransformError struct {
+	// Op is the name of the operation that failed.
+	Op string
+	// Path is the path of the value the operation was applied to, relative to the transformed
+	// value. It is only non-empty within map operations.
+	Path Path
+	Err  error
+}
+
+func (e TransformError) Error() string {
+	if len(e.Path) == 0 {
+		return fmt.Sprintf("transform %s: %v", e.Op, e.Err)
+	}
+	return fmt.Sprintf("transform %s at %s: %v", e.Op, e.Path, e.Err)
+}
+
+func (e TransformError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,126 @@
+package genjson
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTransform(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		in   string
+		want string
+	}{
+		{name: "empty", spec: `[]`, in: `{"a": 1}`, want: `{"a":1}`},
+		{name: "get", spec: `[{"op": "get", "path": "data.items"}]`, in: `{"data": {"items": [1, 2]}}`, want: `[1,2]`},
+		{name: "get-missing", spec: `[{"op": "get", "path": "data.x"}]`, in: `{"data": {}}`, want: `null`},
+		{name: "get-escaped-key", spec: `[{"op": "get", "path": "a\\.b"}]`, in: `{"a.b": 1, "a": {"b": 2}}`, want: `1`},
+		{name: "get-index", spec: `[{"op": "get", "path": "a.1"}]`, in: `{"a": [1, 2]}`, want: `2`},
+		{name: "select", spec: `[{"op": "select", "keys": ["c", "a"]}]`, in: `{"a": 1, "b": 2, "c": 3}`, want: `{"a":1,"c":3}`},
+		{name: "select-duplicate-keys", spec: `[{"op": "select", "keys": ["a"]}]`, in: `{"a": 1, "b": 2, "a": 3}`, want: `{"a":1,"a":3}`},
+		{name: "delete", spec: `[{"op": "delete", "keys": ["password"]}]`, in: `{"user": "u", "password": "p"}`, want: `{"user":"u"}`},
+		{name: "rename", spec: `[{"op": "rename", "keys": {"name": "title", "x": "y"}}]`, in: `{"id": 1, "name": "n", "z": 0}`, want: `{"id":1,"title":"n","z":0}`},
+		{name: "default", spec: `[{"op": "default", "values": {"count": 0, "id": -1}}]`, in: `{"id": 1}`, want: `{"id":1,"count":0}`},
+		{name: "map", spec: `[{"op": "map", "path": "items", "spec": [{"op": "select", "keys": ["id"]}]}]`, in: `{"n": 2, "items": [{"id": 1, "x": 1}, {"id": 2}]}`, want: `{"n":2,"items":[{"id":1},{"id":2}]}`},
+		{name: "map-self", spec: `[{"op": "map", "spec": [{"op": "get", "path": "id"}]}]`, in: `[{"id": 1}, {"id": 2}, {}]`, want: `[1,2,null]`},
+		{name: "map-nested", spec: `[{"op": "map", "path": "a", "spec": [{"op": "map", "path": "b", "spec": [{"op": "default", "values": {"c": true}}]}]}]`, in: `{"a": [{"b": [{}]}]}`, want: `{"a":[{"b":[{"c":true}]}]}`},
+		{
+			name: "pipeline",
+			spec: `[{"op": "get", "path": "user"}, {"op": "rename", "keys": {"name": "title"}}, {"op": "delete", "keys": ["password"]}, {"op": "default", "values": {"admin": false}}]`,
+			in:   `{"user": {"name": "n", "password": "p"}}`,
+			want: `{"title":"n","admin":false}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr, err := CompileTransform(MustDeserializeString(tt.spec))
+			if err != nil {
+				t.Fatalf("unexpected compile error %v", err)
+			}
+			in := MustDeserializeString(tt.in)
+			before := string(Serialize(in))
+			got, err := tr.Transform(in)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if string(Serialize(got)) != tt.want {
+				t.Errorf("unexpected result %s != %s", Serialize(got), tt.want)
+			}
+			if after := string(Serialize(in)); after != before {
+				t.Errorf("input modified %s", after)
+			}
+		})
+	}
+}
+
+func TestTransformErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		in      string
+		wantErr string
+		cause   error
+	}{
+		{name: "select-array", spec: `[{"op": "select", "keys": ["a"]}]`, in: `[1]`, wantErr: `transform select: expected json object but got array`},
+		{name: "map-not-array", spec: `[{"op": "map", "path": "a", "spec": []}]`, in: `{"a": {}}`, wantErr: `transform map at a: expected json array but got object`},
+		{name: "map-missing", spec: `[{"op": "map", "path": "a", "spec": []}]`, in: `{}`, cause: ErrPathNotFound},
+		{name: "map-element", spec: `[{"op": "map", "path": "a", "spec": [{"op": "delete", "keys": []}]}]`, in: `{"a": [{}, 1]}`, wantErr: `transform delete at a[1]: expected json object but got number`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := MustCompileTransform(MustDeserializeString(tt.spec)).Transform(MustDeserializeString(tt.in))
+			var te TransformError
+			if !errors.As(err, &te) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if tt.wantErr != "" && err.Error() != tt.wantErr {
+				t.Errorf("unexpected error %q != %q", err, tt.wantErr)
+			}
+			if tt.cause != nil && !errors.Is(err, tt.cause) {
+				t.Errorf("unexpected cause %v", err)
+			}
+		})
+	}
+}
+
+func TestCompileTransformErrors(t *testing.T) {
+	tests := []struct {
+		spec string
+		path string
+	}{
+		{spec: `{}`, path: ``},
+		{spec: `[1]`, path: `[0]`},
+		{spec: `[{}]`, path: `[0].op`},
+		{spec: `[{"op": "unknown"}]`, path: `[0].op`},
+		{spec: `[{"op": "get", "path": 1}]`, path: `[0].path`},
+		{spec: `[{"op": "get", "path": "a.*"}]`, path: `[0].path`},
+		{spec: `[{"op": "select", "keys": ["a", 1]}]`, path: `[0].keys`},
+		{spec: `[{"op": "delete"}]`, path: `[0].keys`},
+		{spec: `[{"op": "rename", "keys": {"a": 1}}]`, path: `[0].keys.a`},
+		{spec: `[{"op": "default", "values": []}]`, path: `[0].values`},
+		{spec: `[{"op": "map"}]`, path: `[0].spec`},
+		{spec: `[{"op": "map", "spec": [{"op": "get"}, {"op": "x"}]}]`, path: `[0].spec[1].op`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := CompileTransform(MustDeserializeString(tt.spec))
+			var se TransformSpecError
+			if !errors.As(err, &se) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if se.Path.String() != tt.path {
+				t.Errorf("unexpected path %q != %q", se.Path.String(), tt.path)
+			}
+		})
+	}
+}
+
+func TestMustCompileTransformPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected a panic")
+		}
+	}()
+	MustCompileTransform(String("x"))
+}
@@ -0,0 +1,699 @@
+package genjson
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+)
+
+// TypedDecoder decodes json into go values of type T without building a Value for the document.
+// The decoding of T is compiled once by NewTypedDecoder, so that the bytes of each document drive
+// the parser directly into the fields of the target and the values of keys without a matching
+// field are skipped rather than parsed. It is intended for hot paths that decode many documents
+// of the same shape, such as the messages of a service. A TypedDecoder may be used concurrently.
+//
+// Decode produces the same result as Unmarshaler.Unmarshal with the same options. The document is
+// checked to be valid json, without allocating, before it is decoded, so syntax errors are reported
+// as they are by Unmarshal, including those in skipped values, and leave the target unchanged.
+// Values that cannot be decoded directly, such as values unmarshaled into interfaces, types
+// implementing From, fields with hooks and byte slices with Base64Bytes, are deserialized and
+// unmarshaled as usual. If the Unmarshaler has middleware or DisallowDuplicateKeys is set, every
+// document is unmarshaled as usual. Documents are parsed with the default Deserializer options.
+type TypedDecoder[T any] struct {
+	u   *Unmarshaler
+	dec typedDecoder
+}
+
+// typedDecoder decodes the value at d into v, returning the state after the value. The value
+// starts at d, after any whitespace.
+type typedDecoder func(d deserializer, v reflect.Value) (deserializer, error)
+
+// NewTypedDecoder compiles a TypedDecoder for T using the options of u, or of the default
+// Unmarshaler if u is nil. The options of u must not be changed after it is compiled.
+func NewTypedDecoder[T any](u *Unmarshaler) *TypedDecoder[T] {
+	if u == nil {
+		u = &defaultUnmarshaler
+	}
+	c := &typedCompiler{u: u, types: make(map[reflect.Type]*typedDecoder)}
+	return &TypedDecoder[T]{
+		u:   u,
+		dec: c.compile(reflect.TypeOf((*T)(nil)).Elem()),
+	}
+}
+
+// Decode decodes data into v, which must not be nil.
+func (td *TypedDecoder[T]) Decode(data []byte, v *T) error {
+	if v == nil {
+		return ErrInvalidValue
+	}
+	if td.u.chain != nil || td.u.DisallowDuplicateKeys {
+		return td.u.Unmarshal(data, v)
+	}
+	d := skipSpace(newDeserializer(data, &defDeserializer))
+	if d.idx == len(data) {
+		return ErrEmptyInput
+	}
+	if _, ok := validValue(data, d.idx, 0); !ok {
+		// The parser reports the error, or accepts the syntax that validValue does not.
+		if _, _, err := deserializeNext(d); err != nil {
+			return err
+		}
+	}
+	_, err := td.dec(d, reflect.ValueOf(v).Elem())
+	return err
+}
+
+// typedCompiler compiles the decoders of the types reachable from the type of a TypedDecoder.
+type typedCompiler struct {
+	u *Unmarshaler
+	// types holds the decoders compiled so far, including those still being compiled, so that
+	// recursive types refer to their own decoder.
+	types map[reflect.Type]*typedDecoder
+}
+
+func (c *typedCompiler) compile(t reflect.Type) typedDecoder {
+	if dec, ok := c.types[t]; ok {
+		return func(d deserializer, v reflect.Value) (deserializer, error) {
+			return (*dec)(d, v)
+		}
+	}
+	dec := new(typedDecoder)
+	c.types[t] = dec
+	*dec = c.compileType(t)
+	return *dec
+}
+
+// compileType returns the decoder for t, following the order of Unmarshaler.Unmarshal. Pointers
+// are handled before From because the value they point to is checked for From when it is decoded.
+func (c *typedCompiler) compileType(t reflect.Type) typedDecoder {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return c.compilePointer(t)
+	case reflect.Interface:
+		return c.generic
+	}
+	if t.Implements(fromType) || reflect.PointerTo(t).Implements(fromType) {
+		return c.generic
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		return c.compileStruct(t)
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return c.generic
+		}
+		return c.compileMap(t)
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 && c.u.Base64Bytes {
+			return c.generic
+		}
+		return c.compileSlice(t)
+	case reflect.Array:
+		return c.compileArray(t)
+	}
+	return c.scalar
+}
+
+// generic deserializes the value and unmarshals it as Unmarshaler.Unmarshal does.
+func (c *typedCompiler) generic(d deserializer, v reflect.Value) (deserializer, error) {
+	d, o, err := deserializeNext(d)
+	if err != nil {
+		return d, err
+	}
+	return d, unmarshal(&UnmarshalState{u: c.u, node: &o.node}, o.value, v)
+}
+
+// scalar decodes strings, numbers and literals without the parser where possible. Containers and
+// anything else fall back to generic, so that mismatched values fail as they do when unmarshaling.
+func (c *typedCompiler) scalar(d deserializer, v reflect.Value) (deserializer, error) {
+	start := d
+	d, value, ok := scanScalar(d)
+	if !ok {
+		return c.generic(start, v)
+	}
+	if err := unmarshal(&UnmarshalState{u: c.u}, value, v); err != nil {
+		if ue, ok := err.(UnmarshalError); ok && ue.Loc == nil {
+			l := start.loc()
+			ue.Loc = &l
+			return d, ue
+		}
+		return d, err
+	}
+	return d, nil
+}
+
+func (c *typedCompiler) compilePointer(t reflect.Type) typedDecoder {
+	elem := c.compile(t.Elem())
+	return func(d deserializer, v reflect.Value) (deserializer, error) {
+		if peekIs(d, 'n') {
+			// Null leaves the pointer unchanged.
+			return c.scalar(d, v)
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(t.Elem()))
+		}
+		return elem(d, v.Elem())
+	}
+}
+
+func (c *typedCompiler) compileStruct(t reflect.Type) typedDecoder {
+	fields := cachedStructFields(t).byDepth()
+	decs := make([]typedDecoder, len(fields))
+	// names maps the json names to the first field with the name, as structFields.lookup does.
+	names := make(map[string]int, len(fields))
+	folded := make([][]byte, len(fields))
+	for i := len(fields) - 1; i >= 0; i-- {
+		names[fields[i].name] = i
+		folded[i] = []byte(fields[i].name)
+	}
+	for i, f := range fields {
+		if f.tag.hook != "" {
+			decs[i] = c.hooked(f.tag.hook)
+			continue
+		}
+		decs[i] = c.compile(t.FieldByIndex(f.index).Type)
+	}
+	lookup := func(key []byte) (int, bool) {
+		if i, ok := names[string(key)]; ok {
+			return i, true
+		}
+		for i, name := range folded {
+			if bytes.EqualFold(name, key) {
+				return i, true
+			}
+		}
+		return 0, false
+	}
+	return func(d deserializer, v reflect.Value) (deserializer, error) {
+		if !peekIs(d, '{') {
+			return c.scalar(d, v)
+		}
+		obj := d
+		d, more := openContainer(d, '}')
+		for more {
+			keyStart := d
+			var (
+				key []byte
+				err error
+			)
+			if d, key, err = readMemberKey(d); err != nil {
+				return d, err
+			}
+			if i, ok := lookup(key); ok {
+				if d, err = decs[i](d, fieldByIndex(v, fields[i].index)); err != nil {
+					return d, nestedError(err, memberStep(obj, keyStart, key))
+				}
+			} else if c.u.DisallowUnknownFields {
+				return d, memberKeyError(obj, keyStart, key, UnknownFieldError{Key: string(key), Type: t})
+			} else if d, err = skipValue(d); err != nil {
+				return d, err
+			}
+			if d, more, err = nextElem(d, '}'); err != nil {
+				return d, err
+			}
+		}
+		return d, nil
+	}
+}
+
+// hooked decodes the value of a field with a hook. The value is deserialized so that it can be
+// passed to the hook.
+func (c *typedCompiler) hooked(name string) typedDecoder {
+	return func(d deserializer, v reflect.Value) (deserializer, error) {
+		d, o, err := deserializeNext(d)
+		if err != nil {
+			return d, err
+		}
+		s := &UnmarshalState{u: c.u, node: &o.node}
+		value, err := applyHook(s, name, o.value)
+		if err != nil {
+			return d, err
+		}
+		return d, unmarshal(s, value, v)
+	}
+}
+
+func (c *typedCompiler) compileMap(t reflect.Type) typedDecoder {
+	elemDec := c.compile(t.Elem())
+	return func(d deserializer, v reflect.Value) (deserializer, error) {
+		if !peekIs(d, '{') {
+			return c.scalar(d, v)
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(t))
+		}
+		elem := reflect.New(t.Elem()).Elem()
+		obj := d
+		d, more := openContainer(d, '}')
+		for more {
+			keyStart := d
+			var (
+				key []byte
+				err error
+			)
+			if d, key, err = readMemberKey(d); err != nil {
+				return d, err
+			}
+			mk := reflect.ValueOf(string(key)).Convert(t.Key())
+			elem.Set(reflect.Zero(t.Elem()))
+			if c.u.MergeMapValues {
+				if existing := v.MapIndex(mk); existing.IsValid() {
+					elem.Set(existing)
+				}
+			}
+			if d, err = elemDec(d, elem); err != nil {
+				return d, nestedError(err, memberStep(obj, keyStart, key))
+			}
+			v.SetMapIndex(mk, elem)
+			if d, more, err = nextElem(d, '}'); err != nil {
+				return d, err
+			}
+		}
+		return d, nil
+	}
+}
+
+func (c *typedCompiler) compileSlice(t reflect.Type) typedDecoder {
+	elemDec := c.compile(t.Elem())
+	zero := reflect.Zero(t.Elem())
+	return func(d deserializer, v reflect.Value) (deserializer, error) {
+		if !peekIs(d, '[') {
+			return c.scalar(d, v)
+		}
+		out := reflect.MakeSlice(t, 0, 0)
+		d, more := openContainer(d, ']')
+		for i := 0; more; i++ {
+			out = reflect.Append(out, zero)
+			var err error
+			if d, err = elemDec(d, out.Index(i)); err != nil {
+				return d, nestedError(err, Index(i))
+			}
+			if d, more, err = nextElem(d, ']'); err != nil {
+				return d, err
+			}
+		}
+		v.Set(out)
+		return d, nil
+	}
+}
+
+// compileArray returns the decoder for a go array. Arrays of the wrong length fail before their
+// elements do, as they do when unmarshaling, so the elements are counted when decoding stops early.
+func (c *typedCompiler) compileArray(t reflect.Type) typedDecoder {
+	elemDec := c.compile(t.Elem())
+	return func(d deserializer, v reflect.Value) (deserializer, error) {
+		if !peekIs(d, '[') {
+			return c.scalar(d, v)
+		}
+		arr := d
+		out := reflect.New(t).Elem()
+		d, more := openContainer(d, ']')
+		i := 0
+		for ; more; i++ {
+			var err error
+			if i == t.Len() {
+				return d, c.arrayLengthError(arr, t)
+			}
+			if d, err = elemDec(d, out.Index(i)); err != nil {
+				if n, cerr := countElems(arr); cerr == nil && n != t.Len() {
+					return d, c.arrayLengthError(arr, t)
+				}
+				return d, nestedError(err, Index(i))
+			}
+			if d, more, err = nextElem(d, ']'); err != nil {
+				return d, err
+			}
+		}
+		if i != t.Len() {
+			return d, c.arrayLengthError(arr, t)
+		}
+		v.Set(out)
+		return d, nil
+	}
+}
+
+// arrayLengthError returns the error for the array at arr not having the length of t.
+func (c *typedCompiler) arrayLengthError(arr deserializer, t reflect.Type) error {
+	n, err := countElems(arr)
+	if err != nil {
+		return err
+	}
+	ue := unmarshalError(&UnmarshalState{u: c.u}, arrayLengthError(t, n))
+	l := arr.loc()
+	ue.Loc = &l
+	return ue
+}
+
+// ---------------- helpers start ----------------
+
+func peekIs(d deserializer, c byte) bool {
+	return d.idx < len(d.b) && d.b[d.idx] == c
+}
+
+// openContainer reads the opening bracket of a container and the whitespace after it, and reports
+// whether the container has any members.
+func openContainer(d deserializer, closer byte) (deserializer, bool) {
+	d, _, _ = read(d)
+	d = skipSpace(d)
+	if peekIs(d, closer) {
+		d, _, _ = read(d)
+		return d, false
+	}
+	return d, true
+}
+
+// nextElem reads the comma after a member of a container, and the whitespace around it, and
+// reports whether another member follows. The closing bracket is read at the end of the container.
+func nextElem(d deserializer, closer byte) (deserializer, bool, error) {
+	d = skipSpace(d)
+	nd, c, br := read(d)
+	switch {
+	case !br.OK:
+		return d, false, ErrUnexpectedEndOfInput
+	case c == ',':
+		return skipSpace(nd), true, nil
+	case c == closer:
+		return nd, false, nil
+	}
+	return d, false, errNoMatch(d)
+}
+
+// readMemberKey reads the key of an object member and the colon after it, returning the state at
+// the start of the value.
+func readMemberKey(d deserializer) (deserializer, []byte, error) {
+	d, key, err := readKey(d)
+	if err != nil {
+		return d, nil, err
+	}
+	if d, err = peekByte(skipSpace(d), ':'); err != nil {
+		return d, nil, err
+	}
+	return skipSpace(d), key, nil
+}
+
+// countElems returns the number of elements of the array at d.
+func countElems(d deserializer) (int, error) {
+	d, more := openContainer(d, ']')
+	n := 0
+	for ; more; n++ {
+		var err error
+		if d, err = skipValue(d); err != nil {
+			return 0, err
+		}
+		if d, more, err = nextElem(d, ']'); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+// memberStep returns the path step of the member of the object at obj whose key starts at
+// keyStart. The ordinal of duplicate keys is only computed here, on the error path, by counting
+// the earlier members with the same key.
+func memberStep(obj, keyStart deserializer, key []byte) PathStep {
+	ordinal := 0
+	d, _ := openContainer(obj, '}')
+	for d.idx < keyStart.idx {
+		nd, k, err := readMemberKey(d)
+		if err != nil {
+			break
+		}
+		if bytes.Equal(k, key) {
+			ordinal++
+		}
+		if nd, err = skipValue(nd); err != nil {
+			break
+		}
+		if d, _, err = nextElem(nd, '}'); err != nil {
+			break
+		}
+	}
+	if ordinal > 0 {
+		return KeyAt(string(key), ordinal)
+	}
+	return Key(string(key))
+}
+
+// memberKeyError returns an error for the key of a member, located at the start of the key.
+func memberKeyError(obj, keyStart deserializer, key []byte, err error) UnmarshalError {
+	l := keyStart.loc()
+	return UnmarshalError{
+		Cause: err,
+		Field: Path{memberStep(obj, keyStart, key)},
+		Loc:   &l,
+	}
+}
+
+// nestedError prefixes the path of an UnmarshalError returned for a nested value with step.
+// Other errors, such as syntax errors, are returned unchanged.
+func nestedError(err error, step PathStep) error {
+	ue, ok := err.(UnmarshalError)
+	if !ok {
+		return err
+	}
+	ue.Field = append(Path{step}, ue.Field...)
+	return ue
+}
+
+// scanScalar reads a string without escape sequences, a number or a literal, with the same
+// result as the parser. ok is false for values that must be left to the parser.
+func scanScalar(d deserializer) (nd deserializer, v Value, ok bool) {
+	if d.idx >= len(d.b) {
+		return d, nil, false
+	}
+	rest := d.b[d.idx:]
+	switch c := rest[0]; {
+	case c == '"':
+		if !plainStrings(d.opts) {
+			return d, nil, false
+		}
+		i := bytes.IndexAny(rest[1:], "\"\\\n")
+		if i < 0 || rest[1+i] != '"' {
+			return d, nil, false
+		}
+		return advance(d, i+2), String(rest[1 : 1+i]), true
+	case c == '-' || ('0' <= c && c <= '9'):
+		return scanNumber(d)
+	case bytes.HasPrefix(rest, []byte("true")):
+		return advance(d, 4), Bool(true), true
+	case bytes.HasPrefix(rest, []byte("false")):
+		return advance(d, 5), Bool(false), true
+	case bytes.HasPrefix(rest, []byte("null")):
+		return advance(d, 4), Null{}, true
+	}
+	return d, nil, false
+}
+
+// scanNumber reads a number as defaultNumberParser does. Integers of more than 19 digits and
+// malformed numbers are left to the parser, which reports overflows and errors.
+func scanNumber(d deserializer) (deserializer, Value, bool) {
+	b := d.b
+	i := d.idx
+	neg := i < len(b) && b[i] == '-'
+	if neg {
+		i++
+	}
+	start := i
+	i = skipDigits(b, i)
+	if i == start {
+		return d, nil, false
+	}
+	intEnd := i
+	isExp := false
+	if i < len(b) && b[i] == '.' {
+		j := skipDigits(b, i+1)
+		if j == i+1 {
+			return d, nil, false
+		}
+		i = j
+	}
+	if i < len(b) && (b[i] == 'e' || b[i] == 'E') {
+		j := i + 1
+		if j < len(b) && (b[j] == '+' || b[j] == '-') {
+			j++
+		}
+		k := skipDigits(b, j)
+		if k == j {
+			return d, nil, false
+		}
+		i, isExp = k, true
+	}
+	var n Number
+	if i > intEnd {
+		f, err := strconv.ParseFloat(string(b[start:i]), 64)
+		if err != nil {
+			return d, nil, false
+		}
+		n = Number{Float: f, IsFloat: true, IsExp: isExp}
+	} else {
+		if intEnd-start > 19 {
+			return d, nil, false
+		}
+		u, err := strconv.ParseUint(string(b[start:intEnd]), 10, 64)
+		if err != nil {
+			return d, nil, false
+		}
+		n = Number{Integer: u}
+	}
+	n.IsNeg = neg
+	return advance(d, i-d.idx), n, true
+}
+
+func skipDigits(b []byte, i int) int {
+	for i < len(b) && '0' <= b[i] && b[i] <= '9' {
+		i++
+	}
+	return i
+}
+
+// advance moves d forward by n bytes that do not contain a newline.
+func advance(d deserializer, n int) deserializer {
+	d.idx += n
+	d.col += n
+	return d
+}
+
+// maxValidDepth is the nesting depth beyond which validValue leaves values to the parser.
+const maxValidDepth = 1000
+
+// validValue reports whether b[i:] starts with a valid json value, with the index after it. It
+// accepts a subset of the syntax accepted by the parser, so that values it rejects can be left to
+// the parser, which reports the error.
+func validValue(b []byte, i, depth int) (int, bool) {
+	if i >= len(b) || depth == maxValidDepth {
+		return i, false
+	}
+	switch c := b[i]; c {
+	case '{', '[':
+		closer := c + 2
+		i = skipSpaceAt(b, i+1)
+		if i < len(b) && b[i] == closer {
+			return i + 1, true
+		}
+		for {
+			var ok bool
+			if c == '{' {
+				if i >= len(b) || b[i] != '"' {
+					return i, false
+				}
+				if i, ok = validString(b, i+1); !ok {
+					return i, false
+				}
+				if i = skipSpaceAt(b, i); i >= len(b) || b[i] != ':' {
+					return i, false
+				}
+				i = skipSpaceAt(b, i+1)
+			}
+			if i, ok = validValue(b, i, depth+1); !ok {
+				return i, false
+			}
+			if i = skipSpaceAt(b, i); i >= len(b) {
+				return i, false
+			}
+			switch b[i] {
+			case closer:
+				return i + 1, true
+			case ',':
+				i = skipSpaceAt(b, i+1)
+			default:
+				return i, false
+			}
+		}
+	case '"':
+		return validString(b, i+1)
+	case 't':
+		return validLiteral(b, i, "true")
+	case 'f':
+		return validLiteral(b, i, "false")
+	case 'n':
+		return validLiteral(b, i, "null")
+	}
+	return validNumber(b, i)
+}
+
+// validString reports whether b[i:] is the rest of a string after its opening quote, with the
+// index after the closing quote.
+func validString(b []byte, i int) (int, bool) {
+	for ; i < len(b); i++ {
+		switch c := b[i]; {
+		case c == '"':
+			return i + 1, true
+		case c < ' ':
+			return i, false
+		case c == '\\':
+			if i++; i >= len(b) {
+				return i, false
+			}
+			switch b[i] {
+			case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+			case 'u':
+				if i+4 >= len(b) {
+					return i, false
+				}
+				for _, h := range b[i+1 : i+5] {
+					if !isHexDigit(h) {
+						return i, false
+					}
+				}
+				i += 4
+			default:
+				return i, false
+			}
+		}
+	}
+	return i, false
+}
+
+func validLiteral(b []byte, i int, lit string) (int, bool) {
+	if !bytes.HasPrefix(b[i:], []byte(lit)) {
+		return i, false
+	}
+	return i + len(lit), true
+}
+
+// validNumber reports whether b[i:] starts with a number, with the index after it. As with
+// scanNumber, integers of more than 19 digits and floats out of the range of float64 are left to
+// the parser, which reports the overflow.
+func validNumber(b []byte, i int) (int, bool) {
+	if i < len(b) && b[i] == '-' {
+		i++
+	}
+	numStart := i
+	if i = skipDigits(b, i); i == numStart {
+		return i, false
+	}
+	intEnd := i
+	if i < len(b) && b[i] == '.' {
+		start := i + 1
+		if i = skipDigits(b, start); i == start {
+			return i, false
+		}
+	}
+	if i < len(b) && (b[i] == 'e' || b[i] == 'E') {
+		if i++; i < len(b) && (b[i] == '+' || b[i] == '-') {
+			i++
+		}
+		start := i
+		if i = skipDigits(b, start); i == start {
+			return i, false
+		}
+	}
+	if i == intEnd {
+		return i, intEnd-numStart <= 19
+	}
+	_, err := strconv.ParseFloat(string(b[numStart:i]), 64)
+	return i, err == nil
+}
+
+func skipSpaceAt(b []byte, i int) int {
+	for i < len(b) && isSpace(b[i]) {
+		i++
+	}
+	return i
+}
+
+func isHexDigit(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
+
+// ---------------- helpers end ----------------
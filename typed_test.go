@@ -0,0 +1,221 @@
+package genjson
+
+import (
+	"reflect"
+	"testing"
+)
+
+type typedTree struct {
+	Name     string
+	Children []typedTree `json:"children"`
+}
+
+type typedEvent struct {
+	ID      int            `json:"id"`
+	Kind    string         `json:"kind"`
+	Score   float64        `json:"score"`
+	Tags    []string       `json:"tags"`
+	Attrs   map[string]int `json:"attrs"`
+	Owner   *nestedItem    `json:"owner"`
+	Pair    [2]int8        `json:"pair"`
+	Any     any            `json:"any"`
+	State   onOff          `json:"state"`
+	Hooked  int            `json:"hooked,hook=double"`
+	ByIndex map[int]bool   `json:"byIndex"`
+	unmarshalEmbedded
+}
+
+// typedTest compares the result of decoding data with a TypedDecoder to the result of Unmarshal.
+type typedTest[T any] struct {
+	name string
+	data string
+}
+
+func (tt typedTest[T]) run(t *testing.T, u *Unmarshaler) {
+	t.Run(tt.name, func(t *testing.T) {
+		var want, got T
+		wantErr := u.Unmarshal([]byte(tt.data), &want)
+		gotErr := NewTypedDecoder[T](u).Decode([]byte(tt.data), &got)
+		if !reflect.DeepEqual(gotErr, wantErr) {
+			t.Fatalf("unexpected error %v != %v", gotErr, wantErr)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("unexpected result %+v != %+v", got, want)
+		}
+	})
+}
+
+type typedRunner interface {
+	run(t *testing.T, u *Unmarshaler)
+}
+
+func TestTypedDecoder(t *testing.T) {
+	var u Unmarshaler
+	u.RegisterHook("double", func(_ UnmarshalState, v Value) (Value, error) {
+		n, ok := v.(Number)
+		if !ok {
+			return nil, TypeError{Want: TypeNumber, Got: TypeOf(v)}
+		}
+		n.Integer *= 2
+		return n, nil
+	})
+	tests := []typedRunner{
+		typedTest[int]{name: "int", data: ` 12 `},
+		typedTest[int]{name: "negative-int", data: `-12`},
+		typedTest[int8]{name: "overflow", data: `300`},
+		typedTest[uint]{name: "negative-uint", data: `-1`},
+		typedTest[uint64]{name: "large-uint", data: `18446744073709551615`},
+		typedTest[uint64]{name: "uint64-overflow", data: `18446744073709551616`},
+		typedTest[float64]{name: "float", data: `-1.5e3`},
+		typedTest[int]{name: "integral-float", data: `2.0`},
+		typedTest[int]{name: "fractional-float", data: `2.5`},
+		typedTest[string]{name: "string", data: `"a b"`},
+		typedTest[string]{name: "escaped-string", data: `"a\né"`},
+		typedTest[string]{name: "string-newline", data: "\"a\nb\""},
+		typedTest[bool]{name: "bool", data: `true`},
+		typedTest[*int]{name: "null-pointer", data: `null`},
+		typedTest[int]{name: "type-mismatch", data: `"1"`},
+		typedTest[[]int]{name: "empty-slice", data: `[]`},
+		typedTest[[]int]{name: "null-slice", data: `null`},
+		typedTest[[]*int]{name: "slice-of-pointers", data: `[1, null, 3]`},
+		typedTest[[][]string]{name: "nested-slices", data: `[["a"], [], ["b", "c"]]`},
+		typedTest[[2]int]{name: "array", data: `[1, 2]`},
+		typedTest[[2]int]{name: "short-array", data: `[1]`},
+		typedTest[[2]int]{name: "long-array", data: `[1, 2, 3]`},
+		typedTest[[2]int]{name: "long-array-bad-element", data: `[1, "a", 3]`},
+		typedTest[[2]int]{name: "array-bad-element", data: `[1, "a"]`},
+		typedTest[map[string]int]{name: "map", data: `{"a": 1, "b": 2, "a": 3}`},
+		typedTest[map[int]string]{name: "int-keys", data: `{"1": "a", "-2": "b"}`},
+		typedTest[map[bool]int]{name: "unsupported-key", data: `{"true": 1}`},
+		typedTest[map[string][]nestedItem]{name: "map-of-slices", data: `{"a": [{"ID": 1}, {"ID": 2, "Tags": ["x"]}], "b": [], "c": null}`},
+		typedTest[[]any]{name: "interfaces", data: `[1, "a", {"b": [null]}]`},
+		typedTest[typedTree]{name: "recursive", data: `{"Name": "a", "children": [{"Name": "b", "children": [{"Name": "c"}]}]}`},
+		typedTest[typedEvent]{name: "struct", data: `{
+			"id": 7,
+			"kind": "click",
+			"unknown": {"deeply": [{"nested": ["values", 1, true, null]}], "x": "}"},
+			"score": 0.25,
+			"tags": ["a", "b"],
+			"attrs": {"x": 1},
+			"owner": {"ID": 3, "Tags": null},
+			"pair": [1, -2],
+			"any": {"k": [1.5, "v"]},
+			"state": "on",
+			"hooked": 4,
+			"E": 9,
+			"KIND": "folded"
+		}`},
+		typedTest[typedEvent]{name: "struct-error", data: "{\n  \"owner\": {\"ID\": \"a\"}\n}"},
+		typedTest[typedEvent]{name: "duplicate-key-error", data: `{"tags": [], "tags": ["a", 1]}`},
+		typedTest[typedEvent]{name: "escaped-key-error", data: `{"t\u0061gs": [1]}`},
+		typedTest[typedEvent]{name: "from-error", data: `{"state": "maybe"}`},
+		typedTest[typedEvent]{name: "hook-error", data: `{"hooked": "a"}`},
+		typedTest[typedEvent]{name: "unsupported-map-key", data: `{"byIndex": {"2": true}}`},
+		typedTest[typedEvent]{name: "unknown-float-overflow", data: `{"zz": 1e400, "id": 1}`},
+		typedTest[typedEvent]{name: "unknown-integer-overflow", data: `{"zz": 99999999999999999999999, "id": 1}`},
+		typedTest[typedEvent]{name: "unknown-nested-overflow", data: `{"zz": {"a": [1, -1e400]}, "id": 1}`},
+		typedTest[typedEvent]{name: "unknown-large-numbers", data: `{"zz": [1e308, 9999999999999999999, -0.5e-400], "id": 1}`},
+		typedTest[typedEvent]{name: "lenient-syntax", data: "{\"id\": 1, \"X\": 1e, \"kind\": \"a\tb\"}"},
+		typedTest[typedEvent]{name: "null-struct", data: `null`},
+		typedTest[typedEvent]{name: "array-for-struct", data: `[1]`},
+	}
+	for _, tt := range tests {
+		tt.run(t, &u)
+	}
+
+	u.DisallowUnknownFields = true
+	typedTest[typedEvent]{name: "disallow-unknown-fields", data: `{"id": 1, "other": 2}`}.run(t, &u)
+	u.DisallowUnknownFields = false
+	u.MergeMapValues = true
+	typedTest[map[string]map[string]int]{name: "merge-map-values", data: `{"a": {"x": 1}, "a": {"y": 2}}`}.run(t, &u)
+}
+
+func TestTypedDecoderSyntaxErrors(t *testing.T) {
+	for _, data := range []string{
+		``,
+		`   `,
+		`{"id": 1`,
+		`{"id" 1}`,
+		`{"id": 1,}`,
+		`{"unknown": [1}`,
+		`{"tags": ["a" "b"]}`,
+		`{1: 2}`,
+		`{"X": tru}`,
+		`{"X": {"a" 1}}`,
+		`{"X": "\q"}`,
+		`{"X": "\u12"}`,
+		`{"X": [1, -]}`,
+		`{"id": 1,}`,
+		`{"id": 1, "X": [1}`,
+	} {
+		t.Run(data, func(t *testing.T) {
+			// Syntax errors are the errors of Unmarshal and leave the target unchanged.
+			got := typedEvent{Kind: "k"}
+			err := NewTypedDecoder[typedEvent](nil).Decode([]byte(data), &got)
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+			if want := Unmarshal([]byte(data), &typedEvent{}); !reflect.DeepEqual(err, want) {
+				t.Errorf("unexpected error %v != %v", err, want)
+			}
+			if !reflect.DeepEqual(got, typedEvent{Kind: "k"}) {
+				t.Errorf("target modified %+v", got)
+			}
+		})
+	}
+}
+
+func TestTypedDecoderExistingValues(t *testing.T) {
+	// As with Unmarshal, null leaves pointers and maps unchanged and slices are replaced.
+	one := 1
+	got := struct {
+		P *int
+		M map[string]int
+		S []int
+	}{P: &one, M: map[string]int{"a": 1}, S: []int{1, 2, 3}}
+	err := NewTypedDecoder[struct {
+		P *int
+		M map[string]int
+		S []int
+	}](nil).Decode([]byte(`{"P": null, "M": {"b": 2}, "S": [4]}`), &got)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if got.P != &one || !reflect.DeepEqual(got.M, map[string]int{"a": 1, "b": 2}) || !reflect.DeepEqual(got.S, []int{4}) {
+		t.Errorf("unexpected result %+v", got)
+	}
+}
+
+func BenchmarkTypedDecoder(b *testing.B) {
+	data := []byte(`{"id": 7, "kind": "click", "score": 0.25, "tags": ["a", "b", "c"],
+		"attrs": {"x": 1, "y": 2}, "owner": {"ID": 3, "Tags": ["t"]}, "pair": [1, 2],
+		"unknown": {"deeply": [{"nested": ["values", 1, true, null]}]}}`)
+	type event struct {
+		ID    int            `json:"id"`
+		Kind  string         `json:"kind"`
+		Score float64        `json:"score"`
+		Tags  []string       `json:"tags"`
+		Attrs map[string]int `json:"attrs"`
+		Owner *nestedItem    `json:"owner"`
+		Pair  [2]int8        `json:"pair"`
+	}
+	b.Run("Unmarshal", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var e event
+			if err := Unmarshal(data, &e); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("TypedDecoder", func(b *testing.B) {
+		b.ReportAllocs()
+		td := NewTypedDecoder[event](nil)
+		for i := 0; i < b.N; i++ {
+			var e event
+			if err := td.Decode(data, &e); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
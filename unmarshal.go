@@ -1,12 +1,16 @@
 package genjson
 
 import (
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"math"
+	"math/big"
 	"reflect"
-	"strconv"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 var (
@@ -17,25 +21,142 @@ var (
 // TODO: This should contain the unmarsaling options. Things such as required fields, custom
 // unmarshalers etc. should go here.
 type Unmarshaler struct {
+	// SliceWorkers is the number of goroutines used to unmarshal the elements of an array into a
+	// slice. Values less than 2 disable parallel unmarshaling. This is only worthwhile when
+	// unmarshaling each element is expensive.
+	SliceWorkers int
+	// ParallelSliceMinLen is the minimum length an array must have before its elements are
+	// unmarshaled in parallel.
+	ParallelSliceMinLen int
+	// CheckPrecisionLoss causes an error to be returned when a number cannot be represented by
+	// the target float32 or float64 within MaxPrecisionLoss. Floating point numbers are checked
+	// against their parsed float64 value, so this only detects loss for integers and for
+	// float32 targets.
+	CheckPrecisionLoss bool
+	// MaxPrecisionLoss is the maximum absolute difference permitted between a number and its
+	// float representation when CheckPrecisionLoss is set.
+	MaxPrecisionLoss float64
+	// NumberMode controls the go type used for numbers unmarshaled into an empty interface.
+	NumberMode NumberMode
+	// DisallowDuplicateKeys returns a DuplicateKeyError for objects that contain a key more than
+	// once, instead of using the last value.
+	DisallowDuplicateKeys bool
+	// Base64Bytes unmarshals strings into byte slices by decoding them as standard base64, as
+	// encoding/json does.
+	Base64Bytes bool
+	// DisallowUnknownFields returns an UnknownFieldError for object keys that do not match a
+	// field of the struct they are unmarshaled into, instead of ignoring them.
+	DisallowUnknownFields bool
+	// MergeMapValues unmarshals the entries of an object into the existing values of their keys
+	// when the target map already contains them, so that nested structs and maps are updated
+	// rather than replaced. By default the existing value is replaced, as with encoding/json.
+	MergeMapValues bool
+
+	hooks           map[string]DecodeHook
+	implementations map[reflect.Type]reflect.Type
+	middleware      []Middleware
+	chain           DecodeFunc
+}
+
+// DecodeFunc unmarshals a value into the settable go value v.
+type DecodeFunc func(s UnmarshalState, value Value, v reflect.Value) error
+
+// Middleware wraps the DecodeFunc used for every value, including the values nested within arrays
+// and objects. Middleware can inspect or replace the value and target before calling next, handle
+// the value itself, or inspect the error returned by next. Applications can use middleware to
+// layer concerns such as logging, metrics, defaults and coercions onto the Unmarshaler.
+type Middleware func(next DecodeFunc) DecodeFunc
+
+// Use adds middleware to the Unmarshaler. Middleware added first is outermost, so it is called
+// first and returns last. Middleware must be added before the Unmarshaler is used.
+func (u *Unmarshaler) Use(mw ...Middleware) {
+	u.middleware = append(u.middleware, mw...)
+	chain := DecodeFunc(func(s UnmarshalState, value Value, v reflect.Value) error {
+		return decode(&s, value, v)
+	})
+	for i := len(u.middleware) - 1; i >= 0; i-- {
+		chain = u.middleware[i](chain)
+	}
+	u.chain = chain
+}
+
+// NumberMode is the representation of numbers unmarshaled into an empty interface.
+type NumberMode int
+
+const (
+	// NumberModeFloat64Always stores every number as a float64.
+	NumberModeFloat64Always NumberMode = iota
+	// NumberModeInt64WhenIntegral stores numbers written without a fractional part as an int64
+	// if they fit, and as a float64 otherwise.
+	NumberModeInt64WhenIntegral
+	// NumberModeKeep stores the Number value itself, with no loss of precision.
+	NumberModeKeep
+)
+
+// DecodeHook transforms a value before it is unmarshaled into a struct field that references the
+// hook by name in its tag e.g. `json:"ts,hook=unixms"`.
+type DecodeHook func(UnmarshalState, Value) (Value, error)
+
+// RegisterHook registers a named DecodeHook. Hooks must be registered before the Unmarshaler is
+// used. Hooks registered on the Unmarshaler override hooks of the same name registered with
+// RegisterDefaultHook.
+func (u *Unmarshaler) RegisterHook(name string, hook DecodeHook) {
+	if u.hooks == nil {
+		u.hooks = make(map[string]DecodeHook)
+	}
+	u.hooks[name] = hook
 }
 
 // TODO: Circular references should be disallowed as they are not valid json.
 type UnmarshalState struct {
 	u    *Unmarshaler
 	node *node // Optional. Used for location data.
-	key  []string
+	path Path
+}
+
+// Path returns the path of the value being unmarshaled.
+func (s UnmarshalState) Path() Path {
+	return s.path.Append()
 }
 
+// Loc returns the location of the value being unmarshaled, if the Unmarshaler has location
+// information. This is the case if Unmarshal was used.
+func (s UnmarshalState) Loc() (Loc, bool) {
+	if s.node == nil {
+		return Loc{}, false
+	}
+	return s.node.start, true
+}
+
+// From is implemented by types that unmarshal themselves. FromJSON may be implemented on a pointer
+// or a value receiver, see Unmarshaler.Unmarshal for the order in which it is resolved. Errors
+// returned by FromJSON are wrapped in an UnmarshalError unless they already are one.
 type From interface {
 	FromJSON(UnmarshalState, Value) error
 }
 
+var fromType = reflect.TypeOf((*From)(nil)).Elem()
+
 var defaultUnmarshaler Unmarshaler
 
 func Unmarshal(data []byte, v any) error {
 	return defaultUnmarshaler.Unmarshal(data, v)
 }
 
+// Unmarshal deserializes data and unmarshals it into v, which must be a non-nil pointer. Every
+// value, including the values nested within arrays and objects, is unmarshaled into its target
+// by the first of the following that applies:
+//  1. A target interface holding a non-nil pointer is unmarshaled into the value it points to,
+//     unless the value is null.
+//  2. A target pointer is allocated if it is nil and the value it points to is unmarshaled into,
+//     unless the value is null. Null leaves the pointer unchanged.
+//  3. A target whose pointer implements From, because FromJSON has a pointer receiver or because
+//     the target is addressable, calls FromJSON on its address.
+//  4. A target that implements From with a value receiver calls FromJSON on the target itself,
+//     even if it is not addressable.
+//  5. A target interface with a registered implementation is unmarshaled into a new value of the
+//     implementation, see RegisterImplementation.
+//  6. Otherwise the value is unmarshaled by its type, e.g. an Object into a struct or a map.
 func (u *Unmarshaler) Unmarshal(data []byte, v any) error {
 	d, err := deserialize(data)
 	if err != nil {
@@ -48,6 +169,19 @@ func (u *Unmarshaler) UnmarshalValue(value Value, v any) error {
 	return u.unmarshal(value, nil, v)
 }
 
+// RegisterImplementation registers the concrete type of impl to be used when unmarshaling into
+// the interface type I, e.g. RegisterImplementation[Shape](u, &GenericShape{}). If the concrete
+// type is a pointer, a new value is allocated for every unmarshaled value. Implementations must
+// be registered before the Unmarshaler is used. Implementations registered on the Unmarshaler
+// override those registered with RegisterDefaultImplementation.
+func RegisterImplementation[I any](u *Unmarshaler, impl I) {
+	iface := interfaceType[I]()
+	if u.implementations == nil {
+		u.implementations = make(map[reflect.Type]reflect.Type)
+	}
+	u.implementations[iface] = reflect.TypeOf(impl)
+}
+
 func (u *Unmarshaler) unmarshal(value Value, node *node, v any) error {
 	rv := reflect.ValueOf(v)
 	if rv.Kind() != reflect.Pointer || rv.IsNil() {
@@ -57,16 +191,152 @@ func (u *Unmarshaler) unmarshal(value Value, node *node, v any) error {
 		u:    u,
 		node: node,
 	}
-	return value.unmarshal(s, rv)
+	return unmarshal(s, value, rv.Elem())
 }
 
+// unmarshal unmarshals the value into v through the middleware of the Unmarshaler.
 func unmarshal(s *UnmarshalState, value Value, v reflect.Value) error {
+	if s.u.chain != nil {
+		return s.u.chain(*s, value, v)
+	}
+	return decode(s, value, v)
+}
+
+// decode unmarshals the value into v.
+func decode(s *UnmarshalState, value Value, v reflect.Value) error {
+	_, isNull := value.(Null)
+	switch v.Kind() {
+	case reflect.Interface:
+		if e := v.Elem(); !isNull && e.Kind() == reflect.Pointer && !e.IsNil() {
+			return decode(s, value, e.Elem())
+		}
+	case reflect.Pointer:
+	default:
+		if f, ok := fromTarget(v); ok {
+			if err := f.FromJSON(*s, value); err != nil {
+				if ue, ok := err.(UnmarshalError); ok {
+					return ue
+				}
+				return unmarshalError(s, err)
+			}
+			return nil
+		}
+	}
 	if !v.CanSet() {
 		return unmarshalError(s, ErrCannotSet)
 	}
+	if v.Kind() == reflect.Interface {
+		if !isNull {
+			if impl, ok := s.u.implementation(v.Type()); ok {
+				return unmarshalImplementation(s, value, v, impl)
+			}
+		}
+		if v.NumMethod() == 0 {
+			if s.u.DisallowDuplicateKeys {
+				if err := checkDuplicateKeys(s, value); err != nil {
+					return err
+				}
+			}
+			if iv := s.u.interfaceValue(value); iv != nil {
+				v.Set(reflect.ValueOf(iv))
+			} else {
+				v.Set(reflect.Zero(v.Type()))
+			}
+			return nil
+		}
+	}
+	if v.Kind() == reflect.Pointer {
+		if !isNull {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			return decode(s, value, v.Elem())
+		}
+	}
 	return value.unmarshal(s, v)
 }
 
+// fromTarget returns the From implementation used to unmarshal into v, if there is one.
+func fromTarget(v reflect.Value) (From, bool) {
+	if v.CanAddr() && reflect.PointerTo(v.Type()).Implements(fromType) {
+		return v.Addr().Interface().(From), true
+	}
+	if v.Type().Implements(fromType) && v.CanInterface() {
+		return v.Interface().(From), true
+	}
+	return nil, false
+}
+
+// interfaceValue converts the value into the go value stored when unmarshaling into an empty
+// interface. Null becomes nil, arrays become []any, objects become map[string]any and extensions
+// become their data. When an object contains duplicate keys the last value is used.
+func (u *Unmarshaler) interfaceValue(value Value) any {
+	switch value := value.(type) {
+	case Bool:
+		return bool(value)
+	case Number:
+		return u.interfaceNumber(value)
+	case String:
+		return string(value)
+	case Array:
+		a := make([]any, len(value))
+		for i, v := range value {
+			a[i] = u.interfaceValue(v)
+		}
+		return a
+	case Extension:
+		return value.Data
+	case Object:
+		m := make(map[string]any, value.Len())
+		iter := value.Iter()
+		for k, v, ok := iter.Next(); ok; k, v, ok = iter.Next() {
+			m[k] = u.interfaceValue(v)
+		}
+		return m
+	default:
+		return nil
+	}
+}
+
+func (u *Unmarshaler) interfaceNumber(n Number) any {
+	switch u.NumberMode {
+	case NumberModeInt64WhenIntegral:
+		if n.IsFloat {
+			return n.float64()
+		}
+		if n.IsNeg && n.Integer <= 1<<63 {
+			return -int64(n.Integer-1) - 1
+		}
+		if !n.IsNeg && n.Integer <= math.MaxInt64 {
+			return int64(n.Integer)
+		}
+		return n.float64()
+	case NumberModeKeep:
+		return n
+	default:
+		return n.float64()
+	}
+}
+
+// unmarshalImplementation unmarshals into a new value of the registered implementation type and
+// stores it in the interface value v.
+func unmarshalImplementation(s *UnmarshalState, value Value, v reflect.Value, impl reflect.Type) error {
+	if impl.Kind() == reflect.Pointer {
+		p := reflect.New(impl.Elem())
+		if err := unmarshal(s, value, p.Elem()); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+	elem := reflect.New(impl).Elem()
+	if err := unmarshal(s, value, elem); err != nil {
+		return err
+	}
+	v.Set(elem)
+	return nil
+}
+
 func (n Null) unmarshal(s *UnmarshalState, v reflect.Value) error {
 	// TODO: Allow nulls for any valid json values as a unmarshal option.
 	switch v.Kind() {
@@ -139,6 +409,11 @@ func (n Number) unmarshal(s *UnmarshalState, v reflect.Value) error {
 		if rv.OverflowFloat(f) {
 			return unmarshalError(s, overflowError(rv.Type(), n))
 		}
+		if s.u.CheckPrecisionLoss {
+			if loss := n.precisionLoss(rv.Kind()); loss > s.u.MaxPrecisionLoss {
+				return unmarshalError(s, precisionLossError(rv.Type(), n, loss))
+			}
+		}
 		return set(rv, f)
 
 	default:
@@ -154,70 +429,449 @@ func (n Number) looseUint64(t reflect.Type) (uint64, error) {
 		return 0, overflowError(t, n)
 	}
 	u := uint64(n.Float)
-	fmt.Printf("%d, %.1f\n", u, n.Float)
 	if n.Float != float64(u) {
-		fmt.Printf("%d != %.1f\n", u, n.Float)
 		return 0, fractionalFloatError(t, n)
 	}
 	return u, nil
 }
 
 func (n Number) float64() float64 {
+	f := n.Float
 	if !n.IsFloat {
-		return float64(n.Integer)
+		f = float64(n.Integer)
+	}
+	if n.IsNeg {
+		return -f
 	}
-	return n.Float
+	return f
+}
+
+// precisionLoss returns the absolute difference between the number and its representation as a
+// float of the given kind.
+func (n Number) precisionLoss(k reflect.Kind) float64 {
+	f := n.float64()
+	if k == reflect.Float32 {
+		f = float64(float32(f))
+	}
+	if n.IsFloat {
+		return math.Abs(n.float64() - f)
+	}
+	exact := new(big.Float).SetUint64(n.Integer)
+	if n.IsNeg {
+		exact.Neg(exact)
+	}
+	loss, _ := exact.Sub(exact, big.NewFloat(f)).Abs(exact).Float64()
+	return loss
 }
 
 func (st String) unmarshal(s *UnmarshalState, v reflect.Value) error {
 	rv := reflect.Indirect(v)
 	switch rv.Kind() {
-	// TODO: Byte slice (as a compiler option). Maybe as a hex string? Maybe not hard coded but
-	// one the of the default custom unmarshal types, similar to how we will handle time.Time?
 	case reflect.String:
 		return set(rv, string(st))
-	default:
-		return unmarshalInvalidTypeError(s, v.Type(), TypeNull)
+	case reflect.Slice:
+		if !s.u.Base64Bytes || rv.Type().Elem().Kind() != reflect.Uint8 {
+			break
+		}
+		b, err := base64.StdEncoding.DecodeString(string(st))
+		if err != nil {
+			return unmarshalError(s, err)
+		}
+		return set(rv, b)
 	}
+	return unmarshalInvalidTypeError(s, v.Type(), TypeString)
 }
 
 func (a Array) unmarshal(s *UnmarshalState, v reflect.Value) error {
 	rv := reflect.Indirect(v)
 	switch rv.Kind() {
 	case reflect.Slice:
+		out := reflect.MakeSlice(rv.Type(), len(a), len(a))
+		if err := a.unmarshalElems(s, out); err != nil {
+			return err
+		}
+		rv.Set(out)
+		return nil
+	case reflect.Array:
+		if rv.Len() != len(a) {
+			return unmarshalError(s, arrayLengthError(rv.Type(), len(a)))
+		}
 		out := reflect.New(rv.Type()).Elem()
-		elemType := rv.Type().Elem()
-		for i, v := range a {
-			elem := reflect.New(elemType).Elem()
+		if err := a.unmarshalElems(s, out); err != nil {
+			return err
+		}
+		rv.Set(out)
+		return nil
+	default:
+		return unmarshalInvalidTypeError(s, v.Type(), TypeArray)
+	}
+}
+
+// unmarshalElems unmarshals every element of the array into the matching index of out. Elements
+// are unmarshaled in parallel if the Unmarshaler is configured to do so.
+func (a Array) unmarshalElems(s *UnmarshalState, out reflect.Value) error {
+	workers := s.u.SliceWorkers
+	if workers > len(a) {
+		workers = len(a)
+	}
+	if workers <= 1 || len(a) < s.u.ParallelSliceMinLen {
+		for i := range a {
+			if err := a.unmarshalElem(s, i, out.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// Each worker claims the next unprocessed index. Elements are disjoint so no further
+	// synchronization is required.
+	errs := make([]error, len(a))
+	next := int64(-1)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= len(a) {
+					return
+				}
+				errs[i] = a.unmarshalElem(s, i, out.Index(i))
+			}
+		}()
+	}
+	wg.Wait()
+	// Return the first error in element order so that the result is deterministic.
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a Array) unmarshalElem(s *UnmarshalState, i int, elem reflect.Value) error {
+	// new state "frame"
+	ss := *s
+	if s.node != nil {
+		ss.node = &s.node.arrayNodes[i]
+	}
+	ss.path = s.path.Append(Index(i))
+	return unmarshal(&ss, a[i], elem)
+}
+
+func (o Object) unmarshal(s *UnmarshalState, v reflect.Value) error {
+	rv := reflect.Indirect(v)
+	if s.u.DisallowDuplicateKeys && (rv.Kind() == reflect.Struct || rv.Kind() == reflect.Map) {
+		if err := o.checkDuplicateKeys(s); err != nil {
+			return err
+		}
+	}
+	switch rv.Kind() {
+	case reflect.Struct:
+		return o.unmarshalStruct(s, rv)
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return unmarshalInvalidTypeError(s, v.Type(), TypeObject)
+		}
+		return o.unmarshalMap(s, rv)
+	default:
+		return unmarshalInvalidTypeError(s, v.Type(), TypeObject)
+	}
+}
+
+// unmarshalMap adds the entries of the object to the map, allocating the map if it is nil. When a
+// key is duplicated the last value is used.
+func (o Object) unmarshalMap(s *UnmarshalState, rv reflect.Value) error {
+	t := rv.Type()
+	if rv.IsNil() {
+		rv.Set(reflect.MakeMapWithSize(t, o.Len()))
+	}
+	elem := reflect.New(t.Elem()).Elem()
+	for i, e := range o.Entries() {
+		// new state "frame"
+		ss := *s
+		if s.node != nil {
+			ss.node = &s.node.objectNodes[i].node
+		}
+		step := Key(e.Key)
+		if e.Ordinal > 0 {
+			step = KeyAt(e.Key, e.Ordinal)
+		}
+		ss.path = s.path.Append(step)
+		key := reflect.ValueOf(e.Key).Convert(t.Key())
+		elem.Set(reflect.Zero(t.Elem()))
+		if s.u.MergeMapValues {
+			if existing := rv.MapIndex(key); existing.IsValid() {
+				elem.Set(existing)
+			}
+		}
+		if err := unmarshal(&ss, e.Value, elem); err != nil {
+			return err
+		}
+		rv.SetMapIndex(key, elem)
+	}
+	return nil
+}
 
+// unmarshalStruct unmarshals the entries of the object into the matching fields of the struct.
+// Keys are matched against the json names of the fields, falling back to a case insensitive match.
+// Keys without a matching field are ignored. When a key is duplicated the last value is used.
+func (o Object) unmarshalStruct(s *UnmarshalState, rv reflect.Value) error {
+	fields := cachedStructFields(rv.Type())
+	for i, e := range o.Entries() {
+		f, ok := fields.lookup(e.Key)
+		if !ok {
+			if s.u.DisallowUnknownFields {
+				return keyError(s, i, e, UnknownFieldError{Key: e.Key, Type: rv.Type()})
+			}
+			continue
+		}
+		// new state "frame"
+		ss := *s
+		if s.node != nil {
+			ss.node = &s.node.objectNodes[i].node
+		}
+		step := Key(e.Key)
+		if e.Ordinal > 0 {
+			step = KeyAt(e.Key, e.Ordinal)
+		}
+		ss.path = s.path.Append(step)
+		value, err := applyHook(&ss, f.tag.hook, e.Value)
+		if err != nil {
+			return err
+		}
+		if err := unmarshal(&ss, value, fieldByIndex(rv, f.index)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDuplicateKeys returns an error for the first key of the object that is a duplicate.
+func (o Object) checkDuplicateKeys(s *UnmarshalState) error {
+	for i, e := range o.Entries() {
+		if e.Ordinal > 0 {
+			return keyError(s, i, e, DuplicateKeyError{Key: e.Key})
+		}
+	}
+	return nil
+}
+
+// checkDuplicateKeys checks the value and every value nested within it for duplicate keys. It is
+// used for values that are not unmarshaled through Object.unmarshal, such as empty interfaces.
+func checkDuplicateKeys(s *UnmarshalState, value Value) error {
+	switch value := value.(type) {
+	case Array:
+		for i, elem := range value {
 			// new state "frame"
 			ss := *s
 			if s.node != nil {
 				ss.node = &s.node.arrayNodes[i]
 			}
-			ss.key = append(cloneStrings(s.key), strconv.Itoa(i))
-
-			if err := unmarshal(s, v, elem); err != nil {
+			ss.path = s.path.Append(Index(i))
+			if err := checkDuplicateKeys(&ss, elem); err != nil {
 				return err
 			}
+		}
+	case Object:
+		if err := value.checkDuplicateKeys(s); err != nil {
+			return err
+		}
+		for i, e := range value.Entries() {
+			// new state "frame"
+			ss := *s
+			if s.node != nil {
+				ss.node = &s.node.objectNodes[i].node
+			}
+			ss.path = s.path.Append(Key(e.Key))
+			if err := checkDuplicateKeys(&ss, e.Value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ---------------- helpers start ----------------
 
-			out = reflect.Append(out, elem)
+// fieldTag is the parsed json tag of a struct field.
+type fieldTag struct {
+	name      string
+	omitEmpty bool
+	hook      string
+}
+
+func parseFieldTag(tag string) fieldTag {
+	parts := strings.Split(tag, ",")
+	ft := fieldTag{name: parts[0]}
+	for _, opt := range parts[1:] {
+		switch {
+		case opt == "omitempty":
+			ft.omitEmpty = true
+		case strings.HasPrefix(opt, "hook="):
+			ft.hook = strings.TrimPrefix(opt, "hook=")
 		}
+	}
+	return ft
+}
 
-		rv.Set(out)
-		return nil
-	case reflect.Array:
-		panic("unmarshaling into arrays is not implemented yet")
-	default:
-		return unmarshalInvalidTypeError(s, v.Type(), TypeNull)
+// structField is a struct field that is marshaled and can be unmarshaled into.
+type structField struct {
+	name  string
+	index []int
+	tag   fieldTag
+	// readOnly is set for the fields of structs embedded by unexported pointers. They are marshaled
+	// but not unmarshaled, as the pointers cannot be allocated.
+	readOnly bool
+}
+
+// structFields are the fields of a struct type in the order they are marshaled.
+type structFields []structField
+
+var fieldCache sync.Map // map[reflect.Type]structFields
+
+func cachedStructFields(t reflect.Type) structFields {
+	if fields, ok := fieldCache.Load(t); ok {
+		return fields.(structFields)
 	}
+	fields, _ := fieldCache.LoadOrStore(t, typeFields(t))
+	return fields.(structFields)
 }
 
-func (Object) unmarshal(s *UnmarshalState, v reflect.Value) error {
-	panic("not implemented")
+// typeFields returns the exported fields of the struct type in declaration order. The fields of
+// embedded structs without a json name are promoted in place of the embedded field. Structs that
+// embed themselves, directly or indirectly, are only promoted once.
+func typeFields(t reflect.Type) structFields {
+	var fields structFields
+	var walk func(t reflect.Type, index []int, readOnly bool, visited []reflect.Type)
+	walk = func(t reflect.Type, index []int, readOnly bool, visited []reflect.Type) {
+		for _, v := range visited {
+			if v == t {
+				return
+			}
+		}
+		visited = append(visited, t)
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag, hasTag := f.Tag.Lookup("json")
+			if tag == "-" {
+				continue
+			}
+			ft := parseFieldTag(tag)
+			fi := append(append([]int{}, index...), i)
+			if f.Anonymous && ft.name == "" {
+				et := f.Type
+				ptr := et.Kind() == reflect.Pointer
+				if ptr {
+					et = et.Elem()
+				}
+				if et.Kind() == reflect.Struct {
+					walk(et, fi, readOnly || ptr && !f.IsExported(), visited)
+					continue
+				}
+			}
+			if !f.IsExported() {
+				continue
+			}
+			name := ft.name
+			if !hasTag || name == "" {
+				name = f.Name
+			}
+			fields = append(fields, structField{name: name, index: fi, tag: ft, readOnly: readOnly})
+		}
+	}
+	walk(t, nil, false, nil)
+	return fields
 }
 
-// ---------------- helpers start ----------------
+// byDepth returns the fields that can be unmarshaled into, ordered by depth so that fields of the
+// outer struct take precedence over promoted fields.
+func (fields structFields) byDepth() structFields {
+	out := make(structFields, 0, len(fields))
+	for _, f := range fields {
+		if !f.readOnly {
+			out = append(out, f)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return len(out[i].index) < len(out[j].index)
+	})
+	return out
+}
+
+// lookup returns the field with the given json name that can be unmarshaled into. An exact match
+// is preferred over a case insensitive match, and fields of the outer struct over promoted fields.
+func (fields structFields) lookup(name string) (structField, bool) {
+	var (
+		exact, folded       structField
+		hasExact, hasFolded bool
+	)
+	for _, f := range fields {
+		switch {
+		case f.readOnly:
+		case f.name == name:
+			if !hasExact || len(f.index) < len(exact.index) {
+				exact, hasExact = f, true
+			}
+		case strings.EqualFold(f.name, name):
+			if !hasFolded || len(f.index) < len(folded.index) {
+				folded, hasFolded = f, true
+			}
+		}
+	}
+	if hasExact {
+		return exact, true
+	}
+	return folded, hasFolded
+}
+
+// fieldValue returns the nested field of the struct for marshaling, or false if an embedded
+// struct pointer along the way is nil.
+func fieldValue(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// fieldByIndex returns the nested field of the struct, allocating nil embedded struct pointers
+// along the way.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// applyHook runs the named hook on the value. The value is returned unchanged if name is empty.
+func applyHook(s *UnmarshalState, name string, value Value) (Value, error) {
+	if name == "" {
+		return value, nil
+	}
+	hook, ok := s.u.hook(name)
+	if !ok {
+		return nil, unmarshalError(s, UnknownHookError{Name: name})
+	}
+	value, err := hook(*s, value)
+	if err != nil {
+		return nil, unmarshalError(s, err)
+	}
+	return value, nil
+}
 
 func set[V any](r reflect.Value, v V) error {
 	r.Set(reflect.ValueOf(v).Convert(r.Type()))
@@ -231,7 +885,7 @@ func set[V any](r reflect.Value, v V) error {
 type UnmarshalError struct {
 	Cause error
 	// Field is set if the value was part of a nested field e.g. a struct or map.
-	Field []string
+	Field Path
 	// Loc is set if location information is available to the Unmarshaler. This is the case if
 	// Unmarshal was used.
 	Loc *Loc
@@ -245,17 +899,33 @@ func unmarshalError(s *UnmarshalState, e error) UnmarshalError {
 	}
 	return UnmarshalError{
 		Cause: e,
-		Field: cloneStrings(s.key),
+		Field: s.path.Append(),
 		Loc:   loc,
 	}
 }
 
+// keyError returns an error for the key of the i-th entry of the object being unmarshaled. The
+// location of the error is the start of the key.
+func keyError(s *UnmarshalState, i int, e Entry, err error) UnmarshalError {
+	ue := unmarshalError(s, err)
+	step := Key(e.Key)
+	if e.Ordinal > 0 {
+		step = KeyAt(e.Key, e.Ordinal)
+	}
+	ue.Field = s.path.Append(step)
+	if s.node != nil {
+		l := s.node.objectNodes[i].keyStart
+		ue.Loc = &l
+	}
+	return ue
+}
+
 func (ue UnmarshalError) Error() string {
 	sb := strings.Builder{}
 	sb.WriteString("unmarshal error")
 	if len(ue.Field) > 0 {
 		sb.WriteString(" ")
-		sb.WriteString(strings.Join(ue.Field, "."))
+		sb.WriteString(ue.Field.String())
 	}
 	if ue.Loc != nil {
 		sb.WriteString(" ")
@@ -266,6 +936,10 @@ func (ue UnmarshalError) Error() string {
 	return sb.String()
 }
 
+func (ue UnmarshalError) Unwrap() error {
+	return ue.Cause
+}
+
 func locString(l *Loc) string {
 	return fmt.Sprintf("%d:%d", l.Row, l.Col)
 }
@@ -328,6 +1002,61 @@ func negativeUintError(t reflect.Type, number Number) NegativeUintError {
 	return NegativeUintError{t, number}
 }
 
+type PrecisionLossError struct {
+	ValueType reflect.Type
+	Number    Number
+	Loss      float64
+}
+
+func (e PrecisionLossError) Error() string {
+	return fmt.Sprintf("number %s cannot be represented by go type %s without losing %g",
+		e.Number.append(&Serializer{}, 0, make([]byte, 0, 64)),
+		e.ValueType,
+		e.Loss)
+}
+
+func precisionLossError(t reflect.Type, number Number, loss float64) PrecisionLossError {
+	return PrecisionLossError{t, number, loss}
+}
+
+type ArrayLengthError struct {
+	ValueType reflect.Type
+	Len       int
+}
+
+func (e ArrayLengthError) Error() string {
+	return fmt.Sprintf("array of length %d cannot be represented by go type %s", e.Len, e.ValueType)
+}
+
+func arrayLengthError(t reflect.Type, n int) ArrayLengthError {
+	return ArrayLengthError{t, n}
+}
+
+type DuplicateKeyError struct {
+	Key string
+}
+
+func (e DuplicateKeyError) Error() string {
+	return fmt.Sprintf("duplicate key %q", e.Key)
+}
+
+type UnknownFieldError struct {
+	Key  string
+	Type reflect.Type
+}
+
+func (e UnknownFieldError) Error() string {
+	return fmt.Sprintf("key %q does not match a field of go type %s", e.Key, e.Type)
+}
+
+type UnknownHookError struct {
+	Name string
+}
+
+func (e UnknownHookError) Error() string {
+	return fmt.Sprintf("no decode hook registered with name %q", e.Name)
+}
+
 func cloneStrings(strs []string) []string {
 	return append([]string{}, strs...)
 }
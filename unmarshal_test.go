@@ -1,8 +1,10 @@
 package genjson
 
 import (
+	"errors"
 	"math"
 	"reflect"
+	"strconv"
 	"testing"
 )
 
@@ -136,3 +138,668 @@ func TestUnmarshal(t *testing.T) {
 func indirect(v any) any {
 	return reflect.ValueOf(v).Elem().Interface()
 }
+
+func TestUnmarshalParallelSlice(t *testing.T) {
+	a := make(Array, 1000)
+	want := make([]int, len(a))
+	for i := range a {
+		a[i] = integer(uint64(i))
+		want[i] = i
+	}
+	u := Unmarshaler{SliceWorkers: 4, ParallelSliceMinLen: 10}
+	var got []int
+	if err := u.UnmarshalValue(a, &got); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected result %v", got)
+	}
+
+	a[500] = String("a")
+	a[900] = String("b")
+	err := u.UnmarshalValue(a, &got)
+	var ue UnmarshalError
+	if !errors.As(err, &ue) || !reflect.DeepEqual(ue.Field, Path{Index(500)}) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestParseFieldTag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want fieldTag
+	}{
+		{tag: "", want: fieldTag{}},
+		{tag: "name", want: fieldTag{name: "name"}},
+		{tag: ",omitempty", want: fieldTag{omitEmpty: true}},
+		{tag: "ts,hook=unixms", want: fieldTag{name: "ts", hook: "unixms"}},
+		{tag: "ts,omitempty,hook=unixms", want: fieldTag{name: "ts", omitEmpty: true, hook: "unixms"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			if got := parseFieldTag(tt.tag); got != tt.want {
+				t.Errorf("unexpected tag %+v != %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyHook(t *testing.T) {
+	var u Unmarshaler
+	u.RegisterHook("double", func(_ UnmarshalState, v Value) (Value, error) {
+		n := v.(Number)
+		n.Integer *= 2
+		return n, nil
+	})
+	s := &UnmarshalState{u: &u}
+	v, err := applyHook(s, "double", integer(2))
+	if err != nil || v != integer(4) {
+		t.Errorf("unexpected result %v %v", v, err)
+	}
+	_, err = applyHook(s, "missing", integer(2))
+	var uhe UnknownHookError
+	if !errors.As(err, &uhe) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+type shape interface {
+	area() float64
+}
+
+type square float64
+
+func (s square) area() float64 { return float64(s * s) }
+
+type radius float64
+
+func (r *radius) area() float64 { return 3 * float64(*r**r) }
+
+func TestRegisterImplementation(t *testing.T) {
+	var u Unmarshaler
+	RegisterImplementation[shape](&u, square(0))
+
+	var s shape
+	if err := u.Unmarshal([]byte(`2`), &s); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if s != square(2) {
+		t.Errorf("unexpected value %#v", s)
+	}
+
+	var ss []shape
+	if err := u.Unmarshal([]byte(`[1, null, 3]`), &ss); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if want := []shape{square(1), nil, square(3)}; !reflect.DeepEqual(ss, want) {
+		t.Errorf("unexpected value %#v", ss)
+	}
+
+	var unregistered []interface{ area() int }
+	if err := u.Unmarshal([]byte(`[1]`), &unregistered); err == nil {
+		t.Errorf("expected error for unregistered interface")
+	}
+}
+
+func TestRegisterImplementationPointer(t *testing.T) {
+	var u Unmarshaler
+	RegisterImplementation[shape](&u, new(radius))
+	var ss []shape
+	if err := u.Unmarshal([]byte(`[2, 3]`), &ss); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(ss) != 2 || *ss[0].(*radius) != 2 || *ss[1].(*radius) != 3 || ss[0] == ss[1] {
+		t.Errorf("unexpected result %#v", ss)
+	}
+}
+
+func TestUnmarshalPrecisionLoss(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		in       any
+		epsilon  float64
+		wantLoss bool
+	}{
+		{name: "exact-int-float64", data: `9007199254740992`, in: new(float64)},
+		{name: "inexact-int-float64", data: `9007199254740993`, in: new(float64), wantLoss: true},
+		{name: "inexact-int-float64-epsilon", data: `9007199254740993`, in: new(float64), epsilon: 1},
+		{name: "negative-inexact-int-float64", data: `-9007199254740993`, in: new(float64), wantLoss: true},
+		{name: "exact-float-float32", data: `0.5`, in: new(float32)},
+		{name: "inexact-float-float32", data: `0.1`, in: new(float32), wantLoss: true},
+		{name: "inexact-float-float32-epsilon", data: `0.1`, in: new(float32), epsilon: 1e-6},
+		{name: "inexact-int-float32", data: `16777217`, in: new(float32), wantLoss: true},
+		{name: "float-float64", data: `0.1`, in: new(float64)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := Unmarshaler{CheckPrecisionLoss: true, MaxPrecisionLoss: tt.epsilon}
+			err := u.Unmarshal([]byte(tt.data), tt.in)
+			var ple PrecisionLossError
+			if errors.As(err, &ple) != tt.wantLoss {
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+}
+
+func TestUnmarshalInterface(t *testing.T) {
+	data := []byte(`{"id": 9007199254740993, "neg": -9223372036854775808, "big": 18446744073709551615, "f": 1.5, "a": [true, null, "s"]}`)
+	tests := []struct {
+		name string
+		mode NumberMode
+		want any
+	}{
+		{
+			name: "float64-always",
+			mode: NumberModeFloat64Always,
+			want: map[string]any{
+				"id":  float64(9007199254740993),
+				"neg": float64(math.MinInt64),
+				"big": float64(math.MaxUint64),
+				"f":   1.5,
+				"a":   []any{true, nil, "s"},
+			},
+		},
+		{
+			name: "int64-when-integral",
+			mode: NumberModeInt64WhenIntegral,
+			want: map[string]any{
+				"id":  int64(9007199254740993),
+				"neg": int64(math.MinInt64),
+				"big": float64(math.MaxUint64),
+				"f":   1.5,
+				"a":   []any{true, nil, "s"},
+			},
+		},
+		{
+			name: "keep",
+			mode: NumberModeKeep,
+			want: map[string]any{
+				"id":  integer(9007199254740993),
+				"neg": Number{Integer: 1 << 63, IsNeg: true},
+				"big": integer(math.MaxUint64),
+				"f":   float(1.5),
+				"a":   []any{true, nil, "s"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := Unmarshaler{NumberMode: tt.mode}
+			var got any
+			if err := u.Unmarshal(data, &got); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unexpected result %#v != %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		in   any
+		want error
+	}{
+		{name: "array-length", data: `[1, 2, 3]`, in: new([2]int), want: ArrayLengthError{}},
+		{name: "object", data: `{"a": 1}`, in: new([]int), want: InvalidTypeError{}},
+		{name: "string", data: `"a"`, in: new(int), want: InvalidTypeError{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Unmarshal([]byte(tt.data), tt.in)
+			var ue UnmarshalError
+			if !errors.As(err, &ue) || ue.Loc == nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if reflect.TypeOf(ue.Cause) != reflect.TypeOf(tt.want) {
+				t.Errorf("unexpected cause %#v", ue.Cause)
+			}
+		})
+	}
+}
+
+func TestUnmarshalGoArray(t *testing.T) {
+	var got [3]int
+	if err := Unmarshal([]byte(`[1, 2, 3]`), &got); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if got != [3]int{1, 2, 3} {
+		t.Errorf("unexpected result %v", got)
+	}
+}
+
+func TestUnmarshalMiddleware(t *testing.T) {
+	var u Unmarshaler
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next DecodeFunc) DecodeFunc {
+			return func(s UnmarshalState, value Value, v reflect.Value) error {
+				calls = append(calls, name+":"+s.Path().String())
+				return next(s, value, v)
+			}
+		}
+	}
+	// coerce unmarshals numeric strings into integer targets.
+	coerce := func(next DecodeFunc) DecodeFunc {
+		return func(s UnmarshalState, value Value, v reflect.Value) error {
+			if str, ok := value.(String); ok && v.Kind() == reflect.Int {
+				i, err := strconv.Atoi(string(str))
+				if err != nil {
+					return err
+				}
+				v.SetInt(int64(i))
+				return nil
+			}
+			return next(s, value, v)
+		}
+	}
+	u.Use(trace("a"), trace("b"))
+	u.Use(coerce)
+
+	var got []int
+	if err := u.Unmarshal([]byte(`[1, "2"]`), &got); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("unexpected result %v", got)
+	}
+	if want := []string{"a:", "b:", "a:[0]", "b:[0]", "a:[1]", "b:[1]"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("unexpected calls %v", calls)
+	}
+}
+
+type unmarshalEmbedded struct {
+	E int
+	F string `json:"f"`
+}
+
+type UnmarshalPointerEmbedded struct {
+	P bool
+}
+
+type unmarshalStructTest struct {
+	unmarshalEmbedded
+	*UnmarshalPointerEmbedded
+	A       int    `json:"a"`
+	B       string `json:"b,omitempty"`
+	C       []bool
+	F       string `json:"f"`
+	N       *unmarshalNested
+	Hooked  int `json:"hooked,hook=double"`
+	Skip    int `json:"-"`
+	private int
+}
+
+type unmarshalNested struct {
+	X int `json:"x"`
+}
+
+func TestUnmarshalStruct(t *testing.T) {
+	var u Unmarshaler
+	u.RegisterHook("double", func(_ UnmarshalState, v Value) (Value, error) {
+		n := v.(Number)
+		n.Integer *= 2
+		return n, nil
+	})
+	data := `{
+		"a": 1,
+		"b": "b",
+		"c": [true],
+		"E": 2,
+		"f": "outer",
+		"P": true,
+		"n": {"x": 3},
+		"hooked": 4,
+		"Skip": 5,
+		"private": 6,
+		"unknown": 7,
+		"a": 8
+	}`
+	var got unmarshalStructTest
+	if err := u.Unmarshal([]byte(data), &got); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	want := unmarshalStructTest{
+		unmarshalEmbedded:        unmarshalEmbedded{E: 2},
+		UnmarshalPointerEmbedded: &UnmarshalPointerEmbedded{P: true},
+		A:                        8,
+		B:                        "b",
+		C:                        []bool{true},
+		F:                        "outer",
+		N:                        &unmarshalNested{X: 3},
+		Hooked:                   8,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected result %+v != %+v", got, want)
+	}
+}
+
+func TestUnmarshalStructError(t *testing.T) {
+	data := "{\n  \"n\": {\"x\": \"a\"}\n}"
+	var got unmarshalStructTest
+	err := Unmarshal([]byte(data), &got)
+	var ue UnmarshalError
+	if !errors.As(err, &ue) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(ue.Field, Path{Key("n"), Key("x")}) {
+		t.Errorf("unexpected field %s", ue.Field)
+	}
+	if ue.Loc == nil || ue.Loc.Row != 2 || ue.Loc.Col != 14 {
+		t.Errorf("unexpected loc %v", ue.Loc)
+	}
+	if err.Error() != "unmarshal error n.x 2:14: invalid go type int for json value of type string" {
+		t.Errorf("unexpected message %s", err)
+	}
+}
+
+type mapKey string
+
+func TestUnmarshalMap(t *testing.T) {
+	var got map[string]int
+	if err := Unmarshal([]byte(`{"a": 1, "b": 2, "a": 3}`), &got); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if want := map[string]int{"a": 3, "b": 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected result %v", got)
+	}
+
+	existing := map[mapKey][]int{"a": {1}, "b": {2}}
+	if err := Unmarshal([]byte(`{"a": [3, 4], "c": null}`), &existing); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if want := (map[mapKey][]int{"a": {3, 4}, "b": {2}, "c": nil}); !reflect.DeepEqual(existing, want) {
+		t.Errorf("unexpected result %v", existing)
+	}
+
+	var nested map[string]map[string]any
+	if err := Unmarshal([]byte(`{"x": {"y": [true, "z"]}}`), &nested); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if want := (map[string]map[string]any{"x": {"y": []any{true, "z"}}}); !reflect.DeepEqual(nested, want) {
+		t.Errorf("unexpected result %v", nested)
+	}
+
+	var bad map[string]int
+	err := Unmarshal([]byte(`{"a": 1, "a": "b"}`), &bad)
+	var ue UnmarshalError
+	if !errors.As(err, &ue) || !reflect.DeepEqual(ue.Field, Path{KeyAt("a", 1)}) {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	var intKeys map[int]int
+	if err := Unmarshal([]byte(`{"1": 1}`), &intKeys); !errors.As(err, &ue) {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	var boolKeys map[bool]int
+	if err := Unmarshal([]byte(`{"true": 1}`), &boolKeys); !errors.As(err, &ue) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+type nestedItem struct {
+	ID   int
+	Tags []string `json:",omitempty"`
+}
+
+func TestUnmarshalNestedContainers(t *testing.T) {
+	item := func(id int, tags ...string) nestedItem {
+		return nestedItem{ID: id, Tags: tags}
+	}
+	ptr := func(it nestedItem) *nestedItem {
+		return &it
+	}
+	tests := []iUnmarshalTest{
+		unmarshalTest[map[string][]nestedItem]{
+			name:  "map-of-slices",
+			value: MustDeserializeString(`{"a": [{"ID": 1}, {"ID": 2, "Tags": ["x"]}], "b": [], "c": null}`),
+			want:  map[string][]nestedItem{"a": {item(1), item(2, "x")}, "b": {}, "c": nil},
+		}.i(),
+		unmarshalTest[[]map[string]nestedItem]{
+			name:  "slice-of-maps",
+			value: MustDeserializeString(`[{"a": {"ID": 1}}, {}, null]`),
+			want:  []map[string]nestedItem{{"a": item(1)}, {}, nil},
+		}.i(),
+		unmarshalTest[map[string]*nestedItem]{
+			name:  "map-of-pointers",
+			value: MustDeserializeString(`{"a": {"ID": 1}, "b": null}`),
+			want:  map[string]*nestedItem{"a": ptr(item(1)), "b": nil},
+		}.i(),
+		unmarshalTest[[]map[string]*nestedItem]{
+			name:  "slice-of-maps-of-pointers",
+			value: MustDeserializeString(`[{"a": {"ID": 1}}, {"b": null}]`),
+			want:  []map[string]*nestedItem{{"a": ptr(item(1))}, {"b": nil}},
+		}.i(),
+		unmarshalTest[map[string]map[string][]*nestedItem]{
+			name:  "map-of-maps-of-slices-of-pointers",
+			value: MustDeserializeString(`{"a": {"b": [{"ID": 1}, null]}}`),
+			want:  map[string]map[string][]*nestedItem{"a": {"b": {ptr(item(1)), nil}}},
+		}.i(),
+		unmarshalTest[*map[string]*[]nestedItem]{
+			name:  "pointer-to-map-of-pointers-to-slices",
+			value: MustDeserializeString(`{"a": [{"ID": 1}]}`),
+			want:  &map[string]*[]nestedItem{"a": {item(1)}},
+		}.i(),
+		unmarshalTest[[2]map[string][]int]{
+			name:  "go-array-of-maps",
+			value: MustDeserializeString(`[{"a": [1]}, {"b": []}]`),
+			want:  [2]map[string][]int{{"a": {1}}, {"b": {}}},
+		}.i(),
+		unmarshalTest[[][]map[string]nestedItem]{
+			name:  "slice-of-slices-of-maps",
+			value: MustDeserializeString(`[[{"a": {"ID": 1}}], []]`),
+			want:  [][]map[string]nestedItem{{{"a": item(1)}}, {}},
+		}.i(),
+		unmarshalTest[map[string][]nestedItem]{
+			name:    "nested-type-error",
+			value:   MustDeserializeString(`{"a": [{"ID": "x"}]}`),
+			want:    map[string][]nestedItem{},
+			wantErr: true,
+		}.i(),
+		unmarshalTest[[]map[string]*nestedItem]{
+			name:    "nested-pointer-type-error",
+			value:   MustDeserializeString(`[{"a": 1}]`),
+			wantErr: true,
+		}.i(),
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Unmarshal(Serialize(tt.value), tt.in)
+			if err != nil != tt.wantErr {
+				t.Errorf("unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(tt.want, tt.in) {
+				t.Errorf("unexpected result %+v != %+v", indirect(tt.want), indirect(tt.in))
+			}
+		})
+	}
+}
+
+func TestUnmarshalMergeMapValues(t *testing.T) {
+	type target struct {
+		Items    map[string]nestedItem
+		Pointers map[string]*nestedItem
+		Maps     map[string]map[string]int
+	}
+	data := []byte(`{
+		"Items": {"a": {"Tags": ["y"]}, "b": {"ID": 3}},
+		"Pointers": {"a": {"Tags": ["y"]}, "b": {"ID": 3}},
+		"Maps": {"a": {"x": 2}, "b": {"x": 2}}
+	}`)
+	existing := func() target {
+		return target{
+			Items:    map[string]nestedItem{"a": {ID: 1}, "c": {ID: 2}},
+			Pointers: map[string]*nestedItem{"a": {ID: 1}},
+			Maps:     map[string]map[string]int{"a": {"w": 1}},
+		}
+	}
+
+	replaced := existing()
+	if err := Unmarshal(data, &replaced); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	want := target{
+		Items:    map[string]nestedItem{"a": {Tags: []string{"y"}}, "b": {ID: 3}, "c": {ID: 2}},
+		Pointers: map[string]*nestedItem{"a": {Tags: []string{"y"}}, "b": {ID: 3}},
+		Maps:     map[string]map[string]int{"a": {"x": 2}, "b": {"x": 2}},
+	}
+	if !reflect.DeepEqual(replaced, want) {
+		t.Errorf("unexpected replaced value %+v", replaced)
+	}
+
+	u := Unmarshaler{MergeMapValues: true}
+	merged := existing()
+	pointer := merged.Pointers["a"]
+	if err := u.Unmarshal(data, &merged); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	want = target{
+		Items:    map[string]nestedItem{"a": {ID: 1, Tags: []string{"y"}}, "b": {ID: 3}, "c": {ID: 2}},
+		Pointers: map[string]*nestedItem{"a": {ID: 1, Tags: []string{"y"}}, "b": {ID: 3}},
+		Maps:     map[string]map[string]int{"a": {"w": 1, "x": 2}, "b": {"x": 2}},
+	}
+	if !reflect.DeepEqual(merged, want) {
+		t.Errorf("unexpected merged value %+v", merged)
+	}
+	if merged.Pointers["a"] != pointer {
+		t.Errorf("expected the existing pointer to be reused")
+	}
+}
+
+func TestUnmarshalDisallow(t *testing.T) {
+	u := Unmarshaler{DisallowDuplicateKeys: true, DisallowUnknownFields: true}
+	tests := []struct {
+		name      string
+		data      string
+		in        any
+		want      error
+		wantField Path
+		wantLoc   Loc
+	}{
+		{
+			name:      "duplicate-struct",
+			data:      "{\n  \"n\": {\"x\": 1,\n    \"x\": 2}\n}",
+			in:        new(unmarshalStructTest),
+			want:      DuplicateKeyError{Key: "x"},
+			wantField: Path{Key("n"), KeyAt("x", 1)},
+			wantLoc:   Loc{Row: 3, Col: 5, Offset: 22},
+		},
+		{
+			name:      "duplicate-map",
+			data:      `{"a": 1, "a": 2}`,
+			in:        new(map[string]int),
+			want:      DuplicateKeyError{Key: "a"},
+			wantField: Path{KeyAt("a", 1)},
+			wantLoc:   Loc{Row: 1, Col: 10, Offset: 9},
+		},
+		{
+			name:      "duplicate-interface",
+			data:      `[{"a": {"b": 1, "b": 2}}]`,
+			in:        new(any),
+			want:      DuplicateKeyError{Key: "b"},
+			wantField: Path{Index(0), Key("a"), KeyAt("b", 1)},
+			wantLoc:   Loc{Row: 1, Col: 17, Offset: 16},
+		},
+		{
+			name:      "unknown",
+			data:      `{"a": 1, "z": 2}`,
+			in:        new(unmarshalStructTest),
+			want:      UnknownFieldError{Key: "z", Type: reflect.TypeOf(unmarshalStructTest{})},
+			wantField: Path{Key("z")},
+			wantLoc:   Loc{Row: 1, Col: 10, Offset: 9},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := u.Unmarshal([]byte(tt.data), tt.in)
+			var ue UnmarshalError
+			if !errors.As(err, &ue) || ue.Cause != tt.want {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(ue.Field, tt.wantField) {
+				t.Errorf("unexpected field %s", ue.Field)
+			}
+			if ue.Loc == nil || *ue.Loc != tt.wantLoc {
+				t.Errorf("unexpected loc %v", ue.Loc)
+			}
+		})
+	}
+}
+
+// onOff unmarshals booleans and the strings "on" and "off" using a pointer receiver.
+type onOff bool
+
+func (o *onOff) FromJSON(_ UnmarshalState, v Value) error {
+	switch v {
+	case Bool(true), String("on"):
+		*o = true
+	case Bool(false), String("off"):
+		*o = false
+	default:
+		return errors.New("expected a bool, on or off")
+	}
+	return nil
+}
+
+// recorder records the json of the values it is unmarshaled from using a value receiver.
+type recorder struct {
+	out *[]string
+}
+
+func (r recorder) FromJSON(_ UnmarshalState, v Value) error {
+	*r.out = append(*r.out, string(Serialize(v)))
+	return nil
+}
+
+func TestUnmarshalFrom(t *testing.T) {
+	var got struct {
+		A onOff
+		B *onOff
+		C []onOff
+		D map[string]onOff
+		E any
+		F recorder
+	}
+	var e onOff
+	got.E = &e
+	var recorded []string
+	got.F = recorder{out: &recorded}
+	data := `{"A": "on", "B": true, "C": [false, "on"], "D": {"x": "off"}, "E": "on", "F": 1.5}`
+	if err := Unmarshal([]byte(data), &got); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !bool(got.A) || got.B == nil || !bool(*got.B) || !reflect.DeepEqual(got.C, []onOff{false, true}) ||
+		!reflect.DeepEqual(got.D, map[string]onOff{"x": false}) || !bool(e) {
+		t.Errorf("unexpected result %+v", got)
+	}
+	if !reflect.DeepEqual(recorded, []string{"1.5"}) {
+		t.Errorf("unexpected recorded values %v", recorded)
+	}
+
+	// Value receivers are used for targets that are not addressable.
+	var u Unmarshaler
+	u.Use(func(next DecodeFunc) DecodeFunc {
+		return func(s UnmarshalState, value Value, v reflect.Value) error {
+			return next(s, value, reflect.ValueOf(recorder{out: &recorded}))
+		}
+	})
+	var ignored int
+	if err := u.Unmarshal([]byte(`[true]`), &ignored); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(recorded, []string{"1.5", "[true]"}) {
+		t.Errorf("unexpected recorded values %v", recorded)
+	}
+
+	err := Unmarshal([]byte(`{"C": [true, 1]}`), &got)
+	var ue UnmarshalError
+	if !errors.As(err, &ue) || !reflect.DeepEqual(ue.Field, Path{Key("C"), Index(1)}) || ue.Loc == nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
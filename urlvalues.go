@@ -0,0 +1,200 @@
+package genjson
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Notation is the key notation used for nested values in url values.
+type Notation int8
+
+const (
+	// NotationBracket writes nested keys as a[b][c].
+	NotationBracket Notation = iota
+	// NotationDot writes nested keys as a.b.c.
+	NotationDot
+)
+
+func (n Notation) join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	if n == NotationDot {
+		return prefix + "." + key
+	}
+	return prefix + "[" + key + "]"
+}
+
+func (n Notation) split(key string) []string {
+	if n == NotationDot {
+		return strings.Split(key, ".")
+	}
+	i := strings.IndexByte(key, '[')
+	if i < 0 || !strings.HasSuffix(key, "]") {
+		return []string{key}
+	}
+	parts := []string{key[:i]}
+	return append(parts, strings.Split(key[i+1:len(key)-1], "][")...)
+}
+
+// ToURLValues flattens the object into url values, such as for a query string or form body.
+// Nested objects are written using the notation. Arrays of scalars are written as repeated keys,
+// ending in [] with NotationBracket, and arrays containing objects or arrays use the index of each
+// element as a key, which FromURLValues reads back as an array. Null is written as an empty
+// string and empty arrays and objects are not written.
+func ToURLValues(o Object, n Notation) url.Values {
+	values := url.Values{}
+	addURLValues(values, n, "", o)
+	return values
+}
+
+func addURLValues(values url.Values, n Notation, key string, v Value) {
+	switch v := v.(type) {
+	case Object:
+		iter := v.Iter()
+		for k, vv, ok := iter.Next(); ok; k, vv, ok = iter.Next() {
+			addURLValues(values, n, n.join(key, k), vv)
+		}
+	case Array:
+		scalar := true
+		for _, elem := range v {
+			switch elem.(type) {
+			case Object, Array:
+				scalar = false
+			}
+		}
+		if scalar && n == NotationBracket {
+			key += "[]"
+		}
+		for i, elem := range v {
+			if scalar {
+				addURLValues(values, n, key, elem)
+			} else {
+				addURLValues(values, n, n.join(key, strconv.Itoa(i)), elem)
+			}
+		}
+	case String:
+		values.Add(key, string(v))
+	case Null:
+		values.Add(key, "")
+	default:
+		values.Add(key, string(Serialize(v)))
+	}
+}
+
+// FromURLValues builds an object from url values, using the notation to split keys into nested
+// objects. As url values are untyped, every value is a String. Keys with multiple values become
+// arrays, as do bracket keys ending in [] e.g. a[]. Objects whose keys are the indexes 0 to n-1,
+// as written by ToURLValues for arrays containing objects or arrays, become arrays. Other keys are
+// added in sorted order.
+//
+// ToURLValues and FromURLValues round-trip objects except that:
+//   - scalars are read back as strings, and null as an empty string;
+//   - empty arrays and objects are dropped;
+//   - with NotationDot, an array of one scalar is read back as the scalar;
+//   - objects whose keys are the indexes 0 to n-1 are read back as arrays;
+//   - keys containing the separators of the notation are split.
+func FromURLValues(values url.Values, n Notation) (Object, error) {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var root Object
+	root.init()
+	for _, k := range keys {
+		path := n.split(k)
+		forceArray := false
+		if n == NotationBracket && len(path) > 1 && path[len(path)-1] == "" {
+			path = path[:len(path)-1]
+			forceArray = true
+		}
+		var v Value
+		if vs := values[k]; len(vs) == 1 && !forceArray {
+			v = String(vs[0])
+		} else {
+			a := make(Array, len(vs))
+			for i, s := range vs {
+				a[i] = String(s)
+			}
+			v = a
+		}
+		if err := setURLValue(&root, path, v); err != nil {
+			return Object{}, URLValuesError{Key: k, Err: err}
+		}
+	}
+	return indexedArrays(root), nil
+}
+
+// indexedArrays returns a copy of the object with the nested objects whose keys are the indexes 0
+// to n-1 replaced by arrays of their values.
+func indexedArrays(o Object) Object {
+	var out Object
+	out.init()
+	iter := o.Iter()
+	for k, v, ok := iter.Next(); ok; k, v, ok = iter.Next() {
+		if child, ok := v.(Object); ok {
+			child = indexedArrays(child)
+			if a, ok := indexedArray(child); ok {
+				v = a
+			} else {
+				v = child
+			}
+		}
+		out.Add(k, v)
+	}
+	return out
+}
+
+// indexedArray returns the values of the object as an array if its keys are the indexes 0 to n-1.
+func indexedArray(o Object) (Array, bool) {
+	a := make(Array, o.Len())
+	iter := o.Iter()
+	for k, v, ok := iter.Next(); ok; k, v, ok = iter.Next() {
+		i, err := strconv.Atoi(k)
+		if err != nil || i < 0 || i >= len(a) || strconv.Itoa(i) != k {
+			return nil, false
+		}
+		a[i] = v
+	}
+	return a, len(a) > 0
+}
+
+func setURLValue(o *Object, path []string, v Value) error {
+	existing, ok := o.Get(path[0])
+	if len(path) == 1 {
+		if ok {
+			return fmt.Errorf("key %q is set more than once", path[0])
+		}
+		o.Add(path[0], v)
+		return nil
+	}
+	child, isObject := existing.(Object)
+	if ok && !isObject {
+		return fmt.Errorf("key %q is both a value and an object", path[0])
+	}
+	if !ok {
+		child.init()
+		o.Add(path[0], child)
+	}
+	// Object holds a pointer to its entries so child can be modified in place.
+	return setURLValue(&child, path[1:], v)
+}
+
+// URLValuesError is returned by FromURLValues when a key conflicts with another key.
+type URLValuesError struct {
+	Key string
+	Err error
+}
+
+func (e URLValuesError) Error() string {
+	return fmt.Sprintf("url values key %q: %v", e.Key, e.Err)
+}
+
+func (e URLValuesError) Unwrap() error {
+	return e.Err
+}
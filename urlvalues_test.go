@@ -0,0 +1,137 @@
+package genjson
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestToURLValues(t *testing.T) {
+	v, err := Deserialize([]byte(`{"a": 1, "b": {"c": "x", "d": [true, null]}, "e": [{"f": 2}]}`))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	tests := []struct {
+		notation Notation
+		want     url.Values
+	}{
+		{
+			notation: NotationBracket,
+			want: url.Values{
+				"a":       {"1"},
+				"b[c]":    {"x"},
+				"b[d][]":  {"true", ""},
+				"e[0][f]": {"2"},
+			},
+		},
+		{
+			notation: NotationDot,
+			want: url.Values{
+				"a":     {"1"},
+				"b.c":   {"x"},
+				"b.d":   {"true", ""},
+				"e.0.f": {"2"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		if got := ToURLValues(v.(Object), tt.notation); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("unexpected values %v != %v", got, tt.want)
+		}
+	}
+}
+
+func TestFromURLValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   string
+		notation Notation
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:   "bracket",
+			values: "a=1&b[c]=x&b[d]=y&b[d]=z&e[]=w",
+			want:   `{"a":"1","b":{"c":"x","d":["y","z"]},"e":["w"]}`,
+		},
+		{
+			name:     "dot",
+			values:   "a=1&b.c=x&b.d.e=y",
+			notation: NotationDot,
+			want:     `{"a":"1","b":{"c":"x","d":{"e":"y"}}}`,
+		},
+		{
+			name:   "indexes",
+			values: "a[1][b]=y&a[0][b]=x&c[0]=z&c[2]=w&d[01]=v",
+			want:   `{"a":[{"b":"x"},{"b":"y"}],"c":{"0":"z","2":"w"},"d":{"01":"v"}}`,
+		},
+		{
+			name:     "dot-indexes",
+			values:   "a.0.b=x&a.1=y",
+			notation: NotationDot,
+			want:     `{"a":[{"b":"x"},"y"]}`,
+		},
+		{
+			name:    "conflict",
+			values:  "a=1&a[b]=2",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.values)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			o, err := FromURLValues(values, tt.notation)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if err != nil {
+				return
+			}
+			if got := string(Serialize(o)); got != tt.want {
+				t.Errorf("unexpected object %s != %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestURLValuesRoundTrip(t *testing.T) {
+	// url values are untyped, so scalars are read back as strings.
+	for _, data := range []string{
+		`{"a":[{"b":"1"}]}`,
+		`{"a":[{"b":"1","c":["x","y"]},[{"d":"2"}]],"e":{"f":"3"}}`,
+	} {
+		for _, n := range []Notation{NotationBracket, NotationDot} {
+			o, err := FromURLValues(ToURLValues(MustDeserializeString(data).(Object), n), n)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if got := string(Serialize(o)); got != data {
+				t.Errorf("unexpected object %s != %s", got, data)
+			}
+		}
+	}
+
+	// The lossy cases documented by FromURLValues.
+	for _, tt := range []struct {
+		data     string
+		notation Notation
+		want     string
+	}{
+		{data: `{"a":[{"b":1}],"c":null}`, notation: NotationBracket, want: `{"a":[{"b":"1"}],"c":""}`},
+		{data: `{"a":["x"]}`, notation: NotationBracket, want: `{"a":["x"]}`},
+		{data: `{"a":["x"]}`, notation: NotationDot, want: `{"a":"x"}`},
+		{data: `{"a":[["x"],["y","z"]]}`, notation: NotationBracket, want: `{"a":[["x"],["y","z"]]}`},
+		{data: `{"a":[["x"],["y","z"]]}`, notation: NotationDot, want: `{"a":["x",["y","z"]]}`},
+		{data: `{"a":[],"b":{},"c":{"d":[]},"e":"1"}`, notation: NotationBracket, want: `{"e":"1"}`},
+		{data: `{"a":{"0":"x","1":"y"}}`, notation: NotationDot, want: `{"a":["x","y"]}`},
+		{data: `{"a.b":"x"}`, notation: NotationDot, want: `{"a":{"b":"x"}}`},
+	} {
+		o, err := FromURLValues(ToURLValues(MustDeserializeString(tt.data).(Object), tt.notation), tt.notation)
+		if err != nil || string(Serialize(o)) != tt.want {
+			t.Errorf("%s: unexpected result %s %v", tt.data, Serialize(o), err)
+		}
+	}
+}